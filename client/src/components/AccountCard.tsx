@@ -8,7 +8,7 @@ import {
 } from '@mui/material'
 import KeyboardArrowRightIcon from '@mui/icons-material/KeyboardArrowRight'
 import { type Account } from '../lib/api'
-import moneyFormatter from '../lib/formatter'
+import { formatAccountValue } from '../lib/formatter'
 import { Link } from 'react-router-dom'
 
 interface Props {
@@ -55,7 +55,7 @@ export default function AccountCard ({ account }: Props): React.ReactElement {
                 paddingTop={3}
               >
                 {account.values.length > 0 &&
-                  moneyFormatter.format(Number(account.values[0].value))}
+                  formatAccountValue(account, Number(account.values[0].value))}
               </Typography>
             </Box>
             <Box sx={{
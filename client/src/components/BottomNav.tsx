@@ -5,7 +5,8 @@ import BottomNavigationAction from '@mui/material/BottomNavigationAction'
 import PaidIcon from '@mui/icons-material/Paid'
 import CreditCardIcon from '@mui/icons-material/CreditCard'
 import { useLocation, Link } from 'react-router-dom'
-import { Home } from '@mui/icons-material'
+import { Home, Logout as LogoutIcon } from '@mui/icons-material'
+import { Logout } from '../lib/api'
 
 export default function BottomNav (): React.ReactElement {
   return (
@@ -38,6 +39,15 @@ export default function BottomNav (): React.ReactElement {
           value='/liabilities'
           reloadDocument
         />
+        <BottomNavigationAction
+          label='Log out'
+          icon={<LogoutIcon/>}
+          onClick={() => {
+            Logout()
+              .then(() => { window.location.assign('/login') })
+              .catch(console.error)
+          }}
+        />
       </BottomNavigation>
     </Paper>
   )
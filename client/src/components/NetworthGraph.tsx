@@ -49,6 +49,7 @@ const chartOptions = {
 
 export default function NetworthGraph (): React.ReactElement {
   const [networth, setNetworth] = React.useState<NetworthPoint[]>()
+  const [myNetworth, setMyNetworth] = React.useState<NetworthPoint[]>()
 
   React.useEffect(() => {
     GetNetWorth()
@@ -56,6 +57,11 @@ export default function NetworthGraph (): React.ReactElement {
         setNetworth(nw)
       })
       .catch(console.error)
+    GetNetWorth('me')
+      .then((nw) => {
+        setMyNetworth(nw)
+      })
+      .catch(console.error)
   }, [])
 
   if (networth == null) {
@@ -86,6 +92,11 @@ export default function NetworthGraph (): React.ReactElement {
       <Typography variant="h3" color={'black'}>
         {moneyFormatter.format(data.datasets[0].data[data.datasets[0].data.length - 1])}
       </Typography>
+      {myNetworth != null && myNetworth.length > 0 &&
+        <Typography variant="subtitle1" color={'black'}>
+          My share: {moneyFormatter.format(myNetworth[0].value)}
+        </Typography>
+      }
       <Paper sx={{ backgroundColor: 'whitesmoke', borderRadius: 3, padding: 2, width: 330, margin: 2 }}>
         <Line options={chartOptions} data={data}/>
       </Paper>
@@ -12,6 +12,9 @@ import {
 import BottomNav from './components/BottomNav'
 import Home from './views/Home'
 import AccountView from './views/AccountView'
+import LoginView from './views/LoginView'
+import JoinView from './views/JoinView'
+import TokensView from './views/TokensView'
 
 function Base (children?: React.ReactElement): React.ReactElement {
   const darkTheme = createTheme({
@@ -54,6 +57,18 @@ const router = createBrowserRouter([
   {
     path: '/accounts',
     element: Base(<AccountView/>)
+  },
+  {
+    path: '/login',
+    element: Base(<LoginView/>)
+  },
+  {
+    path: '/join',
+    element: Base(<JoinView/>)
+  },
+  {
+    path: '/tokens',
+    element: Base(<TokensView/>)
   }
 ])
 
@@ -1,42 +1,303 @@
 import axios from 'axios'
 
 export interface Account {
+  id: number
   name: string
   class: string
   category: string
   taxBucket: string
+  currency: string
+  precision?: number
   values: AccountValue[]
+  owners?: AccountOwner[]
 }
 
+// AccountOwner gives a household member a percentage share of an account.
+export interface AccountOwner {
+  account_id: number
+  user_id: number
+  share: string
+}
+
+// Member selects whose share of the accounts to return, a user id or 'me' for
+// the logged in user. Leaving it out returns the joint total.
+export type Member = number | 'me'
+
+const memberQuery = (member?: Member): string => member === undefined ? '' : `member=${member}`
+
 export interface AccountValue {
+  id: number
+  account_id: number
   value: string
+  as_of: string
   CreatedAt: string
 }
 
+export interface User {
+  id: number
+  household_id: number
+  username: string
+  admin: boolean
+}
+
+export interface Household {
+  id: number
+  name: string
+  members: User[]
+}
+
+export interface Invitation {
+  token: string
+  expires_at: string
+}
+
+// APIToken lets scripts call the API with an "Authorization: Bearer" header.
+// token is only set in the response that creates it.
+export interface APIToken {
+  id: number
+  name: string
+  scope: 'read' | 'write'
+  last_used_at: string | null
+  created_at: string
+  token?: string
+}
+
 export interface NetworthPoint {
   date: Date
   value: number
 }
 
+export interface NetworthSeries {
+  group: string
+  points: NetworthPoint[]
+}
+
+export interface NetworthBreakdown {
+  groupBy: string
+  total: NetworthPoint[]
+  groups: NetworthSeries[]
+}
+
+export interface Security {
+  id: number
+  symbol: string
+  name: string
+  currency: string
+}
+
+export interface Price {
+  id: number
+  security_id: number
+  date: string
+  price: number
+}
+
+export interface Holding {
+  security: Security
+  quantity: number
+  cost_basis: number
+  price: Price | null
+  value: number
+}
+
+export interface AccountHoldings {
+  date: string
+  value: number
+  holdings: Holding[]
+}
+
+export type CashFlowType = 'contribution' | 'withdrawal' | 'transfer' | 'interest' | 'fee'
+
+export interface CashFlow {
+  id: number
+  account_id: number
+  type: CashFlowType
+  amount: number
+  date: string
+  memo: string
+}
+
+export interface BalanceChange {
+  date: string
+  start: number
+  end: number
+  change: number
+  contributions: number
+  withdrawals: number
+  transfers: number
+  netFlows: number
+  return: number
+  interest: number
+  fees: number
+  growth: number
+}
+
+export interface Performance {
+  start: number
+  end: number
+  netFlows: number
+  gain: number
+  timeWeighted: number | null
+  xirr: number | null
+}
+
+export interface AccountPerformance extends Performance {
+  accountId: number
+  account: string
+}
+
+export interface GroupPerformance extends Performance {
+  group: string
+}
+
+export interface PerformanceReport {
+  from: string
+  to: string
+  currency: string
+  groupBy?: 'class' | 'category' | 'taxBucket'
+  accounts: AccountPerformance[]
+  groups?: GroupPerformance[]
+  total: Performance
+}
+
+export interface GainTotals {
+  shortTerm: number
+  longTerm: number
+  total: number
+}
+
+export interface AccountGains extends GainTotals {
+  accountId: number
+  account: string
+  year?: number
+}
+
+export interface GroupGains extends GainTotals {
+  group: string
+}
+
+export interface RealizedGain {
+  accountId: number
+  saleId: number
+  lotId: number
+  symbol: string
+  currency: string
+  quantity: number
+  acquiredAt: string
+  soldAt: string
+  cost: number
+  proceeds: number
+  gain: number
+  term: 'short' | 'long'
+}
+
+export interface RealizedGainsReport {
+  currency: string
+  gains: RealizedGain[]
+  accounts: AccountGains[]
+  total: GainTotals
+}
+
+export interface UnrealizedGain {
+  accountId: number
+  lotId: number
+  symbol: string
+  currency: string
+  quantity: number
+  acquiredAt: string
+  cost: number
+  price: number | null
+  value: number
+  gain: number
+  term: 'short' | 'long'
+}
+
+export interface UnrealizedGainsReport {
+  date: string
+  currency: string
+  gains: UnrealizedGain[]
+  accounts: AccountGains[]
+  taxBuckets: GroupGains[]
+  total: GainTotals
+}
+
 const client = axios.create({
   baseURL: 'http://localhost:8080/api/',
   headers: {
     'Content-Type': 'application/json'
+  },
+  withCredentials: true
+})
+
+// Pages that can be used without logging in.
+const publicPaths = ['/login', '/join']
+
+// Send anyone who is not logged in to the login page.
+client.interceptors.response.use(undefined, async (error) => {
+  if (axios.isAxiosError(error) && error.response?.status === 401 && !publicPaths.includes(window.location.pathname)) {
+    window.location.assign('/login')
   }
+  return await Promise.reject(error)
 })
 
+export const Login = async (username: string, password: string): Promise<User> => {
+  const response = await client.post<User>('auth/login', { username, password })
+  return response.data
+}
+
+export const Logout = async (): Promise<void> => {
+  await client.post('auth/logout')
+}
+
+export const JoinHousehold = async (token: string, username: string, password: string): Promise<User> => {
+  const response = await client.post<User>('auth/join', { token, username, password })
+  return response.data
+}
+
+export const GetHousehold = async (): Promise<Household> => {
+  const response = await client.get<Household>('household')
+  return response.data
+}
+
+export const CreateInvitation = async (): Promise<Invitation> => {
+  const response = await client.post<Invitation>('household/invitations')
+  return response.data
+}
+
+export const GetAPITokens = async (): Promise<APIToken[]> => {
+  const response = await client.get<APIToken[]>('tokens')
+  return response.data
+}
+
+export const CreateAPIToken = async (name: string, scope: 'read' | 'write'): Promise<APIToken> => {
+  const response = await client.post<APIToken>('tokens', { name, scope })
+  return response.data
+}
+
+export const RevokeAPIToken = async (id: number): Promise<void> => {
+  await client.delete(`tokens/${id}`)
+}
+
 export const GetAccountByName = async (name: string): Promise<Account> => {
   const response = await client.get<Account>(`accounts?name=${name}`)
   return response.data
 }
 
-export const GetAllAccounts = async (): Promise<Account[]> => {
-  const response = await client.get<Account[]>('accounts')
+export const GetAllAccounts = async (member?: Member): Promise<Account[]> => {
+  const response = await client.get<Account[]>(`accounts?${memberQuery(member)}`)
   return response.data
 }
 
-export const GetNetWorth = async (): Promise<NetworthPoint[]> => {
-  const response = await client.get<NetworthPoint[]>('networth')
+// GetNetWorth reports net worth in currency, USD unless given, converting
+// balances held in other currencies.
+export const GetNetWorth = async (member?: Member, currency?: string): Promise<NetworthPoint[]> => {
+  const query = [memberQuery(member), currency === undefined ? '' : `currency=${currency}`]
+  const response = await client.get<NetworthPoint[]>(`networth?${query.filter(q => q !== '').join('&')}`)
+  return response.data
+}
+
+export const GetNetWorthBreakdown = async (groupBy: 'class' | 'category' | 'taxBucket'): Promise<NetworthBreakdown> => {
+  const response = await client.get<NetworthBreakdown>(`networth?groupBy=${groupBy}`)
   return response.data
 }
 
@@ -44,3 +305,60 @@ export const GetAllAccountsByClass = async (cls: string): Promise<Account[]> =>
   const response = await client.get<Account[]>(`accounts?class=${encodeURIComponent(cls)}`)
   return response.data
 }
+
+// GetAccountHoldings values what the account holds on date (YYYY-MM-DD),
+// today unless given.
+export const GetAccountHoldings = async (id: number, date?: string): Promise<AccountHoldings> => {
+  const query = date === undefined ? '' : `?date=${date}`
+  const response = await client.get<AccountHoldings>(`accounts/${id}/holdings${query}`)
+  return response.data
+}
+
+// GetRealizedGains reports gains on sales, for a single year or account if
+// given.
+export const GetRealizedGains = async (year?: number, account?: number): Promise<RealizedGainsReport> => {
+  const params = new URLSearchParams()
+  if (year !== undefined) params.set('year', String(year))
+  if (account !== undefined) params.set('account', String(account))
+  const response = await client.get<RealizedGainsReport>(`gains/realized?${params.toString()}`)
+  return response.data
+}
+
+// GetUnrealizedGains reports gains on the lots held on date (YYYY-MM-DD),
+// today unless given.
+export const GetUnrealizedGains = async (date?: string, account?: number): Promise<UnrealizedGainsReport> => {
+  const params = new URLSearchParams()
+  if (date !== undefined) params.set('date', date)
+  if (account !== undefined) params.set('account', String(account))
+  const response = await client.get<UnrealizedGainsReport>(`gains/unrealized?${params.toString()}`)
+  return response.data
+}
+
+export const GetCashFlows = async (accountId: number): Promise<CashFlow[]> => {
+  const response = await client.get<CashFlow[]>(`accounts/${accountId}/cashflows`)
+  return response.data
+}
+
+export const CreateCashFlow = async (accountId: number, flow: Pick<CashFlow, 'type' | 'amount' | 'date' | 'memo'>): Promise<CashFlow> => {
+  const response = await client.post<CashFlow>(`accounts/${accountId}/cashflows`, flow)
+  return response.data
+}
+
+// GetBalanceChanges splits each month's change in net worth, or in a single
+// account's balance, into net cash flows and investment return.
+export const GetBalanceChanges = async (account?: number): Promise<BalanceChange[]> => {
+  const query = account === undefined ? '' : `?account=${account}`
+  const response = await client.get<BalanceChange[]>(`networth/changes${query}`)
+  return response.data
+}
+
+// GetPerformance returns the time-weighted return and XIRR of each account,
+// and of each group when grouped, between from and to (YYYY-MM-DD).
+export const GetPerformance = async (from?: string, to?: string, groupBy?: 'class' | 'category' | 'taxBucket'): Promise<PerformanceReport> => {
+  const params = new URLSearchParams()
+  if (from !== undefined) params.set('from', from)
+  if (to !== undefined) params.set('to', to)
+  if (groupBy !== undefined) params.set('groupBy', groupBy)
+  const response = await client.get<PerformanceReport>(`performance?${params.toString()}`)
+  return response.data
+}
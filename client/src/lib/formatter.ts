@@ -1,6 +1,21 @@
+import { type Account } from './api'
+
 const moneyFormatter = new Intl.NumberFormat('en-US', {
   style: 'currency',
   currency: 'USD'
 })
 
+// formatAccountValue formats a value of the account in its currency, to the
+// account's precision when it has one and the currency's usual one otherwise.
+export const formatAccountValue = (account: Account, value: number): string => {
+  const digits = account.precision === undefined
+    ? {}
+    : { minimumFractionDigits: account.precision, maximumFractionDigits: account.precision }
+  return new Intl.NumberFormat('en-US', {
+    style: 'currency',
+    currency: account.currency,
+    ...digits
+  }).format(value)
+}
+
 export default moneyFormatter
@@ -23,7 +23,7 @@ import {
 } from 'chart.js'
 import { Line } from 'react-chartjs-2'
 import { type Account, GetAccountByName } from '../lib/api'
-import moneyFormatter from '../lib/formatter'
+import { formatAccountValue } from '../lib/formatter'
 import { useSearchParams } from 'react-router-dom'
 
 ChartJS.register(
@@ -82,7 +82,7 @@ export default function AccountView (): React.ReactElement {
   }
 
   for (const value of account.values) {
-    data.labels.unshift(new Date(value.CreatedAt).toLocaleString())
+    data.labels.unshift(new Date(value.as_of).toLocaleString())
     data.datasets[0].data.unshift(Number(value.value))
   }
 
@@ -126,8 +126,8 @@ export default function AccountView (): React.ReactElement {
                     key={i}
                     sx={{ '&:last-child td, &:last-child th': { border: 0 } }}
                   >
-                    <TableCell component="th" scope="row" sx={{ color: 'black' }}>{new Date(value.CreatedAt).toLocaleString()}</TableCell>
-                    <TableCell sx={{ color: 'black' }}>{moneyFormatter.format(Number(value.value))}</TableCell>
+                    <TableCell component="th" scope="row" sx={{ color: 'black' }}>{new Date(value.as_of).toLocaleString()}</TableCell>
+                    <TableCell sx={{ color: 'black' }}>{formatAccountValue(account, Number(value.value))}</TableCell>
                   </TableRow>
                 ))
               }
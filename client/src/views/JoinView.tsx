@@ -0,0 +1,69 @@
+import React from 'react'
+import {
+  Alert,
+  Button,
+  Grid,
+  TextField,
+  Typography
+} from '@mui/material'
+import { useSearchParams } from 'react-router-dom'
+import axios from 'axios'
+import { JoinHousehold } from '../lib/api'
+
+// JoinView creates a user in a household from the invitation token in the
+// link someone in the household shared.
+export default function JoinView (): React.ReactElement {
+  const [searchParams] = useSearchParams()
+  const [username, setUsername] = React.useState('')
+  const [password, setPassword] = React.useState('')
+  const [error, setError] = React.useState<string>()
+
+  const onSubmit = (event: React.FormEvent): void => {
+    event.preventDefault()
+    JoinHousehold(searchParams.get('token') ?? '', username, password)
+      .then(() => {
+        window.location.assign('/')
+      })
+      .catch((err) => {
+        if (axios.isAxiosError(err) && err.response?.data?.error !== undefined) {
+          setError(err.response.data.error)
+        } else {
+          setError('Could not join the household')
+        }
+      })
+  }
+
+  return (
+    <Grid
+      container
+      justifyContent="center"
+      alignItems="center"
+      direction={'column'}
+      flex={1}
+      marginTop={6}
+    >
+      <Grid item component="form" onSubmit={onSubmit}>
+        <Typography variant="h4" color={'black'} marginBottom={2}>Join household</Typography>
+        {error !== undefined && <Alert severity="error">{error}</Alert>}
+        <TextField
+          label="Username"
+          value={username}
+          onChange={(e) => { setUsername(e.target.value) }}
+          autoComplete="username"
+          margin="normal"
+          fullWidth
+        />
+        <TextField
+          label="Password"
+          type="password"
+          value={password}
+          onChange={(e) => { setPassword(e.target.value) }}
+          autoComplete="new-password"
+          margin="normal"
+          fullWidth
+        />
+        <Button type="submit" variant="contained" fullWidth>Join</Button>
+      </Grid>
+    </Grid>
+  )
+}
@@ -0,0 +1,60 @@
+import React from 'react'
+import {
+  Alert,
+  Button,
+  Grid,
+  TextField,
+  Typography
+} from '@mui/material'
+import { Login } from '../lib/api'
+
+export default function LoginView (): React.ReactElement {
+  const [username, setUsername] = React.useState('')
+  const [password, setPassword] = React.useState('')
+  const [error, setError] = React.useState<string>()
+
+  const onSubmit = (event: React.FormEvent): void => {
+    event.preventDefault()
+    Login(username, password)
+      .then(() => {
+        window.location.assign('/')
+      })
+      .catch(() => {
+        setError('Invalid username or password')
+      })
+  }
+
+  return (
+    <Grid
+      container
+      justifyContent="center"
+      alignItems="center"
+      direction={'column'}
+      flex={1}
+      marginTop={6}
+    >
+      <Grid item component="form" onSubmit={onSubmit}>
+        <Typography variant="h4" color={'black'} marginBottom={2}>Log in</Typography>
+        {error !== undefined && <Alert severity="error">{error}</Alert>}
+        <TextField
+          label="Username"
+          value={username}
+          onChange={(e) => { setUsername(e.target.value) }}
+          autoComplete="username"
+          margin="normal"
+          fullWidth
+        />
+        <TextField
+          label="Password"
+          type="password"
+          value={password}
+          onChange={(e) => { setPassword(e.target.value) }}
+          autoComplete="current-password"
+          margin="normal"
+          fullWidth
+        />
+        <Button type="submit" variant="contained" fullWidth>Log in</Button>
+      </Grid>
+    </Grid>
+  )
+}
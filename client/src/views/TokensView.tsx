@@ -0,0 +1,112 @@
+import React from 'react'
+import {
+  Alert,
+  Button,
+  Grid,
+  List,
+  ListItem,
+  ListItemText,
+  MenuItem,
+  TextField,
+  Typography
+} from '@mui/material'
+import axios from 'axios'
+import { type APIToken, CreateAPIToken, GetAPITokens, RevokeAPIToken } from '../lib/api'
+
+// TokensView lists the logged in user's API tokens and creates and revokes
+// them. A new token is shown once, right after it is created.
+export default function TokensView (): React.ReactElement {
+  const [tokens, setTokens] = React.useState<APIToken[]>([])
+  const [name, setName] = React.useState('')
+  const [scope, setScope] = React.useState<'read' | 'write'>('read')
+  const [created, setCreated] = React.useState<APIToken>()
+  const [error, setError] = React.useState<string>()
+
+  const refresh = (): void => {
+    GetAPITokens()
+      .then(setTokens)
+      .catch(console.error)
+  }
+
+  React.useEffect(refresh, [])
+
+  const onSubmit = (event: React.FormEvent): void => {
+    event.preventDefault()
+    CreateAPIToken(name, scope)
+      .then((token) => {
+        setCreated(token)
+        setName('')
+        setError(undefined)
+        refresh()
+      })
+      .catch((err) => {
+        if (axios.isAxiosError(err) && err.response?.data?.error !== undefined) {
+          setError(err.response.data.error)
+        } else {
+          setError('Could not create the token')
+        }
+      })
+  }
+
+  const onRevoke = (id: number): void => {
+    RevokeAPIToken(id)
+      .then(refresh)
+      .catch(console.error)
+  }
+
+  return (
+    <Grid
+      container
+      justifyContent="center"
+      alignItems="center"
+      direction={'column'}
+      flex={1}
+      marginTop={6}
+    >
+      <Grid item component="form" onSubmit={onSubmit}>
+        <Typography variant="h4" color={'black'} marginBottom={2}>API tokens</Typography>
+        {error !== undefined && <Alert severity="error">{error}</Alert>}
+        {created?.token !== undefined &&
+          <Alert severity="success">
+            Copy the token for {created.name} now, it will not be shown again: {created.token}
+          </Alert>
+        }
+        <TextField
+          label="Name"
+          value={name}
+          onChange={(e) => { setName(e.target.value) }}
+          margin="normal"
+          fullWidth
+        />
+        <TextField
+          select
+          label="Scope"
+          value={scope}
+          onChange={(e) => { setScope(e.target.value as 'read' | 'write') }}
+          margin="normal"
+          fullWidth
+        >
+          <MenuItem value="read">Read only</MenuItem>
+          <MenuItem value="write">Read and write</MenuItem>
+        </TextField>
+        <Button type="submit" variant="contained" fullWidth>Create token</Button>
+      </Grid>
+      <Grid item>
+        <List>
+          {tokens.map((token) => (
+            <ListItem
+              key={token.id}
+              secondaryAction={<Button onClick={() => { onRevoke(token.id) }}>Revoke</Button>}
+            >
+              <ListItemText
+                sx={{ color: 'black' }}
+                primary={`${token.name} (${token.scope})`}
+                secondary={token.last_used_at === null ? 'Never used' : `Last used ${new Date(token.last_used_at).toLocaleString()}`}
+              />
+            </ListItem>
+          ))}
+        </List>
+      </Grid>
+    </Grid>
+  )
+}
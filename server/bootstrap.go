@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Jrc356/financial_dashboard/migrations"
+	"github.com/Jrc356/financial_dashboard/models"
+	"gorm.io/gorm"
+)
+
+// bootstrapCommand creates the first user, an admin of the first household. It
+// refuses to run once a user exists so it cannot be used to get around logging
+// in.
+func bootstrapCommand(db *gorm.DB, args []string) {
+	flags := flag.NewFlagSet("bootstrap", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: bootstrap USERNAME")
+		fmt.Fprintln(flags.Output(), "The password is read from BOOTSTRAP_PASSWORD, or the first line of stdin if that is unset.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if _, err := migrations.Up(db); err != nil {
+		log.Fatal(err)
+	}
+
+	count, err := models.CountUsers(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if count > 0 {
+		log.Fatal("users already exist, bootstrap only creates the first user")
+	}
+
+	password := os.Getenv("BOOTSTRAP_PASSWORD")
+	if password == "" {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("reading password: %s", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	household := lookupHousehold(db, 0)
+	user, err := models.CreateUser(db, household.ID, flags.Arg(0), password, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("created admin user %s", user.Username)
+}
@@ -1,33 +1,50 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 type AccountController struct {
-	DB *gorm.DB
+	Store store.Store
 }
 
-func NewAccountController(db *gorm.DB, router *gin.RouterGroup) AccountController {
-	accountController := AccountController{DB: db}
+func NewAccountController(s store.Store, router *gin.RouterGroup) AccountController {
+	accountController := AccountController{Store: s}
 
 	accountRouter := router.Group("/accounts")
 	{
 		accountRouter.GET("", accountController.GetAccounts)
 		accountRouter.POST("", accountController.CreateOrUpdateAccount)
 		accountRouter.DELETE("", accountController.DeleteAccount)
+		accountRouter.GET("/:id", accountController.GetAccount)
+		accountRouter.PUT("/:id", accountController.UpdateAccount)
+		accountRouter.DELETE("/:id", accountController.DeleteAccountByID)
 
 		accountRouter.POST("/value", accountController.CreateAccountValue)
+		accountRouter.GET("/value/:id", accountController.GetAccountValue)
+		accountRouter.PATCH("/value/:id", accountController.UpdateAccountValue)
+		accountRouter.DELETE("/value/:id", accountController.DeleteAccountValue)
 	}
 
 	return accountController
 }
 
 func (controller *AccountController) CreateOrUpdateAccount(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
 	var account models.Account
 
 	if err := context.BindJSON(&account); err != nil {
@@ -41,22 +58,26 @@ func (controller *AccountController) CreateOrUpdateAccount(context *gin.Context)
 		return
 	}
 
-	exists, err := models.AccountExists(controller.DB, account.Name)
+	exists, err := controller.Store.AccountExists(household, account.Name)
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	if !exists {
-		err := models.CreateAccount(controller.DB, account)
-		if err != nil {
+		account, err := controller.Store.CreateAccount(household, account)
+		if errors.Is(err, models.ErrNotHouseholdMember) {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err != nil {
 			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		} else {
 			context.JSON(http.StatusCreated, account)
 		}
 	} else {
 		var err error
-		account, err = models.UpdateAccount(controller.DB, account.Name, account)
-		if err != nil {
+		account, err = controller.Store.UpdateAccount(household, account.Name, account)
+		if errors.Is(err, models.ErrNotHouseholdMember) {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err != nil {
 			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		} else {
 			context.JSON(http.StatusOK, account)
@@ -64,13 +85,27 @@ func (controller *AccountController) CreateOrUpdateAccount(context *gin.Context)
 	}
 }
 
+// GetAccounts returns the household's accounts, or the one with the given
+// name. With member set to a user ID, or me for the logged in user, only the
+// accounts that member owns a share of are returned, with their values scaled
+// to that share, and a named account the member has no share of is not found.
 func (controller *AccountController) GetAccounts(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	member, ok := parseMember(context, controller.Store, household)
+	if !ok {
+		return
+	}
+
 	name := context.Query("name")
 	var class models.AccountClass = models.AccountClass(context.Query("class"))
 
 	if name != "" {
-		account, err := models.GetAccountByNameWithValues(controller.DB, name)
-		if err == gorm.ErrRecordNotFound {
+		account, err := controller.Store.GetAccountByNameWithValues(household, name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			context.AbortWithStatusJSON(http.StatusNotFound, account)
 			return
 		}
@@ -78,7 +113,12 @@ func (controller *AccountController) GetAccounts(context *gin.Context) {
 			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		context.JSON(http.StatusOK, account)
+		owned := roundValues(forMember([]models.Account{account}, member), member)
+		if len(owned) == 0 {
+			context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("member %d has no share of account %s", member, name)})
+			return
+		}
+		context.JSON(http.StatusOK, owned[0])
 		return
 	}
 
@@ -88,31 +128,126 @@ func (controller *AccountController) GetAccounts(context *gin.Context) {
 			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		accounts, err := models.GetAllAccountsByClassWithValues(controller.DB, class)
+		accounts, err := controller.Store.GetAllAccountsByClassWithValues(household, class)
 		if err != nil {
 			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		context.JSON(http.StatusOK, accounts)
+		context.JSON(http.StatusOK, roundValues(forMember(accounts, member), member))
+		return
+	}
+
+	accounts, err := controller.Store.GetAllAccountsWithValues(household)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, roundValues(forMember(accounts, member), member))
+}
+
+func (controller *AccountController) GetAccount(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
 		return
 	}
 
-	accounts, err := models.GetAllAccountsWithValues(controller.DB)
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	account, err := controller.Store.GetAccountWithValues(household, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	context.JSON(http.StatusOK, accounts)
+	context.JSON(http.StatusOK, account)
+}
+
+// UpdateAccount replaces the details of the account with the given ID. A
+// different name in the body renames the account.
+func (controller *AccountController) UpdateAccount(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	var updates models.Account
+	if err := context.BindJSON(&updates); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.ValidateAccount(updates); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, err := controller.Store.UpdateAccountByID(household, id, updates)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrAccountNameTaken) {
+		context.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrNotHouseholdMember) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, account)
+}
+
+func (controller *AccountController) DeleteAccountByID(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	account, err := controller.Store.DeleteAccountByID(household, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, account)
 }
 
 func (controller *AccountController) DeleteAccount(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
 	name := context.Query("name")
 	if name == "" {
 		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Unset parameter 'name' required."})
 		return
 	}
 
-	exists, err := models.AccountExists(controller.DB, name)
+	exists, err := controller.Store.AccountExists(household, name)
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusInternalServerError, "account does not exist")
 		return
@@ -121,7 +256,7 @@ func (controller *AccountController) DeleteAccount(context *gin.Context) {
 		context.AbortWithStatusJSON(http.StatusNotFound, "account does not exist")
 		return
 	} else {
-		account, err := models.DeleteAccount(controller.DB, name)
+		account, err := controller.Store.DeleteAccount(household, name)
 		if err != nil {
 			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -131,6 +266,11 @@ func (controller *AccountController) DeleteAccount(context *gin.Context) {
 }
 
 func (controller *AccountController) CreateAccountValue(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
 	var accountValue models.AccountValue
 
 	if err := context.BindJSON(&accountValue); err != nil {
@@ -143,7 +283,7 @@ func (controller *AccountController) CreateAccountValue(context *gin.Context) {
 		return
 	}
 
-	exists, err := models.AccountExists(controller.DB, accountValue.AccountName)
+	exists, err := controller.Store.AccountExistsByID(household, accountValue.AccountID)
 	if err != nil {
 		context.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
 		return
@@ -152,7 +292,7 @@ func (controller *AccountController) CreateAccountValue(context *gin.Context) {
 		context.AbortWithStatusJSON(http.StatusBadRequest, "Account does not exist")
 		return
 	} else {
-		err := models.CreateAccountValue(controller.DB, accountValue)
+		accountValue, err := controller.Store.CreateAccountValue(household, accountValue)
 		if err != nil {
 			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -160,3 +300,193 @@ func (controller *AccountController) CreateAccountValue(context *gin.Context) {
 		context.JSON(http.StatusOK, accountValue)
 	}
 }
+
+// accountValueUpdate holds the fields of an account value that may be changed
+// with a PATCH. Fields left out of the request body are not modified.
+type accountValueUpdate struct {
+	AccountID *uint            `json:"account_id"`
+	Value     *decimal.Decimal `json:"value"`
+	AsOf      *time.Time       `json:"as_of"`
+}
+
+func parseID(context *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(context.Param("id"), 10, 0)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id: " + context.Param("id")})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseMember reads the member query parameter, a user ID or me for the
+// logged in user, and checks that they belong to the household. It returns 0
+// when no member is given.
+func parseMember(context *gin.Context, s store.Store, householdID uint) (uint, bool) {
+	member := context.Query("member")
+	if member == "" {
+		return 0, true
+	}
+
+	var id uint
+	if member == "me" {
+		user, _ := CurrentUser(context)
+		id = user.ID
+	} else {
+		parsed, err := strconv.ParseUint(member, 10, 0)
+		if err != nil || parsed == 0 {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid member: " + member})
+			return 0, false
+		}
+		id = uint(parsed)
+	}
+
+	isMember, err := s.IsHouseholdMember(householdID, id)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if !isMember {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("user %d is not a member of the household", id)})
+		return 0, false
+	}
+	return id, true
+}
+
+// forMember returns the accounts the member owns a share of, with every value
+// scaled to their share. Scaled values are not rounded, see roundValues.
+// Member 0 returns accounts unchanged.
+func forMember(accounts []models.Account, member uint) []models.Account {
+	if member == 0 {
+		return accounts
+	}
+	owned := []models.Account{}
+	for _, account := range accounts {
+		share, ok := account.Share(member)
+		if !ok {
+			continue
+		}
+		values := make([]models.AccountValue, len(account.Values))
+		for i, value := range account.Values {
+			value.Value = value.Value.Mul(share).Div(hundred)
+			values[i] = value
+		}
+		account.Values = values
+		owned = append(owned, account)
+	}
+	return owned
+}
+
+var hundred = decimal.NewFromInt(100)
+
+// roundValues rounds the values forMember scaled to the precision of their
+// account, for showing them. Values as recorded, for member 0, are left alone.
+func roundValues(accounts []models.Account, member uint) []models.Account {
+	if member == 0 {
+		return accounts
+	}
+	for i := range accounts {
+		for j := range accounts[i].Values {
+			accounts[i].Values[j].Value = accounts[i].Values[j].Value.Round(accounts[i].ValuePrecision())
+		}
+	}
+	return accounts
+}
+
+func (controller *AccountController) GetAccountValue(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	accountValue, err := controller.Store.GetAccountValue(household, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, accountValue)
+}
+
+func (controller *AccountController) UpdateAccountValue(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	var update accountValueUpdate
+	if err := context.BindJSON(&update); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var updates models.AccountValue
+	if update.AccountID != nil {
+		exists, err := controller.Store.AccountExistsByID(household, *update.AccountID)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !exists {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Account does not exist"})
+			return
+		}
+		updates.AccountID = *update.AccountID
+	}
+	if update.Value != nil {
+		if update.Value.IsZero() {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": `"value" must be > 0`})
+			return
+		}
+		updates.Value = *update.Value
+	}
+	if update.AsOf != nil {
+		updates.AsOf = *update.AsOf
+	}
+
+	accountValue, err := controller.Store.UpdateAccountValue(household, id, updates)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, accountValue)
+}
+
+func (controller *AccountController) DeleteAccountValue(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	accountValue, err := controller.Store.DeleteAccountValue(household, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, accountValue)
+}
@@ -1,349 +1,638 @@
 package controllers
 
 import (
-	"bytes"
-	"database/sql/driver"
-	"io"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/assert/v2"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
-func TestNewAccountController(t *testing.T) {
-	db, _, err := models.CreateMockDatabase()
-	if err != nil {
-		t.Errorf(err.Error())
+// newTestStore returns an in-memory store holding the given accounts in the
+// test user's household.
+func newTestStore(t *testing.T, accounts ...models.Account) *store.MemoryStore {
+	s := store.NewMemoryStore()
+	s.AddMember(testUser.HouseholdID, testUser.ID)
+	s.AddMember(testPartner.HouseholdID, testPartner.ID)
+	for _, account := range accounts {
+		if _, err := s.CreateAccount(testUser.HouseholdID, account); err != nil {
+			t.Fatal(err)
+		}
 	}
-	d, _ := db.DB()
-	defer d.Close()
+	return s
+}
+
+// testAccounts returns a cash account with two values and a loan, the
+// fixtures most account tests start from.
+func testAccounts() []models.Account {
+	asOf := time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC)
+	return []models.Account{
+		{
+			ID:       1,
+			Name:     "test",
+			Class:    models.Asset,
+			Category: models.Cash,
+			Values: []models.AccountValue{
+				{ID: 1, Value: decimal.RequireFromString("532.01"), AsOf: asOf},
+				{ID: 2, Value: decimal.RequireFromString("610.50"), AsOf: asOf.AddDate(0, 1, 0)},
+			},
+		},
+		{
+			ID:       2,
+			Name:     "mortgage",
+			Class:    models.Liability,
+			Category: models.Loan,
+		},
+	}
+}
+
+// cashAccount returns an asset account in the cash category with the given
+// values.
+func cashAccount(id uint, name string, values ...models.AccountValue) models.Account {
+	return models.Account{ID: id, Name: name, Class: models.Asset, Category: models.Cash, Values: values}
+}
 
+// serve handles a request from the logged in test user with the routes
+// register adds to the API.
+func serve(s store.Store, register func(store.Store, *gin.RouterGroup), method, url, body string) *httptest.ResponseRecorder {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	group := router.Group("/api")
-	controller := NewAccountController(db, group)
+	register(s, router.Group("/api", loggedIn))
 
-	assert.Equal(t, controller.DB, db)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	router.ServeHTTP(w, req)
+	return w
 }
 
-func TestCreateOrUpdateAccount(t *testing.T) {
-	db, mock, err := models.CreateMockDatabase()
-	if err != nil {
-		t.Errorf(err.Error())
-	}
-	d, _ := db.DB()
-	defer d.Close()
+func registerAccounts(s store.Store, group *gin.RouterGroup) {
+	NewAccountController(s, group)
+}
+
+func TestNewAccountController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api", loggedIn)
+	accountStore := store.NewMemoryStore()
+	controller := NewAccountController(accountStore, group)
 
+	assert.Equal(t, controller.Store, store.Store(accountStore))
+}
+
+func TestCreateOrUpdateAccount(t *testing.T) {
 	tests := []struct {
-		name               string
-		method             string
-		url                string
-		body               io.Reader
-		responseCode       int
-		expectedStatements []models.ExpectedStatement
+		name         string
+		body         string
+		responseCode int
+		want         *models.Account
 	}{
 		{
 			name:         "should create a valid account that does not yet exist successfully",
-			method:       "POST",
-			url:          "/api/accounts",
-			body:         bytes.NewReader([]byte(`{"name":"test", "class":"asset", "category":"cash"}`)),
+			body:         `{"name":"new", "class":"asset", "category":"cash"}`,
 			responseCode: http.StatusCreated,
-			expectedStatements: append(
-				models.CreateStatementsAccountDoesNotExist("test"),
-				models.CreateStatementsCreateAccount(models.Account{Name: "test", Class: models.Asset, Category: models.Cash})...,
-			),
+			want:         &models.Account{ID: 3, Name: "new", Class: models.Asset, Category: models.Cash},
 		},
 		{
-			name:               "should not create an invalid account - missing retirement tax bucket",
-			method:             "POST",
-			url:                "/api/accounts",
-			body:               bytes.NewReader([]byte(`{"name":"test", "class":"asset", "category":"retirement"}`)),
-			responseCode:       http.StatusBadRequest,
-			expectedStatements: []models.ExpectedStatement{},
+			name:         "should not create an invalid account - missing retirement tax bucket",
+			body:         `{"name":"new", "class":"asset", "category":"retirement"}`,
+			responseCode: http.StatusBadRequest,
 		},
 		{
-			name:               "should not create an invalid account - missing name",
-			method:             "POST",
-			url:                "/api/accounts",
-			body:               bytes.NewReader([]byte(`{"name":"", "class":"asset", "category":"cash"}`)),
-			responseCode:       http.StatusBadRequest,
-			expectedStatements: []models.ExpectedStatement{},
+			name:         "should not create an invalid account - missing name",
+			body:         `{"name":"", "class":"asset", "category":"cash"}`,
+			responseCode: http.StatusBadRequest,
 		},
 		{
-			name:               "should not create an invalid account - missing class",
-			method:             "POST",
-			url:                "/api/accounts",
-			body:               bytes.NewReader([]byte(`{"name":"test", "class":"", "category":"cash"}`)),
-			responseCode:       http.StatusBadRequest,
-			expectedStatements: []models.ExpectedStatement{},
+			name:         "should not create an invalid account - missing class",
+			body:         `{"name":"new", "class":"", "category":"cash"}`,
+			responseCode: http.StatusBadRequest,
 		},
 		{
-			name:               "should not create an invalid account - missing category",
-			method:             "POST",
-			url:                "/api/accounts",
-			body:               bytes.NewReader([]byte(`{"name":"test", "class":"asset", "category":""}`)),
-			responseCode:       http.StatusBadRequest,
-			expectedStatements: []models.ExpectedStatement{},
+			name:         "should not create an invalid account - missing category",
+			body:         `{"name":"new", "class":"asset", "category":""}`,
+			responseCode: http.StatusBadRequest,
 		},
 		{
 			name:         "should update an existing account",
-			method:       "POST",
-			url:          "/api/accounts",
-			body:         bytes.NewReader([]byte(`{"name":"test", "class":"asset", "category":"hsa"}`)),
+			body:         `{"name":"test", "class":"asset", "category":"hsa"}`,
 			responseCode: http.StatusOK,
-			expectedStatements: append(
-				models.CreateStatementsAccountExists("test"),
-				models.CreateStatementsUpdateAccount(
-					models.Account{Name: "test", Class: models.Asset, Category: models.Cash},
-					[]driver.Value{
-						"test",
-						models.Asset,
-						models.HSA,
-						models.AnyTime{},
-						"test",
-					},
-				)...,
-			),
+			want:         &models.Account{ID: 1, Name: "test", Class: models.Asset, Category: models.HSA},
 		},
 	}
 
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	group := router.Group("/api")
-	NewAccountController(db, group)
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			models.LoadStatements(mock, test.expectedStatements)
-			req, _ := http.NewRequest(test.method, test.url, test.body)
-			router.ServeHTTP(w, req)
+			s := newTestStore(t, testAccounts()...)
+			w := serve(s, registerAccounts, "POST", "/api/accounts", test.body)
 			assert.Equal(t, test.responseCode, w.Code)
-			if err := mock.ExpectationsWereMet(); err != nil {
-				t.Errorf("there were unfulfilled expectations: %s", err)
+
+			accounts, _ := s.GetAllAccountsWithValues(testUser.HouseholdID)
+			if test.want == nil {
+				assert.Equal(t, len(testAccounts()), len(accounts))
+				return
+			}
+
+			account, err := s.GetAccount(testUser.HouseholdID, test.want.ID)
+			if err != nil {
+				t.Fatal(err)
 			}
+			assert.Equal(t, test.want.Name, account.Name)
+			assert.Equal(t, test.want.Class, account.Class)
+			assert.Equal(t, test.want.Category, account.Category)
 		})
 	}
 }
 
 func TestGetAccounts(t *testing.T) {
-	db, mock, err := models.CreateMockDatabase()
-	if err != nil {
-		t.Errorf(err.Error())
+	tests := []struct {
+		name         string
+		url          string
+		responseCode int
+		wantNames    []string
+	}{
+		{
+			name:         "should get all accounts if no queries",
+			url:          "/api/accounts",
+			responseCode: http.StatusOK,
+			wantNames:    []string{"test", "mortgage"},
+		},
+		{
+			name:         "should get accounts by class if class query is specified",
+			url:          "/api/accounts?class=liability",
+			responseCode: http.StatusOK,
+			wantNames:    []string{"mortgage"},
+		},
+		{
+			name:         "should return an error if class does not exist",
+			url:          "/api/accounts?class=test",
+			responseCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(newTestStore(t, testAccounts()...), registerAccounts, "GET", test.url, "")
+			assert.Equal(t, test.responseCode, w.Code)
+			if test.wantNames == nil {
+				return
+			}
+
+			var accounts []models.Account
+			if err := json.Unmarshal(w.Body.Bytes(), &accounts); err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, account := range accounts {
+				names = append(names, account.Name)
+			}
+			assert.Equal(t, test.wantNames, names)
+		})
 	}
-	d, _ := db.DB()
-	defer d.Close()
+}
 
-	testAccount := models.Account{
-		Name:     "test",
-		Class:    models.Asset,
-		Category: models.Cash,
+func TestGetAccountsForMember(t *testing.T) {
+	accounts := testAccounts()
+	accounts[0].Owners = []models.AccountOwner{
+		{UserID: testUser.ID, Share: decimal.RequireFromString("66.67")},
+		{UserID: testPartner.ID, Share: decimal.RequireFromString("33.33")},
 	}
+	accounts[1].Owners = []models.AccountOwner{{UserID: testPartner.ID, Share: decimal.NewFromInt(100)}}
 
 	tests := []struct {
-		name               string
-		method             string
-		url                string
-		body               io.Reader
-		responseCode       int
-		expectedStatements []models.ExpectedStatement
+		name         string
+		url          string
+		responseCode int
+		byName       bool
+		wantNames    []string
+		wantValues   []string
 	}{
 		{
-			name:               "should get all accounts if no queries",
-			method:             "GET",
-			url:                "/api/accounts",
-			responseCode:       http.StatusOK,
-			expectedStatements: models.CreateStatementsGetAllAccountsWithValues([]models.Account{testAccount}, 10),
+			name:         "should scale the values of the logged in member's accounts",
+			url:          "/api/accounts?member=me",
+			responseCode: http.StatusOK,
+			wantNames:    []string{"test"},
+			wantValues:   []string{"407.02", "354.69"},
 		},
 		{
-			name:               "should get account by name if name query is specified",
-			method:             "GET",
-			url:                "/api/accounts?name=test",
-			responseCode:       http.StatusOK,
-			expectedStatements: models.CreateStatementsGetAccountByNameWithValues(testAccount, 10),
+			name:         "should get the accounts of another member",
+			url:          "/api/accounts?member=2",
+			responseCode: http.StatusOK,
+			wantNames:    []string{"test", "mortgage"},
+			wantValues:   []string{"203.48", "177.32"},
+		},
+		{
+			name:         "should combine member and class",
+			url:          "/api/accounts?member=2&class=liability",
+			responseCode: http.StatusOK,
+			wantNames:    []string{"mortgage"},
+		},
+		{
+			name:         "should scale the values of a named account",
+			url:          "/api/accounts?member=me&name=test",
+			responseCode: http.StatusOK,
+			byName:       true,
+			wantNames:    []string{"test"},
+			wantValues:   []string{"407.02", "354.69"},
 		},
 		{
-			name:               "should get accounts by class if class query is specified",
-			method:             "GET",
-			url:                "/api/accounts?class=asset",
-			responseCode:       http.StatusOK,
-			expectedStatements: models.CreateStatementsGetAccountsByClassWithValues(models.Asset.String(), []models.Account{testAccount}, 10),
+			name:         "should not find a named account the member has no share of",
+			url:          "/api/accounts?member=me&name=mortgage",
+			responseCode: http.StatusNotFound,
 		},
 		{
-			name:               "should return an error if named account does not exist",
-			method:             "GET",
-			url:                "/api/accounts?name=test",
-			responseCode:       http.StatusNotFound,
-			expectedStatements: models.CreateStatementsAccountCannotBeFound("test"),
+			name:         "should return an error for a user outside the household",
+			url:          "/api/accounts?member=3",
+			responseCode: http.StatusBadRequest,
 		},
 		{
-			name:               "should return an error if class does not exist",
-			method:             "GET",
-			url:                "/api/accounts?class=test",
-			responseCode:       http.StatusBadRequest,
-			expectedStatements: []models.ExpectedStatement{},
+			name:         "should return an error for an invalid member",
+			url:          "/api/accounts?member=partner",
+			responseCode: http.StatusBadRequest,
 		},
 	}
 
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	group := router.Group("/api")
-	NewAccountController(db, group)
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			models.LoadStatements(mock, test.expectedStatements)
-			req, _ := http.NewRequest(test.method, test.url, test.body)
-			router.ServeHTTP(w, req)
+			w := serve(newTestStore(t, accounts...), registerAccounts, "GET", test.url, "")
 			assert.Equal(t, test.responseCode, w.Code)
-			if err := mock.ExpectationsWereMet(); err != nil {
-				t.Errorf("there were unfulfilled expectations: %s", err)
+			if test.wantNames == nil {
+				return
 			}
+
+			var got []models.Account
+			if test.byName {
+				var account models.Account
+				if err := json.Unmarshal(w.Body.Bytes(), &account); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, account)
+			} else if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, account := range got {
+				names = append(names, account.Name)
+			}
+			assert.Equal(t, test.wantNames, names)
+			if test.wantValues == nil {
+				return
+			}
+			values := []string{}
+			for _, value := range got[0].Values {
+				values = append(values, value.Value.String())
+			}
+			assert.Equal(t, test.wantValues, values)
 		})
 	}
 }
 
-func TestDeleteAccounts(t *testing.T) {
-	db, mock, err := models.CreateMockDatabase()
-	if err != nil {
-		t.Errorf(err.Error())
-	}
-	d, _ := db.DB()
-	defer d.Close()
+func TestGetAccountByName(t *testing.T) {
+	s := newTestStore(t, testAccounts()...)
+
+	w := serve(s, registerAccounts, "GET", "/api/accounts?name=test", "")
+	assert.Equal(t, http.StatusOK, w.Code)
 
-	testAccount := models.Account{
-		Name:     "test",
-		Class:    models.Asset,
-		Category: models.Cash,
+	var account models.Account
+	if err := json.Unmarshal(w.Body.Bytes(), &account); err != nil {
+		t.Fatal(err)
 	}
+	assert.Equal(t, "test", account.Name)
+	assert.Equal(t, 2, len(account.Values))
+	assert.Equal(t, "610.5", account.Values[0].Value.String())
+
+	w = serve(s, registerAccounts, "GET", "/api/accounts?name=missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
 
+func TestDeleteAccounts(t *testing.T) {
 	tests := []struct {
-		name               string
-		method             string
-		url                string
-		body               io.Reader
-		responseCode       int
-		expectedStatements []models.ExpectedStatement
+		name         string
+		url          string
+		responseCode int
+		wantDeleted  bool
 	}{
 		{
 			name:         "should delete an existing account by name",
-			method:       "DELETE",
 			url:          "/api/accounts?name=test",
 			responseCode: http.StatusOK,
-			expectedStatements: append(
-				models.CreateStatementsAccountExists("test"),
-				models.CreateStatementsDeleteAccount(testAccount)...,
-			),
+			wantDeleted:  true,
 		},
 		{
-			name:               "should return an error if named account does not exist",
-			method:             "DELETE",
-			url:                "/api/accounts?name=test",
-			responseCode:       http.StatusNotFound,
-			expectedStatements: models.CreateStatementsAccountCannotBeFound("test"),
+			name:         "should return an error if named account does not exist",
+			url:          "/api/accounts?name=missing",
+			responseCode: http.StatusNotFound,
 		},
 		{
-			name:               "should return an error if class does not exist",
-			method:             "DELETE",
-			url:                "/api/accounts?class=test",
-			responseCode:       http.StatusBadRequest,
-			expectedStatements: []models.ExpectedStatement{},
+			name:         "should return an error if no name is given",
+			url:          "/api/accounts?class=test",
+			responseCode: http.StatusBadRequest,
 		},
 	}
 
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	group := router.Group("/api")
-	NewAccountController(db, group)
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			models.LoadStatements(mock, test.expectedStatements)
-			req, _ := http.NewRequest(test.method, test.url, test.body)
-			router.ServeHTTP(w, req)
+			s := newTestStore(t, testAccounts()...)
+			w := serve(s, registerAccounts, "DELETE", test.url, "")
 			assert.Equal(t, test.responseCode, w.Code)
-			if err := mock.ExpectationsWereMet(); err != nil {
-				t.Errorf("there were unfulfilled expectations: %s", err)
-			}
+
+			exists, _ := s.AccountExists(testUser.HouseholdID, "test")
+			assert.Equal(t, !test.wantDeleted, exists)
 		})
 	}
 }
 
 func TestCreateAccountValue(t *testing.T) {
-	db, mock, err := models.CreateMockDatabase()
-	if err != nil {
-		t.Errorf(err.Error())
-	}
-	d, _ := db.DB()
-	defer d.Close()
-
 	tests := []struct {
-		name               string
-		method             string
-		url                string
-		body               io.Reader
-		responseCode       int
-		expectedStatements []models.ExpectedStatement
+		name         string
+		body         string
+		responseCode int
+		wantValues   int
 	}{
 		{
 			name:         "should create an account value",
-			method:       "POST",
-			url:          "/api/accounts/value",
+			body:         `{"account_id": 1, "value": 532.019}`,
 			responseCode: http.StatusOK,
-			body:         bytes.NewReader([]byte(`{"account_name": "test", "value": 532.01}`)),
-			expectedStatements: append(
-				models.CreateStatementsAccountExists("test"),
-				models.CreateStatementsCreateAccountValue(models.AccountValue{AccountName: "test", Value: decimal.NewFromFloat(532.01)})...,
-			),
+			wantValues:   3,
 		},
 		{
-			name:               "should not create an account value for an account that does not exist",
-			method:             "POST",
-			url:                "/api/accounts/value",
-			body:               bytes.NewReader([]byte(`{"account_name": "test", "value": 8791.43}`)),
-			responseCode:       http.StatusBadRequest,
-			expectedStatements: models.CreateStatementsAccountDoesNotExist("test"),
+			name:         "should not create an account value for an account that does not exist",
+			body:         `{"account_id": 3, "value": 8791.43}`,
+			responseCode: http.StatusBadRequest,
+			wantValues:   2,
 		},
 		{
-			name:               "should not create an account value with no value provided",
-			method:             "POST",
-			url:                "/api/accounts/value",
-			body:               bytes.NewReader([]byte(`{"account_name": "test"}`)),
-			responseCode:       http.StatusBadRequest,
-			expectedStatements: []models.ExpectedStatement{},
+			name:         "should not create an account value with no value provided",
+			body:         `{"account_id": 1}`,
+			responseCode: http.StatusBadRequest,
+			wantValues:   2,
 		},
 		{
-			name:               "should not create an account value if no account_name provided",
-			method:             "POST",
-			url:                "/api/accounts/value",
-			body:               bytes.NewReader([]byte(`{"value": 532.23}`)),
-			responseCode:       http.StatusBadRequest,
-			expectedStatements: []models.ExpectedStatement{},
+			name:         "should not create an account value if no account_id provided",
+			body:         `{"value": 532.23}`,
+			responseCode: http.StatusBadRequest,
+			wantValues:   2,
 		},
 	}
 
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	group := router.Group("/api")
-	NewAccountController(db, group)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestStore(t, testAccounts()...)
+			w := serve(s, registerAccounts, "POST", "/api/accounts/value", test.body)
+			assert.Equal(t, test.responseCode, w.Code)
+
+			account, _ := s.GetAccountWithValues(testUser.HouseholdID, 1)
+			assert.Equal(t, test.wantValues, len(account.Values))
+		})
+	}
+
+	t.Run("should keep a new value to 8 decimal places", func(t *testing.T) {
+		s := newTestStore(t, testAccounts()...)
+		w := serve(s, registerAccounts, "POST", "/api/accounts/value", `{"account_id": 1, "value": 0.123456789}`)
+
+		var av models.AccountValue
+		if err := json.Unmarshal(w.Body.Bytes(), &av); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "0.12345679", av.Value.String())
+	})
+}
+
+func TestAccountValueByID(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         string
+		responseCode int
+		check        func(t *testing.T, s store.Store)
+	}{
+		{
+			name:         "should get an account value by id",
+			method:       "GET",
+			url:          "/api/accounts/value/1",
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "should return not found for an unknown account value",
+			method:       "GET",
+			url:          "/api/accounts/value/3",
+			responseCode: http.StatusNotFound,
+		},
+		{
+			name:         "should reject an invalid id",
+			method:       "GET",
+			url:          "/api/accounts/value/abc",
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should update an account value",
+			method:       "PATCH",
+			url:          "/api/accounts/value/1",
+			body:         `{"value": 600.5}`,
+			responseCode: http.StatusOK,
+			check: func(t *testing.T, s store.Store) {
+				av, _ := s.GetAccountValue(testUser.HouseholdID, 1)
+				assert.Equal(t, "600.5", av.Value.String())
+				assert.Equal(t, uint(1), av.AccountID)
+			},
+		},
+		{
+			name:         "should not update an account value to zero",
+			method:       "PATCH",
+			url:          "/api/accounts/value/1",
+			body:         `{"value": 0}`,
+			responseCode: http.StatusBadRequest,
+			check: func(t *testing.T, s store.Store) {
+				av, _ := s.GetAccountValue(testUser.HouseholdID, 1)
+				assert.Equal(t, "532.01", av.Value.String())
+			},
+		},
+		{
+			name:         "should move an account value to another account",
+			method:       "PATCH",
+			url:          "/api/accounts/value/1",
+			body:         `{"account_id": 2}`,
+			responseCode: http.StatusOK,
+			check: func(t *testing.T, s store.Store) {
+				av, _ := s.GetAccountValue(testUser.HouseholdID, 1)
+				assert.Equal(t, uint(2), av.AccountID)
+			},
+		},
+		{
+			name:         "should not move an account value to an account that does not exist",
+			method:       "PATCH",
+			url:          "/api/accounts/value/1",
+			body:         `{"account_id": 3}`,
+			responseCode: http.StatusBadRequest,
+			check: func(t *testing.T, s store.Store) {
+				av, _ := s.GetAccountValue(testUser.HouseholdID, 1)
+				assert.Equal(t, uint(1), av.AccountID)
+			},
+		},
+		{
+			name:         "should delete an account value",
+			method:       "DELETE",
+			url:          "/api/accounts/value/1",
+			responseCode: http.StatusOK,
+			check: func(t *testing.T, s store.Store) {
+				_, err := s.GetAccountValue(testUser.HouseholdID, 1)
+				assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+			},
+		},
+		{
+			name:         "should return not found when deleting an unknown account value",
+			method:       "DELETE",
+			url:          "/api/accounts/value/3",
+			responseCode: http.StatusNotFound,
+		},
+	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			models.LoadStatements(mock, test.expectedStatements)
-			req, _ := http.NewRequest(test.method, test.url, test.body)
-			router.ServeHTTP(w, req)
+			s := newTestStore(t, testAccounts()...)
+			w := serve(s, registerAccounts, test.method, test.url, test.body)
 			assert.Equal(t, test.responseCode, w.Code)
+			if test.check != nil {
+				test.check(t, s)
+			}
 		})
 	}
+}
 
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Errorf("there were unfulfilled expectations: %s", err)
+func TestAccountValueOfDeletedAccount(t *testing.T) {
+	s := newTestStore(t, testAccounts()...)
+	if _, err := s.DeleteAccountByID(testUser.HouseholdID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(s, registerAccounts, "GET", "/api/accounts/value/1", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAccountByID(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         string
+		responseCode int
+		check        func(t *testing.T, s store.Store)
+	}{
+		{
+			name:         "should get an account by id",
+			method:       "GET",
+			url:          "/api/accounts/1",
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "should return not found for an unknown account id",
+			method:       "GET",
+			url:          "/api/accounts/3",
+			responseCode: http.StatusNotFound,
+		},
+		{
+			name:         "should rename an account",
+			method:       "PUT",
+			url:          "/api/accounts/1",
+			body:         `{"name":"renamed", "class":"asset", "category":"cash"}`,
+			responseCode: http.StatusOK,
+			check: func(t *testing.T, s store.Store) {
+				account, _ := s.GetAccountWithValues(testUser.HouseholdID, 1)
+				assert.Equal(t, "renamed", account.Name)
+				assert.Equal(t, 2, len(account.Values))
+			},
+		},
+		{
+			name:         "should not rename an account to a name in use",
+			method:       "PUT",
+			url:          "/api/accounts/1",
+			body:         `{"name":"mortgage", "class":"asset", "category":"cash"}`,
+			responseCode: http.StatusConflict,
+			check: func(t *testing.T, s store.Store) {
+				account, _ := s.GetAccount(testUser.HouseholdID, 1)
+				assert.Equal(t, "test", account.Name)
+			},
+		},
+		{
+			name:         "should not update an account with an invalid body",
+			method:       "PUT",
+			url:          "/api/accounts/1",
+			body:         `{"name":"test", "class":"asset", "category":"retirement"}`,
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should set the owners of an account",
+			method:       "PUT",
+			url:          "/api/accounts/1",
+			body:         `{"name":"test", "class":"asset", "category":"cash", "owners":[{"user_id":1,"share":"60"},{"user_id":2,"share":"40"}]}`,
+			responseCode: http.StatusOK,
+			check: func(t *testing.T, s store.Store) {
+				account, _ := s.GetAccountWithValues(testUser.HouseholdID, 1)
+				assert.Equal(t, 2, len(account.Owners))
+				share, _ := account.Share(testPartner.ID)
+				assert.Equal(t, "40", share.String())
+			},
+		},
+		{
+			name:         "should not accept owner shares that do not add up to 100",
+			method:       "PUT",
+			url:          "/api/accounts/1",
+			body:         `{"name":"test", "class":"asset", "category":"cash", "owners":[{"user_id":1,"share":"60"}]}`,
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should not give an account an owner outside the household",
+			method:       "PUT",
+			url:          "/api/accounts/1",
+			body:         `{"name":"test", "class":"asset", "category":"cash", "owners":[{"user_id":3,"share":"100"}]}`,
+			responseCode: http.StatusBadRequest,
+			check: func(t *testing.T, s store.Store) {
+				account, _ := s.GetAccountWithValues(testUser.HouseholdID, 1)
+				assert.Equal(t, 0, len(account.Owners))
+			},
+		},
+		{
+			name:         "should return not found when updating an unknown account id",
+			method:       "PUT",
+			url:          "/api/accounts/3",
+			body:         `{"name":"test", "class":"asset", "category":"cash"}`,
+			responseCode: http.StatusNotFound,
+		},
+		{
+			name:         "should delete an account by id",
+			method:       "DELETE",
+			url:          "/api/accounts/1",
+			responseCode: http.StatusOK,
+			check: func(t *testing.T, s store.Store) {
+				exists, _ := s.AccountExistsByID(testUser.HouseholdID, 1)
+				assert.Equal(t, false, exists)
+			},
+		},
+		{
+			name:         "should return not found when deleting an unknown account id",
+			method:       "DELETE",
+			url:          "/api/accounts/3",
+			responseCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestStore(t, testAccounts()...)
+			w := serve(s, registerAccounts, test.method, test.url, test.body)
+			assert.Equal(t, test.responseCode, w.Code)
+			if test.check != nil {
+				test.check(t, s)
+			}
+		})
 	}
 }
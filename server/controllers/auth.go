@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SessionCookie is the cookie holding the session token of a logged in user.
+const SessionCookie = "session"
+
+// userKey is where RequireSession keeps the logged in user in the context.
+const userKey = "user"
+
+// tokenKey is where RequireSession keeps the API token a request was
+// authenticated with, if any.
+const tokenKey = "apiToken"
+
+type AuthController struct {
+	DB *gorm.DB
+}
+
+func NewAuthController(db *gorm.DB, router *gin.RouterGroup) AuthController {
+	authController := AuthController{DB: db}
+
+	authRouter := router.Group("/auth")
+	{
+		authRouter.POST("/login", authController.Login)
+		authRouter.POST("/logout", authController.Logout)
+		authRouter.POST("/join", authController.Join)
+		authRouter.GET("/me", authController.RequireSession, authController.Me)
+	}
+
+	return authController
+}
+
+type credentials struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type joinRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// CurrentUser returns the user that RequireSession found for the request.
+func CurrentUser(context *gin.Context) (models.User, bool) {
+	value, ok := context.Get(userKey)
+	if !ok {
+		return models.User{}, false
+	}
+	user, ok := value.(models.User)
+	return user, ok
+}
+
+// householdID returns the household of the logged in user, aborting with 401
+// Unauthorized if nobody is logged in.
+func householdID(context *gin.Context) (uint, bool) {
+	user, ok := CurrentUser(context)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return 0, false
+	}
+	return user.HouseholdID, true
+}
+
+func setSessionCookie(context *gin.Context, token string, maxAge int) {
+	context.SetSameSite(http.SameSiteLaxMode)
+	context.SetCookie(SessionCookie, token, maxAge, "/", "", context.Request.TLS != nil, true)
+}
+
+// RequireSession aborts requests with 401 Unauthorized unless they carry the
+// cookie of a current session or an API token in an "Authorization: Bearer"
+// header. Requests made with a read-only token may only use GET and HEAD and
+// are otherwise aborted with 403 Forbidden.
+func (controller *AuthController) RequireSession(context *gin.Context) {
+	if header := context.GetHeader("Authorization"); header != "" {
+		controller.requireToken(context, header)
+		return
+	}
+
+	token, err := context.Cookie(SessionCookie)
+	if err != nil || token == "" {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+
+	user, err := models.GetSessionUser(controller.DB, token)
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, models.ErrSessionExpired) {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	context.Set(userKey, user)
+	context.Next()
+}
+
+func (controller *AuthController) requireToken(context *gin.Context, header string) {
+	if !strings.HasPrefix(header, "Bearer ") {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "expected a bearer token"})
+		return
+	}
+
+	apiToken, err := models.UseAPIToken(controller.DB, strings.TrimPrefix(header, "Bearer "))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API token"})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	method := context.Request.Method
+	if apiToken.Scope != models.ScopeWrite && method != http.MethodGet && method != http.MethodHead {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API token is read-only"})
+		return
+	}
+
+	context.Set(userKey, apiToken.User)
+	context.Set(tokenKey, apiToken)
+	context.Next()
+}
+
+// Login checks a username and password and starts a session, returned in the
+// session cookie.
+func (controller *AuthController) Login(context *gin.Context) {
+	var creds credentials
+	if err := context.BindJSON(&creds); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := models.AuthenticateUser(controller.DB, creds.Username, creds.Password)
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, _, err := models.CreateSession(controller.DB, user.ID)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	setSessionCookie(context, token, int(models.SessionDuration.Seconds()))
+	context.JSON(http.StatusOK, user)
+}
+
+// Logout ends the current session, if there is one, and clears the cookie.
+func (controller *AuthController) Logout(context *gin.Context) {
+	if token, err := context.Cookie(SessionCookie); err == nil && token != "" {
+		if err := models.DeleteSession(controller.DB, token); err != nil {
+			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	setSessionCookie(context, "", -1)
+	context.Status(http.StatusNoContent)
+}
+
+// Join accepts an invitation to a household, creating a user that is a member
+// of it, and logs the new user in.
+func (controller *AuthController) Join(context *gin.Context) {
+	var request joinRequest
+	if err := context.BindJSON(&request); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidateUser(request.Username, request.Password); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := models.AcceptInvitation(controller.DB, request.Token, request.Username, request.Password)
+	if errors.Is(err, models.ErrInvalidInvitation) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrUsernameTaken) {
+		context.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, _, err := models.CreateSession(controller.DB, user.ID)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	setSessionCookie(context, token, int(models.SessionDuration.Seconds()))
+	context.JSON(http.StatusCreated, user)
+}
+
+func (controller *AuthController) Me(context *gin.Context) {
+	user, _ := CurrentUser(context)
+	context.JSON(http.StatusOK, user)
+}
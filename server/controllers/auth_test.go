@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jrc356/financial_dashboard/migrations"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openSQLiteDatabase returns a migrated SQLite database in a temporary
+// directory.
+func openSQLiteDatabase(t *testing.T) *gorm.DB {
+	db, err := store.Open(
+		store.Config{Driver: store.SQLite, DSN: filepath.Join(t.TempDir(), "test.db")},
+		&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		d, _ := db.DB()
+		d.Close()
+	})
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testUser is the user loggedIn logs in.
+var testUser = models.User{ID: 1, HouseholdID: models.MockHouseholdID, Username: "test"}
+
+// testPartner is another member of testUser's household.
+var testPartner = models.User{ID: 2, HouseholdID: models.MockHouseholdID, Username: "partner"}
+
+// loggedIn stands in for RequireSession in tests of controllers that only
+// need to know who is logged in.
+func loggedIn(context *gin.Context) {
+	context.Set(userKey, testUser)
+	context.Next()
+}
+
+// createAdmin creates a household with an admin that can log in with the
+// password "correct horse".
+func createAdmin(t *testing.T, db *gorm.DB, username string) models.User {
+	household, err := models.CreateHousehold(db, username+"'s household")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := models.CreateUser(db, household.ID, username, "correct horse", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// newAuthRouter serves the auth API and the account, household and token APIs
+// behind a login.
+func newAuthRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	authController := NewAuthController(db, router.Group("/api"))
+	apiRouter := router.Group("/api", authController.RequireSession)
+	NewAccountController(store.NewGormStore(db), apiRouter)
+	NewHouseholdController(db, apiRouter)
+	NewTokenController(db, apiRouter)
+	return router
+}
+
+func serveWithCookie(router *gin.Engine, method, url, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func sessionCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == SessionCookie {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestNewAuthController(t *testing.T) {
+	db := openSQLiteDatabase(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api")
+	controller := NewAuthController(db, group)
+
+	assert.Equal(t, controller.DB, db)
+}
+
+func TestLogin(t *testing.T) {
+	db := openSQLiteDatabase(t)
+	createAdmin(t, db, "admin")
+	router := newAuthRouter(db)
+
+	tests := []struct {
+		name         string
+		body         string
+		responseCode int
+	}{
+		{
+			name:         "should log in with the right password",
+			body:         `{"username":"admin","password":"correct horse"}`,
+			responseCode: http.StatusOK,
+		},
+		{
+			name:         "should not log in with the wrong password",
+			body:         `{"username":"admin","password":"battery staple"}`,
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should not log in an unknown user",
+			body:         `{"username":"root","password":"correct horse"}`,
+			responseCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should require a password",
+			body:         `{"username":"admin"}`,
+			responseCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serveWithCookie(router, "POST", "/api/auth/login", test.body, nil)
+			assert.Equal(t, test.responseCode, w.Code)
+
+			cookie := sessionCookie(w)
+			assert.Equal(t, test.responseCode == http.StatusOK, cookie != nil)
+			if cookie != nil {
+				assert.Equal(t, true, cookie.HttpOnly)
+				assert.NotMatchRegex(t, w.Body.String(), "password")
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	db := openSQLiteDatabase(t)
+	createAdmin(t, db, "admin")
+	router := newAuthRouter(db)
+
+	w := serveWithCookie(router, "GET", "/api/accounts", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serveWithCookie(router, "GET", "/api/accounts", "", &http.Cookie{Name: SessionCookie, Value: "forged"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serveWithCookie(router, "POST", "/api/auth/login", `{"username":"admin","password":"correct horse"}`, nil)
+	cookie := sessionCookie(w)
+
+	w = serveWithCookie(router, "GET", "/api/accounts", "", cookie)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serveWithCookie(router, "GET", "/api/auth/me", "", cookie)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.MatchRegex(t, w.Body.String(), `"username":"admin"`)
+
+	w = serveWithCookie(router, "POST", "/api/auth/logout", "", cookie)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "", sessionCookie(w).Value)
+
+	w = serveWithCookie(router, "GET", "/api/accounts", "", cookie)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CashFlowController struct {
+	Store store.Store
+}
+
+func NewCashFlowController(s store.Store, router *gin.RouterGroup) CashFlowController {
+	cashFlowController := CashFlowController{Store: s}
+
+	accountRouter := router.Group("/accounts")
+	{
+		accountRouter.GET("/:id/cashflows", cashFlowController.GetCashFlows)
+		accountRouter.POST("/:id/cashflows", cashFlowController.CreateCashFlow)
+		accountRouter.DELETE("/cashflows/:id", cashFlowController.DeleteCashFlow)
+	}
+
+	return cashFlowController
+}
+
+// GetCashFlows returns the cash flows recorded for an account, newest first.
+func (controller *CashFlowController) GetCashFlows(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	flows, err := controller.Store.GetCashFlows(household, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, flows)
+}
+
+// CreateCashFlow records money moving in or out of the account on a date, now
+// by default. Amounts are positive, except for transfers which are negative
+// when money leaves the account. A payment toward a liability is a
+// contribution and new borrowing on it a withdrawal.
+func (controller *CashFlowController) CreateCashFlow(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	var flow models.CashFlow
+	if err := context.BindJSON(&flow); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	flow.AccountID = id
+	if err := models.ValidateCashFlow(flow); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := controller.Store.GetAccount(household, id); errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	flow, err := controller.Store.CreateCashFlow(household, flow)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusCreated, flow)
+}
+
+func (controller *CashFlowController) DeleteCashFlow(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	flow, err := controller.Store.DeleteCashFlow(household, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, flow)
+}
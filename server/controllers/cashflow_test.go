@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+// cashFlowsStore returns a store with a 401k, ID 1, opened with 1000 on
+// January 10th 2023 that was worth 1600 at the end of February and 1500 at the
+// end of March, and a savings account, ID 2, opened with 2000 on February 1st
+// that was worth 2010 at the end of March. The 401k had 500 contributed, a
+// fee of 10 and 5 interest in February and 200 withdrawn in March; the
+// savings account earned 10 interest in March.
+func cashFlowsStore(t *testing.T) *store.MemoryStore {
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2023, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	s := newTestStore(t,
+		cashAccount(1, "401k",
+			models.AccountValue{Value: decimal.NewFromInt(1000), AsOf: date(time.January, 10)},
+			models.AccountValue{Value: decimal.NewFromInt(1600), AsOf: date(time.February, 28)},
+			models.AccountValue{Value: decimal.NewFromInt(1500), AsOf: date(time.March, 31)},
+		),
+		cashAccount(2, "savings",
+			models.AccountValue{Value: decimal.NewFromInt(2000), AsOf: date(time.February, 1)},
+			models.AccountValue{Value: decimal.NewFromInt(2010), AsOf: date(time.March, 31)},
+		),
+	)
+
+	addCashFlows(t, s,
+		models.CashFlow{AccountID: 1, Type: models.Contribution, Amount: decimal.NewFromInt(1000), Date: date(time.January, 10)},
+		models.CashFlow{AccountID: 1, Type: models.Contribution, Amount: decimal.NewFromInt(500), Date: date(time.February, 15)},
+		models.CashFlow{AccountID: 1, Type: models.Fee, Amount: decimal.NewFromInt(10), Date: date(time.February, 20)},
+		models.CashFlow{AccountID: 1, Type: models.Interest, Amount: decimal.NewFromInt(5), Date: date(time.February, 25)},
+		models.CashFlow{AccountID: 1, Type: models.Withdrawal, Amount: decimal.NewFromInt(200), Date: date(time.March, 10)},
+		models.CashFlow{AccountID: 2, Type: models.Interest, Amount: decimal.NewFromInt(10), Date: date(time.March, 31)},
+	)
+	return s
+}
+
+// addCashFlows records cash flows in the test user's household.
+func addCashFlows(t *testing.T, s store.Store, flows ...models.CashFlow) {
+	for _, flow := range flows {
+		if _, err := s.CreateCashFlow(testUser.HouseholdID, flow); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func registerCashFlows(s store.Store, group *gin.RouterGroup) {
+	NewCashFlowController(s, group)
+	NewFinanceController(s, group)
+}
+
+func TestCreateCashFlow(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		body         string
+		responseCode int
+	}{
+		{name: "should create a contribution", url: "/api/accounts/1/cashflows", body: `{"type": "contribution", "amount": "250", "date": "2023-04-01T00:00:00Z"}`, responseCode: http.StatusCreated},
+		{name: "should create a transfer out", url: "/api/accounts/1/cashflows", body: `{"type": "transfer", "amount": "-100"}`, responseCode: http.StatusCreated},
+		{name: "should 404 for a missing account", url: "/api/accounts/10/cashflows", body: `{"type": "fee", "amount": "1"}`, responseCode: http.StatusNotFound},
+		{name: "should reject an unknown type", url: "/api/accounts/1/cashflows", body: `{"type": "dividend", "amount": "1"}`, responseCode: http.StatusBadRequest},
+		{name: "should reject a negative withdrawal", url: "/api/accounts/1/cashflows", body: `{"type": "withdrawal", "amount": "-1"}`, responseCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(cashFlowsStore(t), registerCashFlows, "POST", test.url, test.body)
+			assert.Equal(t, test.responseCode, w.Code)
+		})
+	}
+}
+
+func TestGetAndDeleteCashFlows(t *testing.T) {
+	s := cashFlowsStore(t)
+
+	w := serve(s, registerCashFlows, "GET", "/api/accounts/1/cashflows", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var flows []models.CashFlow
+	if err := json.Unmarshal(w.Body.Bytes(), &flows); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, len(flows))
+	assert.Equal(t, models.Withdrawal, flows[0].Type)
+
+	w = serve(s, registerCashFlows, "DELETE", "/api/accounts/cashflows/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(s, registerCashFlows, "DELETE", "/api/accounts/cashflows/1", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serve(s, registerCashFlows, "GET", "/api/accounts/10/cashflows", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetBalanceChanges(t *testing.T) {
+	s := cashFlowsStore(t)
+
+	tests := []struct {
+		url          string
+		responseCode int
+		// want holds start, end, net flows, return and growth for each month.
+		want [][5]string
+	}{
+		{
+			url:          "/api/networth/changes?account=1",
+			responseCode: http.StatusOK,
+			want: [][5]string{
+				{"1000", "1000", "0", "0", "0"},
+				{"1000", "1600", "500", "100", "105"},
+				{"1600", "1500", "-200", "100", "100"},
+			},
+		},
+		{
+			url:          "/api/networth/changes?from=2023-02-01",
+			responseCode: http.StatusOK,
+			want: [][5]string{
+				{"3000", "3600", "500", "100", "105"},
+				{"3600", "3510", "-200", "110", "100"},
+			},
+		},
+		{url: "/api/networth/changes?groupBy=class", responseCode: http.StatusBadRequest},
+		{url: "/api/networth/changes?account=10", responseCode: http.StatusNotFound},
+		{url: "/api/networth/changes?currency=GBP", responseCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			w := serve(s, registerCashFlows, "GET", test.url, "")
+			assert.Equal(t, test.responseCode, w.Code)
+			if test.responseCode != http.StatusOK {
+				return
+			}
+
+			var changes []BalanceChange
+			if err := json.Unmarshal(w.Body.Bytes(), &changes); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, len(test.want), len(changes))
+			for i, change := range changes {
+				got := [5]string{change.Start.String(), change.End.String(), change.NetFlows.String(), change.Return.String(), change.Growth.String()}
+				assert.Equal(t, test.want[i], got)
+				assert.Equal(t, change.Change.String(), change.NetFlows.Add(change.Return).String())
+			}
+		})
+	}
+}
+
+func TestGetBalanceChangesForLiability(t *testing.T) {
+	// A loan owing 10000 in January and 9550 at the end of February, after a
+	// payment of 500 and 50 of interest charged.
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2023, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	s := newTestStore(t, models.Account{
+		ID:       1,
+		Name:     "car loan",
+		Class:    models.Liability,
+		Category: models.Loan,
+		Values: []models.AccountValue{
+			{Value: decimal.NewFromInt(10000), AsOf: date(time.January, 10)},
+			{Value: decimal.NewFromInt(9550), AsOf: date(time.February, 28)},
+		},
+	})
+	addCashFlows(t, s,
+		models.CashFlow{AccountID: 1, Type: models.Contribution, Amount: decimal.NewFromInt(500), Date: date(time.February, 15)},
+		models.CashFlow{AccountID: 1, Type: models.Interest, Amount: decimal.NewFromInt(50), Date: date(time.February, 20)},
+	)
+
+	w := serve(s, registerCashFlows, "GET", "/api/networth/changes?account=1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var changes []BalanceChange
+	if err := json.Unmarshal(w.Body.Bytes(), &changes); err != nil {
+		t.Fatal(err)
+	}
+
+	// The payment is a contribution that adds to net worth, and the interest
+	// is a negative return.
+	assert.Equal(t, 2, len(changes))
+	february := changes[1]
+	assert.Equal(t, "-10000", february.Start.String())
+	assert.Equal(t, "-9550", february.End.String())
+	assert.Equal(t, "500", february.Contributions.String())
+	assert.Equal(t, "500", february.NetFlows.String())
+	assert.Equal(t, "-50", february.Interest.String())
+	assert.Equal(t, "-50", february.Return.String())
+	assert.Equal(t, "0", february.Growth.String())
+}
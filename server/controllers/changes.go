@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// BalanceChange splits the change in balance over a bucket into the net cash
+// flows in and out of the accounts and the investment return on them.
+// Withdrawals and fees are given as positive amounts. Flows count by what
+// they do to net worth, so a contribution to a liability, which pays it
+// down, adds to it like a contribution to an asset.
+type BalanceChange struct {
+	Date          time.Time       `json:"date"`
+	Start         decimal.Decimal `json:"start"`
+	End           decimal.Decimal `json:"end"`
+	Change        decimal.Decimal `json:"change"`
+	Contributions decimal.Decimal `json:"contributions"`
+	Withdrawals   decimal.Decimal `json:"withdrawals"`
+	Transfers     decimal.Decimal `json:"transfers"`
+	NetFlows      decimal.Decimal `json:"netFlows"`
+	// Return is the change that is not down to net flows: interest, less
+	// fees, plus growth in the market value of the accounts.
+	Return   decimal.Decimal `json:"return"`
+	Interest decimal.Decimal `json:"interest"`
+	Fees     decimal.Decimal `json:"fees"`
+	Growth   decimal.Decimal `json:"growth"`
+}
+
+func (c *BalanceChange) round(places int32) {
+	for _, value := range []*decimal.Decimal{
+		&c.Start, &c.End, &c.Change, &c.Contributions, &c.Withdrawals, &c.Transfers,
+		&c.NetFlows, &c.Return, &c.Interest, &c.Fees, &c.Growth,
+	} {
+		*value = value.Round(places)
+	}
+}
+
+// GetBalanceChanges returns, for each bucket of the given interval (a month by
+// default), the change in net worth split into contributions, withdrawals and
+// transfers recorded as cash flows, and the investment return that makes up
+// the rest. account limits the report to a single account. interval, from, to,
+// timezone and currency work as they do for net worth. The balances a bucket
+// starts and ends with and its flows are all converted at the latest exchange
+// rate dated before the end of the bucket, so currency moves do not count as
+// return. Opening an account is not a change either: an account first valued
+// during a bucket starts it at that value, and flows up to that first value are
+// taken to be part of it.
+func (fc *FinanceController) GetBalanceChanges(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	query, err := parseNetWorthQuery(context)
+	if err == nil && query.GroupBy != "" {
+		err = fmt.Errorf("groupBy is not supported for balance changes")
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account uint
+	if param := context.Query("account"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 0)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid account %q", param)})
+			return
+		}
+		account = uint(id)
+		if _, err := fc.Store.GetAccount(household, account); errors.Is(err, gorm.ErrRecordNotFound) {
+			context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		} else if err != nil {
+			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	changes, err := fc.balanceChanges(household, account, query)
+	if errors.Is(err, errTooManyBuckets) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errMissingFXRate) {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range changes {
+		changes[i].round(query.Currency.Precision())
+	}
+	context.JSON(http.StatusOK, changes)
+}
+
+// balanceChanges rolls up the balances of the household's accounts, or of a
+// single account when accountID is set, and their cash flows into buckets.
+func (fc *FinanceController) balanceChanges(householdID, accountID uint, query netWorthQuery) ([]BalanceChange, error) {
+	rates, err := fc.Store.GetFXRates(query.Currency)
+	if err != nil {
+		return nil, err
+	}
+	query.converter = newConverter(query.Currency, rates)
+
+	positions, err := fc.Store.GetAllPositions(householdID)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := fc.Store.GetAllAccountsWithValuesIncludingDeleted(householdID)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err = valueHoldings(fc.Store, accounts, positions)
+	if err != nil {
+		return nil, err
+	}
+	flows, err := fc.Store.GetAllCashFlows(householdID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[uint]models.Account{}
+	kept := []models.Account{}
+	for _, account := range accounts {
+		if accountID != 0 && account.ID != accountID {
+			continue
+		}
+		byID[account.ID] = account
+		kept = append(kept, account)
+	}
+
+	buckets, err := createTimeBuckets(kept, query)
+	if err != nil || len(buckets) == 0 {
+		return []BalanceChange{}, err
+	}
+
+	changes := make([]BalanceChange, len(buckets))
+	for i, bucket := range buckets {
+		changes[i] = BalanceChange{
+			Date:          bucket,
+			Start:         decimal.Zero,
+			End:           decimal.Zero,
+			Contributions: decimal.Zero,
+			Withdrawals:   decimal.Zero,
+			Transfers:     decimal.Zero,
+			Interest:      decimal.Zero,
+			Fees:          decimal.Zero,
+		}
+	}
+	for _, account := range kept {
+		if err := addBalances(changes, account, query); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, flow := range flows {
+		account, ok := byID[flow.AccountID]
+		if !ok {
+			continue
+		}
+		i := sort.Search(len(buckets), func(i int) bool { return buckets[i].After(flow.Date) }) - 1
+		if i < 0 {
+			continue
+		}
+		end := bucketEnd(buckets[i], query)
+		if !flow.Date.Before(end) || !flow.Date.After(firstValue(account)) {
+			continue
+		}
+		if account.DeletedAt.Valid && account.DeletedAt.Time.Before(end) {
+			continue
+		}
+
+		amount, err := query.converter.convert(flow.Amount, account.Currency, end)
+		if err != nil {
+			return nil, err
+		}
+		change := &changes[i]
+		switch flow.Type {
+		case models.Contribution:
+			change.Contributions = change.Contributions.Add(amount)
+		case models.Withdrawal:
+			change.Withdrawals = change.Withdrawals.Add(amount)
+		case models.Transfer:
+			change.Transfers = change.Transfers.Add(amount)
+		case models.Interest:
+			// Interest on a liability is charged rather than earned.
+			if account.Class == models.Liability {
+				amount = amount.Neg()
+			}
+			change.Interest = change.Interest.Add(amount)
+		case models.Fee:
+			change.Fees = change.Fees.Add(amount)
+		}
+	}
+
+	for i := range changes {
+		change := &changes[i]
+		change.Change = change.End.Sub(change.Start)
+		change.NetFlows = change.Contributions.Sub(change.Withdrawals).Add(change.Transfers)
+		change.Return = change.Change.Sub(change.NetFlows)
+		change.Growth = change.Return.Sub(change.Interest).Add(change.Fees)
+	}
+	return changes, nil
+}
+
+// addBalances adds the balance the account starts and ends each bucket with,
+// converted at the latest exchange rate dated before the end of the bucket. An
+// account first valued during a bucket starts it at that value, so opening it
+// does not count as a change, and an account deleted before the end of a
+// bucket no longer counts.
+func addBalances(changes []BalanceChange, account models.Account, query netWorthQuery) error {
+	values := make([]models.AccountValue, len(account.Values))
+	copy(values, account.Values)
+	sort.SliceStable(values, func(i, j int) bool {
+		if values[i].AsOf.Equal(values[j].AsOf) {
+			return values[i].ID < values[j].ID
+		}
+		return values[i].AsOf.Before(values[j].AsOf)
+	})
+	if len(values) == 0 {
+		return nil
+	}
+
+	next := 0
+	var latest *models.AccountValue
+	for i := range changes {
+		start := changes[i].Date
+		end := bucketEnd(start, query)
+		if account.DeletedAt.Valid && account.DeletedAt.Time.Before(end) {
+			break
+		}
+		for next < len(values) && values[next].AsOf.Before(start) {
+			latest = &values[next]
+			next++
+		}
+		opening := latest
+		for next < len(values) && values[next].AsOf.Before(end) {
+			latest = &values[next]
+			next++
+		}
+		if latest == nil {
+			continue
+		}
+		if opening == nil {
+			opening = &values[0]
+		}
+
+		startValue, err := query.converter.convert(opening.Value, account.Currency, end)
+		if err != nil {
+			return err
+		}
+		endValue, err := query.converter.convert(latest.Value, account.Currency, end)
+		if err != nil {
+			return err
+		}
+		if account.Class == models.Liability {
+			startValue, endValue = startValue.Neg(), endValue.Neg()
+		}
+		changes[i].Start = changes[i].Start.Add(startValue)
+		changes[i].End = changes[i].End.Add(endValue)
+	}
+	return nil
+}
+
+// firstValue returns the date of the account's earliest value.
+func firstValue(account models.Account) time.Time {
+	var first time.Time
+	for i, value := range account.Values {
+		if i == 0 || value.AsOf.Before(first) {
+			first = value.AsOf
+		}
+	}
+	return first
+}
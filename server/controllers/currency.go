@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/shopspring/decimal"
+)
+
+var errMissingFXRate = errors.New("missing exchange rate")
+
+// converter converts balances into the reporting currency. Rates quoted the
+// other way round are inverted, but rates are never chained through a third
+// currency. A converter without a currency leaves every balance as it is.
+type converter struct {
+	currency models.Currency
+	// rates holds the rates into currency from every other currency, oldest
+	// first.
+	rates map[models.Currency][]models.FXRate
+}
+
+func newConverter(currency models.Currency, rates []models.FXRate) converter {
+	c := converter{currency: currency, rates: map[models.Currency][]models.FXRate{}}
+	one := decimal.NewFromInt(1)
+	for _, rate := range rates {
+		switch currency {
+		case rate.Quote:
+			c.rates[rate.Base] = append(c.rates[rate.Base], rate)
+		case rate.Base:
+			inverted := models.FXRate{Date: rate.Date, Base: rate.Quote, Quote: rate.Base, Rate: one.Div(rate.Rate)}
+			c.rates[rate.Quote] = append(c.rates[rate.Quote], inverted)
+		}
+	}
+	for _, rates := range c.rates {
+		sort.SliceStable(rates, func(i, j int) bool { return rates[i].Date.Before(rates[j].Date) })
+	}
+	return c
+}
+
+// convert converts value from the currency using the latest rate dated before
+// end. The result is not rounded, that is left to whoever shows it. Accounts
+// without a currency are in models.DefaultCurrency.
+func (c converter) convert(value decimal.Decimal, from models.Currency, end time.Time) (decimal.Decimal, error) {
+	if from == "" {
+		from = models.DefaultCurrency
+	}
+	if c.currency == "" || from == c.currency || value.IsZero() {
+		return value, nil
+	}
+
+	rates := c.rates[from]
+	i := sort.Search(len(rates), func(i int) bool { return !rates[i].Date.Before(end) })
+	if i == 0 {
+		return value, fmt.Errorf("%w: no %s/%s rate before %s", errMissingFXRate, from, c.currency, end.Format(time.RFC3339))
+	}
+	return value.Mul(rates[i-1].Rate), nil
+}
+
+// convertTotals converts balances summed per currency for each bucket and
+// adds them up into points.
+func convertTotals(points []NetWorthPoint, totals map[models.Currency][]decimal.Decimal, query netWorthQuery) error {
+	for currency, values := range totals {
+		for i, value := range values {
+			converted, err := query.converter.convert(value, currency, bucketEnd(points[i].Date, query))
+			if err != nil {
+				return err
+			}
+			points[i].Value = points[i].Value.Add(converted)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func TestConverterConvert(t *testing.T) {
+	jan := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+	c := newConverter("USD", []models.FXRate{
+		{Date: jan, Base: "EUR", Quote: "USD", Rate: decimal.RequireFromString("1.1")},
+		{Date: feb, Base: "EUR", Quote: "USD", Rate: decimal.RequireFromString("1.2")},
+		{Date: jan, Base: "USD", Quote: "JPY", Rate: decimal.RequireFromString("130")},
+	})
+
+	tests := []struct {
+		name    string
+		value   string
+		from    models.Currency
+		end     time.Time
+		want    string
+		wantErr bool
+	}{
+		{name: "should use the latest rate before the end", value: "100", from: "EUR", end: feb.AddDate(0, 0, 1), want: "120"},
+		{name: "should not use a rate dated at the end", value: "100", from: "EUR", end: feb, want: "110"},
+		{name: "should invert rates quoted the other way round without rounding", value: "1000", from: "JPY", end: feb, want: "7.6923076923077"},
+		{name: "should keep balances in the reporting currency", value: "100.005", from: "USD", end: feb, want: "100.005"},
+		{name: "should treat accounts without a currency as the default", value: "100", from: "", end: feb, want: "100"},
+		{name: "should not need a rate for zero", value: "0", from: "GBP", end: feb, want: "0"},
+		{name: "should error before the first rate", value: "100", from: "EUR", end: jan, wantErr: true},
+		{name: "should error without a rate for the pair", value: "100", from: "GBP", end: feb, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			converted, err := c.convert(decimal.RequireFromString(test.value), test.from, test.end)
+			assert.Equal(t, test.wantErr, errors.Is(err, errMissingFXRate))
+			if !test.wantErr {
+				assert.Equal(t, test.want, converted.String())
+			}
+		})
+	}
+}
+
+func TestConverterWithoutCurrency(t *testing.T) {
+	converted, err := converter{}.convert(decimal.NewFromInt(100), "EUR", time.Now())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "100", converted.String())
+}
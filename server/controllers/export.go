@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/export"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ExportController struct {
+	DB *gorm.DB
+}
+
+func NewExportController(db *gorm.DB, router *gin.RouterGroup) ExportController {
+	exportController := ExportController{DB: db}
+	router.GET("/export", exportController.Export)
+	return exportController
+}
+
+// Export streams every account and value of the caller's household as a JSON
+// document (the default) or as CSV with format=csv. The JSON document can be
+// restored with POST /import/json.
+func (controller *ExportController) Export(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	format := context.DefaultQuery("format", "json")
+
+	var write func(*gorm.DB, uint, io.Writer) error
+	var contentType string
+	switch format {
+	case "json":
+		contentType = "application/json"
+		write = export.WriteJSON
+	case "csv":
+		contentType = "text/csv"
+		write = export.WriteCSV
+	default:
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid format: " + format})
+		return
+	}
+
+	filename := fmt.Sprintf("financial-dashboard-%s.%s", time.Now().Format("2006-01-02"), format)
+	context.Header("Content-Type", contentType)
+	context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	context.Status(http.StatusOK)
+
+	// The status has already been sent once streaming starts, so a failure
+	// part way through can only be logged and the response cut short.
+	if err := write(controller.DB, household, context.Writer); err != nil {
+		log.Printf("export failed: %s", err)
+		context.Abort()
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+)
+
+func TestNewExportController(t *testing.T) {
+	db, _, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api", loggedIn)
+	controller := NewExportController(db, group)
+
+	assert.Equal(t, controller.DB, db)
+}
+
+func TestExport(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	tests := []struct {
+		name         string
+		url          string
+		responseCode int
+		contentType  string
+		expectations func()
+	}{
+		{
+			name:         "should reject an unknown format",
+			url:          "/api/export?format=xml",
+			responseCode: http.StatusBadRequest,
+			contentType:  "application/json; charset=utf-8",
+			expectations: func() {},
+		},
+		{
+			name:         "should export JSON by default",
+			url:          "/api/export",
+			responseCode: http.StatusOK,
+			contentType:  "application/json",
+			expectations: func() {
+				mock.ExpectQuery("SELECT (.+) FROM \"accounts\"").
+					WithArgs(testUser.HouseholdID).
+					WillReturnRows(sqlmock.NewRows(models.AccountColumns))
+				mock.ExpectQuery("SELECT (.+) FROM \"ofx_account_mappings\"").
+					WithArgs(testUser.HouseholdID).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery("SELECT (.+) FROM \"cash_flows\"").
+					WithArgs(testUser.HouseholdID).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery("SELECT (.+) FROM \"fx_rates\"").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery("SELECT (.+) FROM \"positions\"").
+					WithArgs(testUser.HouseholdID).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery("SELECT (.+) FROM \"lots\"").
+					WithArgs(testUser.HouseholdID).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery("SELECT (.+) FROM \"sales\"").
+					WithArgs(testUser.HouseholdID).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+		},
+		{
+			name:         "should export CSV",
+			url:          "/api/export?format=csv",
+			responseCode: http.StatusOK,
+			contentType:  "text/csv",
+			expectations: func() {
+				mock.ExpectQuery("SELECT (.+) FROM \"accounts\"").
+					WithArgs(testUser.HouseholdID).
+					WillReturnRows(sqlmock.NewRows(models.AccountColumns))
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api", loggedIn)
+	NewExportController(db, group)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			test.expectations()
+			req, _ := http.NewRequest("GET", test.url, nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, test.responseCode, w.Code)
+			assert.Equal(t, test.contentType, w.Header().Get("Content-Type"))
+			if test.responseCode == http.StatusOK {
+				assert.Equal(t, true, strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment;"))
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -1,32 +1,237 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
-	"gorm.io/gorm"
 )
 
 type FinanceController struct {
-	DB *gorm.DB
+	Store store.Store
 }
 
-func NewFinanceController(db *gorm.DB, router *gin.RouterGroup) {
-	financeController := FinanceController{DB: db}
+func NewFinanceController(s store.Store, router *gin.RouterGroup) FinanceController {
+	financeController := FinanceController{Store: s}
 	router.GET("/networth", financeController.GetNetWorthOverTime)
+	router.GET("/networth/changes", financeController.GetBalanceChanges)
+	return financeController
 }
 
+// GetNetWorthOverTime returns the household's net worth at the end of each
+// bucket of the query's interval, or a NetWorthBreakdown by group when
+// groupBy is set.
 func (fc *FinanceController) GetNetWorthOverTime(context *gin.Context) {
-	accounts, err := models.GetAllAccountsWithValues(fc.DB)
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	query, err := parseNetWorthQuery(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Without a member every account counts in full toward the joint total.
+	query.Member, ok = parseMember(context, fc.Store, household)
+	if !ok {
+		return
+	}
+
+	result, err := fc.netWorth(household, query)
+	if errors.Is(err, errTooManyBuckets) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// A balance that cannot be converted for lack of a rate fails the request.
+	if errors.Is(err, errMissingFXRate) {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result.round(query.Currency.Precision())
+	if query.GroupBy == "" {
+		context.JSON(http.StatusOK, result.Total)
+		return
+	}
+	context.JSON(http.StatusOK, result)
+}
+
+// netWorth aggregates the household's accounts in the database when the store
+// is backed by one that can, and otherwise loads every value and rolls them up
+// in memory. Accounts holding securities are valued from their positions and
+// prices, so they are always rolled up in memory, alongside the database
+// aggregate of the rest.
+func (fc *FinanceController) netWorth(householdID uint, query netWorthQuery) (NetWorthBreakdown, error) {
+	rates, err := fc.Store.GetFXRates(query.Currency)
 	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return NetWorthBreakdown{}, err
+	}
+	// Each balance is converted at the latest rate dated before the end of its
+	// bucket.
+	query.converter = newConverter(query.Currency, rates)
+
+	positions, err := fc.Store.GetAllPositions(householdID)
+	if err != nil {
+		return NetWorthBreakdown{}, err
+	}
+
+	if gormStore, ok := fc.Store.(*store.GormStore); ok && canRollupInDatabase(gormStore.DB, query) {
+		held, err := heldAccounts(gormStore, householdID, positions)
+		if err != nil {
+			return NetWorthBreakdown{}, err
+		}
+		return rollupInDatabase(gormStore.DB, householdID, query, forMember(held, query.Member))
+	}
+
+	accounts, err := fc.Store.GetAllAccountsWithValuesIncludingDeleted(householdID)
+	if err != nil {
+		return NetWorthBreakdown{}, err
+	}
+	accounts, err = valueHoldings(fc.Store, accounts, positions)
+	if err != nil {
+		return NetWorthBreakdown{}, err
+	}
+	accounts = forMember(accounts, query.Member)
+	if query.GroupBy == "" {
+		points, err := rollup(accounts, query)
+		return NetWorthBreakdown{Total: points}, err
+	}
+	return breakdown(accounts, query)
+}
+
+type GroupBy string
+
+const (
+	GroupByClass     GroupBy = "class"
+	GroupByCategory  GroupBy = "category"
+	GroupByTaxBucket GroupBy = "taxBucket"
+)
+
+// unassignedGroup is the group of accounts without a tax bucket.
+const unassignedGroup = "unassigned"
+
+func ParseGroupBy(s string) (GroupBy, error) {
+	switch groupBy := GroupBy(s); groupBy {
+	case GroupByClass, GroupByCategory, GroupByTaxBucket:
+		return groupBy, nil
+	}
+	return "", fmt.Errorf("invalid groupBy %q, expected class, category or taxBucket", s)
+}
+
+// Key returns the group an account belongs to.
+func (g GroupBy) Key(account models.Account) string {
+	switch g {
+	case GroupByClass:
+		return account.Class.String()
+	case GroupByCategory:
+		return account.Category.String()
+	case GroupByTaxBucket:
+		if account.TaxBucket == "" {
+			return unassignedGroup
+		}
+		return account.TaxBucket.String()
 	}
-	networth := rollup(5*time.Second, accounts)
-	context.JSON(http.StatusOK, networth)
+	return ""
+}
+
+// maxBuckets limits how many points a single net worth request can return.
+const maxBuckets = 10000
+
+var errTooManyBuckets = errors.New("too many points")
+
+type netWorthQuery struct {
+	Interval Interval
+	Location *time.Location
+	GroupBy  GroupBy
+	// From and To bound the buckets returned. To is exclusive and a zero value
+	// leaves that end unbounded.
+	From time.Time
+	To   time.Time
+	// Member, when set, is the user whose share of net worth is rolled up.
+	Member uint
+	// Currency is the currency net worth is reported in, converted with the
+	// rates in converter.
+	Currency  models.Currency
+	converter converter
+}
+
+func parseNetWorthQuery(context *gin.Context) (netWorthQuery, error) {
+	query := netWorthQuery{}
+
+	var err error
+	query.Interval, err = ParseInterval(context.DefaultQuery("interval", string(IntervalMonth)))
+	if err != nil {
+		return query, err
+	}
+
+	if groupBy := context.Query("groupBy"); groupBy != "" {
+		query.GroupBy, err = ParseGroupBy(groupBy)
+		if err != nil {
+			return query, err
+		}
+	}
+
+	// Net worth is reported in currency and rounded to its precision.
+	currency := context.DefaultQuery("currency", models.DefaultCurrency.String())
+	query.Currency, err = models.ParseCurrency(strings.ToUpper(currency))
+	if err != nil {
+		return query, err
+	}
+
+	// Buckets start on calendar boundaries in timezone, an IANA name.
+	timezone := context.DefaultQuery("timezone", "UTC")
+	query.Location, err = time.LoadLocation(timezone)
+	if err != nil {
+		return query, fmt.Errorf("invalid timezone %q", timezone)
+	}
+
+	if from := context.Query("from"); from != "" {
+		query.From, _, err = parseQueryTime(from, query.Location)
+		if err != nil {
+			return query, fmt.Errorf("invalid from %q, expected YYYY-MM-DD or an RFC 3339 timestamp", from)
+		}
+	}
+
+	if to := context.Query("to"); to != "" {
+		var dateOnly bool
+		query.To, dateOnly, err = parseQueryTime(to, query.Location)
+		if err != nil {
+			return query, fmt.Errorf("invalid to %q, expected YYYY-MM-DD or an RFC 3339 timestamp", to)
+		}
+		// A date in to includes that whole day.
+		if dateOnly {
+			query.To = query.To.AddDate(0, 0, 1)
+		} else {
+			query.To = query.To.Add(time.Nanosecond)
+		}
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return query, fmt.Errorf("from must be before to")
+	}
+	return query, nil
+}
+
+// parseQueryTime parses a date in loc or an RFC 3339 timestamp, reporting
+// which of the two it was.
+func parseQueryTime(s string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, false, err
 }
 
 type NetWorthPoint struct {
@@ -34,89 +239,189 @@ type NetWorthPoint struct {
 	Value decimal.Decimal `json:"value"`
 }
 
-// returns a sorted map by descending timestamps
-func mapToSortedList(values map[time.Time]decimal.Decimal) []NetWorthPoint {
-	times := []time.Time{}
-	for k := range values {
-		times = append(times, k)
-	}
+// NetWorthSeries is the net worth of the accounts in one group.
+type NetWorthSeries struct {
+	Group  string          `json:"group"`
+	Points []NetWorthPoint `json:"points"`
+}
 
-	sort.SliceStable(times, func(i, j int) bool {
-		return times[i].Before(times[j])
-	})
+// NetWorthBreakdown splits net worth into groups. Every series, including the
+// total, has a point for the same dates so they can be stacked.
+type NetWorthBreakdown struct {
+	GroupBy GroupBy          `json:"groupBy"`
+	Total   []NetWorthPoint  `json:"total"`
+	Groups  []NetWorthSeries `json:"groups"`
+}
 
-	sorted := make([]NetWorthPoint, len(times))
-	for i, ts := range times {
-		sorted[i] = NetWorthPoint{
-			Date:  ts,
-			Value: values[ts],
+// round rounds every point to the given number of decimal places. Net worth is
+// computed from values as recorded and only rounded once it is shown.
+func (b NetWorthBreakdown) round(places int32) {
+	for i := range b.Total {
+		b.Total[i].Value = b.Total[i].Value.Round(places)
+	}
+	for _, group := range b.Groups {
+		for i := range group.Points {
+			group.Points[i].Value = group.Points[i].Value.Round(places)
 		}
 	}
-	return sorted
 }
 
-func createTimeBuckets(accounts []models.Account, interval time.Duration) []time.Time {
-	firstDate := accounts[0].Values[0].CreatedAt
-	lastDate := accounts[0].Values[0].CreatedAt
+// createTimeBuckets returns the start of every bucket from the query's from
+// date, or the earliest value, through to the query's to date, or the latest
+// value.
+func createTimeBuckets(accounts []models.Account, query netWorthQuery) ([]time.Time, error) {
+	var firstDate, lastDate time.Time
+	found := false
 	for _, account := range accounts {
 		for _, value := range account.Values {
-			if value.CreatedAt.Before(firstDate) {
-				firstDate = value.CreatedAt
+			if !found || value.AsOf.Before(firstDate) {
+				firstDate = value.AsOf
 			}
-			if value.CreatedAt.After(lastDate) {
-				lastDate = value.CreatedAt
+			if !found || value.AsOf.After(lastDate) {
+				lastDate = value.AsOf
 			}
+			found = true
 		}
 	}
+	if !found {
+		return []time.Time{}, nil
+	}
+	return bucketsBetween(firstDate, lastDate, query)
+}
+
+// bucketsBetween returns the start of every bucket covering the earliest and
+// latest values, narrowed or widened to the query's date range.
+func bucketsBetween(firstDate, lastDate time.Time, query netWorthQuery) ([]time.Time, error) {
+	if !query.From.IsZero() {
+		firstDate = query.From
+		if lastDate.Before(firstDate) {
+			lastDate = firstDate
+		}
+	}
+	if !query.To.IsZero() {
+		lastDate = query.To.Add(-time.Nanosecond)
+	}
 
-	timeSpan := lastDate.Sub(firstDate)
-	numBuckets := (timeSpan.Nanoseconds() / interval.Nanoseconds()) + 1
 	buckets := []time.Time{}
-	for i := int64(1); i <= numBuckets; i++ {
-		next := time.Duration(i-1) * interval
-		buckets = append(buckets, firstDate.Round(interval).Add(next))
+	for bucket := query.Interval.Truncate(firstDate, query.Location); !bucket.After(lastDate); bucket = query.Interval.Next(bucket) {
+		if len(buckets) == maxBuckets {
+			return nil, fmt.Errorf("%w: interval %s is too small for the date range, it would return more than %d points", errTooManyBuckets, query.Interval, maxBuckets)
+		}
+		buckets = append(buckets, bucket)
 	}
+	return buckets, nil
+}
 
-	return buckets
+func rollup(accounts []models.Account, query netWorthQuery) ([]NetWorthPoint, error) {
+	buckets, err := createTimeBuckets(accounts, query)
+	if err != nil {
+		return nil, err
+	}
+	return rollupBuckets(accounts, buckets, query)
 }
 
-func fillBuckets(accounts []models.Account, values map[time.Time]decimal.Decimal, buckets []time.Time, interval time.Duration) {
+// breakdown rolls up each group of accounts into the buckets of the total so
+// every series lines up.
+func breakdown(accounts []models.Account, query netWorthQuery) (NetWorthBreakdown, error) {
+	buckets, err := createTimeBuckets(accounts, query)
+	if err != nil {
+		return NetWorthBreakdown{}, err
+	}
+
+	groups := map[string][]models.Account{}
 	for _, account := range accounts {
-		var bucketIndex int
-		mostRecentValue := account.Values[0]
-		for i, bucket := range buckets {
-			if bucket == mostRecentValue.CreatedAt.Round(interval) {
-				bucketIndex = i
-			}
-		}
+		key := query.GroupBy.Key(account)
+		groups[key] = append(groups[key], account)
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-		if bucketIndex < len(buckets)-1 {
-			for i := len(buckets) - 1; i > bucketIndex; i-- {
-				bucket := buckets[i]
-				if account.Class == models.Asset {
-					values[bucket] = values[bucket].Add(mostRecentValue.Value)
-				} else {
-					values[bucket] = values[bucket].Sub(mostRecentValue.Value)
-				}
-			}
+	total, err := rollupBuckets(accounts, buckets, query)
+	if err != nil {
+		return NetWorthBreakdown{}, err
+	}
+	result := NetWorthBreakdown{
+		GroupBy: query.GroupBy,
+		Total:   total,
+		Groups:  make([]NetWorthSeries, len(keys)),
+	}
+	for i, key := range keys {
+		points, err := rollupBuckets(groups[key], buckets, query)
+		if err != nil {
+			return NetWorthBreakdown{}, err
 		}
+		result.Groups[i] = NetWorthSeries{Group: key, Points: points}
+	}
+	return result, nil
+}
+
+// bucketEnd returns the exclusive end of the bucket starting at start, cut
+// short at the end of the query's date range.
+func bucketEnd(start time.Time, query netWorthQuery) time.Time {
+	end := query.Interval.Next(start)
+	if !query.To.IsZero() && query.To.Before(end) {
+		return query.To
 	}
+	return end
 }
 
-func rollup(interval time.Duration, accounts []models.Account) []NetWorthPoint {
-	buckets := createTimeBuckets(accounts, interval)
-	values := make(map[time.Time]decimal.Decimal, len(buckets))
-	fillBuckets(accounts, values, buckets, interval)
+// rollupBuckets returns the net worth at the end of each bucket. Each account
+// contributes exactly one balance per bucket, its latest value as of the end
+// of the bucket. Accounts contribute nothing before their first value or once
+// they have been deleted. Balances are summed in their own currency and each
+// sum converted once per bucket.
+func rollupBuckets(accounts []models.Account, buckets []time.Time, query netWorthQuery) ([]NetWorthPoint, error) {
+	points := make([]NetWorthPoint, len(buckets))
+	for i, bucket := range buckets {
+		points[i] = NetWorthPoint{Date: bucket, Value: decimal.Zero}
+	}
+	return points, convertTotals(points, sumBuckets(accounts, buckets, query), query)
+}
 
+// sumBuckets sums the balances rollupBuckets counts in each bucket per
+// currency, before they are converted.
+func sumBuckets(accounts []models.Account, buckets []time.Time, query netWorthQuery) map[models.Currency][]decimal.Decimal {
+	totals := map[models.Currency][]decimal.Decimal{}
 	for _, account := range accounts {
-		for _, accountValue := range account.Values {
-			ts := accountValue.CreatedAt.Round(interval)
+		sums, ok := totals[account.Currency]
+		if !ok {
+			sums = make([]decimal.Decimal, len(buckets))
+			totals[account.Currency] = sums
+		}
+
+		values := make([]models.AccountValue, len(account.Values))
+		copy(values, account.Values)
+		sort.SliceStable(values, func(i, j int) bool {
+			if values[i].AsOf.Equal(values[j].AsOf) {
+				return values[i].ID < values[j].ID
+			}
+			return values[i].AsOf.Before(values[j].AsOf)
+		})
+
+		next := 0
+		var latest *models.AccountValue
+		for i, bucket := range buckets {
+			end := bucketEnd(bucket, query)
+			for next < len(values) && values[next].AsOf.Before(end) {
+				latest = &values[next]
+				next++
+			}
+			if latest == nil {
+				continue
+			}
+			if account.DeletedAt.Valid && account.DeletedAt.Time.Before(end) {
+				break
+			}
+
 			if account.Class == models.Asset {
-				values[ts] = values[ts].Add(accountValue.Value)
+				sums[i] = sums[i].Add(latest.Value)
 			} else {
-				values[ts] = values[ts].Sub(accountValue.Value)
+				sums[i] = sums[i].Sub(latest.Value)
 			}
 		}
 	}
-	return mapToSortedList(values)
+	return totals
 }
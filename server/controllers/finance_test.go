@@ -1,102 +1,33 @@
 package controllers
 
 import (
-	"fmt"
-	"io"
+	"encoding/json"
 	"net/http"
-	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/assert/v2"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 func TestNewFinanceController(t *testing.T) {
-	db, _, err := models.CreateMockDatabase()
-	if err != nil {
-		t.Errorf(err.Error())
-	}
-	d, _ := db.DB()
-	defer d.Close()
-
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	group := router.Group("/api")
-	controller := NewAccountController(db, group)
+	group := router.Group("/api", loggedIn)
+	accountStore := store.NewMemoryStore()
+	controller := NewFinanceController(accountStore, group)
 
-	assert.Equal(t, controller.DB, db)
-}
-
-func TestMapToSortedList(t *testing.T) {
-	now := time.Now()
-	tests := []struct {
-		want  []NetWorthPoint
-		input map[time.Time]decimal.Decimal
-	}{
-		{
-			want: []NetWorthPoint{
-				{
-					Date:  now,
-					Value: decimal.Zero,
-				},
-			},
-			input: map[time.Time]decimal.Decimal{
-				now: decimal.Zero,
-			},
-		},
-		{
-			want: []NetWorthPoint{
-				{
-					Date:  now,
-					Value: decimal.Zero,
-				},
-				{
-					Date:  now.Add(1 * time.Hour),
-					Value: decimal.Zero,
-				},
-				{
-					Date:  now.Add(2 * time.Hour),
-					Value: decimal.Zero,
-				},
-				{
-					Date:  now.Add(3 * time.Hour),
-					Value: decimal.Zero,
-				},
-				{
-					Date:  now.Add(4 * time.Hour),
-					Value: decimal.Zero,
-				},
-				{
-					Date:  now.Add(5 * time.Hour),
-					Value: decimal.Zero,
-				},
-			},
-			input: map[time.Time]decimal.Decimal{
-				now.Add(1 * time.Hour): decimal.Zero,
-				now.Add(5 * time.Hour): decimal.Zero,
-				now:                    decimal.Zero,
-				now.Add(2 * time.Hour): decimal.Zero,
-				now.Add(4 * time.Hour): decimal.Zero,
-				now.Add(3 * time.Hour): decimal.Zero,
-			},
-		},
-	}
-
-	for _, test := range tests {
-		sorted := mapToSortedList(test.input)
-		for i, e := range sorted {
-			assert.Equal(t, e.Date, test.want[i].Date)
-			assert.Equal(t, e.Value, test.want[i].Value)
-		}
-	}
+	assert.Equal(t, controller.Store, store.Store(accountStore))
 }
 
 func TestCreateTimeBuckets(t *testing.T) {
-	now := time.Now()
+	now := time.Now().UTC()
 	interval := 24 * time.Hour
+	query := netWorthQuery{Interval: Interval{Unit: IntervalDay}, Location: time.UTC}
 	tests := []struct {
 		name     string
 		want     []time.Time
@@ -105,16 +36,15 @@ func TestCreateTimeBuckets(t *testing.T) {
 		{
 			name: "create 1 buckets",
 			want: []time.Time{
-				now.Round(interval),
+				now.Truncate(interval),
 			},
 			accounts: []models.Account{
 				{
 					Name: "test",
 					Values: []models.AccountValue{
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -122,9 +52,8 @@ func TestCreateTimeBuckets(t *testing.T) {
 					Name: "test2",
 					Values: []models.AccountValue{
 						{
-							AccountName: "test2",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -133,22 +62,20 @@ func TestCreateTimeBuckets(t *testing.T) {
 		{
 			name: "create 2 buckets",
 			want: []time.Time{
-				now.Round(interval),
-				now.Add(interval).Round(interval),
+				now.Truncate(interval),
+				now.Add(interval).Truncate(interval),
 			},
 			accounts: []models.Account{
 				{
 					Name: "test",
 					Values: []models.AccountValue{
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now.Add(interval),
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now.Add(interval),
 						},
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -156,9 +83,8 @@ func TestCreateTimeBuckets(t *testing.T) {
 					Name: "test2",
 					Values: []models.AccountValue{
 						{
-							AccountName: "test2",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -167,19 +93,18 @@ func TestCreateTimeBuckets(t *testing.T) {
 		{
 			name: "create 4 buckets",
 			want: []time.Time{
-				now.Round(interval),
-				now.Add(interval).Round(interval),
-				now.Add(interval * 2).Round(interval),
-				now.Add(interval * 3).Round(interval),
+				now.Truncate(interval),
+				now.Add(interval).Truncate(interval),
+				now.Add(interval * 2).Truncate(interval),
+				now.Add(interval * 3).Truncate(interval),
 			},
 			accounts: []models.Account{
 				{
 					Name: "test",
 					Values: []models.AccountValue{
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now.Add(interval * 2),
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now.Add(interval * 2),
 						},
 					},
 				},
@@ -187,9 +112,8 @@ func TestCreateTimeBuckets(t *testing.T) {
 					Name: "test",
 					Values: []models.AccountValue{
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now.Add(interval * 3),
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now.Add(interval * 3),
 						},
 					},
 				},
@@ -197,9 +121,8 @@ func TestCreateTimeBuckets(t *testing.T) {
 					Name: "test",
 					Values: []models.AccountValue{
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now.Add(interval * 2),
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now.Add(interval * 2),
 						},
 					},
 				},
@@ -207,9 +130,8 @@ func TestCreateTimeBuckets(t *testing.T) {
 					Name: "test",
 					Values: []models.AccountValue{
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now.Add(interval),
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now.Add(interval),
 						},
 					},
 				},
@@ -217,9 +139,8 @@ func TestCreateTimeBuckets(t *testing.T) {
 					Name: "test2",
 					Values: []models.AccountValue{
 						{
-							AccountName: "test2",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -229,7 +150,7 @@ func TestCreateTimeBuckets(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			buckets := createTimeBuckets(test.accounts, interval)
+			buckets, _ := createTimeBuckets(test.accounts, query)
 			assert.Equal(t, len(buckets), len(test.want))
 			for i := range test.want {
 				if !test.want[i].Equal(buckets[i]) {
@@ -243,75 +164,10 @@ func TestCreateTimeBuckets(t *testing.T) {
 	}
 }
 
-func TestFillBuckets(t *testing.T) {
-	now := time.Now()
-	interval := 24 * time.Hour
-	tests := []struct {
-		name     string
-		want     map[time.Time]decimal.Decimal
-		accounts []models.Account
-	}{
-		{
-			name: "base",
-			want: map[time.Time]decimal.Decimal{
-				now.Add(interval).Round(interval): decimal.NewFromInt(1),
-			},
-			accounts: []models.Account{
-				{
-					Name:     "test",
-					Category: models.Cash,
-					Class:    models.Asset,
-					Values: []models.AccountValue{
-						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now.Add(interval),
-						},
-						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
-						},
-					},
-				},
-				{
-					Name:     "test2",
-					Category: models.Cash,
-					Class:    models.Asset,
-					Values: []models.AccountValue{
-						{
-							AccountName: "test2",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
-						},
-					},
-				},
-			},
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			buckets := createTimeBuckets(test.accounts, interval)
-			values := make(map[time.Time]decimal.Decimal, len(buckets))
-			fillBuckets(test.accounts, values, buckets, interval)
-
-			assert.Equal(t, len(values), len(test.want))
-			for i := range test.want {
-				if !test.want[i].Equal(values[i]) {
-					t.Errorf("wanted: %v, got: %v", test.want[i], values[i])
-				}
-				if !test.want[i].Equal(values[i]) {
-					t.Errorf("wanted: %v, got: %v", test.want[i], values[i])
-				}
-			}
-		})
-	}
-}
-
 func TestRollup(t *testing.T) {
-	now := time.Now()
+	now := time.Now().UTC()
 	interval := 24 * time.Hour
+	query := netWorthQuery{Interval: Interval{Unit: IntervalDay}, Location: time.UTC}
 	tests := []struct {
 		name     string
 		want     []NetWorthPoint
@@ -321,7 +177,7 @@ func TestRollup(t *testing.T) {
 			name: "rollup into 1 window",
 			want: []NetWorthPoint{
 				{
-					Date:  now.Round(interval),
+					Date:  now.Truncate(interval),
 					Value: decimal.Zero,
 				},
 			},
@@ -332,9 +188,8 @@ func TestRollup(t *testing.T) {
 					Class:    models.Asset,
 					Values: []models.AccountValue{
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -344,9 +199,8 @@ func TestRollup(t *testing.T) {
 					Class:    models.Asset,
 					Values: []models.AccountValue{
 						{
-							AccountName: "test2",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -356,9 +210,8 @@ func TestRollup(t *testing.T) {
 					Class:    models.Liability,
 					Values: []models.AccountValue{
 						{
-							AccountName: "test3",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -368,9 +221,8 @@ func TestRollup(t *testing.T) {
 					Class:    models.Liability,
 					Values: []models.AccountValue{
 						{
-							AccountName: "test4",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -380,11 +232,11 @@ func TestRollup(t *testing.T) {
 			name: "rollup into 2 windows",
 			want: []NetWorthPoint{
 				{
-					Date:  now.Round(interval),
+					Date:  now.Truncate(interval),
 					Value: decimal.NewFromInt(1),
 				},
 				{
-					Date:  now.Round(interval).Add(interval),
+					Date:  now.Truncate(interval).Add(interval),
 					Value: decimal.NewFromInt(2),
 				},
 			},
@@ -395,14 +247,12 @@ func TestRollup(t *testing.T) {
 					Class:    models.Asset,
 					Values: []models.AccountValue{
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(3).Round(2),
-							CreatedAt:   now.Add(interval),
+							Value: decimal.NewFromInt(3).Round(2),
+							AsOf:  now.Add(interval),
 						},
 						{
-							AccountName: "test",
-							Value:       decimal.NewFromInt(2).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(2).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -412,9 +262,8 @@ func TestRollup(t *testing.T) {
 					Class:    models.Asset,
 					Values: []models.AccountValue{
 						{
-							AccountName: "test2",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -424,9 +273,8 @@ func TestRollup(t *testing.T) {
 					Class:    models.Liability,
 					Values: []models.AccountValue{
 						{
-							AccountName: "test3",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
 						},
 					},
 				},
@@ -436,9 +284,31 @@ func TestRollup(t *testing.T) {
 					Class:    models.Liability,
 					Values: []models.AccountValue{
 						{
-							AccountName: "test4",
-							Value:       decimal.NewFromInt(1).Round(2),
-							CreatedAt:   now,
+							Value: decimal.NewFromInt(1).Round(2),
+							AsOf:  now,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "rollup buckets by as of date rather than insertion time",
+			want: []NetWorthPoint{
+				{
+					Date:  now.Add(-interval).Truncate(interval),
+					Value: decimal.NewFromInt(1),
+				},
+			},
+			accounts: []models.Account{
+				{
+					Name:     "test",
+					Category: models.Cash,
+					Class:    models.Asset,
+					Values: []models.AccountValue{
+						{
+							Value:     decimal.NewFromInt(1).Round(2),
+							AsOf:      now.Add(-interval),
+							CreatedAt: now,
 						},
 					},
 				},
@@ -448,7 +318,10 @@ func TestRollup(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			values := rollup(interval, test.accounts)
+			values, err := rollup(test.accounts, query)
+			if err != nil {
+				t.Errorf(err.Error())
+			}
 			assert.Equal(t, len(values), len(test.want))
 			for i := range test.want {
 				if !test.want[i].Date.Equal(values[i].Date) {
@@ -462,131 +335,614 @@ func TestRollup(t *testing.T) {
 	}
 }
 
+func registerFinance(s store.Store, group *gin.RouterGroup) {
+	NewFinanceController(s, group)
+}
+
+// netWorthAccounts returns accounts with values at the end of January and
+// February 2023, one of them deleted in March.
+func netWorthAccounts() []models.Account {
+	jan := time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2023, time.February, 28, 0, 0, 0, 0, time.UTC)
+	return []models.Account{
+		{
+			Name:     "checking",
+			Class:    models.Asset,
+			Category: models.Cash,
+			Values: []models.AccountValue{
+				{Value: decimal.NewFromInt(1000), AsOf: jan},
+				{Value: decimal.NewFromInt(1500), AsOf: feb},
+			},
+		},
+		{
+			Name:     "hsa",
+			Class:    models.Asset,
+			Category: models.HSA,
+			Values: []models.AccountValue{
+				{Value: decimal.NewFromInt(200), AsOf: jan},
+			},
+		},
+		{
+			Name:     "card",
+			Class:    models.Liability,
+			Category: models.CreditCard,
+			Values: []models.AccountValue{
+				{Value: decimal.NewFromInt(300), AsOf: feb},
+			},
+		},
+		{
+			Name:      "closed",
+			Class:     models.Asset,
+			Category:  models.Cash,
+			Values:    []models.AccountValue{{Value: decimal.NewFromInt(50), AsOf: jan}},
+			DeletedAt: gorm.DeletedAt{Time: time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		},
+	}
+}
+
 func TestGetNetworthOverTime(t *testing.T) {
-	db, mock, err := models.CreateMockDatabase()
-	if err != nil {
-		t.Errorf(err.Error())
+	tests := []struct {
+		name         string
+		url          string
+		responseCode int
+		want         []string
+	}{
+		{
+			name:         "should return the net worth at the end of each month",
+			url:          "/api/networth",
+			responseCode: http.StatusOK,
+			want:         []string{"1250", "1450"},
+		},
+		{
+			name:         "should limit the dates returned",
+			url:          "/api/networth?from=2023-02-01",
+			responseCode: http.StatusOK,
+			want:         []string{"1450"},
+		},
+		{
+			name:         "should carry balances forward and drop deleted accounts",
+			url:          "/api/networth?from=2023-02-01&to=2023-03-31",
+			responseCode: http.StatusOK,
+			want:         []string{"1450", "1400"},
+		},
+		{
+			name:         "should support durations",
+			url:          "/api/networth?interval=24h&from=2023-02-27&to=2023-02-28",
+			responseCode: http.StatusOK,
+			want:         []string{"1250", "1450"},
+		},
 	}
-	d, _ := db.DB()
-	defer d.Close()
 
-	dateRegex := "[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(-[0-9]{2}:[0-9]{2})?Z?"
-	responseRegex := fmt.Sprintf(`(\[{"date":"%s","value":".*"}\])+`, dateRegex)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(newTestStore(t, netWorthAccounts()...), registerFinance, "GET", test.url, "")
+			assert.Equal(t, test.responseCode, w.Code)
+
+			var points []NetWorthPoint
+			if err := json.Unmarshal(w.Body.Bytes(), &points); err != nil {
+				t.Fatal(err)
+			}
+			values := []string{}
+			for _, point := range points {
+				values = append(values, point.Value.String())
+			}
+			assert.Equal(t, test.want, values)
+		})
+	}
+}
+
+func TestGetNetworthOverTimeForMember(t *testing.T) {
+	// checking is shared unevenly, the hsa belongs to testUser, the card to
+	// testPartner and the closed account to neither.
+	accounts := netWorthAccounts()
+	accounts[0].Owners = []models.AccountOwner{
+		{UserID: testUser.ID, Share: decimal.RequireFromString("66.67")},
+		{UserID: testPartner.ID, Share: decimal.RequireFromString("33.33")},
+	}
+	accounts[1].Owners = []models.AccountOwner{{UserID: testUser.ID, Share: decimal.NewFromInt(100)}}
+	accounts[2].Owners = []models.AccountOwner{{UserID: testPartner.ID, Share: decimal.NewFromInt(100)}}
 
 	tests := []struct {
-		name               string
-		method             string
-		url                string
-		body               io.Reader
-		responseCode       int
-		responseBody       string
-		expectedStatements []models.ExpectedStatement
+		name         string
+		url          string
+		responseCode int
+		want         []string
 	}{
 		{
-			name:         "should successfully return a list of networth over time",
-			method:       "GET",
+			name:         "should count every account in full without a member",
 			url:          "/api/networth",
 			responseCode: http.StatusOK,
-			responseBody: responseRegex,
-			expectedStatements: models.CreateStatementsGetAllAccountsWithValues([]models.Account{
-				{
-					Name:     "test",
-					Category: models.Cash,
-					Class:    models.Asset,
-				},
-				{
-					Name:     "test2",
-					Category: models.HSA,
-					Class:    models.Asset,
-				},
-				{
-					Name:     "test3",
-					Category: models.Loan,
-					Class:    models.Liability,
-				},
-				{
-					Name:     "test4",
-					Category: models.CreditCard,
-					Class:    models.Liability,
-				},
-			}, 1),
+			want:         []string{"1250", "1450"},
+		},
+		{
+			name:         "should count the logged in member's share",
+			url:          "/api/networth?member=me",
+			responseCode: http.StatusOK,
+			want:         []string{"866.7", "1200.05"},
 		},
 		{
-			name:         "should successfully return a list of networth over time",
-			method:       "GET",
+			name:         "should count another member's share",
+			url:          "/api/networth?member=2",
+			responseCode: http.StatusOK,
+			want:         []string{"333.3", "199.95"},
+		},
+		{
+			name:         "should return an error for a user outside the household",
+			url:          "/api/networth?member=3",
+			responseCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(newTestStore(t, accounts...), registerFinance, "GET", test.url, "")
+			assert.Equal(t, test.responseCode, w.Code)
+			if test.want == nil {
+				return
+			}
+
+			var points []NetWorthPoint
+			if err := json.Unmarshal(w.Body.Bytes(), &points); err != nil {
+				t.Fatal(err)
+			}
+			values := []string{}
+			for _, point := range points {
+				values = append(values, point.Value.String())
+			}
+			assert.Equal(t, test.want, values)
+		})
+	}
+}
+
+func TestGetNetworthOverTimeInCurrency(t *testing.T) {
+	// The hsa holds 200 EUR. EUR/USD is 1.1 in January and 1.2 from
+	// mid-February.
+	accounts := netWorthAccounts()
+	accounts[1].Currency = "EUR"
+	rates := []models.FXRate{
+		{Date: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC), Base: "EUR", Quote: "USD", Rate: decimal.RequireFromString("1.1")},
+		{Date: time.Date(2023, time.February, 15, 0, 0, 0, 0, time.UTC), Base: "EUR", Quote: "USD", Rate: decimal.RequireFromString("1.2")},
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		responseCode int
+		want         []string
+	}{
+		{
+			name:         "should convert into USD at the rate in effect at the end of each month",
 			url:          "/api/networth",
 			responseCode: http.StatusOK,
-			responseBody: responseRegex,
-			expectedStatements: models.CreateStatementsGetAllAccountsWithValues([]models.Account{
-				{
-					Name:     "test",
-					Category: models.Cash,
-					Class:    models.Asset,
-				},
-				{
-					Name:     "test2",
-					Category: models.Cash,
-					Class:    models.Asset,
-				},
+			want:         []string{"1270", "1490"},
+		},
+		{
+			name:         "should convert into the reporting currency with inverted rates",
+			url:          "/api/networth?currency=eur",
+			responseCode: http.StatusOK,
+			want:         []string{"1154.55", "1241.67"},
+		},
+		{
+			name:         "should return an error when a rate is missing",
+			url:          "/api/networth?currency=GBP",
+			responseCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "should not need a rate before an account has a balance",
+			url:          "/api/networth?from=2022-12-01",
+			responseCode: http.StatusOK,
+			want:         []string{"0", "1270", "1490"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestStore(t, accounts...)
+			for _, rate := range rates {
+				if _, err := s.SaveFXRate(rate); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w := serve(s, registerFinance, "GET", test.url, "")
+			assert.Equal(t, test.responseCode, w.Code)
+			if test.want == nil {
+				return
+			}
+
+			var points []NetWorthPoint
+			if err := json.Unmarshal(w.Body.Bytes(), &points); err != nil {
+				t.Fatal(err)
+			}
+			values := []string{}
+			for _, point := range points {
+				values = append(values, point.Value.String())
+			}
+			assert.Equal(t, test.want, values)
+		})
+	}
+}
+
+func TestGetNetworthOverTimeRoundsToCurrency(t *testing.T) {
+	s := newTestStore(t, models.Account{
+		Name:     "wallet",
+		Class:    models.Asset,
+		Category: models.Cash,
+		Currency: "BTC",
+		Values: []models.AccountValue{
+			{Value: decimal.RequireFromString("0.12345679"), AsOf: time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		},
+	})
+	rate := models.FXRate{Date: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC), Base: "BTC", Quote: "USD", Rate: decimal.RequireFromString("30000.5")}
+	if _, err := s.SaveFXRate(rate); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "/api/networth", want: "3703.77"},
+		{url: "/api/networth?currency=BTC", want: "0.12345679"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			w := serve(s, registerFinance, "GET", test.url, "")
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var points []NetWorthPoint
+			if err := json.Unmarshal(w.Body.Bytes(), &points); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, 1, len(points))
+			assert.Equal(t, test.want, points[0].Value.String())
+		})
+	}
+}
+
+func TestGetNetworthOverTimeWithoutAccounts(t *testing.T) {
+	w := serve(store.NewMemoryStore(), registerFinance, "GET", "/api/networth", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+}
+
+func TestRollupCalendarIntervals(t *testing.T) {
+	jan := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	accounts := []models.Account{
+		{
+			Name:     "test",
+			Category: models.Cash,
+			Class:    models.Asset,
+			Values: []models.AccountValue{
+				{Value: decimal.NewFromInt(30), AsOf: jan.AddDate(0, 2, 30)},
+				{Value: decimal.NewFromInt(10), AsOf: jan.AddDate(0, 0, 14)},
+			},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query netWorthQuery
+		want  []NetWorthPoint
+	}{
+		{
+			name:  "should bucket by calendar month and carry values into empty months",
+			query: netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC},
+			want: []NetWorthPoint{
+				{Date: jan, Value: decimal.NewFromInt(10)},
+				{Date: jan.AddDate(0, 1, 0), Value: decimal.NewFromInt(10)},
+				{Date: jan.AddDate(0, 2, 0), Value: decimal.NewFromInt(30)},
+			},
+		},
+		{
+			name:  "should bucket by quarter",
+			query: netWorthQuery{Interval: Interval{Unit: IntervalQuarter}, Location: time.UTC},
+			want: []NetWorthPoint{
+				{Date: jan, Value: decimal.NewFromInt(30)},
+			},
+		},
+		{
+			name: "should carry values from before the date range into it",
+			query: netWorthQuery{
+				Interval: Interval{Unit: IntervalMonth},
+				Location: time.UTC,
+				From:     jan.AddDate(0, 1, 0),
+				To:       jan.AddDate(0, 4, 0),
+			},
+			want: []NetWorthPoint{
+				{Date: jan.AddDate(0, 1, 0), Value: decimal.NewFromInt(10)},
+				{Date: jan.AddDate(0, 2, 0), Value: decimal.NewFromInt(30)},
+				{Date: jan.AddDate(0, 3, 0), Value: decimal.NewFromInt(30)},
+			},
+		},
+		{
+			name: "should not include values after the end of the date range",
+			query: netWorthQuery{
+				Interval: Interval{Unit: IntervalMonth},
+				Location: time.UTC,
+				To:       jan.AddDate(0, 2, 15),
+			},
+			want: []NetWorthPoint{
+				{Date: jan, Value: decimal.NewFromInt(10)},
+				{Date: jan.AddDate(0, 1, 0), Value: decimal.NewFromInt(10)},
+				{Date: jan.AddDate(0, 2, 0), Value: decimal.NewFromInt(10)},
+			},
+		},
+		{
+			name:  "should carry the latest value into a range after it",
+			query: netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC, From: jan.AddDate(1, 0, 0)},
+			want: []NetWorthPoint{
+				{Date: jan.AddDate(1, 0, 0), Value: decimal.NewFromInt(30)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := rollup(accounts, test.query)
+			if err != nil {
+				t.Errorf(err.Error())
+			}
+			assert.Equal(t, len(test.want), len(values))
+			for i := range test.want {
+				if !test.want[i].Date.Equal(values[i].Date) {
+					t.Errorf("wanted: %v, got: %v", test.want[i].Date, values[i].Date)
+				}
+				if !test.want[i].Value.Equal(values[i].Value) {
+					t.Errorf("wanted: %v, got: %v", test.want[i].Value, values[i].Value)
+				}
+			}
+		})
+	}
+}
+
+func TestRollupLastObservationCarriedForward(t *testing.T) {
+	jan := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	query := netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC}
+	tests := []struct {
+		name     string
+		want     []decimal.Decimal
+		accounts []models.Account
+	}{
+		{
+			name: "should use only the latest of several values in a bucket",
+			want: []decimal.Decimal{decimal.NewFromInt(300)},
+			accounts: []models.Account{
 				{
-					Name:     "test3",
-					Category: models.Cash,
-					Class:    models.Liability,
+					Name:  "checking",
+					Class: models.Asset,
+					Values: []models.AccountValue{
+						{ID: 1, Value: decimal.NewFromInt(100), AsOf: jan},
+						{ID: 3, Value: decimal.NewFromInt(300), AsOf: jan.AddDate(0, 0, 20)},
+						{ID: 2, Value: decimal.NewFromInt(200), AsOf: jan.AddDate(0, 0, 10)},
+					},
 				},
+			},
+		},
+		{
+			name: "should carry a value forward across a gap",
+			want: []decimal.Decimal{decimal.NewFromInt(100), decimal.NewFromInt(100), decimal.NewFromInt(100), decimal.NewFromInt(50)},
+			accounts: []models.Account{
 				{
-					Name:     "test4",
-					Category: models.CreditCard,
-					Class:    models.Liability,
+					Name:  "checking",
+					Class: models.Asset,
+					Values: []models.AccountValue{
+						{Value: decimal.NewFromInt(50), AsOf: jan.AddDate(0, 3, 0)},
+						{Value: decimal.NewFromInt(100), AsOf: jan},
+					},
 				},
-			}, 1),
+			},
 		},
 		{
-			name:         "should successfully return a list of networth over time",
-			method:       "GET",
-			url:          "/api/networth",
-			responseCode: http.StatusOK,
-			responseBody: responseRegex,
-			expectedStatements: models.CreateStatementsGetAllAccountsWithValues([]models.Account{
+			name: "should not include an account before its first value",
+			want: []decimal.Decimal{decimal.NewFromInt(100), decimal.NewFromInt(60)},
+			accounts: []models.Account{
 				{
-					Name:     "test",
-					Category: models.Cash,
-					Class:    models.Asset,
+					Name:  "checking",
+					Class: models.Asset,
+					Values: []models.AccountValue{
+						{Value: decimal.NewFromInt(100), AsOf: jan},
+					},
 				},
 				{
-					Name:     "test2",
-					Category: models.Loan,
-					Class:    models.Asset,
+					Name:  "credit card",
+					Class: models.Liability,
+					Values: []models.AccountValue{
+						{Value: decimal.NewFromInt(40), AsOf: jan.AddDate(0, 1, 0)},
+					},
 				},
+			},
+		},
+		{
+			name: "should drop a deleted account after it was deleted",
+			want: []decimal.Decimal{decimal.NewFromInt(150), decimal.NewFromInt(150), decimal.NewFromInt(100)},
+			accounts: []models.Account{
 				{
-					Name:     "test3",
-					Category: models.Loan,
-					Class:    models.Liability,
+					Name:  "checking",
+					Class: models.Asset,
+					Values: []models.AccountValue{
+						{Value: decimal.NewFromInt(100), AsOf: jan.AddDate(0, 2, 0)},
+						{Value: decimal.NewFromInt(100), AsOf: jan},
+					},
 				},
 				{
-					Name:     "test4",
-					Category: models.CreditCard,
-					Class:    models.Liability,
+					Name:      "old savings",
+					Class:     models.Asset,
+					DeletedAt: gorm.DeletedAt{Time: jan.AddDate(0, 2, 10), Valid: true},
+					Values: []models.AccountValue{
+						{Value: decimal.NewFromInt(50), AsOf: jan},
+					},
 				},
-			}, 1),
+			},
 		},
 	}
 
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	group := router.Group("/api")
-	NewFinanceController(db, group)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := rollup(test.accounts, query)
+			if err != nil {
+				t.Errorf(err.Error())
+			}
+			assert.Equal(t, len(test.want), len(values))
+			for i := range test.want {
+				if !test.want[i].Equal(values[i].Value) {
+					t.Errorf("bucket %v wanted: %v, got: %v", values[i].Date, test.want[i], values[i].Value)
+				}
+			}
+		})
+	}
+}
+
+func TestRollupTooManyBuckets(t *testing.T) {
+	accounts := []models.Account{
+		{
+			Name:  "test",
+			Class: models.Asset,
+			Values: []models.AccountValue{
+				{Value: decimal.NewFromInt(1), AsOf: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+	query := netWorthQuery{
+		Interval: Interval{Duration: time.Second},
+		Location: time.UTC,
+		To:       time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	_, err := rollup(accounts, query)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestGetNetworthOverTimeInvalidQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "should reject an unknown interval", url: "/api/networth?interval=fortnight"},
+		{name: "should reject a negative duration", url: "/api/networth?interval=-1h"},
+		{name: "should reject an unknown time zone", url: "/api/networth?timezone=Mars/Olympus_Mons"},
+		{name: "should reject an invalid from date", url: "/api/networth?from=yesterday"},
+		{name: "should reject an invalid to date", url: "/api/networth?to=2023-13-01"},
+		{name: "should reject from after to", url: "/api/networth?from=2023-06-01&to=2023-01-01"},
+		{name: "should reject an unknown groupBy", url: "/api/networth?groupBy=owner"},
+		{name: "should reject an invalid currency", url: "/api/networth?currency=dollars"},
+		{name: "should reject too many points", url: "/api/networth?interval=1s"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(newTestStore(t, netWorthAccounts()...), registerFinance, "GET", test.url, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.MatchRegex(t, w.Body.String(), `{"error":".+"}`)
+		})
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	jan := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	accounts := []models.Account{
+		{
+			Name:     "checking",
+			Class:    models.Asset,
+			Category: models.Cash,
+			Values: []models.AccountValue{
+				{Value: decimal.NewFromInt(100), AsOf: jan.AddDate(0, 1, 0)},
+			},
+		},
+		{
+			Name:      "401k",
+			Class:     models.Asset,
+			Category:  models.Retirement,
+			TaxBucket: models.TaxDeferred,
+			Values: []models.AccountValue{
+				{Value: decimal.NewFromInt(1000), AsOf: jan},
+			},
+		},
+		{
+			Name:     "mortgage",
+			Class:    models.Liability,
+			Category: models.Loan,
+			Values: []models.AccountValue{
+				{Value: decimal.NewFromInt(500), AsOf: jan},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		groupBy    GroupBy
+		wantGroups []string
+	}{
+		{
+			name:       "should group by class",
+			groupBy:    GroupByClass,
+			wantGroups: []string{"asset", "liability"},
+		},
+		{
+			name:       "should group by category",
+			groupBy:    GroupByCategory,
+			wantGroups: []string{"cash", "loan", "retirement"},
+		},
+		{
+			name:       "should group accounts without a tax bucket as unassigned",
+			groupBy:    GroupByTaxBucket,
+			wantGroups: []string{"tax-deferred", "unassigned"},
+		},
+	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			models.LoadStatements(mock, test.expectedStatements)
-			req, _ := http.NewRequest(test.method, test.url, test.body)
-			router.ServeHTTP(w, req)
-			if err := mock.ExpectationsWereMet(); err != nil {
-				t.Errorf("there were unfulfilled expectations: %s", err)
+			query := netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC, GroupBy: test.groupBy}
+			result, err := breakdown(accounts, query)
+			if err != nil {
+				t.Errorf(err.Error())
+			}
+
+			assert.Equal(t, test.groupBy, result.GroupBy)
+			assert.Equal(t, 2, len(result.Total))
+			assert.Equal(t, len(test.wantGroups), len(result.Groups))
+			for i, group := range result.Groups {
+				assert.Equal(t, test.wantGroups[i], group.Group)
+				assert.Equal(t, len(result.Total), len(group.Points))
+			}
+
+			for i, point := range result.Total {
+				sum := decimal.Zero
+				for _, group := range result.Groups {
+					if !point.Date.Equal(group.Points[i].Date) {
+						t.Errorf("group %s is not aligned with the total: %v != %v", group.Group, group.Points[i].Date, point.Date)
+					}
+					sum = sum.Add(group.Points[i].Value)
+				}
+				if !sum.Equal(point.Value) {
+					t.Errorf("groups sum to %v, total is %v", sum, point.Value)
+				}
 			}
-			assert.Equal(t, test.responseCode, w.Code)
-			assert.MatchRegex(t, w.Body.String(), test.responseBody)
 		})
 	}
 }
+
+func TestGetNetworthOverTimeGroupBy(t *testing.T) {
+	w := serve(newTestStore(t, netWorthAccounts()...), registerFinance, "GET", "/api/networth?groupBy=category", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result NetWorthBreakdown
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, GroupByCategory, result.GroupBy)
+	assert.Equal(t, 2, len(result.Total))
+
+	got := map[string][]string{}
+	for _, series := range result.Groups {
+		for _, point := range series.Points {
+			got[series.Group] = append(got[series.Group], point.Value.String())
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"cash":        {"1050", "1550"},
+		"credit-card": {"0", "-300"},
+		"hsa":         {"200", "200"},
+	}, got)
+}
@@ -0,0 +1,450 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type GainsController struct {
+	Store store.Store
+}
+
+func NewGainsController(s store.Store, router *gin.RouterGroup) GainsController {
+	gainsController := GainsController{Store: s}
+
+	gainsRouter := router.Group("/gains")
+	{
+		gainsRouter.GET("/realized", gainsController.GetRealizedGains)
+		gainsRouter.GET("/unrealized", gainsController.GetUnrealizedGains)
+	}
+
+	return gainsController
+}
+
+// Term is the holding period a gain is taxed under.
+type Term string
+
+const (
+	ShortTerm Term = "short"
+	LongTerm  Term = "long"
+)
+
+func holdingTerm(acquiredAt, soldAt time.Time) Term {
+	if models.IsLongTerm(acquiredAt, soldAt) {
+		return LongTerm
+	}
+	return ShortTerm
+}
+
+// GainTotals adds up gains by holding period.
+type GainTotals struct {
+	ShortTerm decimal.Decimal `json:"shortTerm"`
+	LongTerm  decimal.Decimal `json:"longTerm"`
+	Total     decimal.Decimal `json:"total"`
+}
+
+func (t *GainTotals) add(term Term, gain decimal.Decimal) {
+	if term == LongTerm {
+		t.LongTerm = t.LongTerm.Add(gain)
+	} else {
+		t.ShortTerm = t.ShortTerm.Add(gain)
+	}
+	t.Total = t.Total.Add(gain)
+}
+
+func (t *GainTotals) round(places int32) {
+	t.ShortTerm = t.ShortTerm.Round(places)
+	t.LongTerm = t.LongTerm.Round(places)
+	t.Total = t.Total.Round(places)
+}
+
+// AccountGains is the total of an account's gains, for a single year in the
+// realized gains report.
+type AccountGains struct {
+	AccountID uint   `json:"accountId"`
+	Account   string `json:"account"`
+	Year      int    `json:"year,omitempty"`
+	GainTotals
+}
+
+// GroupGains is the total of the gains of a group of accounts.
+type GroupGains struct {
+	Group string `json:"group"`
+	GainTotals
+}
+
+// RealizedGain is the gain made selling part of a lot, in the currency of its
+// account.
+type RealizedGain struct {
+	AccountID  uint            `json:"accountId"`
+	SaleID     uint            `json:"saleId"`
+	LotID      uint            `json:"lotId"`
+	Symbol     string          `json:"symbol"`
+	Currency   models.Currency `json:"currency"`
+	Quantity   decimal.Decimal `json:"quantity"`
+	AcquiredAt time.Time       `json:"acquiredAt"`
+	SoldAt     time.Time       `json:"soldAt"`
+	Cost       decimal.Decimal `json:"cost"`
+	Proceeds   decimal.Decimal `json:"proceeds"`
+	Gain       decimal.Decimal `json:"gain"`
+	Term       Term            `json:"term"`
+}
+
+// RealizedGainsReport lists every realized gain with totals per account and
+// year, and overall, in the reporting currency.
+type RealizedGainsReport struct {
+	Currency models.Currency `json:"currency"`
+	Gains    []RealizedGain  `json:"gains"`
+	Accounts []AccountGains  `json:"accounts"`
+	Total    GainTotals      `json:"total"`
+}
+
+// UnrealizedGain is the gain on the part of a lot still held, valued at the
+// latest price of its security, in the currency of its account. Price is nil
+// when the security has no price yet, and the lot is then left out of the
+// totals.
+type UnrealizedGain struct {
+	AccountID  uint             `json:"accountId"`
+	LotID      uint             `json:"lotId"`
+	Symbol     string           `json:"symbol"`
+	Currency   models.Currency  `json:"currency"`
+	Quantity   decimal.Decimal  `json:"quantity"`
+	AcquiredAt time.Time        `json:"acquiredAt"`
+	Cost       decimal.Decimal  `json:"cost"`
+	Price      *decimal.Decimal `json:"price"`
+	Value      decimal.Decimal  `json:"value"`
+	Gain       decimal.Decimal  `json:"gain"`
+	Term       Term             `json:"term"`
+}
+
+// UnrealizedGainsReport lists the unrealized gain on every open lot with
+// totals per account, per tax bucket and overall, in the reporting currency.
+type UnrealizedGainsReport struct {
+	Date       time.Time        `json:"date"`
+	Currency   models.Currency  `json:"currency"`
+	Gains      []UnrealizedGain `json:"gains"`
+	Accounts   []AccountGains   `json:"accounts"`
+	TaxBuckets []GroupGains     `json:"taxBuckets"`
+	Total      GainTotals       `json:"total"`
+}
+
+// gainsQuery holds what the gains reports have in common.
+type gainsQuery struct {
+	// Account, when set, limits the report to that account.
+	Account   uint
+	Currency  models.Currency
+	converter converter
+	accounts  map[uint]models.Account
+	lots      []models.Lot
+	sales     []models.Sale
+}
+
+// parseGainsQuery parses the account and currency parameters and loads the
+// household's accounts, lots and sales, aborting the request on failure.
+func (controller *GainsController) parseGainsQuery(context *gin.Context, householdID uint) (gainsQuery, bool) {
+	query := gainsQuery{}
+
+	currency := context.DefaultQuery("currency", models.DefaultCurrency.String())
+	var err error
+	query.Currency, err = models.ParseCurrency(strings.ToUpper(currency))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return query, false
+	}
+
+	if param := context.Query("account"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 0)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid account %q", param)})
+			return query, false
+		}
+		query.Account = uint(id)
+		if _, err := controller.Store.GetAccount(householdID, query.Account); errors.Is(err, gorm.ErrRecordNotFound) {
+			context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return query, false
+		} else if err != nil {
+			context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return query, false
+		}
+	}
+
+	rates, err := controller.Store.GetFXRates(query.Currency)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return query, false
+	}
+	query.converter = newConverter(query.Currency, rates)
+
+	accounts, err := controller.Store.GetAllAccountsWithValuesIncludingDeleted(householdID)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return query, false
+	}
+	query.accounts = map[uint]models.Account{}
+	for _, account := range accounts {
+		query.accounts[account.ID] = account
+	}
+
+	query.lots, err = controller.Store.GetAllLots(householdID)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return query, false
+	}
+	query.sales, err = controller.Store.GetAllSales(householdID)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return query, false
+	}
+	return query, true
+}
+
+// GetRealizedGains reports the gains made on every sale, split by holding
+// period into short and long term, with totals per account and calendar year.
+// year limits the report to sales in that year and account to a single
+// account. Totals are converted into currency, USD by default, at the latest
+// exchange rate on or before each sale, and a gain that cannot be converted
+// fails the request with 422 Unprocessable Entity.
+func (controller *GainsController) GetRealizedGains(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	var year int
+	if param := context.Query("year"); param != "" {
+		var err error
+		year, err = strconv.Atoi(param)
+		if err != nil || year < 1 {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid year %q", param)})
+			return
+		}
+	}
+
+	query, ok := controller.parseGainsQuery(context, household)
+	if !ok {
+		return
+	}
+
+	lots := map[uint]models.Lot{}
+	for _, lot := range query.lots {
+		lots[lot.ID] = lot
+	}
+
+	report := RealizedGainsReport{Currency: query.Currency, Gains: []RealizedGain{}, Accounts: []AccountGains{}}
+	type accountYear struct {
+		account uint
+		year    int
+	}
+	totals := map[accountYear]*AccountGains{}
+	for _, sale := range query.sales {
+		if query.Account != 0 && sale.AccountID != query.Account {
+			continue
+		}
+		if year != 0 && sale.SoldAt.Year() != year {
+			continue
+		}
+		account := query.accounts[sale.AccountID]
+		precision := account.ValuePrecision()
+
+		key := accountYear{account: sale.AccountID, year: sale.SoldAt.Year()}
+		total, ok := totals[key]
+		if !ok {
+			total = &AccountGains{AccountID: account.ID, Account: account.Name, Year: key.year}
+			totals[key] = total
+		}
+
+		for _, match := range sale.Matches {
+			lot := lots[match.LotID]
+			gain := RealizedGain{
+				AccountID:  sale.AccountID,
+				SaleID:     sale.ID,
+				LotID:      lot.ID,
+				Currency:   account.Currency,
+				Quantity:   match.Quantity,
+				AcquiredAt: lot.AcquiredAt,
+				SoldAt:     sale.SoldAt,
+				Cost:       match.Cost,
+				Proceeds:   match.Proceeds,
+				Gain:       match.Proceeds.Sub(match.Cost),
+				Term:       holdingTerm(lot.AcquiredAt, sale.SoldAt),
+			}
+			if lot.Security != nil {
+				gain.Symbol = lot.Security.Symbol
+			}
+
+			converted, err := query.converter.convert(gain.Gain, account.Currency, sale.SoldAt.Add(time.Nanosecond))
+			if errors.Is(err, errMissingFXRate) {
+				context.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+				return
+			}
+			total.add(gain.Term, converted)
+			report.Total.add(gain.Term, converted)
+
+			gain.Cost = gain.Cost.Round(precision)
+			gain.Proceeds = gain.Proceeds.Round(precision)
+			gain.Gain = gain.Gain.Round(precision)
+			report.Gains = append(report.Gains, gain)
+		}
+	}
+
+	for _, total := range totals {
+		total.round(query.Currency.Precision())
+		report.Accounts = append(report.Accounts, *total)
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool {
+		if report.Accounts[i].AccountID == report.Accounts[j].AccountID {
+			return report.Accounts[i].Year < report.Accounts[j].Year
+		}
+		return report.Accounts[i].AccountID < report.Accounts[j].AccountID
+	})
+	report.Total.round(query.Currency.Precision())
+	context.JSON(http.StatusOK, report)
+}
+
+// GetUnrealizedGains reports the gain on every lot still held on date, today
+// by default, valued at the latest price of its security on or before it.
+// Gains are split into short and long term by how long the lot has been held,
+// with totals per account and per tax bucket. date accepts a date
+// (YYYY-MM-DD), which includes that whole day, or an RFC 3339 timestamp.
+// account limits the report to a single account. Totals are converted into
+// currency, USD by default, at the latest exchange rate on or before date.
+func (controller *GainsController) GetUnrealizedGains(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	date := time.Now()
+	if param := context.Query("date"); param != "" {
+		var dateOnly bool
+		var err error
+		date, dateOnly, err = parseQueryTime(param, time.UTC)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid date " + param + ", expected YYYY-MM-DD or an RFC 3339 timestamp"})
+			return
+		}
+		if dateOnly {
+			date = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+	}
+
+	query, ok := controller.parseGainsQuery(context, household)
+	if !ok {
+		return
+	}
+
+	ids := map[uint]struct{}{}
+	for _, lot := range query.lots {
+		ids[lot.SecurityID] = struct{}{}
+	}
+	securities := make([]uint, 0, len(ids))
+	for id := range ids {
+		securities = append(securities, id)
+	}
+	prices, err := controller.Store.GetPrices(securities)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	latest := map[uint]decimal.Decimal{}
+	latestDate := map[uint]time.Time{}
+	for _, price := range prices {
+		if price.Date.After(date) || price.Date.Before(latestDate[price.SecurityID]) {
+			continue
+		}
+		latest[price.SecurityID] = price.Price
+		latestDate[price.SecurityID] = price.Date
+	}
+
+	report := UnrealizedGainsReport{Date: date, Currency: query.Currency, Gains: []UnrealizedGain{}, Accounts: []AccountGains{}, TaxBuckets: []GroupGains{}}
+	accountTotals := map[uint]*AccountGains{}
+	bucketTotals := map[string]*GroupGains{}
+	for _, lot := range query.lots {
+		if query.Account != 0 && lot.AccountID != query.Account {
+			continue
+		}
+		if lot.AcquiredAt.After(date) {
+			continue
+		}
+		open := models.OpenQuantity(lot, query.sales, date)
+		if !open.IsPositive() {
+			continue
+		}
+		account := query.accounts[lot.AccountID]
+		precision := account.ValuePrecision()
+
+		gain := UnrealizedGain{
+			AccountID:  lot.AccountID,
+			LotID:      lot.ID,
+			Currency:   account.Currency,
+			Quantity:   open,
+			AcquiredAt: lot.AcquiredAt,
+			Cost:       lot.Cost.Mul(open).Div(lot.Quantity),
+			Value:      decimal.Zero,
+			Gain:       decimal.Zero,
+			Term:       holdingTerm(lot.AcquiredAt, date),
+		}
+		if lot.Security != nil {
+			gain.Symbol = lot.Security.Symbol
+		}
+
+		if price, ok := latest[lot.SecurityID]; ok {
+			gain.Price = &price
+			gain.Value = open.Mul(price)
+			gain.Gain = gain.Value.Sub(gain.Cost)
+
+			converted, err := query.converter.convert(gain.Gain, account.Currency, date.Add(time.Nanosecond))
+			if errors.Is(err, errMissingFXRate) {
+				context.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+				return
+			}
+
+			total, ok := accountTotals[account.ID]
+			if !ok {
+				total = &AccountGains{AccountID: account.ID, Account: account.Name}
+				accountTotals[account.ID] = total
+			}
+			total.add(gain.Term, converted)
+
+			bucket := GroupByTaxBucket.Key(account)
+			group, ok := bucketTotals[bucket]
+			if !ok {
+				group = &GroupGains{Group: bucket}
+				bucketTotals[bucket] = group
+			}
+			group.add(gain.Term, converted)
+
+			report.Total.add(gain.Term, converted)
+		}
+
+		gain.Cost = gain.Cost.Round(precision)
+		gain.Value = gain.Value.Round(precision)
+		gain.Gain = gain.Gain.Round(precision)
+		report.Gains = append(report.Gains, gain)
+	}
+
+	places := query.Currency.Precision()
+	for _, total := range accountTotals {
+		total.round(places)
+		report.Accounts = append(report.Accounts, *total)
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool { return report.Accounts[i].AccountID < report.Accounts[j].AccountID })
+	for _, group := range bucketTotals {
+		group.round(places)
+		report.TaxBuckets = append(report.TaxBuckets, *group)
+	}
+	sort.Slice(report.TaxBuckets, func(i, j int) bool { return report.TaxBuckets[i].Group < report.TaxBuckets[j].Group })
+	report.Total.round(places)
+	context.JSON(http.StatusOK, report)
+}
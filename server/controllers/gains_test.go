@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+// lotsStore returns a store with a taxable brokerage account, ID 1, that
+// bought 10 VTI for 1000 on March 1st 2021 and 10 more for 1500 on June 1st
+// 2022, then sold 12 for 2400 on September 1st 2022. VTI was priced at 180 at
+// the start of 2023. A second account, ID 2, holds no lots.
+func lotsStore(t *testing.T) *store.MemoryStore {
+	brokerage := cashAccount(1, "brokerage")
+	brokerage.TaxBucket = models.Taxable
+	s := newTestStore(t, brokerage, cashAccount(2, "cash"))
+
+	vti := addSecurity(t, s, models.Security{Symbol: "VTI"},
+		models.Price{Date: time.Date(2023, time.January, 2, 0, 0, 0, 0, time.UTC), Price: decimal.NewFromInt(180)},
+	)
+	addLot(t, s, models.Lot{AccountID: 1, SecurityID: vti.ID, Quantity: decimal.NewFromInt(10), Cost: decimal.NewFromInt(1000), AcquiredAt: time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)})
+	addLot(t, s, models.Lot{AccountID: 1, SecurityID: vti.ID, Quantity: decimal.NewFromInt(10), Cost: decimal.NewFromInt(1500), AcquiredAt: time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)})
+	addSale(t, s, models.Sale{
+		AccountID:  1,
+		SecurityID: vti.ID,
+		Quantity:   decimal.NewFromInt(12),
+		Proceeds:   decimal.NewFromInt(2400),
+		SoldAt:     time.Date(2022, time.September, 1, 0, 0, 0, 0, time.UTC),
+	})
+	return s
+}
+
+func registerGains(s store.Store, group *gin.RouterGroup) {
+	NewGainsController(s, group)
+}
+
+func TestGetRealizedGains(t *testing.T) {
+	s := lotsStore(t)
+
+	tests := []struct {
+		url          string
+		responseCode int
+		gains        int
+		shortTerm    string
+		longTerm     string
+	}{
+		{url: "/api/gains/realized", responseCode: http.StatusOK, gains: 2, shortTerm: "100", longTerm: "1000"},
+		{url: "/api/gains/realized?year=2022&account=1", responseCode: http.StatusOK, gains: 2, shortTerm: "100", longTerm: "1000"},
+		{url: "/api/gains/realized?year=2023", responseCode: http.StatusOK, gains: 0, shortTerm: "0", longTerm: "0"},
+		{url: "/api/gains/realized?account=2", responseCode: http.StatusOK, gains: 0, shortTerm: "0", longTerm: "0"},
+		{url: "/api/gains/realized?year=last", responseCode: http.StatusBadRequest},
+		{url: "/api/gains/realized?account=10", responseCode: http.StatusNotFound},
+		{url: "/api/gains/realized?currency=GBP", responseCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			w := serve(s, registerGains, "GET", test.url, "")
+			assert.Equal(t, test.responseCode, w.Code)
+			if test.responseCode != http.StatusOK {
+				return
+			}
+
+			var report RealizedGainsReport
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.gains, len(report.Gains))
+			assert.Equal(t, test.shortTerm, report.Total.ShortTerm.String())
+			assert.Equal(t, test.longTerm, report.Total.LongTerm.String())
+			if test.gains == 0 {
+				return
+			}
+			assert.Equal(t, LongTerm, report.Gains[0].Term)
+			assert.Equal(t, "VTI", report.Gains[0].Symbol)
+			assert.Equal(t, 1, len(report.Accounts))
+			assert.Equal(t, 2022, report.Accounts[0].Year)
+			assert.Equal(t, "1100", report.Accounts[0].Total.String())
+		})
+	}
+}
+
+func TestGetUnrealizedGains(t *testing.T) {
+	s := lotsStore(t)
+
+	tests := []struct {
+		url          string
+		responseCode int
+		quantity     string
+		term         Term
+		total        string
+	}{
+		{url: "/api/gains/unrealized?date=2023-05-01", responseCode: http.StatusOK, quantity: "8", term: ShortTerm, total: "240"},
+		{url: "/api/gains/unrealized?date=2023-07-01&account=1", responseCode: http.StatusOK, quantity: "8", term: LongTerm, total: "240"},
+		{url: "/api/gains/unrealized?date=2022-12-31", responseCode: http.StatusOK, quantity: "8", term: ShortTerm, total: "0"},
+		{url: "/api/gains/unrealized?date=yesterday", responseCode: http.StatusBadRequest},
+		{url: "/api/gains/unrealized?account=10", responseCode: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			w := serve(s, registerGains, "GET", test.url, "")
+			assert.Equal(t, test.responseCode, w.Code)
+			if test.responseCode != http.StatusOK {
+				return
+			}
+
+			var report UnrealizedGainsReport
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, 1, len(report.Gains))
+			assert.Equal(t, test.quantity, report.Gains[0].Quantity.String())
+			assert.Equal(t, test.term, report.Gains[0].Term)
+			assert.Equal(t, test.total, report.Total.Total.String())
+			if report.Gains[0].Price == nil {
+				assert.Equal(t, 0, len(report.TaxBuckets))
+				return
+			}
+			assert.Equal(t, 1, len(report.TaxBuckets))
+			assert.Equal(t, models.Taxable.String(), report.TaxBuckets[0].Group)
+			assert.Equal(t, test.total, report.TaxBuckets[0].Total.String())
+		})
+	}
+}
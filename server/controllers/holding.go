@@ -0,0 +1,488 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type HoldingController struct {
+	Store store.Store
+}
+
+func NewHoldingController(s store.Store, router *gin.RouterGroup) HoldingController {
+	holdingController := HoldingController{Store: s}
+
+	securityRouter := router.Group("/securities")
+	{
+		securityRouter.GET("", holdingController.GetSecurities)
+		securityRouter.POST("", holdingController.CreateSecurity)
+		securityRouter.GET("/:id/prices", holdingController.GetPrices)
+	}
+
+	accountRouter := router.Group("/accounts")
+	{
+		accountRouter.GET("/:id/holdings", holdingController.GetHoldings)
+		accountRouter.GET("/:id/positions", holdingController.GetPositions)
+		accountRouter.POST("/:id/positions", holdingController.CreatePosition)
+		accountRouter.DELETE("/positions/:id", holdingController.DeletePosition)
+		accountRouter.GET("/:id/lots", holdingController.GetLots)
+		accountRouter.POST("/:id/lots", holdingController.CreateLot)
+		accountRouter.GET("/:id/sales", holdingController.GetSales)
+		accountRouter.POST("/:id/sales", holdingController.CreateSale)
+	}
+
+	return holdingController
+}
+
+func (controller *HoldingController) GetSecurities(context *gin.Context) {
+	securities, err := controller.Store.GetSecurities()
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, securities)
+}
+
+// CreateSecurity adds a security that accounts can hold positions in. The
+// symbol is upper-cased and must not be in use already. Securities are shared
+// by every household, so only admins may create them.
+func (controller *HoldingController) CreateSecurity(context *gin.Context) {
+	user, ok := CurrentUser(context)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+	if !user.Admin {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "only admins can create securities"})
+		return
+	}
+
+	var security models.Security
+	if err := context.BindJSON(&security); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidateSecurity(security); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	security, err := controller.Store.CreateSecurity(security)
+	if errors.Is(err, models.ErrSecurityExists) {
+		context.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusCreated, security)
+}
+
+// GetPrices returns the price history of a security, oldest first.
+func (controller *HoldingController) GetPrices(context *gin.Context) {
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	if _, err := controller.Store.GetSecurity(id); errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	prices, err := controller.Store.GetPrices([]uint{id})
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, prices)
+}
+
+// AccountHoldings is what an account holds on a date, and what it is worth.
+type AccountHoldings struct {
+	Date     time.Time        `json:"date"`
+	Value    decimal.Decimal  `json:"value"`
+	Holdings []models.Holding `json:"holdings"`
+}
+
+// GetHoldings values an account's positions on date, today by default, at the
+// latest prices on or before it. date accepts a date (YYYY-MM-DD), which
+// includes that whole day, or an RFC 3339 timestamp. Values are rounded to
+// the account's precision.
+func (controller *HoldingController) GetHoldings(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	date := time.Now()
+	if param := context.Query("date"); param != "" {
+		var dateOnly bool
+		var err error
+		date, dateOnly, err = parseQueryTime(param, time.UTC)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid date " + param + ", expected YYYY-MM-DD or an RFC 3339 timestamp"})
+			return
+		}
+		if dateOnly {
+			date = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+	}
+
+	account, err := controller.Store.GetAccount(household, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	positions, err := controller.Store.GetPositions(household, id)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	prices, err := controller.Store.GetPrices(securityIDs(positions))
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	holdings := models.ValueHoldings(positions, prices, date)
+	precision := account.ValuePrecision()
+	for i := range holdings {
+		holdings[i].Value = holdings[i].Value.Round(precision)
+	}
+	context.JSON(http.StatusOK, AccountHoldings{
+		Date:     date,
+		Value:    models.HoldingsValue(holdings),
+		Holdings: holdings,
+	})
+}
+
+// GetPositions returns every position recorded for an account, newest first.
+func (controller *HoldingController) GetPositions(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	positions, err := controller.Store.GetPositions(household, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, positions)
+}
+
+// CreatePosition records the quantity of a security held in the account as of
+// a date, now by default. It replaces the account's previous position in the
+// security from that date on; a quantity of zero records that it was sold.
+func (controller *HoldingController) CreatePosition(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	var position models.Position
+	if err := context.BindJSON(&position); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	position.AccountID = id
+	if err := models.ValidatePosition(position); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !controller.checkHolding(context, household, id, position.SecurityID) {
+		return
+	}
+
+	position, err := controller.Store.CreatePosition(household, position)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusCreated, position)
+}
+
+func (controller *HoldingController) DeletePosition(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	position, err := controller.Store.DeletePosition(household, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, position)
+}
+
+// checkHolding checks that the account exists and can hold the security,
+// aborting the request if not.
+func (controller *HoldingController) checkHolding(context *gin.Context, householdID, accountID, securityID uint) bool {
+	account, err := controller.Store.GetAccount(householdID, accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	security, err := controller.Store.GetSecurity(securityID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "security does not exist"})
+		return false
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := models.CheckPositionCurrency(account, security); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// GetLots returns the lots bought in an account, oldest first.
+func (controller *HoldingController) GetLots(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	if _, err := controller.Store.GetAccount(household, id); errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	lots, err := controller.Store.GetAllLots(household)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	accountLots := []models.Lot{}
+	for _, lot := range lots {
+		if lot.AccountID == id {
+			accountLots = append(accountLots, lot)
+		}
+	}
+	context.JSON(http.StatusOK, accountLots)
+}
+
+// CreateLot records a purchase of a security in the account, now by default,
+// and adds it to the account's position in the security.
+func (controller *HoldingController) CreateLot(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	var lot models.Lot
+	if err := context.BindJSON(&lot); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	lot.AccountID = id
+	if err := models.ValidateLot(lot); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !controller.checkHolding(context, household, id, lot.SecurityID) {
+		return
+	}
+
+	lot, err := controller.Store.CreateLot(household, lot)
+	if errors.Is(err, models.ErrTradeOutOfOrder) {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusCreated, lot)
+}
+
+// GetSales returns the sales from an account with the lots they were matched
+// against, oldest first.
+func (controller *HoldingController) GetSales(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	if _, err := controller.Store.GetAccount(household, id); errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sales, err := controller.Store.GetAllSales(household)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	accountSales := []models.Sale{}
+	for _, sale := range sales {
+		if sale.AccountID == id {
+			accountSales = append(accountSales, sale)
+		}
+	}
+	context.JSON(http.StatusOK, accountSales)
+}
+
+// CreateSale records a sale of a security from the account, now by default,
+// and takes it out of the account's position in the security. The quantity
+// sold is matched against the oldest lots open on the date of the sale, or
+// with method specific-id against the lots listed in matches.
+func (controller *HoldingController) CreateSale(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	var sale models.Sale
+	if err := context.BindJSON(&sale); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sale.AccountID = id
+	if err := models.ValidateSale(sale); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !controller.checkHolding(context, household, id, sale.SecurityID) {
+		return
+	}
+
+	sale, err := controller.Store.CreateSale(household, sale)
+	if errors.Is(err, models.ErrTradeOutOfOrder) || errors.Is(err, models.ErrInsufficientLots) {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusCreated, sale)
+}
+
+// securityIDs returns the securities the positions are in, each once.
+func securityIDs(positions []models.Position) []uint {
+	ids := []uint{}
+	seen := map[uint]bool{}
+	for _, position := range positions {
+		if !seen[position.SecurityID] {
+			seen[position.SecurityID] = true
+			ids = append(ids, position.SecurityID)
+		}
+	}
+	return ids
+}
+
+// valueHoldings values accounts that hold positions from their positions and
+// the prices of their securities from their first position on. Values
+// recorded before then, from before the account's holdings were tracked, are
+// kept as its earlier history; later ones are replaced. Accounts without
+// positions are left as they are.
+func valueHoldings(s store.Store, accounts []models.Account, positions []models.Position) ([]models.Account, error) {
+	if len(positions) == 0 {
+		return accounts, nil
+	}
+	prices, err := s.GetPrices(securityIDs(positions))
+	if err != nil {
+		return nil, err
+	}
+
+	byAccount := map[uint][]models.Position{}
+	for _, position := range positions {
+		byAccount[position.AccountID] = append(byAccount[position.AccountID], position)
+	}
+	for i, account := range accounts {
+		held, ok := byAccount[account.ID]
+		if !ok {
+			continue
+		}
+		derived := models.HoldingValues(held, prices)
+		values := []models.AccountValue{}
+		for _, value := range account.Values {
+			if value.AsOf.Before(derived[0].AsOf) {
+				values = append(values, value)
+			}
+		}
+		accounts[i].Values = append(values, derived...)
+	}
+	return accounts, nil
+}
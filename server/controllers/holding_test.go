@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+// holdingsStore returns a store with a brokerage account, ID 1, that was
+// worth 1800 on December 1st 2022, before its holdings were tracked, then
+// bought 10 VTI on January 15th 2023 and 5 more on February 15th, and a cash
+// account. VTI was priced at 200 at the start of January and 210 at the
+// start of February. VWRL is priced in EUR, so the brokerage cannot hold it.
+func holdingsStore(t *testing.T) *store.MemoryStore {
+	jan := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestStore(t,
+		cashAccount(1, "brokerage",
+			models.AccountValue{Value: decimal.NewFromInt(1800), AsOf: jan.AddDate(0, -1, 0)},
+			models.AccountValue{Value: decimal.NewFromInt(999999), AsOf: jan.AddDate(0, 1, 0)},
+		),
+		cashAccount(2, "cash", models.AccountValue{Value: decimal.NewFromInt(100), AsOf: jan.AddDate(0, 0, 20)}),
+	)
+
+	vti := addSecurity(t, s, models.Security{Symbol: "VTI"},
+		models.Price{Date: jan, Price: decimal.NewFromInt(200)},
+		models.Price{Date: jan.AddDate(0, 1, 0), Price: decimal.NewFromInt(210)},
+	)
+	addSecurity(t, s, models.Security{Symbol: "VWRL", Currency: "EUR"})
+	for i, quantity := range []int64{10, 15} {
+		addPosition(t, s, models.Position{
+			AccountID:  1,
+			SecurityID: vti.ID,
+			Quantity:   decimal.NewFromInt(quantity),
+			CostBasis:  decimal.NewFromInt(quantity * 200),
+			AsOf:       jan.AddDate(0, i, 14),
+		})
+	}
+	return s
+}
+
+// addSecurity creates a security with the given prices.
+func addSecurity(t *testing.T, s store.Store, security models.Security, prices ...models.Price) models.Security {
+	security, err := s.CreateSecurity(security)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, price := range prices {
+		price.SecurityID = security.ID
+		if _, err := s.SavePrice(price); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return security
+}
+
+// addPosition records a position in the test user's household.
+func addPosition(t *testing.T, s store.Store, position models.Position) {
+	if _, err := s.CreatePosition(testUser.HouseholdID, position); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addLot records a purchase in the test user's household.
+func addLot(t *testing.T, s store.Store, lot models.Lot) {
+	if _, err := s.CreateLot(testUser.HouseholdID, lot); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addSale records a sale in the test user's household.
+func addSale(t *testing.T, s store.Store, sale models.Sale) {
+	if _, err := s.CreateSale(testUser.HouseholdID, sale); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func registerHoldings(s store.Store, group *gin.RouterGroup) {
+	NewHoldingController(s, group)
+}
+
+func TestCreateSecurity(t *testing.T) {
+	admin := testUser
+	admin.Admin = true
+
+	tests := []struct {
+		name         string
+		user         models.User
+		body         string
+		responseCode int
+	}{
+		{name: "should only let admins create securities", user: testUser, body: `{"symbol": "VXUS"}`, responseCode: http.StatusForbidden},
+		{name: "should create a security", user: admin, body: `{"symbol": "vxus", "name": "Vanguard Total International"}`, responseCode: http.StatusCreated},
+		{name: "should reject a symbol in use", user: admin, body: `{"symbol": "VTI"}`, responseCode: http.StatusConflict},
+		{name: "should reject an invalid symbol", user: admin, body: `{"symbol": "VTI/X"}`, responseCode: http.StatusBadRequest},
+		{name: "should reject an invalid currency", user: admin, body: `{"symbol": "VXUS", "currency": "dollars"}`, responseCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.Default()
+			group := router.Group("/api", func(context *gin.Context) {
+				context.Set(userKey, test.user)
+			})
+			NewHoldingController(holdingsStore(t), group)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/securities", strings.NewReader(test.body))
+			router.ServeHTTP(w, req)
+			assert.Equal(t, test.responseCode, w.Code)
+		})
+	}
+}
+
+func TestCreatePosition(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		body         string
+		responseCode int
+	}{
+		{name: "should create a position", url: "/api/accounts/2/positions", body: `{"security_id": 1, "quantity": "1.5", "cost_basis": "300"}`, responseCode: http.StatusCreated},
+		{name: "should 404 for a missing account", url: "/api/accounts/10/positions", body: `{"security_id": 1, "quantity": "1"}`, responseCode: http.StatusNotFound},
+		{name: "should reject a missing security", url: "/api/accounts/2/positions", body: `{"security_id": 10, "quantity": "1"}`, responseCode: http.StatusBadRequest},
+		{name: "should reject a security in another currency", url: "/api/accounts/2/positions", body: `{"security_id": 2, "quantity": "1"}`, responseCode: http.StatusBadRequest},
+		{name: "should reject a negative quantity", url: "/api/accounts/2/positions", body: `{"security_id": 1, "quantity": "-1"}`, responseCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(holdingsStore(t), registerHoldings, "POST", test.url, test.body)
+			assert.Equal(t, test.responseCode, w.Code)
+		})
+	}
+}
+
+func TestGetHoldings(t *testing.T) {
+	s := holdingsStore(t)
+
+	tests := []struct {
+		url          string
+		responseCode int
+		value        string
+		quantity     string
+	}{
+		{url: "/api/accounts/1/holdings?date=2023-01-15", responseCode: http.StatusOK, value: "2000", quantity: "10"},
+		{url: "/api/accounts/1/holdings?date=2023-02-14", responseCode: http.StatusOK, value: "2100", quantity: "10"},
+		{url: "/api/accounts/1/holdings?date=2023-02-15", responseCode: http.StatusOK, value: "3150", quantity: "15"},
+		{url: "/api/accounts/1/holdings?date=15/02/2023", responseCode: http.StatusBadRequest},
+		{url: "/api/accounts/10/holdings", responseCode: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			w := serve(s, registerHoldings, "GET", test.url, "")
+			assert.Equal(t, test.responseCode, w.Code)
+			if test.responseCode != http.StatusOK {
+				return
+			}
+
+			var holdings AccountHoldings
+			if err := json.Unmarshal(w.Body.Bytes(), &holdings); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.value, holdings.Value.String())
+			assert.Equal(t, 1, len(holdings.Holdings))
+			assert.Equal(t, "VTI", holdings.Holdings[0].Security.Symbol)
+			assert.Equal(t, test.quantity, holdings.Holdings[0].Quantity.String())
+		})
+	}
+}
+
+func TestCreateLot(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		body         string
+		responseCode int
+	}{
+		{name: "should create a lot", url: "/api/accounts/2/lots", body: `{"security_id": 1, "quantity": "2", "cost": "400", "acquired_at": "2023-03-01T00:00:00Z"}`, responseCode: http.StatusCreated},
+		{name: "should 404 for a missing account", url: "/api/accounts/10/lots", body: `{"security_id": 1, "quantity": "1"}`, responseCode: http.StatusNotFound},
+		{name: "should reject a security in another currency", url: "/api/accounts/2/lots", body: `{"security_id": 2, "quantity": "1"}`, responseCode: http.StatusBadRequest},
+		{name: "should reject a zero quantity", url: "/api/accounts/2/lots", body: `{"security_id": 1, "quantity": "0"}`, responseCode: http.StatusBadRequest},
+		{name: "should reject a lot before the latest position", url: "/api/accounts/1/lots", body: `{"security_id": 1, "quantity": "1", "acquired_at": "2023-02-01T00:00:00Z"}`, responseCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(holdingsStore(t), registerHoldings, "POST", test.url, test.body)
+			assert.Equal(t, test.responseCode, w.Code)
+		})
+	}
+}
+
+func TestCreateSale(t *testing.T) {
+	s := holdingsStore(t)
+	w := serve(s, registerHoldings, "POST", "/api/accounts/2/lots", `{"security_id": 1, "quantity": "10", "cost": "2000", "acquired_at": "2023-01-10T00:00:00Z"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	tests := []struct {
+		name         string
+		body         string
+		responseCode int
+	}{
+		{name: "should reject selling more than the lots hold", body: `{"security_id": 1, "quantity": "11", "sold_at": "2023-03-01T00:00:00Z"}`, responseCode: http.StatusUnprocessableEntity},
+		{name: "should reject an unknown method", body: `{"security_id": 1, "quantity": "1", "method": "lifo"}`, responseCode: http.StatusBadRequest},
+		{name: "should sell the chosen lot", body: `{"security_id": 1, "quantity": "4", "proceeds": "880", "sold_at": "2023-03-01T00:00:00Z", "method": "specific-id", "matches": [{"lot_id": 1, "quantity": "4"}]}`, responseCode: http.StatusCreated},
+		{name: "should reject a sale before the last trade", body: `{"security_id": 1, "quantity": "1", "sold_at": "2023-02-01T00:00:00Z"}`, responseCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(s, registerHoldings, "POST", "/api/accounts/2/sales", test.body)
+			assert.Equal(t, test.responseCode, w.Code)
+		})
+	}
+
+	w = serve(s, registerHoldings, "GET", "/api/accounts/2/sales", "")
+	var sales []models.Sale
+	if err := json.Unmarshal(w.Body.Bytes(), &sales); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(sales))
+	assert.Equal(t, "800", sales[0].Matches[0].Cost.String())
+
+	w = serve(s, registerHoldings, "GET", "/api/accounts/2/holdings?date=2023-03-01", "")
+	var holdings AccountHoldings
+	if err := json.Unmarshal(w.Body.Bytes(), &holdings); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "6", holdings.Holdings[0].Quantity.String())
+}
+
+func TestDeletePosition(t *testing.T) {
+	s := holdingsStore(t)
+
+	w := serve(s, registerHoldings, "DELETE", "/api/accounts/positions/2", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(s, registerHoldings, "DELETE", "/api/accounts/positions/2", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(s, registerHoldings, "GET", "/api/accounts/1/positions", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var positions []models.Position
+	if err := json.Unmarshal(w.Body.Bytes(), &positions); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(positions))
+}
+
+func TestGetNetworthOverTimeWithHoldings(t *testing.T) {
+	// The brokerage's value from before its first position still counts, and
+	// the one recorded since is ignored in favour of its holdings.
+	w := serve(holdingsStore(t), registerFinance, "GET", "/api/networth", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var points []NetWorthPoint
+	if err := json.Unmarshal(w.Body.Bytes(), &points); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, len(points))
+	assert.Equal(t, "1800", points[0].Value.String())
+	assert.Equal(t, "2100", points[1].Value.String())
+	assert.Equal(t, "3250", points[2].Value.String())
+}
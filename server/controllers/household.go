@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type HouseholdController struct {
+	DB *gorm.DB
+}
+
+func NewHouseholdController(db *gorm.DB, router *gin.RouterGroup) HouseholdController {
+	householdController := HouseholdController{DB: db}
+
+	householdRouter := router.Group("/household")
+	{
+		householdRouter.GET("", householdController.GetHousehold)
+		// An invitation can be redeemed for a session, so handing them out
+		// needs one: otherwise an API token could turn itself into a login.
+		householdRouter.POST("/invitations", requireBrowserSession, householdController.CreateInvitation)
+	}
+
+	return householdController
+}
+
+// HouseholdDetails is a household along with the users that are members of it.
+type HouseholdDetails struct {
+	models.Household
+	Members []models.User `json:"members"`
+}
+
+// GetHousehold returns the caller's household and its members.
+func (controller *HouseholdController) GetHousehold(context *gin.Context) {
+	id, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	household, err := models.GetHousehold(controller.DB, id)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	members, err := models.GetHouseholdMembers(controller.DB, id)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, HouseholdDetails{Household: household, Members: members})
+}
+
+// CreateInvitation invites someone to the caller's household. The returned
+// token is only shown once and is passed to POST /auth/join to create a user
+// in the household.
+func (controller *HouseholdController) CreateInvitation(context *gin.Context) {
+	user, ok := CurrentUser(context)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+
+	token, invitation, err := models.CreateInvitation(controller.DB, user.HouseholdID, user.ID)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusCreated, gin.H{"token": token, "expires_at": invitation.ExpiresAt})
+}
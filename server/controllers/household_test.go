@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+)
+
+func TestNewHouseholdController(t *testing.T) {
+	db := openSQLiteDatabase(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api")
+	controller := NewHouseholdController(db, group)
+
+	assert.Equal(t, controller.DB, db)
+}
+
+// login logs a user in and returns their session cookie.
+func login(t *testing.T, router *gin.Engine, username string) *http.Cookie {
+	w := serveWithCookie(router, "POST", "/api/auth/login", `{"username":"`+username+`","password":"correct horse"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("logging in as %s: %d %s", username, w.Code, w.Body.String())
+	}
+	return sessionCookie(w)
+}
+
+// invite creates an invitation to the household of the user with the cookie
+// and returns its token.
+func invite(t *testing.T, router *gin.Engine, cookie *http.Cookie) string {
+	w := serveWithCookie(router, "POST", "/api/household/invitations", "", cookie)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("inviting: %d %s", w.Code, w.Body.String())
+	}
+	var invitation struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &invitation); err != nil {
+		t.Fatal(err)
+	}
+	return invitation.Token
+}
+
+func TestJoinHousehold(t *testing.T) {
+	db := openSQLiteDatabase(t)
+	createAdmin(t, db, "admin")
+	router := newAuthRouter(db)
+	admin := login(t, router, "admin")
+	token := invite(t, router, admin)
+
+	tests := []struct {
+		name         string
+		body         string
+		responseCode int
+	}{
+		{
+			name:         "should not join with an unknown invitation",
+			body:         `{"token":"forged","username":"partner","password":"correct horse"}`,
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should not join with a short password",
+			body:         `{"token":"` + token + `","username":"partner","password":"short"}`,
+			responseCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should not join with a username in use",
+			body:         `{"token":"` + token + `","username":"admin","password":"correct horse"}`,
+			responseCode: http.StatusConflict,
+		},
+		{
+			name:         "should join with an invitation",
+			body:         `{"token":"` + token + `","username":"partner","password":"correct horse"}`,
+			responseCode: http.StatusCreated,
+		},
+		{
+			name:         "should not join twice with the same invitation",
+			body:         `{"token":"` + token + `","username":"other","password":"correct horse"}`,
+			responseCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serveWithCookie(router, "POST", "/api/auth/join", test.body, nil)
+			assert.Equal(t, test.responseCode, w.Code)
+			assert.Equal(t, test.responseCode == http.StatusCreated, sessionCookie(w) != nil)
+		})
+	}
+
+	w := serveWithCookie(router, "GET", "/api/household", "", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var household HouseholdDetails
+	if err := json.Unmarshal(w.Body.Bytes(), &household); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(household.Members))
+	assert.Equal(t, "partner", household.Members[1].Username)
+	assert.Equal(t, false, household.Members[1].Admin)
+}
+
+func TestHouseholdIsolation(t *testing.T) {
+	db := openSQLiteDatabase(t)
+	createAdmin(t, db, "admin")
+	createAdmin(t, db, "neighbour")
+	router := newAuthRouter(db)
+	admin := login(t, router, "admin")
+	neighbour := login(t, router, "neighbour")
+
+	w := serveWithCookie(router, "POST", "/api/accounts", `{"name":"checking","class":"asset","category":"cash"}`, admin)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var account models.Account
+	if err := json.Unmarshal(w.Body.Bytes(), &account); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint(1), account.ID)
+
+	// A partner who joins the household sees its accounts.
+	token := invite(t, router, admin)
+	w = serveWithCookie(router, "POST", "/api/auth/join", `{"token":"`+token+`","username":"partner","password":"correct horse"}`, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	partner := sessionCookie(w)
+
+	w = serveWithCookie(router, "GET", "/api/accounts", "", partner)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.MatchRegex(t, w.Body.String(), `"name":"checking"`)
+
+	// Another household neither sees nor can change them, and can use the
+	// same account names.
+	w = serveWithCookie(router, "GET", "/api/accounts", "", neighbour)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]", w.Body.String())
+
+	w = serveWithCookie(router, "GET", "/api/accounts/1", "", neighbour)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveWithCookie(router, "POST", "/api/accounts/value", `{"account_id":1,"value":100}`, neighbour)
+	assert.Equal(t, true, w.Code >= http.StatusBadRequest)
+
+	w = serveWithCookie(router, "DELETE", "/api/accounts/1", "", neighbour)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveWithCookie(router, "POST", "/api/accounts", `{"name":"checking","class":"asset","category":"cash"}`, neighbour)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = serveWithCookie(router, "GET", "/api/accounts/1", "", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Jrc356/financial_dashboard/importer"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ImportController struct {
+	DB *gorm.DB
+}
+
+func NewImportController(db *gorm.DB, router *gin.RouterGroup) ImportController {
+	importController := ImportController{DB: db}
+
+	importRouter := router.Group("/import")
+	{
+		importRouter.POST("/csv", importController.ImportCSV)
+		importRouter.POST("/ofx", importController.ImportOFX)
+		importRouter.POST("/json", importController.ImportJSON)
+		importRouter.POST("/fx", importController.ImportFXRates)
+		importRouter.POST("/prices", importController.ImportPrices)
+		importRouter.GET("/ofx/mappings", importController.GetOFXAccountMappings)
+		importRouter.PUT("/ofx/mappings", importController.SaveOFXAccountMapping)
+		importRouter.DELETE("/ofx/mappings/:id", importController.DeleteOFXAccountMapping)
+	}
+
+	return importController
+}
+
+// uploadedFile returns the request's "file" form field for multipart uploads
+// and the raw request body otherwise.
+func uploadedFile(context *gin.Context) (io.ReadCloser, error) {
+	if strings.HasPrefix(context.ContentType(), "multipart/") {
+		header, err := context.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		return header.Open()
+	}
+	return context.Request.Body, nil
+}
+
+// ImportCSV imports account values from a CSV file. Set createAccounts=true to
+// create accounts that do not exist yet from the class and category columns.
+func (controller *ImportController) ImportCSV(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	opts := importer.CSVOptions{}
+	if createAccounts := context.Query("createAccounts"); createAccounts != "" {
+		var err error
+		opts.CreateAccounts, err = strconv.ParseBool(createAccounts)
+		if err != nil {
+			context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid createAccounts: " + createAccounts})
+			return
+		}
+	}
+
+	file, err := uploadedFile(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	report, err := importer.ImportCSV(controller.DB, household, file, opts)
+	if errors.Is(err, importer.ErrInvalidFile) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !report.Committed {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	context.JSON(http.StatusOK, report)
+}
+
+// ImportOFX imports the ledger balance or investment position totals from an
+// OFX or QFX file into the accounts mapped to the file's OFX account IDs.
+func (controller *ImportController) ImportOFX(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	file, err := uploadedFile(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	report, err := importer.ImportOFX(controller.DB, household, file)
+	if errors.Is(err, importer.ErrInvalidFile) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !report.Committed {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	context.JSON(http.StatusOK, report)
+}
+
+// ImportJSON restores a JSON export into the caller's household, which must
+// not have any accounts yet. The data in it that every household shares is
+// only restored for admins.
+func (controller *ImportController) ImportJSON(context *gin.Context) {
+	user, ok := CurrentUser(context)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+
+	file, err := uploadedFile(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	report, err := importer.ImportJSON(controller.DB, user.HouseholdID, file, importer.JSONOptions{SharedData: user.Admin})
+	if errors.Is(err, importer.ErrInvalidFile) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !report.Committed {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	context.JSON(http.StatusOK, report)
+}
+
+// ImportFXRates imports exchange rates from a CSV file with date, base, quote
+// and rate columns. Rates are shared by every household, so only admins may
+// import them.
+func (controller *ImportController) ImportFXRates(context *gin.Context) {
+	user, ok := CurrentUser(context)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+	if !user.Admin {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "only admins can import exchange rates"})
+		return
+	}
+
+	file, err := uploadedFile(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	report, err := importer.ImportFXRates(controller.DB, file)
+	if errors.Is(err, importer.ErrInvalidFile) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !report.Committed {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	context.JSON(http.StatusOK, report)
+}
+
+// ImportPrices imports security prices from a CSV file with symbol, date and
+// price columns, creating securities that do not exist yet. Prices are shared
+// by every household, so only admins may import them.
+func (controller *ImportController) ImportPrices(context *gin.Context) {
+	user, ok := CurrentUser(context)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+	if !user.Admin {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "only admins can import prices"})
+		return
+	}
+
+	file, err := uploadedFile(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	report, err := importer.ImportPrices(controller.DB, file)
+	if errors.Is(err, importer.ErrInvalidFile) {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !report.Committed {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	context.JSON(http.StatusOK, report)
+}
+
+func (controller *ImportController) GetOFXAccountMappings(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	mappings, err := models.GetOFXAccountMappings(controller.DB, household)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, mappings)
+}
+
+func (controller *ImportController) SaveOFXAccountMapping(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	var mapping models.OFXAccountMapping
+	if err := context.BindJSON(&mapping); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exists, err := models.AccountExistsByID(controller.DB, household, mapping.AccountID)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !exists {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Account does not exist"})
+		return
+	}
+
+	mapping, err = models.SaveOFXAccountMapping(controller.DB, household, mapping)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, mapping)
+}
+
+func (controller *ImportController) DeleteOFXAccountMapping(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	mapping, err := models.DeleteOFXAccountMapping(controller.DB, household, id)
+	if err == gorm.ErrRecordNotFound {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, mapping)
+}
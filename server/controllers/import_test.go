@@ -0,0 +1,376 @@
+package controllers
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+)
+
+func multipartCSV(t *testing.T, contents string) (io.Reader, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "balances.csv")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	part.Write([]byte(contents))
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func TestNewImportController(t *testing.T) {
+	db, _, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api", loggedIn)
+	controller := NewImportController(db, group)
+
+	assert.Equal(t, controller.DB, db)
+}
+
+func TestImportCSV(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	multipartBody, multipartContentType := multipartCSV(t, "account,date,value\ntest,not a date,1")
+
+	tests := []struct {
+		name         string
+		url          string
+		body         io.Reader
+		contentType  string
+		responseCode int
+		expectations func()
+	}{
+		{
+			name:         "should reject a file with an invalid header",
+			url:          "/api/import/csv",
+			body:         strings.NewReader("name,value\ntest,1"),
+			contentType:  "text/csv",
+			responseCode: http.StatusBadRequest,
+			expectations: func() {},
+		},
+		{
+			name:         "should reject an invalid createAccounts option",
+			url:          "/api/import/csv?createAccounts=maybe",
+			body:         strings.NewReader("account,date,value"),
+			contentType:  "text/csv",
+			responseCode: http.StatusBadRequest,
+			expectations: func() {},
+		},
+		{
+			name:         "should commit a file with no failed rows",
+			url:          "/api/import/csv",
+			body:         strings.NewReader("account,date,value"),
+			contentType:  "text/csv",
+			responseCode: http.StatusOK,
+			expectations: func() {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:         "should roll back an uploaded file with failed rows",
+			url:          "/api/import/csv",
+			body:         multipartBody,
+			contentType:  multipartContentType,
+			responseCode: http.StatusUnprocessableEntity,
+			expectations: func() {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api", loggedIn)
+	NewImportController(db, group)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			test.expectations()
+			req, _ := http.NewRequest("POST", test.url, test.body)
+			req.Header.Set("Content-Type", test.contentType)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, test.responseCode, w.Code)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestImportFXRates(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	admin := testUser
+	admin.Admin = true
+
+	tests := []struct {
+		name         string
+		user         models.User
+		body         string
+		responseCode int
+		expectations func()
+	}{
+		{
+			name:         "should only let admins import rates",
+			user:         testUser,
+			body:         "date,base,quote,rate",
+			responseCode: http.StatusForbidden,
+			expectations: func() {},
+		},
+		{
+			name:         "should reject a file with an invalid header",
+			user:         admin,
+			body:         "date,pair,rate",
+			responseCode: http.StatusBadRequest,
+			expectations: func() {},
+		},
+		{
+			name:         "should roll back a file with failed rows",
+			user:         admin,
+			body:         "date,base,quote,rate\n2023-01-31,EUR,USD,0",
+			responseCode: http.StatusUnprocessableEntity,
+			expectations: func() {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.Default()
+			group := router.Group("/api", func(context *gin.Context) {
+				context.Set(userKey, test.user)
+			})
+			NewImportController(db, group)
+
+			w := httptest.NewRecorder()
+			test.expectations()
+			req, _ := http.NewRequest("POST", "/api/import/fx", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", "text/csv")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, test.responseCode, w.Code)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestImportPrices(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	admin := testUser
+	admin.Admin = true
+
+	tests := []struct {
+		name         string
+		user         models.User
+		body         string
+		responseCode int
+		expectations func()
+	}{
+		{
+			name:         "should only let admins import prices",
+			user:         testUser,
+			body:         "symbol,date,price",
+			responseCode: http.StatusForbidden,
+			expectations: func() {},
+		},
+		{
+			name:         "should reject a file with an invalid header",
+			user:         admin,
+			body:         "ticker,date,close",
+			responseCode: http.StatusBadRequest,
+			expectations: func() {},
+		},
+		{
+			name:         "should roll back a file with failed rows",
+			user:         admin,
+			body:         "symbol,date,price\nVTI,2023-01-31,cheap",
+			responseCode: http.StatusUnprocessableEntity,
+			expectations: func() {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.Default()
+			group := router.Group("/api", func(context *gin.Context) {
+				context.Set(userKey, test.user)
+			})
+			NewImportController(db, group)
+
+			w := httptest.NewRecorder()
+			test.expectations()
+			req, _ := http.NewRequest("POST", "/api/import/prices", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", "text/csv")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, test.responseCode, w.Code)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestOFXAccountMappings(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	tests := []struct {
+		name               string
+		method             string
+		url                string
+		body               io.Reader
+		responseCode       int
+		expectedStatements []models.ExpectedStatement
+	}{
+		{
+			name:               "should not map an OFX account to an account that does not exist",
+			method:             "PUT",
+			url:                "/api/import/ofx/mappings",
+			body:               strings.NewReader(`{"ofx_account_id": "0001234", "account_id": 3}`),
+			responseCode:       http.StatusBadRequest,
+			expectedStatements: models.CreateStatementsAccountDoesNotExistByID(3),
+		},
+		{
+			name:               "should require an OFX account id",
+			method:             "PUT",
+			url:                "/api/import/ofx/mappings",
+			body:               strings.NewReader(`{"account_id": 3}`),
+			responseCode:       http.StatusBadRequest,
+			expectedStatements: []models.ExpectedStatement{},
+		},
+		{
+			name:               "should reject an invalid mapping id",
+			method:             "DELETE",
+			url:                "/api/import/ofx/mappings/abc",
+			responseCode:       http.StatusBadRequest,
+			expectedStatements: []models.ExpectedStatement{},
+		},
+		{
+			name:               "should reject a file that is not OFX",
+			method:             "POST",
+			url:                "/api/import/ofx",
+			body:               strings.NewReader("account,date,value"),
+			responseCode:       http.StatusBadRequest,
+			expectedStatements: []models.ExpectedStatement{},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api", loggedIn)
+	NewImportController(db, group)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			models.LoadStatements(mock, test.expectedStatements)
+			req, _ := http.NewRequest(test.method, test.url, test.body)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, test.responseCode, w.Code)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestImportJSON(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	tests := []struct {
+		name         string
+		body         io.Reader
+		responseCode int
+		expectations func()
+	}{
+		{
+			name:         "should reject a file that is not an export",
+			body:         strings.NewReader("account,date,value"),
+			responseCode: http.StatusBadRequest,
+			expectations: func() {},
+		},
+		{
+			name:         "should reject importing into a household with accounts",
+			body:         strings.NewReader(`{"version":1,"accounts":[],"ofx_account_mappings":[]}`),
+			responseCode: http.StatusBadRequest,
+			expectations: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT count(.+) FROM \"accounts\" WHERE household_id").
+					WithArgs(testUser.HouseholdID).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api", loggedIn)
+	NewImportController(db, group)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			test.expectations()
+			req, _ := http.NewRequest("POST", "/api/import/json", test.body)
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, test.responseCode, w.Code)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+)
+
+type IntervalUnit string
+
+const (
+	IntervalDay     IntervalUnit = "day"
+	IntervalWeek    IntervalUnit = "week"
+	IntervalMonth   IntervalUnit = "month"
+	IntervalQuarter IntervalUnit = "quarter"
+	IntervalYear    IntervalUnit = "year"
+)
+
+// Interval is the width of the buckets net worth is rolled up into. Calendar
+// units start buckets on calendar boundaries (weeks start on Monday) in the
+// requested time zone. Otherwise Duration is a fixed width, with buckets
+// aligned to multiples of it since the zero time.
+type Interval struct {
+	Unit     IntervalUnit
+	Duration time.Duration
+}
+
+// ParseInterval accepts a calendar unit (day, week, month, quarter, year) or a
+// positive duration such as "12h".
+func ParseInterval(s string) (Interval, error) {
+	switch unit := IntervalUnit(s); unit {
+	case IntervalDay, IntervalWeek, IntervalMonth, IntervalQuarter, IntervalYear:
+		return Interval{Unit: unit}, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return Interval{}, fmt.Errorf("invalid interval %q, expected day, week, month, quarter, year or a duration", s)
+	}
+	if d <= 0 {
+		return Interval{}, fmt.Errorf("invalid interval %q, duration must be positive", s)
+	}
+	return Interval{Duration: d}, nil
+}
+
+func (i Interval) String() string {
+	if i.Unit != "" {
+		return string(i.Unit)
+	}
+	return i.Duration.String()
+}
+
+// Truncate returns the start of the bucket containing t in loc.
+func (i Interval) Truncate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	year, month, day := t.Date()
+	switch i.Unit {
+	case IntervalDay:
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	case IntervalWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, loc)
+	case IntervalMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	case IntervalQuarter:
+		return time.Date(year, ((month-1)/3)*3+1, 1, 0, 0, 0, 0, loc)
+	case IntervalYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	}
+	return t.Truncate(i.Duration)
+}
+
+// Next returns the start of the bucket after the one starting at start.
+func (i Interval) Next(start time.Time) time.Time {
+	switch i.Unit {
+	case IntervalDay:
+		return start.AddDate(0, 0, 1)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	case IntervalQuarter:
+		return start.AddDate(0, 3, 0)
+	case IntervalYear:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.Add(i.Duration)
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Interval
+		wantErr bool
+	}{
+		{input: "day", want: Interval{Unit: IntervalDay}},
+		{input: "week", want: Interval{Unit: IntervalWeek}},
+		{input: "month", want: Interval{Unit: IntervalMonth}},
+		{input: "quarter", want: Interval{Unit: IntervalQuarter}},
+		{input: "year", want: Interval{Unit: IntervalYear}},
+		{input: "12h", want: Interval{Duration: 12 * time.Hour}},
+		{input: "fortnight", wantErr: true},
+		{input: "0s", wantErr: true},
+		{input: "-1h", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			interval, err := ParseInterval(test.input)
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.want, interval)
+		})
+	}
+}
+
+func TestIntervalTruncate(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	// Wednesday 2023-08-16 02:30 UTC is Tuesday evening in New York.
+	ts := time.Date(2023, time.August, 16, 2, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		interval Interval
+		location *time.Location
+		want     time.Time
+		wantNext time.Time
+	}{
+		{
+			name:     "day",
+			interval: Interval{Unit: IntervalDay},
+			location: time.UTC,
+			want:     time.Date(2023, time.August, 16, 0, 0, 0, 0, time.UTC),
+			wantNext: time.Date(2023, time.August, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day in another time zone",
+			interval: Interval{Unit: IntervalDay},
+			location: newYork,
+			want:     time.Date(2023, time.August, 15, 0, 0, 0, 0, newYork),
+			wantNext: time.Date(2023, time.August, 16, 0, 0, 0, 0, newYork),
+		},
+		{
+			name:     "week starts on monday",
+			interval: Interval{Unit: IntervalWeek},
+			location: time.UTC,
+			want:     time.Date(2023, time.August, 14, 0, 0, 0, 0, time.UTC),
+			wantNext: time.Date(2023, time.August, 21, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "month",
+			interval: Interval{Unit: IntervalMonth},
+			location: time.UTC,
+			want:     time.Date(2023, time.August, 1, 0, 0, 0, 0, time.UTC),
+			wantNext: time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "quarter",
+			interval: Interval{Unit: IntervalQuarter},
+			location: time.UTC,
+			want:     time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC),
+			wantNext: time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "year",
+			interval: Interval{Unit: IntervalYear},
+			location: time.UTC,
+			want:     time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+			wantNext: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "duration",
+			interval: Interval{Duration: 6 * time.Hour},
+			location: time.UTC,
+			want:     time.Date(2023, time.August, 16, 0, 0, 0, 0, time.UTC),
+			wantNext: time.Date(2023, time.August, 16, 6, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := test.interval.Truncate(ts, test.location)
+			if !test.want.Equal(start) {
+				t.Errorf("wanted: %v, got: %v", test.want, start)
+			}
+			next := test.interval.Next(start)
+			if !test.wantNext.Equal(next) {
+				t.Errorf("wanted next: %v, got: %v", test.wantNext, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// postgresIntervals maps calendar units to their date_trunc field and the
+// interval between buckets.
+var postgresIntervals = map[IntervalUnit]struct {
+	field string
+	step  string
+}{
+	IntervalDay:     {field: "day", step: "1 day"},
+	IntervalWeek:    {field: "week", step: "1 week"},
+	IntervalMonth:   {field: "month", step: "1 month"},
+	IntervalQuarter: {field: "quarter", step: "3 months"},
+	IntervalYear:    {field: "year", step: "1 year"},
+}
+
+// groupExpressions select the group of an account in netWorthSQL, matching
+// GroupBy.Key.
+var groupExpressions = map[GroupBy]string{
+	"":               "''",
+	GroupByClass:     "a.class",
+	GroupByCategory:  "a.category",
+	GroupByTaxBucket: "COALESCE(NULLIF(a.tax_bucket, ''), '" + unassignedGroup + "')",
+}
+
+// netWorthSQL computes rollupBuckets in Postgres for the accounts of
+// @household, other than those in @held, summing balances per currency for
+// the caller to convert.
+const netWorthSQL = `
+WITH buckets AS (
+	SELECT
+		bucket,
+		LEAST((bucket + CAST(@step AS interval)) AT TIME ZONE @tz, CAST(@to AS timestamptz)) AS bucket_end
+	FROM generate_series(CAST(@first AS timestamp), CAST(@last AS timestamp), CAST(@step AS interval)) AS bucket
+),
+-- Each account's last value in every bucket it has values in, with values
+-- from before the first bucket counted in the first bucket.
+latest AS (
+	SELECT account_id, bucket, value
+	FROM (
+		SELECT
+			v.account_id,
+			v.value,
+			GREATEST(date_trunc(@field, v.as_of AT TIME ZONE @tz), CAST(@first AS timestamp)) AS bucket,
+			row_number() OVER (
+				PARTITION BY v.account_id, GREATEST(date_trunc(@field, v.as_of AT TIME ZONE @tz), CAST(@first AS timestamp))
+				ORDER BY v.as_of DESC, v.id DESC
+			) AS position
+		FROM account_values v
+		JOIN accounts a ON a.id = v.account_id
+		WHERE a.household_id = @household
+			AND a.id NOT IN @held
+			AND (CAST(@to AS timestamptz) IS NULL OR v.as_of < CAST(@to AS timestamptz))
+	) ranked
+	WHERE position = 1
+),
+-- Every account paired with every bucket, numbering the buckets it has
+-- values in so first_value over each run of buckets carries its last value
+-- forward. With a member, only that member's accounts are kept, weighted by
+-- their share as forMember does.
+carried AS (
+	SELECT
+		a.id,
+		a.class,
+		a.currency,
+		%s AS grp,
+		a.deleted_at,
+		b.bucket,
+		b.bucket_end,
+		l.value,
+		COALESCE(o.share / 100, 1) AS weight,
+		count(l.value) OVER (PARTITION BY a.id ORDER BY b.bucket) AS observed
+	FROM accounts a
+	CROSS JOIN buckets b
+	LEFT JOIN latest l ON l.account_id = a.id AND l.bucket = b.bucket
+	LEFT JOIN account_owners o ON o.account_id = a.id AND o.user_id = @member
+	WHERE a.household_id = @household
+		AND a.id NOT IN @held
+		AND (CAST(@member AS bigint) = 0 OR o.user_id IS NOT NULL)
+),
+filled AS (
+	SELECT
+		*,
+		first_value(value) OVER (PARTITION BY id, observed ORDER BY bucket) AS balance
+	FROM carried
+)
+SELECT
+	bucket AT TIME ZONE @tz AS bucket,
+	grp,
+	currency,
+	SUM(CASE
+		WHEN observed = 0 OR deleted_at < bucket_end THEN 0
+		WHEN class = 'asset' THEN balance * weight
+		ELSE -balance * weight
+	END) AS value
+FROM filled
+GROUP BY bucket, grp, currency
+ORDER BY bucket, grp, currency
+`
+
+// canRollupInDatabase reports whether rollupInDatabase supports the query.
+// Durations cannot be expressed with date_trunc, so they are always rolled up
+// in memory.
+func canRollupInDatabase(db *gorm.DB, query netWorthQuery) bool {
+	_, ok := postgresIntervals[query.Interval.Unit]
+	return ok && db.Dialector.Name() == "postgres"
+}
+
+// heldAccounts loads the household's accounts that hold positions, valued
+// from them by valueHoldings.
+func heldAccounts(s *store.GormStore, householdID uint, positions []models.Position) ([]models.Account, error) {
+	if len(positions) == 0 {
+		return []models.Account{}, nil
+	}
+	ids := []uint{}
+	seen := map[uint]bool{}
+	for _, position := range positions {
+		if !seen[position.AccountID] {
+			seen[position.AccountID] = true
+			ids = append(ids, position.AccountID)
+		}
+	}
+	accounts, err := models.GetAccountsWithValuesIncludingDeleted(s.DB, householdID, ids)
+	if err != nil {
+		return nil, err
+	}
+	return valueHoldings(s, accounts, positions)
+}
+
+// rollupInDatabase returns the same result as breakdown, or rollup in Total
+// when the query has no groupBy, aggregating the household's values in the
+// database except for those of held, which must already be limited to the
+// query's member.
+func rollupInDatabase(db *gorm.DB, householdID uint, query netWorthQuery, held []models.Account) (NetWorthBreakdown, error) {
+	// 0 is never an account's ID, and keeps NOT IN from being given an empty
+	// list.
+	heldIDs := []uint{0}
+	for _, account := range held {
+		heldIDs = append(heldIDs, account.ID)
+	}
+
+	var bounds struct {
+		First sql.NullTime
+		Last  sql.NullTime
+	}
+	err := db.Raw(`SELECT MIN(v.as_of) AS first, MAX(v.as_of) AS last
+		FROM account_values v
+		JOIN accounts a ON a.id = v.account_id
+		LEFT JOIN account_owners o ON o.account_id = a.id AND o.user_id = ?
+		WHERE a.household_id = ? AND a.id NOT IN ? AND (? = 0 OR o.user_id IS NOT NULL)`, query.Member, householdID, heldIDs, query.Member).
+		Scan(&bounds).Error
+	if err != nil {
+		return NetWorthBreakdown{}, err
+	}
+	first, last, found := bounds.First.Time, bounds.Last.Time, bounds.First.Valid
+	for _, account := range held {
+		for _, value := range account.Values {
+			if !found || value.AsOf.Before(first) {
+				first = value.AsOf
+			}
+			if !found || value.AsOf.After(last) {
+				last = value.AsOf
+			}
+			found = true
+		}
+	}
+
+	result := NetWorthBreakdown{GroupBy: query.GroupBy, Total: []NetWorthPoint{}, Groups: []NetWorthSeries{}}
+	if !found {
+		return result, nil
+	}
+	buckets, err := bucketsBetween(first, last, query)
+	if err != nil || len(buckets) == 0 {
+		return result, err
+	}
+
+	var to interface{}
+	if !query.To.IsZero() {
+		to = query.To
+	}
+	interval := postgresIntervals[query.Interval.Unit]
+	var rows []struct {
+		Bucket   time.Time
+		Grp      string
+		Currency models.Currency
+		Value    decimal.Decimal
+	}
+	err = db.Raw(fmt.Sprintf(netWorthSQL, groupExpressions[query.GroupBy]), map[string]interface{}{
+		"household": householdID,
+		"held":      heldIDs,
+		"member":    query.Member,
+		"field":     interval.field,
+		"step":      interval.step,
+		"tz":        query.Location.String(),
+		"first":     buckets[0].Format(postgresTimestamp),
+		"last":      buckets[len(buckets)-1].Format(postgresTimestamp),
+		"to":        to,
+	}).Scan(&rows).Error
+	if err != nil {
+		return NetWorthBreakdown{}, err
+	}
+
+	index := make(map[int64]int, len(buckets))
+	for i, bucket := range buckets {
+		index[bucket.Unix()] = i
+	}
+	newPoints := func() []NetWorthPoint {
+		points := make([]NetWorthPoint, len(buckets))
+		for i, bucket := range buckets {
+			points[i] = NetWorthPoint{Date: bucket, Value: decimal.Zero}
+		}
+		return points
+	}
+	add := func(totals map[models.Currency][]decimal.Decimal, currency models.Currency, i int, value decimal.Decimal) {
+		if _, ok := totals[currency]; !ok {
+			totals[currency] = make([]decimal.Decimal, len(buckets))
+		}
+		totals[currency][i] = totals[currency][i].Add(value)
+	}
+
+	total := map[models.Currency][]decimal.Decimal{}
+	groups := map[string]map[models.Currency][]decimal.Decimal{}
+	for _, row := range rows {
+		i, ok := index[row.Bucket.Unix()]
+		if !ok {
+			continue
+		}
+		if _, ok := groups[row.Grp]; !ok {
+			groups[row.Grp] = map[models.Currency][]decimal.Decimal{}
+		}
+		add(groups[row.Grp], row.Currency, i, row.Value)
+		add(total, row.Currency, i, row.Value)
+	}
+	// The values of held accounts, such as those valued from holdings, cannot
+	// be read from the database, so they are rolled up in memory into the
+	// same buckets.
+	for _, account := range held {
+		key := query.GroupBy.Key(account)
+		if _, ok := groups[key]; !ok {
+			groups[key] = map[models.Currency][]decimal.Decimal{}
+		}
+		for currency, sums := range sumBuckets([]models.Account{account}, buckets, query) {
+			for i, value := range sums {
+				add(groups[key], currency, i, value)
+				add(total, currency, i, value)
+			}
+		}
+	}
+
+	result.Total = newPoints()
+	if err := convertTotals(result.Total, total, query); err != nil {
+		return NetWorthBreakdown{}, err
+	}
+	if query.GroupBy == "" {
+		return result, nil
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		points := newPoints()
+		if err := convertTotals(points, groups[key], query); err != nil {
+			return NetWorthBreakdown{}, err
+		}
+		result.Groups = append(result.Groups, NetWorthSeries{Group: key, Points: points})
+	}
+	return result, nil
+}
+
+// postgresTimestamp formats a bucket's wall clock time as a Postgres
+// timestamp without time zone.
+const postgresTimestamp = "2006-01-02 15:04:05"
@@ -0,0 +1,428 @@
+package controllers
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/migrations"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCanRollupInDatabase(t *testing.T) {
+	db, _, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	assert.Equal(t, true, canRollupInDatabase(db, netWorthQuery{Interval: Interval{Unit: IntervalMonth}}))
+	assert.Equal(t, false, canRollupInDatabase(db, netWorthQuery{Interval: Interval{Duration: time.Hour}}))
+}
+
+func TestRollupInDatabase(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	jan := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT MIN\\(v.as_of\\) AS first, MAX\\(v.as_of\\) AS last").
+		WithArgs(uint(0), models.MockHouseholdID, uint(0), uint(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"first", "last"}).AddRow(jan.AddDate(0, 0, 14), jan.AddDate(0, 1, 3)))
+	mock.ExpectQuery("WITH buckets AS").
+		WithArgs(
+			"1 month", "UTC", nil, "2023-01-01 00:00:00", "2023-02-01 00:00:00", "1 month",
+			"month", "UTC", "2023-01-01 00:00:00", "month", "UTC", "2023-01-01 00:00:00",
+			models.MockHouseholdID, uint(0), nil, nil, uint(0), models.MockHouseholdID, uint(0), uint(0), "UTC",
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "grp", "currency", "value"}).
+			AddRow(jan, "cash", "EUR", decimal.NewFromInt(10)).
+			AddRow(jan, "cash", "USD", decimal.NewFromInt(100)).
+			AddRow(jan, "loan", "USD", decimal.NewFromInt(-40)).
+			AddRow(jan.AddDate(0, 1, 0), "cash", "EUR", decimal.NewFromInt(10)).
+			AddRow(jan.AddDate(0, 1, 0), "cash", "USD", decimal.NewFromInt(150)).
+			AddRow(jan.AddDate(0, 1, 0), "loan", "USD", decimal.NewFromInt(-30)))
+
+	query := netWorthQuery{
+		Interval:  Interval{Unit: IntervalMonth},
+		Location:  time.UTC,
+		GroupBy:   GroupByCategory,
+		Currency:  "USD",
+		converter: newConverter("USD", []models.FXRate{{Date: jan, Base: "EUR", Quote: "USD", Rate: decimal.RequireFromString("1.5")}}),
+	}
+	result, err := rollupInDatabase(db, models.MockHouseholdID, query, nil)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	assert.Equal(t, 2, len(result.Total))
+	assert.Equal(t, "75", result.Total[0].Value.String())
+	assert.Equal(t, "135", result.Total[1].Value.String())
+	assert.Equal(t, 2, len(result.Groups))
+	assert.Equal(t, "cash", result.Groups[0].Group)
+	assert.Equal(t, "165", result.Groups[0].Points[1].Value.String())
+	assert.Equal(t, "loan", result.Groups[1].Group)
+	assert.Equal(t, "-40", result.Groups[1].Points[0].Value.String())
+}
+
+// TestRollupInDatabaseWithHeldAccounts checks that accounts rolled up in
+// memory are left out of the aggregate and added into its buckets, widening
+// them to cover their values.
+func TestRollupInDatabaseWithHeldAccounts(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	jan := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	held := models.Account{
+		ID:       7,
+		Class:    models.Asset,
+		Category: models.Retirement,
+		Currency: "USD",
+		Values: []models.AccountValue{
+			{Value: decimal.NewFromInt(500), AsOf: jan.AddDate(0, 0, -12)},
+			{Value: decimal.NewFromInt(700), AsOf: jan.AddDate(0, 0, 19)},
+		},
+	}
+
+	mock.ExpectQuery("SELECT MIN\\(v.as_of\\) AS first, MAX\\(v.as_of\\) AS last").
+		WithArgs(uint(0), models.MockHouseholdID, uint(0), uint(7), uint(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"first", "last"}).AddRow(jan.AddDate(0, 0, 14), jan.AddDate(0, 1, 3)))
+	mock.ExpectQuery("WITH buckets AS").
+		WithArgs(
+			"1 month", "UTC", nil, "2022-12-01 00:00:00", "2023-02-01 00:00:00", "1 month",
+			"month", "UTC", "2022-12-01 00:00:00", "month", "UTC", "2022-12-01 00:00:00",
+			models.MockHouseholdID, uint(0), uint(7), nil, nil, uint(0), models.MockHouseholdID, uint(0), uint(7), uint(0), "UTC",
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "grp", "currency", "value"}).
+			AddRow(jan, "cash", "USD", decimal.NewFromInt(100)).
+			AddRow(jan.AddDate(0, 1, 0), "cash", "USD", decimal.NewFromInt(150)))
+
+	query := netWorthQuery{
+		Interval:  Interval{Unit: IntervalMonth},
+		Location:  time.UTC,
+		GroupBy:   GroupByCategory,
+		Currency:  "USD",
+		converter: newConverter("USD", nil),
+	}
+	result, err := rollupInDatabase(db, models.MockHouseholdID, query, []models.Account{held})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	got := []string{}
+	for _, point := range result.Total {
+		got = append(got, point.Value.String())
+	}
+	assert.Equal(t, []string{"500", "800", "850"}, got)
+	assert.Equal(t, 2, len(result.Groups))
+	assert.Equal(t, "retirement", result.Groups[1].Group)
+	assert.Equal(t, "700", result.Groups[1].Points[2].Value.String())
+}
+
+func TestRollupInDatabaseWithoutValues(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectQuery("SELECT MIN\\(v.as_of\\) AS first, MAX\\(v.as_of\\) AS last").
+		WithArgs(uint(0), models.MockHouseholdID, uint(0), uint(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"first", "last"}).AddRow(nil, nil))
+
+	result, err := rollupInDatabase(db, models.MockHouseholdID, netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC}, nil)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+	assert.Equal(t, 0, len(result.Total))
+}
+
+// generateHistory returns accounts with a value for every day of the given
+// number of years, one of them deleted part way through and every third held
+// in EUR.
+func generateHistory(numAccounts int, years int) []models.Account {
+	r := rand.New(rand.NewSource(1))
+	start := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	end := start.AddDate(years, 0, 0)
+	categories := []models.AccountCategory{models.Cash, models.Retirement, models.HSA, models.RealEstate}
+
+	accounts := make([]models.Account, numAccounts)
+	for i := range accounts {
+		account := models.Account{
+			Name:     fmt.Sprintf("account %d", i),
+			Class:    models.Asset,
+			Category: categories[i%len(categories)],
+		}
+		if i%5 == 4 {
+			account.Class = models.Liability
+			account.Category = models.Loan
+		}
+		if account.Category == models.Retirement {
+			account.TaxBucket = models.TaxDeferred
+		}
+		if i%3 == 2 {
+			account.Currency = "EUR"
+		}
+		if i == 1 {
+			account.DeletedAt = gorm.DeletedAt{Time: start.AddDate(years/2, 0, 0), Valid: true}
+		}
+
+		for day := start.AddDate(0, 0, i); day.Before(end); day = day.AddDate(0, 0, 1) {
+			account.Values = append(account.Values, models.AccountValue{
+				Value: decimal.NewFromInt(r.Int63n(1000000)).Shift(-2),
+				AsOf:  day,
+			})
+		}
+		accounts[i] = account
+	}
+	return accounts
+}
+
+// openTestDatabase loads accounts into a household in a new schema of the
+// Postgres database in TEST_DATABASE_DSN. Everything is created in a
+// transaction that is rolled back when the test ends. Tests are skipped
+// without a database.
+func openTestDatabase(tb testing.TB, accounts []models.Account) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		tb.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tx := db.Begin()
+	tb.Cleanup(func() { tx.Rollback() })
+
+	if err := tx.Exec("CREATE SCHEMA networth_test").Error; err != nil {
+		tb.Fatal(err)
+	}
+	if err := tx.Exec("SET LOCAL search_path TO networth_test").Error; err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := migrations.Up(tx); err != nil {
+		tb.Fatal(err)
+	}
+
+	household, err := models.CreateHousehold(tx, "networth")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for i := range accounts {
+		values := accounts[i].Values
+		accounts[i].Values = nil
+		accounts[i].HouseholdID = household.ID
+		if err := tx.Create(&accounts[i]).Error; err != nil {
+			tb.Fatal(err)
+		}
+		for j := range values {
+			values[j].AccountID = accounts[i].ID
+		}
+		if err := tx.CreateInBatches(&values, 1000).Error; err != nil {
+			tb.Fatal(err)
+		}
+		accounts[i].Values = values
+	}
+	return tx
+}
+
+// TestRollupInDatabaseMatchesRollup checks the SQL aggregation against the in
+// memory rollup on a real database.
+func TestRollupInDatabaseMatchesRollup(t *testing.T) {
+	accounts := generateHistory(10, 2)
+	db := openTestDatabase(t, accounts)
+
+	// The member owns half of every other account and all of the fourth.
+	member, err := models.CreateUser(db, accounts[0].HouseholdID, "member", "correct horse", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range accounts {
+		share := decimal.NewFromInt(50)
+		if i == 3 {
+			share = decimal.NewFromInt(100)
+		} else if i%2 == 1 {
+			continue
+		}
+		owner := models.AccountOwner{AccountID: accounts[i].ID, UserID: member.ID, Share: share}
+		if err := db.Create(&owner).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// EUR/USD moves every month, from a month before the first value.
+	r := rand.New(rand.NewSource(2))
+	for month := time.Date(2019, time.December, 1, 0, 0, 0, 0, time.UTC); month.Year() < 2022; month = month.AddDate(0, 1, 0) {
+		rate := models.FXRate{Date: month, Base: "EUR", Quote: "USD", Rate: decimal.NewFromInt(100 + r.Int63n(20)).Shift(-2)}
+		if _, err := models.SaveFXRate(db, rate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rates, err := models.GetFXRates(db, "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := models.GetAllAccountsWithValuesIncludingDeleted(db, accounts[0].HouseholdID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query netWorthQuery
+	}{
+		{
+			name:  "month",
+			query: netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC},
+		},
+		{
+			name:  "week in another time zone",
+			query: netWorthQuery{Interval: Interval{Unit: IntervalWeek}, Location: newYork},
+		},
+		{
+			name: "quarter within a date range",
+			query: netWorthQuery{
+				Interval: Interval{Unit: IntervalQuarter},
+				Location: time.UTC,
+				From:     time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC),
+				To:       time.Date(2021, time.August, 15, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "day by category",
+			query: netWorthQuery{Interval: Interval{Unit: IntervalDay}, Location: time.UTC, GroupBy: GroupByCategory},
+		},
+		{
+			name:  "year by tax bucket",
+			query: netWorthQuery{Interval: Interval{Unit: IntervalYear}, Location: time.UTC, GroupBy: GroupByTaxBucket},
+		},
+		{
+			name:  "month by category for a member",
+			query: netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC, GroupBy: GroupByCategory, Member: member.ID},
+		},
+		{
+			name: "week by category in EUR",
+			query: netWorthQuery{
+				Interval:  Interval{Unit: IntervalWeek},
+				Location:  time.UTC,
+				GroupBy:   GroupByCategory,
+				Currency:  "EUR",
+				converter: newConverter("EUR", rates),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, err := breakdown(forMember(loaded, test.query.Member), test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := rollupInDatabase(db, accounts[0].HouseholdID, test.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, len(want.Total), len(got.Total))
+			for i := range want.Total {
+				if !want.Total[i].Date.Equal(got.Total[i].Date) || !want.Total[i].Value.Equal(got.Total[i].Value) {
+					t.Errorf("point %d wanted: %v %v, got: %v %v", i, want.Total[i].Date, want.Total[i].Value, got.Total[i].Date, got.Total[i].Value)
+				}
+			}
+			if test.query.GroupBy == "" {
+				return
+			}
+			assert.Equal(t, len(want.Groups), len(got.Groups))
+			for i := range want.Groups {
+				assert.Equal(t, want.Groups[i].Group, got.Groups[i].Group)
+				for j := range want.Groups[i].Points {
+					if !want.Groups[i].Points[j].Value.Equal(got.Groups[i].Points[j].Value) {
+						t.Errorf("%s point %d wanted: %v, got: %v", want.Groups[i].Group, j, want.Groups[i].Points[j].Value, got.Groups[i].Points[j].Value)
+					}
+				}
+			}
+		})
+	}
+}
+
+// The benchmarks compare rolling up five years of daily values for 20
+// accounts in memory, including loading them, with aggregating them in
+// Postgres. Both need TEST_DATABASE_DSN; BenchmarkRollup also runs on its
+// own without loading from the database.
+
+func BenchmarkRollup(b *testing.B) {
+	accounts := generateHistory(20, 5)
+	query := netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := rollup(accounts, query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRollupLoadingFromDatabase(b *testing.B) {
+	accounts := generateHistory(20, 5)
+	db := openTestDatabase(b, accounts)
+	query := netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		loaded, err := models.GetAllAccountsWithValuesIncludingDeleted(db, accounts[0].HouseholdID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := rollup(loaded, query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRollupInDatabase(b *testing.B) {
+	accounts := generateHistory(20, 5)
+	db := openTestDatabase(b, accounts)
+	query := netWorthQuery{Interval: Interval{Unit: IntervalMonth}, Location: time.UTC}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := rollupInDatabase(db, models.MockHouseholdID, query, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
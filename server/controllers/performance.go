@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+type PerformanceController struct {
+	Store store.Store
+}
+
+func NewPerformanceController(s store.Store, router *gin.RouterGroup) PerformanceController {
+	performanceController := PerformanceController{Store: s}
+	router.GET("/performance", performanceController.GetPerformance)
+	return performanceController
+}
+
+// returnPlaces is how many decimal places returns, given as fractions, are
+// rounded to.
+const returnPlaces = 6
+
+// Performance is how a set of accounts did over a range. NetFlows is the
+// money contributed less the money withdrawn, and Gain what the accounts made
+// on top of that. TimeWeighted and XIRR are fractions, XIRR a yearly rate;
+// either is nil when there was nothing invested to earn a return on.
+type Performance struct {
+	Start        decimal.Decimal  `json:"start"`
+	End          decimal.Decimal  `json:"end"`
+	NetFlows     decimal.Decimal  `json:"netFlows"`
+	Gain         decimal.Decimal  `json:"gain"`
+	TimeWeighted *decimal.Decimal `json:"timeWeighted"`
+	XIRR         *decimal.Decimal `json:"xirr"`
+}
+
+type AccountPerformance struct {
+	AccountID uint   `json:"accountId"`
+	Account   string `json:"account"`
+	Performance
+}
+
+type GroupPerformance struct {
+	Group string `json:"group"`
+	Performance
+}
+
+// PerformanceReport holds the performance of every account, of each group when
+// grouped, and of all of them together, in the reporting currency.
+type PerformanceReport struct {
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	Currency models.Currency      `json:"currency"`
+	GroupBy  GroupBy              `json:"groupBy,omitempty"`
+	Accounts []AccountPerformance `json:"accounts"`
+	Groups   []GroupPerformance   `json:"groups,omitempty"`
+	Total    Performance          `json:"total"`
+}
+
+type performanceQuery struct {
+	From      time.Time
+	To        time.Time
+	GroupBy   GroupBy
+	Currency  models.Currency
+	converter converter
+}
+
+func parsePerformanceQuery(context *gin.Context) (performanceQuery, error) {
+	query := performanceQuery{}
+
+	var err error
+	if groupBy := context.Query("groupBy"); groupBy != "" {
+		query.GroupBy, err = ParseGroupBy(groupBy)
+		if err != nil {
+			return query, err
+		}
+	}
+
+	currency := context.DefaultQuery("currency", models.DefaultCurrency.String())
+	query.Currency, err = models.ParseCurrency(strings.ToUpper(currency))
+	if err != nil {
+		return query, err
+	}
+
+	timezone := context.DefaultQuery("timezone", "UTC")
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return query, fmt.Errorf("invalid timezone %q", timezone)
+	}
+
+	if from := context.Query("from"); from != "" {
+		query.From, _, err = parseQueryTime(from, location)
+		if err != nil {
+			return query, fmt.Errorf("invalid from %q, expected YYYY-MM-DD or an RFC 3339 timestamp", from)
+		}
+	}
+
+	query.To = time.Now()
+	if to := context.Query("to"); to != "" {
+		var dateOnly bool
+		query.To, dateOnly, err = parseQueryTime(to, location)
+		if err != nil {
+			return query, fmt.Errorf("invalid to %q, expected YYYY-MM-DD or an RFC 3339 timestamp", to)
+		}
+		if dateOnly {
+			query.To = query.To.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+	}
+
+	if !query.From.IsZero() && !query.From.Before(query.To) {
+		return query, fmt.Errorf("from must be before to")
+	}
+	return query, nil
+}
+
+// GetPerformance returns the time-weighted return and XIRR of each asset
+// account between from and to, and of all of them together. from defaults to
+// the earliest value and to to now; both accept a date (YYYY-MM-DD), where to
+// includes that whole day, or an RFC 3339 timestamp, and dates are in
+// timezone, UTC by default. With groupBy set to class, category or taxBucket
+// the report also holds the performance of each group.
+//
+// Contributions, withdrawals and transfers recorded as cash flows are money
+// moving in and out; interest and fees are part of the return. An account
+// opened during the range counts as its first value moving in, and one
+// deleted as its last value moving out. Liabilities are left out.
+//
+// The time-weighted return links Modified Dietz returns between the dates
+// values were recorded on, so it is only as fine-grained as the values are.
+// Amounts are converted into currency, USD by default, at the latest exchange
+// rate on or before their date, and one that cannot be converted fails the
+// request with 422 Unprocessable Entity.
+func (pc *PerformanceController) GetPerformance(context *gin.Context) {
+	household, ok := householdID(context)
+	if !ok {
+		return
+	}
+
+	query, err := parsePerformanceQuery(context)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := pc.performance(household, query)
+	if errors.Is(err, errMissingFXRate) {
+		context.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, report)
+}
+
+func (pc *PerformanceController) performance(householdID uint, query performanceQuery) (PerformanceReport, error) {
+	rates, err := pc.Store.GetFXRates(query.Currency)
+	if err != nil {
+		return PerformanceReport{}, err
+	}
+	query.converter = newConverter(query.Currency, rates)
+
+	positions, err := pc.Store.GetAllPositions(householdID)
+	if err != nil {
+		return PerformanceReport{}, err
+	}
+	all, err := pc.Store.GetAllAccountsWithValuesIncludingDeleted(householdID)
+	if err != nil {
+		return PerformanceReport{}, err
+	}
+	all, err = valueHoldings(pc.Store, all, positions)
+	if err != nil {
+		return PerformanceReport{}, err
+	}
+	cashFlows, err := pc.Store.GetAllCashFlows(householdID)
+	if err != nil {
+		return PerformanceReport{}, err
+	}
+
+	accounts := []models.Account{}
+	for _, account := range all {
+		if account.Class != models.Asset || len(account.Values) == 0 {
+			continue
+		}
+		if account.DeletedAt.Valid && !query.From.IsZero() && !account.DeletedAt.Time.After(query.From) {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+	if query.From.IsZero() {
+		for _, account := range accounts {
+			if first := firstValue(account); query.From.IsZero() || first.Before(query.From) {
+				query.From = first
+			}
+		}
+		if query.From.IsZero() || !query.From.Before(query.To) {
+			query.From = query.To
+		}
+	}
+
+	opened := []models.Account{}
+	for _, account := range accounts {
+		if !firstValue(account).After(query.To) {
+			opened = append(opened, account)
+		}
+	}
+	accounts = opened
+
+	flows := map[uint][]models.CashFlow{}
+	for _, flow := range cashFlows {
+		if flow.Type.IsExternal() {
+			flows[flow.AccountID] = append(flows[flow.AccountID], flow)
+		}
+	}
+
+	report := PerformanceReport{
+		From:     query.From,
+		To:       query.To,
+		Currency: query.Currency,
+		GroupBy:  query.GroupBy,
+		Accounts: []AccountPerformance{},
+	}
+	for _, account := range accounts {
+		performance, err := accountsPerformance([]models.Account{account}, flows, query)
+		if err != nil {
+			return report, err
+		}
+		report.Accounts = append(report.Accounts, AccountPerformance{AccountID: account.ID, Account: account.Name, Performance: performance})
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool { return report.Accounts[i].AccountID < report.Accounts[j].AccountID })
+
+	if query.GroupBy != "" {
+		groups := map[string][]models.Account{}
+		for _, account := range accounts {
+			key := query.GroupBy.Key(account)
+			groups[key] = append(groups[key], account)
+		}
+		report.Groups = []GroupPerformance{}
+		for key, members := range groups {
+			performance, err := accountsPerformance(members, flows, query)
+			if err != nil {
+				return report, err
+			}
+			report.Groups = append(report.Groups, GroupPerformance{Group: key, Performance: performance})
+		}
+		sort.Slice(report.Groups, func(i, j int) bool { return report.Groups[i].Group < report.Groups[j].Group })
+	}
+
+	report.Total, err = accountsPerformance(accounts, flows, query)
+	return report, err
+}
+
+// accountsPerformance works out the performance of the accounts taken
+// together, so transfers between them cancel out.
+func accountsPerformance(accounts []models.Account, flows map[uint][]models.CashFlow, query performanceQuery) (Performance, error) {
+	inRange := func(t time.Time) bool { return t.After(query.From) && !t.After(query.To) }
+
+	dates := []time.Time{query.From, query.To}
+	external := []models.ExternalFlow{}
+	addFlow := func(account models.Account, date time.Time, amount decimal.Decimal) error {
+		converted, err := query.converter.convert(amount, account.Currency, date.Add(time.Nanosecond))
+		if err != nil {
+			return err
+		}
+		external = append(external, models.ExternalFlow{Date: date, Amount: converted})
+		return nil
+	}
+
+	for _, account := range accounts {
+		first := firstValue(account)
+		for _, value := range account.Values {
+			if inRange(value.AsOf) {
+				dates = append(dates, value.AsOf)
+			}
+		}
+		if inRange(first) {
+			if err := addFlow(account, first, balanceAt(account, first)); err != nil {
+				return Performance{}, err
+			}
+		}
+		deleted := account.DeletedAt.Valid
+		if deleted && inRange(account.DeletedAt.Time) {
+			dates = append(dates, account.DeletedAt.Time)
+			last := balanceAt(account, account.DeletedAt.Time.Add(-time.Nanosecond))
+			if err := addFlow(account, account.DeletedAt.Time, last.Neg()); err != nil {
+				return Performance{}, err
+			}
+		}
+		for _, flow := range flows[account.ID] {
+			// Flows up to the first value are part of it, and there are none
+			// once an account has been deleted.
+			if !inRange(flow.Date) || !flow.Date.After(first) || (deleted && !flow.Date.Before(account.DeletedAt.Time)) {
+				continue
+			}
+			if err := addFlow(account, flow.Date, flow.Effect(account.Class)); err != nil {
+				return Performance{}, err
+			}
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	valuations := []models.Valuation{}
+	for i, date := range dates {
+		if i > 0 && date.Equal(dates[i-1]) {
+			continue
+		}
+		value := decimal.Zero
+		for _, account := range accounts {
+			converted, err := query.converter.convert(balanceAt(account, date), account.Currency, date.Add(time.Nanosecond))
+			if err != nil {
+				return Performance{}, err
+			}
+			value = value.Add(converted)
+		}
+		valuations = append(valuations, models.Valuation{Date: date, Value: value})
+	}
+
+	places := query.Currency.Precision()
+	performance := Performance{
+		Start:    valuations[0].Value,
+		End:      valuations[len(valuations)-1].Value,
+		NetFlows: decimal.Zero,
+	}
+	for _, flow := range external {
+		performance.NetFlows = performance.NetFlows.Add(flow.Amount)
+	}
+	performance.Gain = performance.End.Sub(performance.Start).Sub(performance.NetFlows).Round(places)
+
+	if twr, err := models.TimeWeightedReturn(valuations, external); err == nil {
+		twr = twr.Round(returnPlaces)
+		performance.TimeWeighted = &twr
+	}
+
+	// Seen from the investor, the starting value and contributions are paid
+	// in and withdrawals and the end value paid out.
+	investor := []models.ExternalFlow{{Date: query.From, Amount: performance.Start.Neg()}}
+	for _, flow := range external {
+		investor = append(investor, models.ExternalFlow{Date: flow.Date, Amount: flow.Amount.Neg()})
+	}
+	investor = append(investor, models.ExternalFlow{Date: query.To, Amount: performance.End})
+	if rate, err := models.XIRR(investor); err == nil {
+		xirr := decimal.NewFromFloat(rate).Round(returnPlaces)
+		performance.XIRR = &xirr
+	}
+
+	performance.Start = performance.Start.Round(places)
+	performance.End = performance.End.Round(places)
+	performance.NetFlows = performance.NetFlows.Round(places)
+	return performance, nil
+}
+
+// balanceAt returns the account's latest value as of t, or zero before its
+// first value or once it has been deleted.
+func balanceAt(account models.Account, t time.Time) decimal.Decimal {
+	if account.DeletedAt.Valid && !account.DeletedAt.Time.After(t) {
+		return decimal.Zero
+	}
+	var latest *models.AccountValue
+	for i, value := range account.Values {
+		if value.AsOf.After(t) {
+			continue
+		}
+		if latest == nil || value.AsOf.After(latest.AsOf) || (value.AsOf.Equal(latest.AsOf) && value.ID > latest.ID) {
+			latest = &account.Values[i]
+		}
+	}
+	if latest == nil {
+		return decimal.Zero
+	}
+	return latest.Value
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func registerPerformance(s store.Store, group *gin.RouterGroup) {
+	NewPerformanceController(s, group)
+}
+
+func TestGetPerformance(t *testing.T) {
+	s := cashFlowsStore(t)
+
+	w := serve(s, registerPerformance, "GET", "/api/performance?from=2023-01-10&to=2023-03-31&groupBy=taxBucket", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report PerformanceReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 2, len(report.Accounts))
+	k401 := report.Accounts[0]
+	assert.Equal(t, "401k", k401.Account)
+	assert.Equal(t, "1000", k401.Start.String())
+	assert.Equal(t, "1500", k401.End.String())
+	assert.Equal(t, "300", k401.NetFlows.String())
+	assert.Equal(t, "200", k401.Gain.String())
+	assert.Equal(t, "0.162599", k401.TimeWeighted.String())
+	assert.Equal(t, true, k401.XIRR.IsPositive())
+
+	// The savings account opened in the range, so its first value is money
+	// moving in rather than a gain.
+	savings := report.Accounts[1]
+	assert.Equal(t, "2000", savings.NetFlows.String())
+	assert.Equal(t, "10", savings.Gain.String())
+	assert.Equal(t, "0.005", savings.TimeWeighted.String())
+
+	assert.Equal(t, 1, len(report.Groups))
+	assert.Equal(t, unassignedGroup, report.Groups[0].Group)
+	assert.Equal(t, report.Total.Gain.String(), report.Groups[0].Gain.String())
+	assert.Equal(t, "2300", report.Total.NetFlows.String())
+	assert.Equal(t, "210", report.Total.Gain.String())
+	assert.Equal(t, "0.063587", report.Total.TimeWeighted.String())
+}
+
+func TestGetPerformanceWithHoldings(t *testing.T) {
+	// A 401k valued by hand at 1000 and then 1100 before its holdings were
+	// tracked from March, when 5 VTI were worth 1200 and then 1250.
+	date := func(month time.Month) time.Time {
+		return time.Date(2023, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+	s := newTestStore(t, models.Account{
+		ID:       1,
+		Name:     "401k",
+		Class:    models.Asset,
+		Category: models.Retirement,
+		Values: []models.AccountValue{
+			{Value: decimal.NewFromInt(1000), AsOf: date(time.January)},
+			{Value: decimal.NewFromInt(1100), AsOf: date(time.February)},
+		},
+	})
+	vti := addSecurity(t, s, models.Security{Symbol: "VTI"},
+		models.Price{Date: date(time.March), Price: decimal.NewFromInt(240)},
+		models.Price{Date: date(time.April), Price: decimal.NewFromInt(250)},
+	)
+	addPosition(t, s, models.Position{AccountID: 1, SecurityID: vti.ID, Quantity: decimal.NewFromInt(5), CostBasis: decimal.NewFromInt(1200), AsOf: date(time.March)})
+
+	w := serve(s, registerPerformance, "GET", "/api/performance?from=2023-01-15&to=2023-04-30", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var report PerformanceReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	// The money in the account before its first position is its starting
+	// value, not a contribution when the holdings take over.
+	assert.Equal(t, 1, len(report.Accounts))
+	k401 := report.Accounts[0].Performance
+	assert.Equal(t, "1000", k401.Start.String())
+	assert.Equal(t, "1250", k401.End.String())
+	assert.Equal(t, "0", k401.NetFlows.String())
+	assert.Equal(t, "250", k401.Gain.String())
+	assert.Equal(t, "0.25", k401.TimeWeighted.String())
+}
+
+func TestGetPerformanceQuery(t *testing.T) {
+	s := cashFlowsStore(t)
+
+	tests := []struct {
+		url          string
+		responseCode int
+	}{
+		{url: "/api/performance", responseCode: http.StatusOK},
+		{url: "/api/performance?from=2023-03-01&to=2023-02-01", responseCode: http.StatusBadRequest},
+		{url: "/api/performance?groupBy=owner", responseCode: http.StatusBadRequest},
+		{url: "/api/performance?to=yesterday", responseCode: http.StatusBadRequest},
+		{url: "/api/performance?currency=GBP", responseCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			w := serve(s, registerPerformance, "GET", test.url, "")
+			assert.Equal(t, test.responseCode, w.Code)
+		})
+	}
+}
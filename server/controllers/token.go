@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TokenController struct {
+	DB *gorm.DB
+}
+
+// NewTokenController serves the logged in user's API tokens. The routes need
+// a session: a token cannot be used to create, list or revoke tokens.
+func NewTokenController(db *gorm.DB, router *gin.RouterGroup) TokenController {
+	tokenController := TokenController{DB: db}
+
+	tokenRouter := router.Group("/tokens", requireBrowserSession)
+	{
+		tokenRouter.GET("", tokenController.GetTokens)
+		tokenRouter.POST("", tokenController.CreateToken)
+		tokenRouter.DELETE("/:id", tokenController.DeleteToken)
+	}
+
+	return tokenController
+}
+
+type tokenRequest struct {
+	Name  string            `json:"name" binding:"required"`
+	Scope models.TokenScope `json:"scope" binding:"required"`
+}
+
+// CreatedToken is a new API token along with the token itself, which is only
+// ever returned when it is created.
+type CreatedToken struct {
+	models.APIToken
+	Token string `json:"token"`
+}
+
+// requireBrowserSession aborts requests authenticated with an API token with
+// 403 Forbidden. It guards the routes that could let a token mint more
+// credentials: managing tokens and inviting members.
+func requireBrowserSession(context *gin.Context) {
+	if _, ok := context.Get(tokenKey); ok {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this needs a browser session, it cannot be used with an API token"})
+		return
+	}
+	context.Next()
+}
+
+func (controller *TokenController) GetTokens(context *gin.Context) {
+	user, ok := CurrentUser(context)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+
+	tokens, err := models.GetAPITokens(controller.DB, user.ID)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken creates an API token with a read or write scope. Scripts send
+// it in an "Authorization: Bearer" header.
+func (controller *TokenController) CreateToken(context *gin.Context) {
+	user, ok := CurrentUser(context)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+
+	var request tokenRequest
+	if err := context.BindJSON(&request); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := models.ParseTokenScope(request.Scope.String()); err != nil {
+		context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, apiToken, err := models.CreateAPIToken(controller.DB, user.ID, request.Name, request.Scope)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusCreated, CreatedToken{APIToken: apiToken, Token: token})
+}
+
+// DeleteToken revokes one of the logged in user's API tokens.
+func (controller *TokenController) DeleteToken(context *gin.Context) {
+	user, ok := CurrentUser(context)
+	if !ok {
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not logged in"})
+		return
+	}
+
+	id, ok := parseID(context)
+	if !ok {
+		return
+	}
+
+	apiToken, err := models.DeleteAPIToken(controller.DB, user.ID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		context.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	context.JSON(http.StatusOK, apiToken)
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+)
+
+func TestNewTokenController(t *testing.T) {
+	db := openSQLiteDatabase(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	group := router.Group("/api")
+	controller := NewTokenController(db, group)
+
+	assert.Equal(t, controller.DB, db)
+}
+
+// createToken creates an API token for the user with the cookie and returns
+// it.
+func createToken(t *testing.T, router *gin.Engine, cookie *http.Cookie, scope models.TokenScope) CreatedToken {
+	w := serveWithCookie(router, "POST", "/api/tokens", `{"name":"cron","scope":"`+scope.String()+`"}`, cookie)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("creating token: %d %s", w.Code, w.Body.String())
+	}
+	var token CreatedToken
+	if err := json.Unmarshal(w.Body.Bytes(), &token); err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func serveWithToken(router *gin.Engine, method, url, body, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPITokens(t *testing.T) {
+	db := openSQLiteDatabase(t)
+	createAdmin(t, db, "admin")
+	createAdmin(t, db, "neighbour")
+	router := newAuthRouter(db)
+	admin := login(t, router, "admin")
+	neighbour := login(t, router, "neighbour")
+
+	w := serveWithCookie(router, "POST", "/api/tokens", `{"name":"cron","scope":"admin"}`, admin)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	read := createToken(t, router, admin, models.ScopeRead)
+	write := createToken(t, router, admin, models.ScopeWrite)
+	assert.NotEqual(t, "", write.Token)
+	assert.Equal(t, models.ScopeWrite, write.Scope)
+
+	// A write token can push balances, a read token can only look at them.
+	w = serveWithToken(router, "POST", "/api/accounts", `{"name":"checking","class":"asset","category":"cash"}`, write.Token)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = serveWithToken(router, "POST", "/api/accounts/value", `{"account_id":1,"value":100}`, write.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serveWithToken(router, "GET", "/api/accounts", "", read.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.MatchRegex(t, w.Body.String(), `"name":"checking"`)
+	w = serveWithToken(router, "POST", "/api/accounts/value", `{"account_id":1,"value":200}`, read.Token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serveWithToken(router, "GET", "/api/accounts", "", "forged")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = serveWithCookie(router, "GET", "/api/accounts", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Tokens cannot be used to manage tokens.
+	w = serveWithToken(router, "POST", "/api/tokens", `{"name":"more","scope":"write"}`, write.Token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = serveWithToken(router, "GET", "/api/tokens", "", write.Token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Nor to invite someone, which would give whoever redeems the invitation
+	// a session in the household.
+	w = serveWithToken(router, "POST", "/api/household/invitations", "", write.Token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = serveWithToken(router, "GET", "/api/household", "", read.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serveWithCookie(router, "GET", "/api/tokens", "", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tokens []models.APIToken
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(tokens))
+	assert.NotEqual(t, nil, tokens[0].LastUsedAt)
+	assert.Equal(t, false, strings.Contains(w.Body.String(), write.Token))
+
+	w = serveWithCookie(router, "GET", "/api/tokens", "", neighbour)
+	assert.Equal(t, "[]", w.Body.String())
+	w = serveWithCookie(router, "DELETE", "/api/tokens/2", "", neighbour)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveWithCookie(router, "DELETE", "/api/tokens/2", "", admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serveWithToken(router, "GET", "/api/accounts", "", write.Token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/Jrc356/financial_dashboard/export"
+	"gorm.io/gorm"
+)
+
+func exportCommand(db *gorm.DB, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "json", "export format, json or csv")
+	householdID := flags.Uint("household", 0, "household to export, the first household when 0")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: export [-format json|csv] [-household id] FILE")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var write func(*gorm.DB, uint, io.Writer) error
+	switch *format {
+	case "json":
+		write = export.WriteJSON
+	case "csv":
+		write = export.WriteCSV
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	household := lookupHousehold(db, *householdID)
+
+	file, err := os.Create(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := write(db, household.ID, file); err != nil {
+		file.Close()
+		log.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("exported to %s", flags.Arg(0))
+}
@@ -0,0 +1,272 @@
+// Package export writes every account, value, cash flow, position, lot, sale
+// and import mapping of a household, along with the exchange rates, securities and prices they are
+// valued with, out of the database so it can be backed up or moved to another
+// instance.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"gorm.io/gorm"
+)
+
+// Version is the version of the JSON document written by WriteJSON. Version 2
+// added exchange rates, version 3 positions, with their securities and
+// prices, version 4 lots and sales and version 5 cash flows.
+const Version = 5
+
+// batchSize is the number of accounts loaded from the database at a time.
+const batchSize = 100
+
+// Document is the JSON export format. It includes deleted accounts and the
+// timestamps of every record so importing it reproduces the exported data
+// exactly.
+type Document struct {
+	Version            int                        `json:"version"`
+	ExportedAt         time.Time                  `json:"exported_at"`
+	Accounts           []models.Account           `json:"accounts"`
+	OFXAccountMappings []models.OFXAccountMapping `json:"ofx_account_mappings"`
+	CashFlows          []models.CashFlow          `json:"cash_flows"`
+	// FXRates are shared by every household, so all of them are exported.
+	FXRates []models.FXRate `json:"fx_rates"`
+	// Securities are those the household's positions, lots and sales are
+	// in, with their Prices.
+	Securities []models.Security `json:"securities"`
+	Prices     []models.Price    `json:"prices"`
+	Positions  []models.Position `json:"positions"`
+	Lots       []models.Lot      `json:"lots"`
+	// Sales hold the lot matches that close their lots.
+	Sales []models.Sale `json:"sales"`
+}
+
+// eachAccount calls fn for every account of the household, including deleted
+// ones, with its values loaded oldest first. Accounts are loaded in batches,
+// ordered by ID, so large databases are never held in memory at once.
+func eachAccount(db *gorm.DB, householdID uint, fn func(models.Account) error) error {
+	var accounts []models.Account
+	result := db.Unscoped().
+		Where("household_id = ?", householdID).
+		Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("as_of, id") }).
+		FindInBatches(&accounts, batchSize, func(tx *gorm.DB, batch int) error {
+			for _, account := range accounts {
+				if err := fn(account); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	return result.Error
+}
+
+// WriteJSON streams a Document to w.
+func WriteJSON(db *gorm.DB, householdID uint, w io.Writer) error {
+	header, err := json.Marshal(time.Now())
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `{"version":%d,"exported_at":%s,"accounts":[`, Version, header); err != nil {
+		return err
+	}
+
+	first := true
+	err = eachAccount(db, householdID, func(account models.Account) error {
+		if account.Values == nil {
+			account.Values = []models.AccountValue{}
+		}
+		data, err := json.Marshal(account)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, "]"); err != nil {
+		return err
+	}
+
+	mappings, err := models.GetOFXAccountMappings(db, householdID)
+	if err != nil {
+		return err
+	}
+	if mappings == nil {
+		mappings = []models.OFXAccountMapping{}
+	}
+	if err := writeField(w, "ofx_account_mappings", mappings); err != nil {
+		return err
+	}
+
+	flows, err := models.GetAllCashFlows(db, householdID)
+	if err != nil {
+		return err
+	}
+	if err := writeField(w, "cash_flows", flows); err != nil {
+		return err
+	}
+
+	rates, err := models.GetAllFXRates(db)
+	if err != nil {
+		return err
+	}
+	if err := writeField(w, "fx_rates", rates); err != nil {
+		return err
+	}
+
+	positions, err := models.GetAllPositions(db, householdID)
+	if err != nil {
+		return err
+	}
+	lots, err := models.GetAllLots(db, householdID)
+	if err != nil {
+		return err
+	}
+	sales, err := models.GetAllSales(db, householdID)
+	if err != nil {
+		return err
+	}
+
+	ids := []uint{}
+	seen := map[uint]bool{}
+	addSecurity := func(id uint) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, position := range positions {
+		addSecurity(position.SecurityID)
+	}
+	for i := range lots {
+		addSecurity(lots[i].SecurityID)
+		// The securities are written once, on their own.
+		lots[i].Security = nil
+	}
+	for _, sale := range sales {
+		addSecurity(sale.SecurityID)
+	}
+	securities, err := models.GetSecuritiesByID(db, ids)
+	if err != nil {
+		return err
+	}
+	prices, err := models.GetPrices(db, ids)
+	if err != nil {
+		return err
+	}
+	if err := writeField(w, "securities", securities); err != nil {
+		return err
+	}
+	if err := writeField(w, "prices", prices); err != nil {
+		return err
+	}
+	if err := writeField(w, "positions", positions); err != nil {
+		return err
+	}
+	if err := writeField(w, "lots", lots); err != nil {
+		return err
+	}
+	if err := writeField(w, "sales", sales); err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "}")
+	return err
+}
+
+// writeField writes a field following the accounts of a Document.
+func writeField(w io.Writer, name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, `,%q:%s`, name, data)
+	return err
+}
+
+// CSVHeader lists the columns written by WriteCSV.
+var CSVHeader = []string{
+	"account_id",
+	"account",
+	"class",
+	"category",
+	"tax_bucket",
+	"currency",
+	"account_created_at",
+	"account_updated_at",
+	"account_deleted_at",
+	"value_id",
+	"date",
+	"value",
+	"value_created_at",
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// WriteCSV streams one row per account value to w, repeating the account's
+// details on each row. Accounts without values are written as a single row
+// with empty value columns.
+func WriteCSV(db *gorm.DB, householdID uint, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(CSVHeader); err != nil {
+		return err
+	}
+
+	err := eachAccount(db, householdID, func(account models.Account) error {
+		deletedAt := ""
+		if account.DeletedAt.Valid {
+			deletedAt = formatTime(account.DeletedAt.Time)
+		}
+		accountColumns := []string{
+			strconv.FormatUint(uint64(account.ID), 10),
+			account.Name,
+			account.Class.String(),
+			account.Category.String(),
+			account.TaxBucket.String(),
+			account.Currency.String(),
+			formatTime(account.CreatedAt),
+			formatTime(account.UpdatedAt),
+			deletedAt,
+		}
+
+		if len(account.Values) == 0 {
+			return writer.Write(append(accountColumns, "", "", "", ""))
+		}
+		for _, value := range account.Values {
+			row := append(append([]string{}, accountColumns...),
+				strconv.FormatUint(uint64(value.ID), 10),
+				formatTime(value.AsOf),
+				value.Value.String(),
+				formatTime(value.CreatedAt),
+			)
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
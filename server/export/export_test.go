@@ -0,0 +1,212 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	createdAt = time.Date(2023, time.January, 2, 3, 4, 5, 123456000, time.UTC)
+	deletedAt = time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	asOf      = time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func expectExport(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT \\* FROM \"accounts\" WHERE household_id = \\$1 ORDER BY \"accounts\".\"id\" LIMIT 100").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows(models.AccountColumns).
+			AddRow(1, "Checking", "asset", "cash", "", createdAt, createdAt, nil).
+			AddRow(2, "Old Loan", "liability", "loan", "", createdAt, deletedAt, deletedAt))
+	mock.ExpectQuery("SELECT \\* FROM \"account_values\" WHERE \"account_values\".\"account_id\" IN").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows(models.AccountValuesColumns).
+			AddRow(10, 1, "1520.25", asOf, createdAt))
+	mock.ExpectQuery("SELECT \\* FROM \"ofx_account_mappings\" WHERE household_id").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ofx_account_id", "account_id", "created_at", "updated_at"}).
+			AddRow(3, "0001234", 1, createdAt, createdAt))
+	mock.ExpectQuery("SELECT \\* FROM \"cash_flows\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "type", "amount", "date", "memo", "created_at"}).
+			AddRow(8, 1, "contribution", "500", asOf, "paycheck", createdAt))
+	mock.ExpectQuery("SELECT \\* FROM \"fx_rates\" ORDER BY date, id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date", "base", "quote", "rate"}).
+			AddRow(4, asOf, "EUR", "USD", "1.08"))
+	mock.ExpectQuery("SELECT \\* FROM \"positions\" WHERE account_id IN").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "account_id", "security_id", "quantity", "cost_basis", "as_of", "created_at"}).
+			AddRow(6, 1, 5, "10", "2000", asOf, createdAt))
+	mock.ExpectQuery("SELECT \\* FROM \"lots\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM \"sales\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM \"securities\" WHERE id IN").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "symbol", "name", "currency"}).
+			AddRow(5, "VTI", "Vanguard Total Stock Market", "USD"))
+	mock.ExpectQuery("SELECT \\* FROM \"prices\" WHERE security_id IN").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "security_id", "date", "price"}).
+			AddRow(7, 5, asOf, "210"))
+}
+
+func TestWriteJSON(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	expectExport(mock)
+
+	var buf bytes.Buffer
+	if err := WriteJSON(db, models.MockHouseholdID, &buf); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	var document Document
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("export is not valid JSON: %s\n%s", err, buf.String())
+	}
+
+	assert.Equal(t, Version, document.Version)
+	assert.Equal(t, 2, len(document.Accounts))
+	assert.Equal(t, "Checking", document.Accounts[0].Name)
+	assert.Equal(t, createdAt, document.Accounts[0].CreatedAt)
+	assert.Equal(t, false, document.Accounts[0].DeletedAt.Valid)
+	assert.Equal(t, 1, len(document.Accounts[0].Values))
+	assert.Equal(t, uint(10), document.Accounts[0].Values[0].ID)
+	assert.Equal(t, "1520.25", document.Accounts[0].Values[0].Value.String())
+	assert.Equal(t, asOf, document.Accounts[0].Values[0].AsOf)
+	assert.Equal(t, true, document.Accounts[1].DeletedAt.Valid)
+	assert.Equal(t, deletedAt, document.Accounts[1].DeletedAt.Time)
+	assert.Equal(t, 0, len(document.Accounts[1].Values))
+	assert.Equal(t, 1, len(document.OFXAccountMappings))
+	assert.Equal(t, "0001234", document.OFXAccountMappings[0].OFXAccountID)
+	assert.Equal(t, 1, len(document.FXRates))
+	assert.Equal(t, "1.08", document.FXRates[0].Rate.String())
+	assert.Equal(t, 1, len(document.CashFlows))
+	assert.Equal(t, models.Contribution, document.CashFlows[0].Type)
+	assert.Equal(t, 1, len(document.Positions))
+	assert.Equal(t, "10", document.Positions[0].Quantity.String())
+	assert.Equal(t, 1, len(document.Securities))
+	assert.Equal(t, "VTI", document.Securities[0].Symbol)
+	assert.Equal(t, 1, len(document.Prices))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWriteJSONEmpty(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM \"accounts\" WHERE household_id").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows(models.AccountColumns))
+	mock.ExpectQuery("SELECT \\* FROM \"ofx_account_mappings\" WHERE household_id").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM \"cash_flows\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM \"fx_rates\"").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM \"positions\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM \"lots\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM \"sales\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	var buf bytes.Buffer
+	if err := WriteJSON(db, models.MockHouseholdID, &buf); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	var document Document
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("export is not valid JSON: %s\n%s", err, buf.String())
+	}
+	assert.Equal(t, 0, len(document.Accounts))
+	assert.Equal(t, 0, len(document.OFXAccountMappings))
+	assert.Equal(t, 0, len(document.FXRates))
+	assert.Equal(t, 0, len(document.CashFlows))
+	assert.Equal(t, 0, len(document.Positions))
+	assert.Equal(t, 0, len(document.Lots))
+	assert.Equal(t, 0, len(document.Sales))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM \"accounts\" WHERE household_id = \\$1 ORDER BY \"accounts\".\"id\" LIMIT 100").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows(append(models.AccountColumns, "Currency")).
+			AddRow(1, "Checking", "asset", "cash", "", createdAt, createdAt, nil, "USD").
+			AddRow(2, "Old Loan", "liability", "loan", "", createdAt, deletedAt, deletedAt, "EUR"))
+	mock.ExpectQuery("SELECT \\* FROM \"account_values\"").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows(models.AccountValuesColumns).
+			AddRow(10, 1, decimal.RequireFromString("1520.25"), asOf, createdAt).
+			AddRow(11, 1, decimal.RequireFromString("1610.00"), asOf.AddDate(0, 1, 0), createdAt))
+
+	var buf bytes.Buffer
+	if err := WriteCSV(db, models.MockHouseholdID, &buf); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %s", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, got %d", len(records))
+	}
+
+	assert.Equal(t, CSVHeader, records[0])
+	assert.Equal(t, []string{
+		"1", "Checking", "asset", "cash", "", "USD",
+		"2023-01-02T03:04:05.123456Z", "2023-01-02T03:04:05.123456Z", "",
+		"10", "2023-02-01T00:00:00Z", "1520.25", "2023-01-02T03:04:05.123456Z",
+	}, records[1])
+	assert.Equal(t, "11", records[2][9])
+	assert.Equal(t, "1610", records[2][11])
+	assert.Equal(t, []string{
+		"2", "Old Loan", "liability", "loan", "", "EUR",
+		"2023-01-02T03:04:05.123456Z", "2023-03-01T00:00:00Z", "2023-03-01T00:00:00Z",
+		"", "", "", "",
+	}, records[3])
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
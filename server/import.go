@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Jrc356/financial_dashboard/importer"
+	"gorm.io/gorm"
+)
+
+func printReport(report importer.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tACCOUNT\tSTATUS\tMESSAGE")
+	for _, row := range report.Rows {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", row.Line, row.Account, row.Status, row.Message)
+	}
+	w.Flush()
+	fmt.Printf("created: %d, skipped: %d, failed: %d\n", report.Created, report.Skipped, report.Failed)
+}
+
+func importCommand(db *gorm.DB, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	householdID := flags.Uint("household", 0, "household to import into, the first household when 0")
+	createAccounts := flags.Bool("create-accounts", false, "create accounts that do not exist from the class and category columns")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: import csv [-create-accounts] [-household id] FILE")
+		fmt.Fprintln(flags.Output(), "       import ofx [-household id] FILE")
+		fmt.Fprintln(flags.Output(), "       import json [-household id] FILE")
+		fmt.Fprintln(flags.Output(), "       import fx FILE")
+		fmt.Fprintln(flags.Output(), "       import prices FILE")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	format := args[0]
+	flags.Parse(args[1:])
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	var report importer.Report
+	switch format {
+	case "csv":
+		household := lookupHousehold(db, *householdID)
+		report, err = importer.ImportCSV(db, household.ID, file, importer.CSVOptions{CreateAccounts: *createAccounts})
+	case "ofx", "qfx":
+		household := lookupHousehold(db, *householdID)
+		report, err = importer.ImportOFX(db, household.ID, file)
+	case "json":
+		household := lookupHousehold(db, *householdID)
+		report, err = importer.ImportJSON(db, household.ID, file, importer.JSONOptions{SharedData: true})
+	case "fx":
+		report, err = importer.ImportFXRates(db, file)
+	case "prices":
+		report, err = importer.ImportPrices(db, file)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	printReport(report)
+	if !report.Committed {
+		log.Fatal("import failed, no rows were written")
+	}
+}
@@ -0,0 +1,238 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// CSVOptions controls how a CSV file is imported.
+type CSVOptions struct {
+	// CreateAccounts creates accounts that do not exist yet from the class,
+	// category, tax_bucket and currency columns instead of failing the row.
+	CreateAccounts bool
+}
+
+const (
+	columnAccount   = "account"
+	columnDate      = "date"
+	columnValue     = "value"
+	columnClass     = "class"
+	columnCategory  = "category"
+	columnTaxBucket = "tax_bucket"
+	columnCurrency  = "currency"
+)
+
+var dateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+}
+
+type csvColumns map[string]int
+
+func (c csvColumns) get(record []string, column string) string {
+	i, ok := c[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func parseHeader(header []string) (csvColumns, error) {
+	return parseColumns(header,
+		[]string{columnAccount, columnDate, columnValue},
+		[]string{columnClass, columnCategory, columnTaxBucket, columnCurrency},
+	)
+}
+
+// parseColumns maps the names in header to their position. Every required
+// column must be present and every other column must be optional.
+func parseColumns(header []string, required, optional []string) (csvColumns, error) {
+	known := map[string]bool{}
+	for _, name := range append(append([]string{}, required...), optional...) {
+		known[name] = true
+	}
+
+	columns := csvColumns{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing required column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+}
+
+// parseValue accepts plain decimals as well as values formatted by
+// spreadsheets such as "$1,234.56".
+func parseValue(s string) (decimal.Decimal, error) {
+	cleaned := strings.NewReplacer("$", "", ",", "").Replace(s)
+	value, err := decimal.NewFromString(cleaned)
+	if err != nil {
+		return value, fmt.Errorf("invalid value %q", s)
+	}
+	if value.IsZero() {
+		return value, fmt.Errorf(`"value" must be > 0`)
+	}
+	return value, nil
+}
+
+// ImportCSV imports account values from r. The first row must be a header
+// naming the account, date and value columns, and optionally the class,
+// category, tax_bucket and currency columns used to create missing accounts.
+// Values that are already recorded for the same account, date and amount are
+// skipped, so importing the same file twice is harmless, while a different
+// value for a recorded date is added as a new value. Accounts are looked up
+// and created in the given household.
+func ImportCSV(db *gorm.DB, householdID uint, r io.Reader, opts CSVOptions) (Report, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: reading csv header: %s", ErrInvalidFile, err)
+	}
+	columns, err := parseHeader(header)
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+
+	report := Report{Rows: []RowResult{}}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		accounts := map[string]models.Account{}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				var parseErr *csv.ParseError
+				if errors.As(err, &parseErr) {
+					report.add(RowResult{Line: parseErr.Line, Status: RowFailed, Message: parseErr.Err.Error()})
+					continue
+				}
+				return err
+			}
+			line, _ := reader.FieldPos(0)
+
+			row, err := importCSVRow(tx, householdID, columns, record, accounts, opts)
+			if err != nil {
+				return err
+			}
+			row.Line = line
+			report.add(row)
+		}
+
+		if report.Failed > 0 {
+			return errRowsFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errRowsFailed) {
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+
+	report.Committed = true
+	return report, nil
+}
+
+// importCSVRow imports a single record. Problems with the record itself are
+// reported in the returned RowResult; the error is reserved for database
+// failures that abort the whole import.
+func importCSVRow(tx *gorm.DB, householdID uint, columns csvColumns, record []string, accounts map[string]models.Account, opts CSVOptions) (RowResult, error) {
+	name := columns.get(record, columnAccount)
+	row := RowResult{Account: name}
+	failed := func(format string, args ...interface{}) (RowResult, error) {
+		row.Status = RowFailed
+		row.Message = fmt.Sprintf(format, args...)
+		return row, nil
+	}
+
+	if name == "" {
+		return failed("no account name provided")
+	}
+	asOf, err := parseDate(columns.get(record, columnDate))
+	if err != nil {
+		return failed(err.Error())
+	}
+	value, err := parseValue(columns.get(record, columnValue))
+	if err != nil {
+		return failed(err.Error())
+	}
+
+	account, ok := accounts[name]
+	if !ok {
+		account, err = models.GetAccountByName(tx, householdID, name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if !opts.CreateAccounts {
+				return failed("account %s does not exist", name)
+			}
+
+			account = models.Account{
+				Name:      name,
+				Class:     models.AccountClass(columns.get(record, columnClass)),
+				Category:  models.AccountCategory(columns.get(record, columnCategory)),
+				TaxBucket: models.TaxBucket(columns.get(record, columnTaxBucket)),
+				Currency:  models.Currency(strings.ToUpper(columns.get(record, columnCurrency))),
+			}
+			if err := models.ValidateAccount(account); err != nil {
+				return failed("cannot create account %s: %s", name, err)
+			}
+			account, err = models.CreateAccount(tx, householdID, account)
+			if err != nil {
+				return row, err
+			}
+			row.Message = fmt.Sprintf("created account %s", name)
+		} else if err != nil {
+			return row, err
+		}
+		accounts[name] = account
+	}
+
+	av := models.AccountValue{AccountID: account.ID, Value: value, AsOf: asOf}
+	exists, err := models.AccountValueExists(tx, householdID, av)
+	if err != nil {
+		return row, err
+	}
+	if exists {
+		row.Status = RowSkipped
+		row.Message = "value already recorded"
+		return row, nil
+	}
+
+	if _, err := models.CreateAccountValue(tx, householdID, av); err != nil {
+		return row, err
+	}
+	row.Status = RowCreated
+	return row, nil
+}
@@ -0,0 +1,330 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		wantErr bool
+	}{
+		{
+			name:    "should accept the required columns",
+			header:  []string{"account", "date", "value"},
+			wantErr: false,
+		},
+		{
+			name:    "should accept optional columns in any order and case",
+			header:  []string{"Value", " Account", "DATE", "class", "category", "tax_bucket"},
+			wantErr: false,
+		},
+		{
+			name:    "should reject a missing required column",
+			header:  []string{"account", "value"},
+			wantErr: true,
+		},
+		{
+			name:    "should reject unknown columns",
+			header:  []string{"account", "date", "value", "notes"},
+			wantErr: true,
+		},
+		{
+			name:    "should reject duplicate columns",
+			header:  []string{"account", "date", "value", "value"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseHeader(test.header)
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    decimal.Decimal
+		wantErr bool
+	}{
+		{input: "1234.56", want: decimal.NewFromFloat(1234.56)},
+		{input: "$1,234.56", want: decimal.NewFromFloat(1234.56)},
+		{input: "-20", want: decimal.NewFromInt(-20)},
+		{input: "0", wantErr: true},
+		{input: "abc", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			value, err := parseValue(test.input)
+			assert.Equal(t, test.wantErr, err != nil)
+			if !test.wantErr && !test.want.Equal(value) {
+				t.Errorf("wanted: %v, got: %v", test.want, value)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{input: "2023-05-31", want: time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC)},
+		{input: "2023-05-31T12:00:00Z", want: time.Date(2023, time.May, 31, 12, 0, 0, 0, time.UTC)},
+		{input: "05/31/2023", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			date, err := parseDate(test.input)
+			assert.Equal(t, test.wantErr, err != nil)
+			if !test.wantErr && !test.want.Equal(date) {
+				t.Errorf("wanted: %v, got: %v", test.want, date)
+			}
+		})
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	account := models.Account{ID: 1, Name: "test", Class: models.Asset, Category: models.Cash}
+	asOf := time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC)
+	file := strings.Join([]string{
+		"account,date,value",
+		"test,2023-05-31,100.00",
+		"test,2023-05-31,100.00",
+	}, "\n")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM \"accounts\" WHERE household_id = .+ AND name").
+		WithArgs(models.MockHouseholdID, "test").
+		WillReturnRows(models.AccountToSQLRow(account))
+	mock.ExpectQuery("SELECT count(.+) FROM \"account_values\"").
+		WithArgs(models.MockHouseholdID, account.ID, asOf, decimal.NewFromInt(100).Round(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT count(.+) FROM \"accounts\"").
+		WithArgs(models.MockHouseholdID, account.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO \"account_values\"").
+		WithArgs(account.ID, decimal.NewFromInt(100).Round(2), asOf, models.AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT count(.+) FROM \"account_values\"").
+		WithArgs(models.MockHouseholdID, account.ID, asOf, decimal.NewFromInt(100).Round(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	report, err := ImportCSV(db, models.MockHouseholdID, strings.NewReader(file), CSVOptions{})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, true, report.Committed)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 0, report.Failed)
+	assert.Equal(t, 2, report.Rows[0].Line)
+	assert.Equal(t, RowSkipped, report.Rows[1].Status)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportCSVSameDateDifferentValue(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	account := models.Account{ID: 1, Name: "test", Class: models.Asset, Category: models.Cash}
+	asOf := time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC)
+	file := strings.Join([]string{
+		"account,date,value",
+		"test,2023-05-31,120.00",
+	}, "\n")
+
+	// 100 is already recorded on the date, which does not make 120 a
+	// duplicate.
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM \"accounts\" WHERE household_id = .+ AND name").
+		WithArgs(models.MockHouseholdID, "test").
+		WillReturnRows(models.AccountToSQLRow(account))
+	mock.ExpectQuery("SELECT count(.+) FROM \"account_values\"").
+		WithArgs(models.MockHouseholdID, account.ID, asOf, decimal.NewFromInt(120).Round(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT count(.+) FROM \"accounts\"").
+		WithArgs(models.MockHouseholdID, account.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO \"account_values\"").
+		WithArgs(account.ID, decimal.NewFromInt(120).Round(2), asOf, models.AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	report, err := ImportCSV(db, models.MockHouseholdID, strings.NewReader(file), CSVOptions{})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, true, report.Committed)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 0, report.Skipped)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportCSVCreatesAccounts(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	file := strings.Join([]string{
+		"account,date,value,class,category,tax_bucket,currency",
+		"My 401k,2023-05-31,100.00,asset,retirement,tax-deferred,eur",
+	}, "\n")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM \"accounts\" WHERE household_id = .+ AND name").
+		WithArgs(models.MockHouseholdID, "My 401k").
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectExec("INSERT INTO \"accounts\"").
+		WithArgs(models.MockHouseholdID, "My 401k", models.Asset, models.Retirement, models.TaxDeferred, models.Currency("EUR"), nil, models.AnyTime{}, models.AnyTime{}, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT count(.+) FROM \"account_values\"").
+		WithArgs(models.MockHouseholdID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT count(.+) FROM \"accounts\"").
+		WithArgs(models.MockHouseholdID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO \"account_values\"").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	report, err := ImportCSV(db, models.MockHouseholdID, strings.NewReader(file), CSVOptions{CreateAccounts: true})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, true, report.Committed)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, "created account My 401k", report.Rows[0].Message)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportCSVRollsBackOnFailedRows(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	file := strings.Join([]string{
+		"account,date,value",
+		"test,yesterday,100.00",
+		"missing,2023-05-31,100.00",
+		",2023-05-31,100.00",
+	}, "\n")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM \"accounts\" WHERE household_id = .+ AND name").
+		WithArgs(models.MockHouseholdID, "missing").
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectRollback()
+
+	report, err := ImportCSV(db, models.MockHouseholdID, strings.NewReader(file), CSVOptions{})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, false, report.Committed)
+	assert.Equal(t, 3, report.Failed)
+	for i, row := range report.Rows {
+		assert.Equal(t, i+2, row.Line)
+		assert.Equal(t, RowFailed, row.Status)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportCSVInvalidHeader(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	_, err = ImportCSV(db, models.MockHouseholdID, strings.NewReader("name,value\ntest,1"), CSVOptions{})
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("wanted: %v, got: %v", ErrInvalidFile, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportCSVMalformedRow(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	file := strings.Join([]string{
+		"account,date,value",
+		`"Checking,2024-01-01,100`,
+	}, "\n")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	report, err := ImportCSV(db, models.MockHouseholdID, strings.NewReader(file), CSVOptions{})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, false, report.Committed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 2, report.Rows[0].Line)
+	assert.Equal(t, RowFailed, report.Rows[0].Status)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	columnBase  = "base"
+	columnQuote = "quote"
+	columnRate  = "rate"
+)
+
+// ImportFXRates imports exchange rates from r. The first row must be a header
+// naming the date, base, quote and rate columns, where rate is the price of
+// one unit of the base currency in the quote currency. A rate recorded for the
+// same pair and date is replaced. Rates are shared by every household.
+func ImportFXRates(db *gorm.DB, r io.Reader) (Report, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: reading csv header: %s", ErrInvalidFile, err)
+	}
+	columns, err := parseColumns(header, []string{columnDate, columnBase, columnQuote, columnRate}, nil)
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+
+	report := Report{Rows: []RowResult{}}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				var parseErr *csv.ParseError
+				if errors.As(err, &parseErr) {
+					report.add(RowResult{Line: parseErr.Line, Status: RowFailed, Message: parseErr.Err.Error()})
+					continue
+				}
+				return err
+			}
+			line, _ := reader.FieldPos(0)
+
+			row, err := importFXRateRow(tx, columns, record)
+			if err != nil {
+				return err
+			}
+			row.Line = line
+			report.add(row)
+		}
+
+		if report.Failed > 0 {
+			return errRowsFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errRowsFailed) {
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+
+	report.Committed = true
+	return report, nil
+}
+
+// importFXRateRow imports a single rate. The row's Account holds the currency
+// pair, such as EUR/USD.
+func importFXRateRow(tx *gorm.DB, columns csvColumns, record []string) (RowResult, error) {
+	rate := models.FXRate{
+		Base:  models.Currency(strings.ToUpper(columns.get(record, columnBase))),
+		Quote: models.Currency(strings.ToUpper(columns.get(record, columnQuote))),
+	}
+	row := RowResult{Account: fmt.Sprintf("%s/%s", rate.Base, rate.Quote)}
+	failed := func(message string) (RowResult, error) {
+		row.Status = RowFailed
+		row.Message = message
+		return row, nil
+	}
+
+	var err error
+	rate.Date, err = parseDate(columns.get(record, columnDate))
+	if err != nil {
+		return failed(err.Error())
+	}
+	value := columns.get(record, columnRate)
+	rate.Rate, err = decimal.NewFromString(value)
+	if err != nil {
+		return failed(fmt.Sprintf("invalid rate %q", value))
+	}
+	if err := models.ValidateFXRate(rate); err != nil {
+		return failed(err.Error())
+	}
+
+	if _, err := models.SaveFXRate(tx, rate); err != nil {
+		return row, err
+	}
+	row.Status = RowCreated
+	return row, nil
+}
@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func TestImportFXRates(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	file := strings.Join([]string{
+		"date,base,quote,rate",
+		"2023-01-31,eur,usd,1.0866",
+		"2023-02-28,EUR,USD,1.0576",
+	}, "\n")
+
+	mock.ExpectBegin()
+	for i, rate := range []string{"1.0866", "1.0576"} {
+		mock.ExpectExec("INSERT INTO \"fx_rates\" .* ON CONFLICT \\(\"base\",\"quote\",\"date\"\\) DO UPDATE SET \"rate\"").
+			WithArgs(models.AnyTime{}, models.Currency("EUR"), models.Currency("USD"), decimal.RequireFromString(rate)).
+			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+	}
+	mock.ExpectCommit()
+
+	report, err := ImportFXRates(db, strings.NewReader(file))
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, true, report.Committed)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, "EUR/USD", report.Rows[0].Account)
+	assert.Equal(t, 3, report.Rows[1].Line)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportFXRatesRollsBackOnFailedRows(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	file := strings.Join([]string{
+		"date,base,quote,rate",
+		"yesterday,EUR,USD,1.08",
+		"2023-01-31,EUR,EUR,1",
+		"2023-01-31,EUR,USD,-1",
+		"2023-01-31,EUR,USD,one",
+	}, "\n")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	report, err := ImportFXRates(db, strings.NewReader(file))
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, false, report.Committed)
+	assert.Equal(t, 4, report.Failed)
+
+	_, err = ImportFXRates(db, strings.NewReader("date,from,to,rate\n2023-01-31,EUR,USD,1"))
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("wanted: %v, got: %v", ErrInvalidFile, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportFXRatesMalformedRow(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	file := strings.Join([]string{
+		"date,base,quote,rate",
+		`"2023-01-31,EUR,USD,1.0866`,
+	}, "\n")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	report, err := ImportFXRates(db, strings.NewReader(file))
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, false, report.Committed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 2, report.Rows[0].Line)
+	assert.Equal(t, RowFailed, report.Rows[0].Status)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,293 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Jrc356/financial_dashboard/export"
+	"github.com/Jrc356/financial_dashboard/models"
+	"gorm.io/gorm"
+)
+
+// errNotEmpty rolls back a JSON import when the household already has data.
+var errNotEmpty = errors.New("household already has accounts")
+
+// errNotRestorable rolls back a JSON import whose rows refer to rows missing
+// from the document.
+var errNotRestorable = errors.New("export is inconsistent")
+
+// JSONOptions controls how a JSON export is imported.
+type JSONOptions struct {
+	// SharedData also restores the exchange rates, securities and prices in
+	// the export, which every household shares. Without it they are left as
+	// they are, and the securities positions are in must already exist.
+	SharedData bool
+}
+
+// ImportJSON restores a document written by export.WriteJSON into the given
+// household, which must have no accounts. Timestamps and deleted accounts are
+// restored exactly, but the database assigns new IDs so restored rows never
+// collide with another household's; the references between them are
+// rewritten to match. Exports from earlier versions are accepted.
+func ImportJSON(db *gorm.DB, householdID uint, r io.Reader, options JSONOptions) (Report, error) {
+	var document export.Document
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&document); err != nil {
+		return Report{}, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+	if document.Version < 1 || document.Version > export.Version {
+		return Report{}, fmt.Errorf("%w: unsupported export version %d", ErrInvalidFile, document.Version)
+	}
+
+	report := Report{Rows: []RowResult{}}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		existing, err := models.HouseholdHasAccounts(tx, householdID)
+		if err != nil {
+			return err
+		}
+		if existing {
+			return errNotEmpty
+		}
+
+		// accountIDs maps the IDs in the export to the ones the accounts were
+		// restored with.
+		accountIDs := map[uint]uint{}
+		for _, account := range document.Accounts {
+			exportedID := account.ID
+			row, id, err := importJSONAccount(tx, householdID, account)
+			if err != nil {
+				return err
+			}
+			report.add(row)
+			if row.Status == RowCreated {
+				accountIDs[exportedID] = id
+			}
+		}
+		if report.Failed > 0 {
+			return errRowsFailed
+		}
+
+		for _, mapping := range document.OFXAccountMappings {
+			accountID, ok := accountIDs[mapping.AccountID]
+			if !ok {
+				return fmt.Errorf("%w: OFX account %s is mapped to unknown account %d", errNotRestorable, mapping.OFXAccountID, mapping.AccountID)
+			}
+			mapping.ID = 0
+			mapping.AccountID = accountID
+			mapping.HouseholdID = householdID
+			if err := tx.Create(&mapping).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, flow := range document.CashFlows {
+			accountID, ok := accountIDs[flow.AccountID]
+			if !ok {
+				return fmt.Errorf("%w: cash flow %d is in unknown account %d", errNotRestorable, flow.ID, flow.AccountID)
+			}
+			if err := models.ValidateCashFlow(flow); err != nil {
+				return fmt.Errorf("%w: cash flow %d: %s", errNotRestorable, flow.ID, err)
+			}
+			flow.ID = 0
+			flow.AccountID = accountID
+			if err := tx.Create(&flow).Error; err != nil {
+				return err
+			}
+		}
+
+		securityIDs, err := importJSONSecurities(tx, document, options)
+		if err != nil {
+			return err
+		}
+		if err := importJSONHoldings(tx, document, accountIDs, securityIDs); err != nil {
+			return err
+		}
+
+		if !options.SharedData {
+			return nil
+		}
+		for _, rate := range document.FXRates {
+			if err := models.ValidateFXRate(rate); err != nil {
+				return fmt.Errorf("%w: exchange rate %s/%s: %s", errNotRestorable, rate.Base, rate.Quote, err)
+			}
+			rate.ID = 0
+			if _, err := models.SaveFXRate(tx, rate); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errRowsFailed) {
+		return report, nil
+	}
+	if errors.Is(err, errNotEmpty) || errors.Is(err, errNotRestorable) {
+		return report, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	report.Committed = true
+	return report, nil
+}
+
+// importJSONSecurities matches the securities in the document with those in
+// the database by symbol, creating the ones that are missing and restoring
+// their prices when options allow it. It returns the IDs the securities have
+// in the database, keyed by their IDs in the document.
+func importJSONSecurities(tx *gorm.DB, document export.Document, options JSONOptions) (map[uint]uint, error) {
+	securityIDs := map[uint]uint{}
+	for _, security := range document.Securities {
+		exportedID := security.ID
+		existing, err := models.GetSecurityBySymbol(tx, security.Symbol)
+		if err == nil {
+			securityIDs[exportedID] = existing.ID
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if !options.SharedData {
+			return nil, fmt.Errorf("%w: security %s does not exist, an admin has to add it", errNotRestorable, security.Symbol)
+		}
+		if err := models.ValidateSecurity(security); err != nil {
+			return nil, fmt.Errorf("%w: security %s: %s", errNotRestorable, security.Symbol, err)
+		}
+		security.ID = 0
+		created, err := models.CreateSecurity(tx, security)
+		if err != nil {
+			return nil, err
+		}
+		securityIDs[exportedID] = created.ID
+	}
+
+	if !options.SharedData {
+		return securityIDs, nil
+	}
+	for _, price := range document.Prices {
+		securityID, ok := securityIDs[price.SecurityID]
+		if !ok {
+			return nil, fmt.Errorf("%w: price %d is of unknown security %d", errNotRestorable, price.ID, price.SecurityID)
+		}
+		if err := models.ValidatePrice(price); err != nil {
+			return nil, fmt.Errorf("%w: price %d: %s", errNotRestorable, price.ID, err)
+		}
+		price.ID = 0
+		price.SecurityID = securityID
+		if _, err := models.SavePrice(tx, price); err != nil {
+			return nil, err
+		}
+	}
+	return securityIDs, nil
+}
+
+// importJSONHoldings restores the positions, lots and sales of the document
+// under new IDs. The positions are restored as they were exported rather than
+// traded again from the lots and sales.
+func importJSONHoldings(tx *gorm.DB, document export.Document, accountIDs, securityIDs map[uint]uint) error {
+	for _, position := range document.Positions {
+		accountID, ok := accountIDs[position.AccountID]
+		if !ok {
+			return fmt.Errorf("%w: position %d is in unknown account %d", errNotRestorable, position.ID, position.AccountID)
+		}
+		securityID, ok := securityIDs[position.SecurityID]
+		if !ok {
+			return fmt.Errorf("%w: position %d is in unknown security %d", errNotRestorable, position.ID, position.SecurityID)
+		}
+		position.ID = 0
+		position.AccountID = accountID
+		position.SecurityID = securityID
+		position.Security = nil
+		if err := tx.Create(&position).Error; err != nil {
+			return err
+		}
+	}
+
+	lotIDs := map[uint]uint{}
+	for _, lot := range document.Lots {
+		exportedID := lot.ID
+		accountID, ok := accountIDs[lot.AccountID]
+		if !ok {
+			return fmt.Errorf("%w: lot %d is in unknown account %d", errNotRestorable, lot.ID, lot.AccountID)
+		}
+		securityID, ok := securityIDs[lot.SecurityID]
+		if !ok {
+			return fmt.Errorf("%w: lot %d is in unknown security %d", errNotRestorable, lot.ID, lot.SecurityID)
+		}
+		lot.ID = 0
+		lot.AccountID = accountID
+		lot.SecurityID = securityID
+		lot.Security = nil
+		if err := tx.Create(&lot).Error; err != nil {
+			return err
+		}
+		lotIDs[exportedID] = lot.ID
+	}
+
+	for _, sale := range document.Sales {
+		accountID, ok := accountIDs[sale.AccountID]
+		if !ok {
+			return fmt.Errorf("%w: sale %d is in unknown account %d", errNotRestorable, sale.ID, sale.AccountID)
+		}
+		securityID, ok := securityIDs[sale.SecurityID]
+		if !ok {
+			return fmt.Errorf("%w: sale %d is in unknown security %d", errNotRestorable, sale.ID, sale.SecurityID)
+		}
+		for i, match := range sale.Matches {
+			lotID, ok := lotIDs[match.LotID]
+			if !ok {
+				return fmt.Errorf("%w: sale %d is matched with unknown lot %d", errNotRestorable, sale.ID, match.LotID)
+			}
+			sale.Matches[i].ID = 0
+			sale.Matches[i].SaleID = 0
+			sale.Matches[i].LotID = lotID
+		}
+		sale.ID = 0
+		sale.AccountID = accountID
+		sale.SecurityID = securityID
+		if err := tx.Create(&sale).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importJSONAccount restores the account and its values under new IDs and
+// returns the ID the account was given.
+func importJSONAccount(tx *gorm.DB, householdID uint, account models.Account) (RowResult, uint, error) {
+	row := RowResult{Account: account.Name}
+	if err := models.ValidateAccount(account); err != nil {
+		row.Status = RowFailed
+		row.Message = err.Error()
+		return row, 0, nil
+	}
+
+	// Owners refer to users, which are not exported, so accounts are restored
+	// without them and count toward the household as a whole.
+	values := account.Values
+	account.ID = 0
+	account.Values = nil
+	account.Owners = nil
+	account.HouseholdID = householdID
+	if account.Currency == "" {
+		account.Currency = models.DefaultCurrency
+	}
+	if err := tx.Create(&account).Error; err != nil {
+		return row, 0, err
+	}
+
+	for _, value := range values {
+		value.ID = 0
+		value.AccountID = account.ID
+		if err := tx.Create(&value).Error; err != nil {
+			return row, 0, err
+		}
+	}
+
+	row.Status = RowCreated
+	row.Message = fmt.Sprintf("%d values", len(values))
+	return row, account.ID, nil
+}
@@ -0,0 +1,407 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/export"
+	"github.com/Jrc356/financial_dashboard/migrations"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestImportJSONRoundTrip exports accounts from one database and checks that
+// importing the export writes back the same rows, under the IDs the database
+// gives them.
+func TestImportJSONRoundTrip(t *testing.T) {
+	createdAt := time.Date(2023, time.January, 2, 3, 4, 5, 123456000, time.UTC)
+	deletedAt := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	asOf := time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+	value := decimal.RequireFromString("1520.25")
+
+	source, sourceMock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := source.DB()
+	defer d.Close()
+
+	sourceMock.ExpectQuery("SELECT \\* FROM \"accounts\" WHERE household_id").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows(models.AccountColumns).
+			AddRow(4, "Checking", "asset", "cash", "", createdAt, createdAt, nil).
+			AddRow(9, "Old Loan", "liability", "loan", "", createdAt, deletedAt, deletedAt))
+	sourceMock.ExpectQuery("SELECT \\* FROM \"account_values\"").
+		WithArgs(4, 9).
+		WillReturnRows(sqlmock.NewRows(models.AccountValuesColumns).
+			AddRow(12, 4, value, asOf, createdAt))
+	sourceMock.ExpectQuery("SELECT \\* FROM \"ofx_account_mappings\" WHERE household_id").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ofx_account_id", "account_id", "created_at", "updated_at"}).
+			AddRow(3, "0001234", 4, createdAt, createdAt))
+	sourceMock.ExpectQuery("SELECT \\* FROM \"cash_flows\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sourceMock.ExpectQuery("SELECT \\* FROM \"fx_rates\"").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sourceMock.ExpectQuery("SELECT \\* FROM \"positions\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sourceMock.ExpectQuery("SELECT \\* FROM \"lots\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	sourceMock.ExpectQuery("SELECT \\* FROM \"sales\"").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	var buf bytes.Buffer
+	if err := export.WriteJSON(source, models.MockHouseholdID, &buf); err != nil {
+		t.Errorf(err.Error())
+	}
+
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ = db.DB()
+	defer d.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT count(.+) FROM \"accounts\" WHERE household_id").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO \"accounts\"").
+		WithArgs(models.MockHouseholdID, "Checking", "asset", "cash", "", models.DefaultCurrency, nil, createdAt, createdAt, nil).
+		WillReturnResult(sqlmock.NewResult(21, 1))
+	mock.ExpectExec("INSERT INTO \"account_values\"").
+		WithArgs(21, value, asOf, createdAt).
+		WillReturnResult(sqlmock.NewResult(40, 1))
+	mock.ExpectExec("INSERT INTO \"accounts\"").
+		WithArgs(models.MockHouseholdID, "Old Loan", "liability", "loan", "", models.DefaultCurrency, nil, createdAt, deletedAt, deletedAt).
+		WillReturnResult(sqlmock.NewResult(22, 1))
+	mock.ExpectExec("INSERT INTO \"ofx_account_mappings\"").
+		WithArgs(models.MockHouseholdID, "0001234", 21, createdAt, createdAt).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectCommit()
+
+	report, err := ImportJSON(db, models.MockHouseholdID, &buf, JSONOptions{})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, true, report.Committed)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, "1 values", report.Rows[0].Message)
+
+	if err := sourceMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled export expectations: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// openSQLite returns a migrated SQLite database in a temporary directory, with
+// a household to import into.
+func openSQLite(t *testing.T) (*gorm.DB, uint) {
+	db, err := store.Open(
+		store.Config{Driver: store.SQLite, DSN: filepath.Join(t.TempDir(), "test.db")},
+		&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		d, _ := db.DB()
+		d.Close()
+	})
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	household, err := models.CreateHousehold(db, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, household.ID
+}
+
+// exportJSON returns the export of the household.
+func exportJSON(t *testing.T, source *gorm.DB, householdID uint) []byte {
+	var buf bytes.Buffer
+	if err := export.WriteJSON(source, householdID, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// roundTrip exports the household and imports the export into a new database.
+func roundTrip(t *testing.T, source *gorm.DB, householdID uint, options JSONOptions) (*gorm.DB, uint) {
+	data := exportJSON(t, source, householdID)
+	target, targetHousehold := openSQLite(t)
+	report, err := ImportJSON(target, targetHousehold, bytes.NewReader(data), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, true, report.Committed)
+	return target, targetHousehold
+}
+
+// TestImportJSONRoundTripSQLite checks that everything an export holds is
+// restored by importing it into another database.
+func TestImportJSONRoundTripSQLite(t *testing.T) {
+	asOf := time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+	source, household := openSQLite(t)
+
+	savings, err := models.CreateAccount(source, household, models.Account{Name: "Euro Savings", Class: models.Asset, Category: models.Cash, Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.CreateAccountValue(source, household, models.AccountValue{AccountID: savings.ID, Value: decimal.NewFromInt(1000), AsOf: asOf}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.CreateCashFlow(source, household, models.CashFlow{AccountID: savings.ID, Type: models.Contribution, Amount: decimal.NewFromInt(200), Date: asOf, Memo: "transfer from checking"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.SaveFXRate(source, models.FXRate{Date: asOf, Base: "EUR", Quote: "USD", Rate: decimal.RequireFromString("1.08")}); err != nil {
+		t.Fatal(err)
+	}
+	// A security created first keeps the source's IDs from lining up with the
+	// target's.
+	if _, err := models.CreateSecurity(source, models.Security{Symbol: "BND"}); err != nil {
+		t.Fatal(err)
+	}
+	vti, err := models.CreateSecurity(source, models.Security{Symbol: "VTI", Name: "Vanguard Total Stock Market"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.SavePrice(source, models.Price{SecurityID: vti.ID, Date: asOf, Price: decimal.NewFromInt(210)}); err != nil {
+		t.Fatal(err)
+	}
+	retirement, err := models.CreateAccount(source, household, models.Account{Name: "Roth IRA", Class: models.Asset, Category: models.Retirement, TaxBucket: models.Roth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.CreatePosition(source, household, models.Position{AccountID: retirement.ID, SecurityID: vti.ID, Quantity: decimal.NewFromInt(10), AsOf: asOf}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.CreateLot(source, household, models.Lot{AccountID: retirement.ID, SecurityID: vti.ID, Quantity: decimal.NewFromInt(5), Cost: decimal.NewFromInt(1000), AcquiredAt: asOf.AddDate(0, 0, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.CreateSale(source, household, models.Sale{AccountID: retirement.ID, SecurityID: vti.ID, Quantity: decimal.NewFromInt(2), Proceeds: decimal.NewFromInt(450), SoldAt: asOf.AddDate(0, 0, 2), Method: models.FIFO}); err != nil {
+		t.Fatal(err)
+	}
+
+	target, targetHousehold := roundTrip(t, source, household, JSONOptions{SharedData: true})
+
+	accounts, err := models.GetAllAccountsWithValuesIncludingDeleted(target, targetHousehold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(accounts))
+	assert.Equal(t, models.Currency("EUR"), accounts[0].Currency)
+	assert.Equal(t, 1, len(accounts[0].Values))
+	assert.Equal(t, "1000", accounts[0].Values[0].Value.String())
+
+	flows, err := models.GetAllCashFlows(target, targetHousehold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(flows))
+	assert.Equal(t, accounts[0].ID, flows[0].AccountID)
+	assert.Equal(t, "200", flows[0].Amount.String())
+	assert.Equal(t, "transfer from checking", flows[0].Memo)
+
+	rates, err := models.GetAllFXRates(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(rates))
+	assert.Equal(t, "1.08", rates[0].Rate.String())
+
+	targetVTI, err := models.GetSecurityBySymbol(target, "VTI")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Vanguard Total Stock Market", targetVTI.Name)
+	prices, err := models.GetPrices(target, []uint{targetVTI.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(prices))
+	assert.Equal(t, "210", prices[0].Price.String())
+	positions, err := models.GetAllPositions(target, targetHousehold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The position recorded directly and those left by the lot and the sale.
+	assert.Equal(t, 3, len(positions))
+	assert.Equal(t, accounts[1].ID, positions[0].AccountID)
+	assert.Equal(t, targetVTI.ID, positions[0].SecurityID)
+	assert.Equal(t, "10", positions[0].Quantity.String())
+	assert.Equal(t, "13", positions[2].Quantity.String())
+
+	lots, err := models.GetAllLots(target, targetHousehold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(lots))
+	assert.Equal(t, accounts[1].ID, lots[0].AccountID)
+	assert.Equal(t, targetVTI.ID, lots[0].SecurityID)
+	sales, err := models.GetAllSales(target, targetHousehold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(sales))
+	assert.Equal(t, targetVTI.ID, sales[0].SecurityID)
+	assert.Equal(t, 1, len(sales[0].Matches))
+	assert.Equal(t, lots[0].ID, sales[0].Matches[0].LotID)
+	assert.Equal(t, "400", sales[0].Matches[0].Cost.String())
+
+	// Shared data is left alone unless asked for, so the securities positions
+	// are in have to exist already.
+	data := exportJSON(t, source, household)
+	target, targetHousehold = openSQLite(t)
+	_, err = ImportJSON(target, targetHousehold, bytes.NewReader(data), JSONOptions{})
+	assert.Equal(t, true, errors.Is(err, ErrInvalidFile))
+
+	targetVTI, err = models.CreateSecurity(target, models.Security{Symbol: "VTI"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := ImportJSON(target, targetHousehold, bytes.NewReader(data), JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, true, report.Committed)
+	rates, err = models.GetAllFXRates(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(rates))
+	prices, err = models.GetPrices(target, []uint{targetVTI.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(prices))
+	positions, err = models.GetAllPositions(target, targetHousehold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, len(positions))
+	assert.Equal(t, targetVTI.ID, positions[0].SecurityID)
+}
+
+func TestImportJSONNonEmptyDatabase(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT count(.+) FROM \"accounts\" WHERE household_id").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	_, err = ImportJSON(db, models.MockHouseholdID, strings.NewReader(`{"version":1,"accounts":[],"ofx_account_mappings":[]}`), JSONOptions{})
+	assert.Equal(t, true, errors.Is(err, ErrInvalidFile))
+	assert.Equal(t, false, strings.Contains(err.Error(), "2"))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportJSONUnknownMappedAccount(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT count(.+) FROM \"accounts\" WHERE household_id").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectRollback()
+
+	_, err = ImportJSON(db, models.MockHouseholdID, strings.NewReader(`{"version":1,"accounts":[],"ofx_account_mappings":[{"ofx_account_id":"0001234","account_id":4}]}`), JSONOptions{})
+	assert.Equal(t, true, errors.Is(err, ErrInvalidFile))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportJSONInvalidAccount(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT count(.+) FROM \"accounts\" WHERE household_id").
+		WithArgs(models.MockHouseholdID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectRollback()
+
+	report, err := ImportJSON(db, models.MockHouseholdID, strings.NewReader(`{"version":1,"accounts":[{"id":1,"name":"Checking","class":"equity","category":"cash"}]}`), JSONOptions{})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, false, report.Committed)
+	assert.Equal(t, 1, report.Failed)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportJSONInvalidFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "should reject malformed JSON",
+			input: `{"version":1,"accounts":[`,
+		},
+		{
+			name:  "should reject an unsupported version",
+			input: `{"version":99,"accounts":[]}`,
+		},
+		{
+			name:  "should reject unknown fields",
+			input: `{"version":1,"accounts":[],"budgets":[]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, _, err := models.CreateMockDatabase()
+			if err != nil {
+				t.Errorf(err.Error())
+			}
+			d, _ := db.DB()
+			defer d.Close()
+
+			_, err = ImportJSON(db, models.MockHouseholdID, strings.NewReader(test.input), JSONOptions{})
+			assert.Equal(t, true, errors.Is(err, ErrInvalidFile))
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ofxNode is an element of an OFX document. OFX 1.x files are SGML where leaf
+// elements are usually not closed, while OFX 2.x files are XML; both are read
+// into the same tree.
+type ofxNode struct {
+	name     string
+	value    string
+	children []*ofxNode
+}
+
+// find returns the first descendant with the given name.
+func (n *ofxNode) find(name string) *ofxNode {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+		if found := child.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns every descendant with the given name.
+func (n *ofxNode) findAll(name string) []*ofxNode {
+	found := []*ofxNode{}
+	for _, child := range n.children {
+		if child.name == name {
+			found = append(found, child)
+		}
+		found = append(found, child.findAll(name)...)
+	}
+	return found
+}
+
+// text returns the value of the first descendant with the given name.
+func (n *ofxNode) text(name string) string {
+	if found := n.find(name); found != nil {
+		return found.value
+	}
+	return ""
+}
+
+var ofxTag = regexp.MustCompile(`<(/?)([A-Za-z0-9_.]+)[^>]*>`)
+
+func parseOFXTree(data string) (*ofxNode, error) {
+	start := strings.Index(strings.ToUpper(data), "<OFX>")
+	if start < 0 {
+		return nil, fmt.Errorf("no <OFX> element found")
+	}
+	data = data[start:]
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	matches := ofxTag.FindAllStringSubmatchIndex(data, -1)
+	for i, match := range matches {
+		closing := data[match[2]:match[3]] == "/"
+		name := strings.ToUpper(data[match[4]:match[5]])
+		parent := stack[len(stack)-1]
+
+		if closing {
+			// Close the matching element, implicitly closing any unclosed
+			// elements inside it. Closing tags for leaf elements that were
+			// never pushed are ignored.
+			for j := len(stack) - 1; j > 0; j-- {
+				if stack[j].name == name {
+					stack = stack[:j]
+					break
+				}
+			}
+			continue
+		}
+
+		end := len(data)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		node := &ofxNode{name: name, value: strings.TrimSpace(data[match[1]:end])}
+		parent.children = append(parent.children, node)
+		if node.value == "" {
+			stack = append(stack, node)
+		}
+	}
+
+	if len(root.children) == 0 {
+		return nil, fmt.Errorf("empty <OFX> element")
+	}
+	return root.children[0], nil
+}
+
+var ofxDate = regexp.MustCompile(`^(\d{8})(\d{6})?(?:\.\d+)?(?:\[([+-]?\d+(?:\.\d+)?)(?::[^\]]*)?\])?$`)
+
+// parseOFXDate parses OFX dates of the form YYYYMMDD[HHMMSS[.XXX]][offset:TZ].
+// Dates without an offset are UTC as the OFX specification requires.
+func parseOFXDate(s string) (time.Time, error) {
+	match := ofxDate.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return time.Time{}, fmt.Errorf("invalid OFX date %q", s)
+	}
+
+	clock := match[2]
+	if clock == "" {
+		clock = "000000"
+	}
+	location := time.UTC
+	if match[3] != "" {
+		hours, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid OFX date %q", s)
+		}
+		location = time.FixedZone("", int(hours*3600))
+	}
+	return time.ParseInLocation("20060102150405", match[1]+clock, location)
+}
+
+// OFXStatement is a balance read from an OFX statement.
+type OFXStatement struct {
+	// AccountID is the ACCTID the financial institution uses for the account.
+	AccountID string
+	// Source is the element the value was read from: LEDGERBAL, AVAILBAL or
+	// INVPOSLIST for investment positions.
+	Source string
+	Value  decimal.Decimal
+	AsOf   time.Time
+}
+
+func parseOFXBalance(accountID string, statement *ofxNode) (OFXStatement, error) {
+	for _, source := range []string{"LEDGERBAL", "AVAILBAL"} {
+		balance := statement.find(source)
+		if balance == nil {
+			continue
+		}
+
+		value, err := decimal.NewFromString(balance.text("BALAMT"))
+		if err != nil {
+			return OFXStatement{}, fmt.Errorf("invalid %s amount for account %s", source, accountID)
+		}
+		asOf, err := parseOFXDate(balance.text("DTASOF"))
+		if err != nil {
+			return OFXStatement{}, err
+		}
+		return OFXStatement{AccountID: accountID, Source: source, Value: value, AsOf: asOf}, nil
+	}
+	return OFXStatement{}, fmt.Errorf("no LEDGERBAL or AVAILBAL for account %s", accountID)
+}
+
+// parseOFXInvestment totals the market value of every position plus the
+// available cash in an investment statement.
+func parseOFXInvestment(accountID string, statement *ofxNode) (OFXStatement, error) {
+	asOf, err := parseOFXDate(statement.text("DTASOF"))
+	if err != nil {
+		return OFXStatement{}, err
+	}
+
+	total := decimal.Zero
+	for _, position := range statement.findAll("INVPOS") {
+		value, err := decimal.NewFromString(position.text("MKTVAL"))
+		if err != nil {
+			return OFXStatement{}, fmt.Errorf("invalid position market value for account %s", accountID)
+		}
+		total = total.Add(value)
+	}
+	if cash := statement.text("AVAILCASH"); cash != "" {
+		value, err := decimal.NewFromString(cash)
+		if err != nil {
+			return OFXStatement{}, fmt.Errorf("invalid available cash for account %s", accountID)
+		}
+		total = total.Add(value)
+	}
+	return OFXStatement{AccountID: accountID, Source: "INVPOSLIST", Value: total, AsOf: asOf}, nil
+}
+
+// ParseOFX reads the balances of every bank, credit card and investment
+// statement in an OFX or QFX file.
+func ParseOFX(r io.Reader) ([]OFXStatement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseOFXTree(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+
+	statements := []OFXStatement{}
+	for _, kind := range []struct {
+		statement   string
+		accountFrom string
+		parse       func(string, *ofxNode) (OFXStatement, error)
+	}{
+		{statement: "STMTRS", accountFrom: "BANKACCTFROM", parse: parseOFXBalance},
+		{statement: "CCSTMTRS", accountFrom: "CCACCTFROM", parse: parseOFXBalance},
+		{statement: "INVSTMTRS", accountFrom: "INVACCTFROM", parse: parseOFXInvestment},
+	} {
+		for _, node := range root.findAll(kind.statement) {
+			accountID := ""
+			if from := node.find(kind.accountFrom); from != nil {
+				accountID = from.text("ACCTID")
+			}
+			if accountID == "" {
+				return nil, fmt.Errorf("%w: %s has no %s ACCTID", ErrInvalidFile, kind.statement, kind.accountFrom)
+			}
+
+			statement, err := kind.parse(accountID, node)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+			}
+			statements = append(statements, statement)
+		}
+	}
+
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("%w: no statements found", ErrInvalidFile)
+	}
+	return statements, nil
+}
+
+// ImportOFX records the balance of every statement in an OFX or QFX file as
+// a value of the account it is mapped to. Statements for OFX accounts without
+// a mapping fail the import. Liability balances, which institutions report as
+// negative amounts owed, are stored as positive values like every other
+// liability. Only the household's mappings and accounts are used.
+func ImportOFX(db *gorm.DB, householdID uint, r io.Reader) (Report, error) {
+	statements, err := ParseOFX(r)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Rows: []RowResult{}}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			row, err := importOFXStatement(tx, householdID, statement)
+			if err != nil {
+				return err
+			}
+			report.add(row)
+		}
+
+		if report.Failed > 0 {
+			return errRowsFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errRowsFailed) {
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+
+	report.Committed = true
+	return report, nil
+}
+
+func importOFXStatement(tx *gorm.DB, householdID uint, statement OFXStatement) (RowResult, error) {
+	row := RowResult{Account: statement.AccountID}
+
+	mapping, err := models.GetOFXAccountMapping(tx, householdID, statement.AccountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		row.Status = RowFailed
+		row.Message = fmt.Sprintf("no account mapped to OFX account %s", statement.AccountID)
+		return row, nil
+	}
+	if err != nil {
+		return row, err
+	}
+
+	account, err := models.GetAccount(tx, householdID, mapping.AccountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		row.Status = RowFailed
+		row.Message = fmt.Sprintf("account %d mapped to OFX account %s does not exist", mapping.AccountID, statement.AccountID)
+		return row, nil
+	}
+	if err != nil {
+		return row, err
+	}
+	row.Account = account.Name
+
+	value := statement.Value
+	if account.Class == models.Liability {
+		value = value.Abs()
+	}
+	if value.IsZero() {
+		row.Status = RowSkipped
+		row.Message = fmt.Sprintf("%s balance is zero", statement.Source)
+		return row, nil
+	}
+
+	av := models.AccountValue{AccountID: account.ID, Value: value, AsOf: statement.AsOf}
+	exists, err := models.AccountValueExists(tx, householdID, av)
+	if err != nil {
+		return row, err
+	}
+	if exists {
+		row.Status = RowSkipped
+		row.Message = "value already recorded"
+		return row, nil
+	}
+
+	if _, err := models.CreateAccountValue(tx, householdID, av); err != nil {
+		return row, err
+	}
+	row.Status = RowCreated
+	row.Message = fmt.Sprintf("%s from OFX account %s", statement.Source, statement.AccountID)
+	return row, nil
+}
@@ -0,0 +1,309 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const sgmlBankStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<SIGNONMSGSRSV1>
+<SONRS>
+<STATUS><CODE>0<SEVERITY>INFO</STATUS>
+<DTSERVER>20230601120000
+</SONRS>
+</SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<TRNUID>1
+<STMTRS>
+<CURDEF>USD
+<BANKACCTFROM>
+<BANKID>123456789
+<ACCTID>0001234
+<ACCTTYPE>SAVINGS
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20230501
+<DTEND>20230531
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20230515
+<TRNAMT>250.00
+<NAME>Transfer
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>15234.56
+<DTASOF>20230531120000.000[-5:EST]
+</LEDGERBAL>
+<AVAILBAL>
+<BALAMT>15000.00
+<DTASOF>20230531
+</AVAILBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlCreditCardStatement = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+	<CREDITCARDMSGSRSV1>
+		<CCSTMTTRNRS>
+			<TRNUID>1</TRNUID>
+			<CCSTMTRS>
+				<CURDEF>USD</CURDEF>
+				<CCACCTFROM>
+					<ACCTID>4111111111111111</ACCTID>
+				</CCACCTFROM>
+				<AVAILBAL>
+					<BALAMT>-1820.15</BALAMT>
+					<DTASOF>20230530</DTASOF>
+				</AVAILBAL>
+			</CCSTMTRS>
+		</CCSTMTTRNRS>
+	</CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+const sgmlInvestmentStatement = `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX>
+<INVSTMTMSGSRSV1>
+<INVSTMTTRNRS>
+<TRNUID>1
+<INVSTMTRS>
+<DTASOF>20230531
+<CURDEF>USD
+<INVACCTFROM>
+<BROKERID>broker.example.com
+<ACCTID>X-401K
+</INVACCTFROM>
+<INVPOSLIST>
+<POSMF>
+<INVPOS>
+<SECID><UNIQUEID>123456789<UNIQUEIDTYPE>CUSIP</SECID>
+<HELDINACCT>CASH
+<POSTYPE>LONG
+<UNITS>100
+<UNITPRICE>150.25
+<MKTVAL>15025.00
+<DTPRICEASOF>20230531
+</INVPOS>
+</POSMF>
+<POSSTOCK>
+<INVPOS>
+<SECID><UNIQUEID>987654321<UNIQUEIDTYPE>CUSIP</SECID>
+<HELDINACCT>CASH
+<POSTYPE>LONG
+<UNITS>10
+<UNITPRICE>42.00
+<MKTVAL>420.00
+<DTPRICEASOF>20230531
+</INVPOS>
+</POSSTOCK>
+</INVPOSLIST>
+<INVBAL>
+<AVAILCASH>54.75
+<MARGINBALANCE>0
+<SHORTBALANCE>0
+</INVBAL>
+</INVSTMTRS>
+</INVSTMTTRNRS>
+</INVSTMTMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFXDate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{input: "20230531", want: time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC)},
+		{input: "20230531120000", want: time.Date(2023, time.May, 31, 12, 0, 0, 0, time.UTC)},
+		{input: "20230531120000.000[-5:EST]", want: time.Date(2023, time.May, 31, 17, 0, 0, 0, time.UTC)},
+		{input: "20230531120000[+5.5:IST]", want: time.Date(2023, time.May, 31, 6, 30, 0, 0, time.UTC)},
+		{input: "2023-05-31", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			date, err := parseOFXDate(test.input)
+			assert.Equal(t, test.wantErr, err != nil)
+			if !test.wantErr && !test.want.Equal(date) {
+				t.Errorf("wanted: %v, got: %v", test.want, date)
+			}
+		})
+	}
+}
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    []OFXStatement
+		wantErr bool
+	}{
+		{
+			name: "should read the ledger balance from an SGML bank statement",
+			file: sgmlBankStatement,
+			want: []OFXStatement{
+				{
+					AccountID: "0001234",
+					Source:    "LEDGERBAL",
+					Value:     decimal.NewFromFloat(15234.56),
+					AsOf:      time.Date(2023, time.May, 31, 17, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "should fall back to the available balance in an XML credit card statement",
+			file: xmlCreditCardStatement,
+			want: []OFXStatement{
+				{
+					AccountID: "4111111111111111",
+					Source:    "AVAILBAL",
+					Value:     decimal.NewFromFloat(-1820.15),
+					AsOf:      time.Date(2023, time.May, 30, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "should total investment positions and cash",
+			file: sgmlInvestmentStatement,
+			want: []OFXStatement{
+				{
+					AccountID: "X-401K",
+					Source:    "INVPOSLIST",
+					Value:     decimal.NewFromFloat(15499.75),
+					AsOf:      time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name:    "should reject a file that is not OFX",
+			file:    "account,date,value\n",
+			wantErr: true,
+		},
+		{
+			name:    "should reject a file without statements",
+			file:    "<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, err := ParseOFX(strings.NewReader(test.file))
+			if test.wantErr {
+				if !errors.Is(err, ErrInvalidFile) {
+					t.Errorf("wanted: %v, got: %v", ErrInvalidFile, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf(err.Error())
+			}
+
+			assert.Equal(t, len(test.want), len(statements))
+			for i := range test.want {
+				assert.Equal(t, test.want[i].AccountID, statements[i].AccountID)
+				assert.Equal(t, test.want[i].Source, statements[i].Source)
+				if !test.want[i].Value.Equal(statements[i].Value) {
+					t.Errorf("wanted: %v, got: %v", test.want[i].Value, statements[i].Value)
+				}
+				if !test.want[i].AsOf.Equal(statements[i].AsOf) {
+					t.Errorf("wanted: %v, got: %v", test.want[i].AsOf, statements[i].AsOf)
+				}
+			}
+		})
+	}
+}
+
+func TestImportOFX(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	account := models.Account{ID: 7, Name: "Credit Card", Class: models.Liability, Category: models.CreditCard}
+	asOf := time.Date(2023, time.May, 30, 0, 0, 0, 0, time.UTC)
+	value := decimal.NewFromFloat(1820.15)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM \"ofx_account_mappings\" WHERE household_id = .+ AND ofx_account_id").
+		WithArgs(models.MockHouseholdID, "4111111111111111").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ofx_account_id", "account_id"}).AddRow(1, "4111111111111111", account.ID))
+	mock.ExpectQuery("SELECT .* FROM \"accounts\" WHERE household_id = .+ AND \"accounts\".\"id\"").
+		WithArgs(models.MockHouseholdID, account.ID).
+		WillReturnRows(models.AccountToSQLRow(account))
+	mock.ExpectQuery("SELECT count(.+) FROM \"account_values\"").
+		WithArgs(models.MockHouseholdID, account.ID, asOf, value).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT count(.+) FROM \"accounts\"").
+		WithArgs(models.MockHouseholdID, account.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO \"account_values\"").
+		WithArgs(account.ID, value, asOf, models.AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	report, err := ImportOFX(db, models.MockHouseholdID, strings.NewReader(xmlCreditCardStatement))
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, true, report.Committed)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, account.Name, report.Rows[0].Account)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportOFXUnmappedAccount(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM \"ofx_account_mappings\" WHERE household_id = .+ AND ofx_account_id").
+		WithArgs(models.MockHouseholdID, "0001234").
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectRollback()
+
+	report, err := ImportOFX(db, models.MockHouseholdID, strings.NewReader(sgmlBankStatement))
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, false, report.Committed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, "0001234", report.Rows[0].Account)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	columnSymbol = "symbol"
+	columnPrice  = "price"
+	columnName   = "name"
+)
+
+// ImportPrices imports security prices from r. The first row must be a header
+// naming the symbol, date and price columns, and optionally the name and
+// currency columns used to create securities that do not exist yet. A price
+// recorded for the same security and date is replaced. Securities and prices
+// are shared by every household.
+func ImportPrices(db *gorm.DB, r io.Reader) (Report, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: reading csv header: %s", ErrInvalidFile, err)
+	}
+	columns, err := parseColumns(header,
+		[]string{columnSymbol, columnDate, columnPrice},
+		[]string{columnName, columnCurrency},
+	)
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+
+	report := Report{Rows: []RowResult{}}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		securities := map[string]models.Security{}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				var parseErr *csv.ParseError
+				if errors.As(err, &parseErr) {
+					report.add(RowResult{Line: parseErr.Line, Status: RowFailed, Message: parseErr.Err.Error()})
+					continue
+				}
+				return err
+			}
+			line, _ := reader.FieldPos(0)
+
+			row, err := importPriceRow(tx, columns, record, securities)
+			if err != nil {
+				return err
+			}
+			row.Line = line
+			report.add(row)
+		}
+
+		if report.Failed > 0 {
+			return errRowsFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errRowsFailed) {
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+
+	report.Committed = true
+	return report, nil
+}
+
+// importPriceRow imports a single price. The row's Account holds the symbol of
+// the security.
+func importPriceRow(tx *gorm.DB, columns csvColumns, record []string, securities map[string]models.Security) (RowResult, error) {
+	row := RowResult{Account: columns.get(record, columnSymbol)}
+	failed := func(message string) (RowResult, error) {
+		row.Status = RowFailed
+		row.Message = message
+		return row, nil
+	}
+
+	symbol, err := models.ParseSymbol(row.Account)
+	if err != nil {
+		return failed(err.Error())
+	}
+	row.Account = symbol
+	date, err := parseDate(columns.get(record, columnDate))
+	if err != nil {
+		return failed(err.Error())
+	}
+	value := columns.get(record, columnPrice)
+	price, err := decimal.NewFromString(value)
+	if err != nil {
+		return failed(fmt.Sprintf("invalid price %q", value))
+	}
+
+	security, ok := securities[symbol]
+	if !ok {
+		security, err = models.GetSecurityBySymbol(tx, symbol)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			security = models.Security{
+				Symbol:   symbol,
+				Name:     columns.get(record, columnName),
+				Currency: models.Currency(strings.ToUpper(columns.get(record, columnCurrency))),
+			}
+			if err := models.ValidateSecurity(security); err != nil {
+				return failed(fmt.Sprintf("cannot create security %s: %s", symbol, err))
+			}
+			security, err = models.CreateSecurity(tx, security)
+			if err != nil {
+				return row, err
+			}
+			row.Message = fmt.Sprintf("created security %s", symbol)
+		} else if err != nil {
+			return row, err
+		}
+		securities[symbol] = security
+	}
+
+	p := models.Price{SecurityID: security.ID, Date: date, Price: price}
+	if err := models.ValidatePrice(p); err != nil {
+		return failed(err.Error())
+	}
+	if _, err := models.SavePrice(tx, p); err != nil {
+		return row, err
+	}
+	row.Status = RowCreated
+	return row, nil
+}
@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func TestImportPrices(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	file := strings.Join([]string{
+		"symbol,date,price,currency",
+		"vti,2023-01-31,204.42,USD",
+		"VTI,2023-02-28,199.07,USD",
+		"VXUS,2023-01-31,55.13,",
+	}, "\n")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM \"securities\" WHERE symbol = ").
+		WithArgs("VTI").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "symbol", "name", "currency"}).AddRow(1, "VTI", "Vanguard Total Stock Market", "USD"))
+	for i, price := range []string{"204.42", "199.07"} {
+		mock.ExpectExec("INSERT INTO \"prices\" .* ON CONFLICT \\(\"security_id\",\"date\"\\) DO UPDATE SET \"price\"").
+			WithArgs(1, models.AnyTime{}, decimal.RequireFromString(price)).
+			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+	}
+	mock.ExpectQuery("SELECT .* FROM \"securities\" WHERE symbol = ").
+		WithArgs("VXUS").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT count(.+) FROM \"securities\"").
+		WithArgs("VXUS").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO \"securities\"").
+		WithArgs("VXUS", "", models.DefaultCurrency).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO \"prices\"").
+		WithArgs(2, models.AnyTime{}, decimal.RequireFromString("55.13")).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+
+	report, err := ImportPrices(db, strings.NewReader(file))
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, true, report.Committed)
+	assert.Equal(t, 3, report.Created)
+	assert.Equal(t, "VTI", report.Rows[0].Account)
+	assert.Equal(t, "created security VXUS", report.Rows[2].Message)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportPricesRollsBackOnFailedRows(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	file := strings.Join([]string{
+		"symbol,date,price",
+		"VTI,yesterday,204.42",
+		"VTI/X,2023-01-31,204.42",
+		"VTI,2023-01-31,lots",
+	}, "\n")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	report, err := ImportPrices(db, strings.NewReader(file))
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, false, report.Committed)
+	assert.Equal(t, 3, report.Failed)
+
+	_, err = ImportPrices(db, strings.NewReader("ticker,date,price\nVTI,2023-01-31,1"))
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("wanted: %v, got: %v", ErrInvalidFile, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportPricesMalformedRow(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	file := strings.Join([]string{
+		"symbol,date,price",
+		`"VTI,2023-01-31,204.1`,
+	}, "\n")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	report, err := ImportPrices(db, strings.NewReader(file))
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	assert.Equal(t, false, report.Committed)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 2, report.Rows[0].Line)
+	assert.Equal(t, RowFailed, report.Rows[0].Status)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Package importer loads account balances, exchange rates and security prices
+// from external files.
+package importer
+
+import "errors"
+
+type RowStatus string
+
+const (
+	RowCreated RowStatus = "created"
+	RowSkipped RowStatus = "skipped"
+	RowFailed  RowStatus = "failed"
+)
+
+// RowResult describes what happened to a single imported row. Line is the
+// line number in the source file, when the format has meaningful lines.
+// Account names the account the row was for, the currency pair of an exchange
+// rate or the symbol of a security.
+type RowResult struct {
+	Line    int       `json:"line,omitempty"`
+	Account string    `json:"account"`
+	Status  RowStatus `json:"status"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Report summarises an import. Imports run in a single transaction, so when
+// any row fails nothing is written and Committed is false.
+type Report struct {
+	Committed bool        `json:"committed"`
+	Created   int         `json:"created"`
+	Skipped   int         `json:"skipped"`
+	Failed    int         `json:"failed"`
+	Rows      []RowResult `json:"rows"`
+}
+
+func (r *Report) add(row RowResult) {
+	switch row.Status {
+	case RowCreated:
+		r.Created++
+	case RowSkipped:
+		r.Skipped++
+	case RowFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// ErrInvalidFile is returned when an import file cannot be read at all, as
+// opposed to individual rows failing.
+var ErrInvalidFile = errors.New("invalid import file")
+
+// errRowsFailed rolls back an import transaction when rows failed validation.
+var errRowsFailed = errors.New("one or more rows failed to import")
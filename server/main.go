@@ -3,36 +3,20 @@ package main
 import (
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
-	"time"
+	"strings"
 
 	"github.com/Jrc356/financial_dashboard/controllers"
+	"github.com/Jrc356/financial_dashboard/migrations"
 	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
 	"github.com/gin-gonic/contrib/cors"
 	"github.com/gin-gonic/contrib/static"
 	"github.com/gin-gonic/gin"
-	"github.com/shopspring/decimal"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-var (
-	db *gorm.DB
-)
-
-func randomDollarAmount() decimal.Decimal {
-	dollars := rand.Intn(100000)
-	change := rand.Float32()
-	amount := fmt.Sprintf("%d%.2f", dollars, change)
-	d, err := decimal.NewFromString(amount)
-	if err != nil {
-		panic(err)
-	}
-	return d
-}
-
 func getenv(key, fallback string) string {
 	value := os.Getenv(key)
 	if len(value) == 0 {
@@ -41,122 +25,124 @@ func getenv(key, fallback string) string {
 	return value
 }
 
-func init() {
-	connStr := fmt.Sprintf(
-		"user=%s password=%s host=%s port=%s dbname=%s sslmode=disable",
-		getenv("DB_USER", "postgres"),
-		getenv("DB_PASSWORD", "postgres"),
-		getenv("DB_HOST", "localhost"),
-		getenv("DB_PORT", "5432"),
-		getenv("DB_NAME", "postgres"),
-	)
+// openDatabase connects to the database selected by DB_DRIVER. Postgres, the
+// default, is configured by the other DB_ variables. SQLite keeps everything
+// in the file at DB_PATH.
+func openDatabase() *gorm.DB {
+	driver, err := store.ParseDriver(getenv("DB_DRIVER", string(store.Postgres)))
+	if err != nil {
+		log.Panicln(err)
+	}
 
-	var err error
-	db, err = gorm.Open(postgres.Open(connStr), &gorm.Config{
+	config := store.Config{Driver: driver}
+	switch driver {
+	case store.Postgres:
+		config.DSN = fmt.Sprintf(
+			"user=%s password=%s host=%s port=%s dbname=%s sslmode=disable",
+			getenv("DB_USER", "postgres"),
+			getenv("DB_PASSWORD", "postgres"),
+			getenv("DB_HOST", "localhost"),
+			getenv("DB_PORT", "5432"),
+			getenv("DB_NAME", "postgres"),
+		)
+	case store.SQLite:
+		config.DSN = getenv("DB_PATH", "financial_dashboard.db")
+	}
+
+	db, err := store.Open(config, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 	if err != nil {
 		log.Panicln(err)
 	}
+	return db
+}
 
-	db.Migrator().DropTable(&models.Account{})
-	db.Migrator().DropTable(&models.AccountValue{})
-
-	db.AutoMigrate(
-		&models.Account{},
-		&models.AccountValue{},
-	)
+// defaultHouseholdName names the household created by commands that run
+// before anyone has logged in.
+const defaultHouseholdName = "Household"
 
-	// TODO: Remove this test data
-	accounts := []models.Account{
-		{
-			Name:     "Our Savings Account",
-			Class:    models.Asset,
-			Category: models.Cash,
-		},
-		{
-			Name:     "Our Checking Account",
-			Class:    models.Asset,
-			Category: models.Cash,
-		},
-		{
-			Name:      "My 401k",
-			Class:     models.Asset,
-			Category:  models.Retirement,
-			TaxBucket: models.TaxDeferred,
-		},
-		{
-			Name:      "SO 401k",
-			Class:     models.Asset,
-			Category:  models.Retirement,
-			TaxBucket: models.TaxDeferred,
-		},
-		{
-			Name:      "My IRA",
-			Class:     models.Asset,
-			Category:  models.Retirement,
-			TaxBucket: models.Roth,
-		},
-		{
-			Name:      "SO IRA",
-			Class:     models.Asset,
-			Category:  models.Retirement,
-			TaxBucket: models.Roth,
-		},
-		{
-			Name:      "House",
-			Class:     models.Asset,
-			Category:  models.Retirement,
-			TaxBucket: models.Roth,
-		},
-		{
-			Name:     "Student Loan",
-			Class:    models.Liability,
-			Category: models.Loan,
-		},
-		{
-			Name:     "Mortgage",
-			Class:    models.Liability,
-			Category: models.Loan,
-		},
-		{
-			Name:     "Auto Loan",
-			Class:    models.Liability,
-			Category: models.Loan,
-		},
-		{
-			Name:     "Credit Card",
-			Class:    models.Liability,
-			Category: models.CreditCard,
-		},
+// lookupHousehold returns the household with the given ID for commands that
+// run without a logged in user. An ID of 0 selects the first household,
+// creating it if there are none yet.
+func lookupHousehold(db *gorm.DB, id uint) models.Household {
+	var household models.Household
+	var err error
+	if id == 0 {
+		household, err = models.FirstOrCreateHousehold(db, defaultHouseholdName)
+	} else {
+		household, err = models.GetHousehold(db, id)
 	}
-	for _, account := range accounts {
-		if err := models.CreateAccount(db, account); err != nil {
-			log.Panic(err)
-		}
+	if err != nil {
+		log.Fatalf("household %d: %s", id, err)
+	}
+	return household
+}
 
-		go func(account models.Account) {
-			for i := 0; i < 10; i++ {
-				value := models.AccountValue{
-					AccountName: account.Name,
-					Value:       randomDollarAmount(),
-				}
+// corsMiddleware allows the origins in CORS_ORIGINS, a comma separated list, to
+// call the API with the session cookie.
+func corsMiddleware() gin.HandlerFunc {
+	config := cors.DefaultConfig()
+	config.AllowAllOrigins = false
+	config.AllowedOrigins = strings.Split(getenv("CORS_ORIGINS", "http://localhost:3000"), ",")
+	config.AllowCredentials = true
+	config.AddAllowedMethods("DELETE")
+	return cors.New(config)
+}
 
-				if err := models.CreateAccountValue(db, value); err != nil {
-					log.Panic(err)
-				}
-				time.Sleep(2 * time.Second)
-			}
-		}(account)
+func serve(db *gorm.DB) {
+	applied, err := migrations.Up(db)
+	if err != nil {
+		log.Panic(err)
+	}
+	for _, m := range applied {
+		log.Printf("applied migration %d (%s)", m.ID, m.Name)
 	}
-}
 
-func main() {
 	router := gin.Default()
 	router.Use(static.Serve("/", static.LocalFile("./client/build", true)))
-	router.Use(cors.Default())
-	apiRouter := router.Group("/api")
-	controllers.NewAccountController(db, apiRouter)
-	controllers.NewFinanceController(db, apiRouter)
+	router.Use(corsMiddleware())
+	authController := controllers.NewAuthController(db, router.Group("/api"))
+	apiRouter := router.Group("/api", authController.RequireSession)
+	accountStore := store.NewGormStore(db)
+	controllers.NewAccountController(accountStore, apiRouter)
+	controllers.NewFinanceController(accountStore, apiRouter)
+	controllers.NewHoldingController(accountStore, apiRouter)
+	controllers.NewGainsController(accountStore, apiRouter)
+	controllers.NewCashFlowController(accountStore, apiRouter)
+	controllers.NewPerformanceController(accountStore, apiRouter)
+	controllers.NewImportController(db, apiRouter)
+	controllers.NewExportController(db, apiRouter)
+	controllers.NewHouseholdController(db, apiRouter)
+	controllers.NewTokenController(db, apiRouter)
+	startPriceScheduler(db)
 	router.Run()
 }
+
+func main() {
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	db := openDatabase()
+	switch command {
+	case "serve":
+		serve(db)
+	case "migrate":
+		migrateCommand(db, args)
+	case "seed":
+		seedCommand(db, args)
+	case "import":
+		importCommand(db, args)
+	case "export":
+		exportCommand(db, args)
+	case "prices":
+		pricesCommand(db, args)
+	case "bootstrap":
+		bootstrapCommand(db, args)
+	default:
+		log.Fatalf("unknown command %q, expected one of: serve, migrate, seed, import, export, prices, bootstrap", command)
+	}
+}
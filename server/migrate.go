@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Jrc356/financial_dashboard/migrations"
+	"gorm.io/gorm"
+)
+
+func migrateCommand(db *gorm.DB, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: migrate [-steps n] up|down|status")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	switch flags.Arg(0) {
+	case "up":
+		applied, err := migrations.Up(db)
+		for _, m := range applied {
+			log.Printf("applied migration %d (%s)", m.ID, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "down":
+		reverted, err := migrations.Down(db, *steps)
+		for _, m := range reverted {
+			log.Printf("reverted migration %d (%s)", m.ID, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		statuses, err := migrations.GetStatus(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.ID, s.Name, applied)
+		}
+		w.Flush()
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// The structs below are a snapshot of the schema this migration creates. They
+// intentionally do not reference the models package so later model changes do
+// not alter what this migration does.
+
+type accountV1 struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"uniqueIndex:idx_accounts_name,where:deleted_at IS NULL"`
+	Class     string
+	Category  string
+	TaxBucket string
+	Values    []accountValueV1 `gorm:"foreignKey:AccountID"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (accountV1) TableName() string {
+	return "accounts"
+}
+
+type accountValueV1 struct {
+	ID        uint
+	AccountID uint            `gorm:"index"`
+	Value     decimal.Decimal `gorm:"type:decimal(19,2)"`
+	AsOf      time.Time       `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (accountValueV1) TableName() string {
+	return "account_values"
+}
+
+var createAccounts = Migration{
+	ID:   1,
+	Name: "create_accounts",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&accountV1{}, &accountValueV1{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&accountValueV1{}, &accountV1{})
+	},
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ofxAccountMappingV2 struct {
+	ID           uint
+	OFXAccountID string `gorm:"uniqueIndex"`
+	AccountID    uint
+	Account      accountV1
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (ofxAccountMappingV2) TableName() string {
+	return "ofx_account_mappings"
+}
+
+var createOFXAccountMappings = Migration{
+	ID:   2,
+	Name: "create_ofx_account_mappings",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&ofxAccountMappingV2{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&ofxAccountMappingV2{})
+	},
+}
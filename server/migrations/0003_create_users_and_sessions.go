@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userV3 struct {
+	ID           uint
+	Username     string `gorm:"uniqueIndex"`
+	PasswordHash string
+	Admin        bool
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (userV3) TableName() string {
+	return "users"
+}
+
+type sessionV3 struct {
+	ID        uint
+	TokenHash string `gorm:"uniqueIndex"`
+	UserID    uint   `gorm:"index"`
+	User      userV3 `gorm:"constraint:OnDelete:CASCADE"`
+	ExpiresAt time.Time
+
+	CreatedAt time.Time
+}
+
+func (sessionV3) TableName() string {
+	return "sessions"
+}
+
+var createUsersAndSessions = Migration{
+	ID:   3,
+	Name: "create_users_and_sessions",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&userV3{}, &sessionV3{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&sessionV3{}, &userV3{})
+	},
+}
@@ -0,0 +1,139 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type householdV4 struct {
+	ID   uint
+	Name string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (householdV4) TableName() string {
+	return "households"
+}
+
+type invitationV4 struct {
+	ID          uint
+	TokenHash   string `gorm:"uniqueIndex"`
+	HouseholdID uint   `gorm:"index"`
+	InvitedByID uint
+	ExpiresAt   time.Time
+	AcceptedAt  *time.Time
+
+	CreatedAt time.Time
+}
+
+func (invitationV4) TableName() string {
+	return "invitations"
+}
+
+type accountV4 struct {
+	ID          uint
+	HouseholdID uint   `gorm:"uniqueIndex:idx_accounts_household_name,priority:1,where:deleted_at IS NULL"`
+	Name        string `gorm:"uniqueIndex:idx_accounts_household_name,priority:2,where:deleted_at IS NULL"`
+}
+
+func (accountV4) TableName() string {
+	return "accounts"
+}
+
+type userV4 struct {
+	ID          uint
+	HouseholdID uint `gorm:"index"`
+}
+
+func (userV4) TableName() string {
+	return "users"
+}
+
+type ofxAccountMappingV4 struct {
+	ID           uint
+	HouseholdID  uint   `gorm:"uniqueIndex:idx_ofx_account_mappings_household_ofx_account,priority:1"`
+	OFXAccountID string `gorm:"uniqueIndex:idx_ofx_account_mappings_household_ofx_account,priority:2"`
+}
+
+func (ofxAccountMappingV4) TableName() string {
+	return "ofx_account_mappings"
+}
+
+// addHouseholds makes accounts, users and OFX mappings belong to a household.
+// Accounts and mappings are unique per household rather than globally. Data
+// created before households existed is moved into a single household.
+var addHouseholds = Migration{
+	ID:   4,
+	Name: "add_households",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.CreateTable(&householdV4{}, &invitationV4{}); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&accountV4{}, &userV4{}, &ofxAccountMappingV4{}} {
+			if err := m.AddColumn(model, "HouseholdID"); err != nil {
+				return err
+			}
+		}
+
+		var accounts, users int64
+		if err := tx.Table("accounts").Count(&accounts).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("users").Count(&users).Error; err != nil {
+			return err
+		}
+		if accounts > 0 || users > 0 {
+			household := householdV4{Name: "Household"}
+			if err := tx.Create(&household).Error; err != nil {
+				return err
+			}
+			for _, table := range []string{"accounts", "users", "ofx_account_mappings"} {
+				if err := tx.Exec("UPDATE "+table+" SET household_id = ?", household.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := m.DropIndex(&accountV1{}, "idx_accounts_name"); err != nil {
+			return err
+		}
+		if err := m.CreateIndex(&accountV4{}, "idx_accounts_household_name"); err != nil {
+			return err
+		}
+		if err := m.DropIndex(&ofxAccountMappingV2{}, "OFXAccountID"); err != nil {
+			return err
+		}
+		if err := m.CreateIndex(&ofxAccountMappingV4{}, "idx_ofx_account_mappings_household_ofx_account"); err != nil {
+			return err
+		}
+		return m.CreateIndex(&userV4{}, "HouseholdID")
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropIndex(&userV4{}, "HouseholdID"); err != nil {
+			return err
+		}
+		if err := m.DropIndex(&ofxAccountMappingV4{}, "idx_ofx_account_mappings_household_ofx_account"); err != nil {
+			return err
+		}
+		if err := m.CreateIndex(&ofxAccountMappingV2{}, "OFXAccountID"); err != nil {
+			return err
+		}
+		if err := m.DropIndex(&accountV4{}, "idx_accounts_household_name"); err != nil {
+			return err
+		}
+		if err := m.CreateIndex(&accountV1{}, "idx_accounts_name"); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&accountV4{}, &userV4{}, &ofxAccountMappingV4{}} {
+			if err := m.DropColumn(model, "HouseholdID"); err != nil {
+				return err
+			}
+		}
+		return m.DropTable(&invitationV4{}, &householdV4{})
+	},
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestAddHouseholdsMovesExistingData(t *testing.T) {
+	db, err := store.Open(
+		store.Config{Driver: store.SQLite, DSN: filepath.Join(t.TempDir(), "test.db")},
+		&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	if _, err := up(db, Migrations[:3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&accountV1{Name: "Checking", Class: "asset", Category: "cash"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&userV3{Username: "admin"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var households []householdV4
+	db.Find(&households)
+	assert.Equal(t, 1, len(households))
+
+	var account accountV4
+	db.First(&account)
+	assert.Equal(t, households[0].ID, account.HouseholdID)
+	var user userV4
+	db.First(&user)
+	assert.Equal(t, households[0].ID, user.HouseholdID)
+
+	// Names are now unique per household rather than across every account.
+	err = db.Create(&accountV4{HouseholdID: households[0].ID + 1, Name: "Checking"}).Error
+	assert.Equal(t, nil, err)
+	err = db.Create(&accountV4{HouseholdID: households[0].ID, Name: "Checking"}).Error
+	assert.NotEqual(t, nil, err)
+}
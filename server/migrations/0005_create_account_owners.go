@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type accountOwnerV5 struct {
+	AccountID uint            `gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint            `gorm:"primaryKey;autoIncrement:false;index"`
+	Share     decimal.Decimal `gorm:"type:decimal(5,2)"`
+}
+
+func (accountOwnerV5) TableName() string {
+	return "account_owners"
+}
+
+// createAccountOwners lets members of a household own a percentage of an
+// account. Existing accounts are left without owners and so stay joint.
+var createAccountOwners = Migration{
+	ID:   5,
+	Name: "create_account_owners",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&accountOwnerV5{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&accountOwnerV5{})
+	},
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiTokenV6 struct {
+	ID         uint
+	Name       string
+	TokenHash  string `gorm:"uniqueIndex"`
+	UserID     uint   `gorm:"index"`
+	Scope      string
+	LastUsedAt *time.Time
+
+	CreatedAt time.Time
+}
+
+func (apiTokenV6) TableName() string {
+	return "api_tokens"
+}
+
+var createAPITokens = Migration{
+	ID:   6,
+	Name: "create_api_tokens",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&apiTokenV6{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&apiTokenV6{})
+	},
+}
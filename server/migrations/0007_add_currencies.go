@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type accountV7 struct {
+	ID       uint
+	Currency string `gorm:"type:varchar(3);not null;default:USD"`
+}
+
+func (accountV7) TableName() string {
+	return "accounts"
+}
+
+type fxRateV7 struct {
+	ID    uint
+	Date  time.Time       `gorm:"uniqueIndex:idx_fx_rates_pair_date,priority:3"`
+	Base  string          `gorm:"uniqueIndex:idx_fx_rates_pair_date,priority:1"`
+	Quote string          `gorm:"uniqueIndex:idx_fx_rates_pair_date,priority:2"`
+	Rate  decimal.Decimal `gorm:"type:decimal(19,8)"`
+}
+
+func (fxRateV7) TableName() string {
+	return "fx_rates"
+}
+
+// addCurrencies gives every account a currency, USD for existing accounts, and
+// creates the table of exchange rates used to convert between them.
+var addCurrencies = Migration{
+	ID:   7,
+	Name: "add_currencies",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.AddColumn(&accountV7{}, "Currency"); err != nil {
+			return err
+		}
+		return m.CreateTable(&fxRateV7{})
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropTable(&fxRateV7{}); err != nil {
+			return err
+		}
+		if err := m.DropColumn(&accountV7{}, "Currency"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses its
+		// indexes.
+		if !m.HasIndex(&accountV1{}, "DeletedAt") {
+			if err := m.CreateIndex(&accountV1{}, "DeletedAt"); err != nil {
+				return err
+			}
+		}
+		if !m.HasIndex(&accountV4{}, "idx_accounts_household_name") {
+			return m.CreateIndex(&accountV4{}, "idx_accounts_household_name")
+		}
+		return nil
+	},
+}
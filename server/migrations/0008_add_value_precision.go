@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type accountV8 struct {
+	ID        uint
+	Precision *int32
+}
+
+func (accountV8) TableName() string {
+	return "accounts"
+}
+
+type accountValueV8 struct {
+	ID        uint
+	AccountID uint            `gorm:"index"`
+	Value     decimal.Decimal `gorm:"type:decimal(28,8)"`
+	AsOf      time.Time       `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (accountValueV8) TableName() string {
+	return "account_values"
+}
+
+// addValuePrecision stores values to 8 decimal places rather than to the cent,
+// and lets accounts override the precision of their currency. Reverting it
+// rounds values back to the cent.
+var addValuePrecision = Migration{
+	ID:   8,
+	Name: "add_value_precision",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.AddColumn(&accountV8{}, "Precision"); err != nil {
+			return err
+		}
+		return alterValueType(tx, &accountValueV8{})
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := alterValueType(tx, &accountValueV1{}); err != nil {
+			return err
+		}
+		if err := m.DropColumn(&accountV8{}, "Precision"); err != nil {
+			return err
+		}
+		return restoreAccountIndexes(m)
+	},
+}
+
+// restoreAccountIndexes recreates the indexes of accounts after SQLite rebuilt
+// the table to drop a column.
+func restoreAccountIndexes(m gorm.Migrator) error {
+	if !m.HasIndex(&accountV1{}, "DeletedAt") {
+		if err := m.CreateIndex(&accountV1{}, "DeletedAt"); err != nil {
+			return err
+		}
+	}
+	if !m.HasIndex(&accountV4{}, "idx_accounts_household_name") {
+		return m.CreateIndex(&accountV4{}, "idx_accounts_household_name")
+	}
+	return nil
+}
+
+// alterValueType changes the type of account_values.value to the one model
+// declares. SQLite does not limit the precision of decimals, so its table is
+// left as it is.
+func alterValueType(tx *gorm.DB, model interface{}) error {
+	if tx.Dialector.Name() == "sqlite" {
+		return nil
+	}
+	return tx.Migrator().AlterColumn(model, "Value")
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type securityV9 struct {
+	ID       uint
+	Symbol   string `gorm:"uniqueIndex"`
+	Name     string
+	Currency string `gorm:"type:varchar(3);not null;default:USD"`
+}
+
+func (securityV9) TableName() string {
+	return "securities"
+}
+
+type positionV9 struct {
+	ID         uint
+	AccountID  uint            `gorm:"index"`
+	SecurityID uint            `gorm:"index"`
+	Quantity   decimal.Decimal `gorm:"type:decimal(28,8)"`
+	CostBasis  decimal.Decimal `gorm:"type:decimal(28,8)"`
+	AsOf       time.Time       `gorm:"index"`
+	CreatedAt  time.Time
+}
+
+func (positionV9) TableName() string {
+	return "positions"
+}
+
+type priceV9 struct {
+	ID         uint
+	SecurityID uint            `gorm:"uniqueIndex:idx_prices_security_date,priority:1"`
+	Date       time.Time       `gorm:"uniqueIndex:idx_prices_security_date,priority:2"`
+	Price      decimal.Decimal `gorm:"type:decimal(28,8)"`
+}
+
+func (priceV9) TableName() string {
+	return "prices"
+}
+
+// createHoldings adds securities, the positions accounts hold in them and the
+// price history used to value those positions.
+var createHoldings = Migration{
+	ID:   9,
+	Name: "create_holdings",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&securityV9{}, &positionV9{}, &priceV9{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&priceV9{}, &positionV9{}, &securityV9{})
+	},
+}
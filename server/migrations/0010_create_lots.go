@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type lotV10 struct {
+	ID         uint
+	AccountID  uint            `gorm:"index"`
+	SecurityID uint            `gorm:"index"`
+	Quantity   decimal.Decimal `gorm:"type:decimal(28,8)"`
+	Cost       decimal.Decimal `gorm:"type:decimal(28,8)"`
+	AcquiredAt time.Time       `gorm:"index"`
+	CreatedAt  time.Time
+}
+
+func (lotV10) TableName() string {
+	return "lots"
+}
+
+type saleV10 struct {
+	ID         uint
+	AccountID  uint            `gorm:"index"`
+	SecurityID uint            `gorm:"index"`
+	Quantity   decimal.Decimal `gorm:"type:decimal(28,8)"`
+	Proceeds   decimal.Decimal `gorm:"type:decimal(28,8)"`
+	SoldAt     time.Time       `gorm:"index"`
+	Method     string
+	CreatedAt  time.Time
+}
+
+func (saleV10) TableName() string {
+	return "sales"
+}
+
+type lotMatchV10 struct {
+	ID       uint
+	SaleID   uint            `gorm:"index"`
+	LotID    uint            `gorm:"index"`
+	Quantity decimal.Decimal `gorm:"type:decimal(28,8)"`
+	Cost     decimal.Decimal `gorm:"type:decimal(28,8)"`
+	Proceeds decimal.Decimal `gorm:"type:decimal(28,8)"`
+}
+
+func (lotMatchV10) TableName() string {
+	return "lot_matches"
+}
+
+// createLots tracks purchases of securities as lots and the sales matched
+// against them, for reporting gains.
+var createLots = Migration{
+	ID:   10,
+	Name: "create_lots",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&lotV10{}, &saleV10{}, &lotMatchV10{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&lotMatchV10{}, &saleV10{}, &lotV10{})
+	},
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type cashFlowV11 struct {
+	ID        uint
+	AccountID uint `gorm:"index"`
+	Type      string
+	Amount    decimal.Decimal `gorm:"type:decimal(28,8)"`
+	Date      time.Time       `gorm:"index"`
+	Memo      string
+	CreatedAt time.Time
+}
+
+func (cashFlowV11) TableName() string {
+	return "cash_flows"
+}
+
+// createCashFlows records money moving in and out of accounts, so changes in
+// balance can be split into flows and investment return.
+var createCashFlows = Migration{
+	ID:   11,
+	Name: "create_cash_flows",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&cashFlowV11{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&cashFlowV11{})
+	},
+}
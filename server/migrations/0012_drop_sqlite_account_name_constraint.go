@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type accountV12 struct {
+	ID   uint
+	Name string
+}
+
+func (accountV12) TableName() string {
+	return "accounts"
+}
+
+// dropSQLiteAccountNameConstraint removes the unique constraint SQLite put on
+// account names when createOFXAccountMappings migrated the accounts table it
+// refers to. Names are only unique per household, through the index
+// addHouseholds created. Postgres never had the constraint, so its table is
+// left as it is.
+var dropSQLiteAccountNameConstraint = Migration{
+	ID:   12,
+	Name: "drop_sqlite_account_name_constraint",
+	Up: func(tx *gorm.DB) error {
+		if tx.Dialector.Name() != "sqlite" {
+			return nil
+		}
+		m := tx.Migrator()
+		if err := m.AlterColumn(&accountV12{}, "Name"); err != nil {
+			return err
+		}
+		return restoreAccountIndexes(m)
+	},
+	Down: func(tx *gorm.DB) error {
+		return nil
+	},
+}
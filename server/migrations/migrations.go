@@ -0,0 +1,200 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a numbered, reversible change to the database schema. Up and
+// Down run inside a transaction together with the bookkeeping in the
+// schema_migrations table.
+type Migration struct {
+	ID   uint
+	Name string
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error
+}
+
+// Status reports whether a migration has been applied to the database.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type schemaMigration struct {
+	ID        uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+const createSchemaMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	id bigint PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamp NOT NULL
+)`
+
+// Migrations lists every migration in the order it is applied. New migrations
+// are appended with the next ID; a migration that has been released must not
+// be edited, add a new one instead.
+var Migrations = []Migration{
+	createAccounts,
+	createOFXAccountMappings,
+	createUsersAndSessions,
+	addHouseholds,
+	createAccountOwners,
+	createAPITokens,
+	addCurrencies,
+	addValuePrecision,
+	createHoldings,
+	createLots,
+	createCashFlows,
+	dropSQLiteAccountNameConstraint,
+}
+
+// Up applies all pending migrations and returns the ones that were applied.
+func Up(db *gorm.DB) ([]Migration, error) {
+	return up(db, Migrations)
+}
+
+// Down reverts the most recently applied migrations, at most steps of them,
+// and returns the ones that were reverted.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	return down(db, Migrations, steps)
+}
+
+// GetStatus returns every known migration along with whether it has been
+// applied.
+func GetStatus(db *gorm.DB) ([]Status, error) {
+	return status(db, Migrations)
+}
+
+func validate(migrations []Migration) error {
+	for i, m := range migrations {
+		if m.ID == 0 {
+			return fmt.Errorf("migration %q has no id", m.Name)
+		}
+		if m.Up == nil || m.Down == nil {
+			return fmt.Errorf("migration %d (%s) must define both up and down", m.ID, m.Name)
+		}
+		if i > 0 && m.ID <= migrations[i-1].ID {
+			return fmt.Errorf("migration %d (%s) is out of order", m.ID, m.Name)
+		}
+	}
+	return nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[uint]schemaMigration, error) {
+	if result := db.Exec(createSchemaMigrationsTable); result.Error != nil {
+		return nil, result.Error
+	}
+
+	var rows []schemaMigration
+	result := db.Order("id").Find(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	applied := make(map[uint]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.ID] = row
+	}
+	return applied, nil
+}
+
+func up(db *gorm.DB, migrations []Migration) ([]Migration, error) {
+	if err := validate(migrations); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, m := range migrations {
+		if _, ok := applied[m.ID]; ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{ID: m.ID, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("applying migration %d (%s): %w", m.ID, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func down(db *gorm.DB, migrations []Migration, steps int) ([]Migration, error) {
+	if err := validate(migrations); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]Migration, len(migrations))
+	for _, m := range migrations {
+		byID[m.ID] = m
+	}
+
+	ids := make([]uint, 0, len(applied))
+	for id := range applied {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+
+	done := []Migration{}
+	for _, id := range ids {
+		if len(done) >= steps {
+			break
+		}
+
+		m, ok := byID[id]
+		if !ok {
+			return done, fmt.Errorf("applied migration %d (%s) is unknown to this build", id, applied[id].Name)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{ID: m.ID}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("reverting migration %d (%s): %w", m.ID, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func status(db *gorm.DB, migrations []Migration) ([]Status, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(migrations))
+	for i, m := range migrations {
+		row, ok := applied[m.ID]
+		statuses[i] = Status{Migration: m, Applied: ok, AppliedAt: row.AppliedAt}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,219 @@
+package migrations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+func execMigration(name, statement string) Migration {
+	return Migration{
+		Name: name,
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE " + statement).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE " + statement).Error
+		},
+	}
+}
+
+func testMigrations() []Migration {
+	one := execMigration("one", "one")
+	one.ID = 1
+	two := execMigration("two", "two")
+	two.ID = 2
+	return []Migration{one, two}
+}
+
+func appliedRows(ids ...uint) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "name", "applied_at"})
+	for _, id := range ids {
+		rows.AddRow(id, "", time.Now())
+	}
+	return rows
+}
+
+func TestRegisteredMigrationsAreValid(t *testing.T) {
+	if err := validate(Migrations); err != nil {
+		t.Errorf(err.Error())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	outOfOrder := testMigrations()
+	outOfOrder[0], outOfOrder[1] = outOfOrder[1], outOfOrder[0]
+
+	noID := testMigrations()
+	noID[0].ID = 0
+
+	noDown := testMigrations()
+	noDown[1].Down = nil
+
+	tests := []struct {
+		name       string
+		migrations []Migration
+		wantErr    bool
+	}{
+		{
+			name:       "should accept ascending migrations",
+			migrations: testMigrations(),
+			wantErr:    false,
+		},
+		{
+			name:       "should reject migrations out of order",
+			migrations: outOfOrder,
+			wantErr:    true,
+		},
+		{
+			name:       "should reject a migration without an id",
+			migrations: noID,
+			wantErr:    true,
+		},
+		{
+			name:       "should reject a migration without a down",
+			migrations: noDown,
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validate(test.migrations)
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestUp(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM \"schema_migrations\"").WillReturnRows(appliedRows(1))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE two").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO \"schema_migrations\"").
+		WithArgs(2, "two", models.AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	applied, err := up(db, testMigrations())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	assert.Equal(t, 1, len(applied))
+	assert.Equal(t, uint(2), applied[0].ID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpRollsBackFailedMigration(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM \"schema_migrations\"").WillReturnRows(appliedRows())
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE one").WillReturnError(gorm.ErrInvalidData)
+	mock.ExpectRollback()
+
+	applied, err := up(db, testMigrations())
+	if err == nil {
+		t.Errorf("wanted error, got nil")
+	}
+	assert.Equal(t, 0, len(applied))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDown(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM \"schema_migrations\"").WillReturnRows(appliedRows(1, 2))
+	mock.ExpectBegin()
+	mock.ExpectExec("DROP TABLE two").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM \"schema_migrations\"").
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	reverted, err := down(db, testMigrations(), 1)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	assert.Equal(t, 1, len(reverted))
+	assert.Equal(t, uint(2), reverted[0].ID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDownUnknownMigration(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM \"schema_migrations\"").WillReturnRows(appliedRows(1, 2, 3))
+
+	reverted, err := down(db, testMigrations(), 1)
+	if err == nil {
+		t.Errorf("wanted error, got nil")
+	}
+	assert.Equal(t, 0, len(reverted))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM \"schema_migrations\"").WillReturnRows(appliedRows(1))
+
+	statuses, err := status(db, testMigrations())
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	assert.Equal(t, 2, len(statuses))
+	assert.Equal(t, true, statuses[0].Applied)
+	assert.Equal(t, false, statuses[1].Applied)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -89,12 +90,21 @@ func ParseTaxBucket(s string) (tb TaxBucket, err error) {
 	return t, nil
 }
 
+// ErrAccountNameTaken is returned when creating or renaming an account to a
+// name that another account in the household already uses.
+var ErrAccountNameTaken = errors.New("account name already in use")
+
 type Account struct {
-	Name      string          `json:"name" gorm:"primaryKey" binding:"required"`
-	Class     AccountClass    `json:"class" binding:"required"`
-	Category  AccountCategory `json:"category" binding:"required"`
-	TaxBucket TaxBucket       `json:"taxBucket"`
-	Values    []AccountValue  `json:"values" gorm:"foreignKey:AccountName;references:Name"`
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	HouseholdID uint            `json:"household_id" gorm:"uniqueIndex:idx_accounts_household_name,priority:1,where:deleted_at IS NULL"`
+	Name        string          `json:"name" gorm:"uniqueIndex:idx_accounts_household_name,priority:2,where:deleted_at IS NULL" binding:"required"`
+	Class       AccountClass    `json:"class" binding:"required"`
+	Category    AccountCategory `json:"category" binding:"required"`
+	TaxBucket   TaxBucket       `json:"taxBucket"`
+	Currency    Currency        `json:"currency"`
+	Precision   *int32          `json:"precision,omitempty"`
+	Values      []AccountValue  `json:"values"`
+	Owners      []AccountOwner  `json:"owners,omitempty"`
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -133,65 +143,214 @@ func ValidateAccount(account Account) error {
 		}
 	}
 
-	return nil
+	if account.Currency != "" {
+		if _, err := ParseCurrency(account.Currency.String()); err != nil {
+			return err
+		}
+	}
+	if account.Precision != nil && (*account.Precision < 0 || *account.Precision > MaxPrecision) {
+		return fmt.Errorf("precision must be between 0 and %d, got %d", MaxPrecision, *account.Precision)
+	}
+
+	return ValidateOwners(account.Owners)
+}
+
+// ValuePrecision returns the number of decimal places the account's values are
+// shown with: its own precision when set, and otherwise its currency's.
+func (account Account) ValuePrecision() int32 {
+	if account.Precision != nil {
+		return *account.Precision
+	}
+	if account.Currency == "" {
+		return DefaultCurrency.Precision()
+	}
+	return account.Currency.Precision()
+}
+
+// inHousehold limits a query on accounts to those owned by the household.
+func inHousehold(db *gorm.DB, householdID uint) *gorm.DB {
+	return db.Where("household_id = ?", householdID)
+}
+
+func AccountExists(db *gorm.DB, householdID uint, name string) (bool, error) {
+	count := int64(0)
+	result := inHousehold(db.Model(&Account{}), householdID).Where("name = ?", name).Count(&count)
+	err := result.Error
+	if result.Error == gorm.ErrRecordNotFound {
+		err = nil
+	}
+	return count > 0, err
+
 }
 
-func AccountExists(db *gorm.DB, name string) (bool, error) {
+func AccountExistsByID(db *gorm.DB, householdID, id uint) (bool, error) {
 	count := int64(0)
-	result := db.Model(&Account{}).Where("name = ?", name).Count(&count)
+	result := inHousehold(db.Model(&Account{}), householdID).Where("id = ?", id).Count(&count)
 	err := result.Error
 	if result.Error == gorm.ErrRecordNotFound {
 		err = nil
 	}
 	return count > 0, err
+}
 
+// HouseholdHasAccounts reports whether the household has any accounts,
+// including deleted ones.
+func HouseholdHasAccounts(db *gorm.DB, householdID uint) (bool, error) {
+	count := int64(0)
+	result := inHousehold(db.Unscoped().Model(&Account{}), householdID).Count(&count)
+	return count > 0, result.Error
 }
 
-func CreateAccount(db *gorm.DB, account Account) error {
+// CreateAccount creates the account in the household, regardless of any
+// household set on it, along with its owners. Its ID and values are left to
+// the database and CreateAccountValue, so they cannot claim existing rows.
+// Accounts without a currency are kept in DefaultCurrency.
+func CreateAccount(db *gorm.DB, householdID uint, account Account) (Account, error) {
+	account.ID = 0
+	account.HouseholdID = householdID
+	account.Values = nil
+	account.DeletedAt = gorm.DeletedAt{}
+	if account.Currency == "" {
+		account.Currency = DefaultCurrency
+	}
+	if err := checkMembers(db, householdID, account.Owners); err != nil {
+		return account, err
+	}
+	for i := range account.Owners {
+		account.Owners[i].Share = account.Owners[i].Share.Round(2)
+	}
 	result := db.Create(&account)
-	return result.Error
+	return account, result.Error
 }
 
-func GetAllAccountsWithValues(db *gorm.DB) ([]Account, error) {
+func GetAllAccountsWithValues(db *gorm.DB, householdID uint) ([]Account, error) {
 	var accounts []Account
-	result := db.Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("created_at desc") }).Find(&accounts)
+	result := inHousehold(db, householdID).Preload("Owners").Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("as_of desc") }).Find(&accounts)
 	return accounts, result.Error
 }
 
-func GetAccountByNameWithValues(db *gorm.DB, accountName string) (Account, error) {
+// GetAllAccountsWithValuesIncludingDeleted also returns deleted accounts, for
+// history that should still include them up until they were deleted.
+func GetAllAccountsWithValuesIncludingDeleted(db *gorm.DB, householdID uint) ([]Account, error) {
+	var accounts []Account
+	result := inHousehold(db.Unscoped(), householdID).Preload("Owners").Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("as_of desc") }).Find(&accounts)
+	return accounts, result.Error
+}
+
+// GetAccountsWithValuesIncludingDeleted is GetAllAccountsWithValuesIncludingDeleted
+// limited to the accounts with the given IDs.
+func GetAccountsWithValuesIncludingDeleted(db *gorm.DB, householdID uint, ids []uint) ([]Account, error) {
+	var accounts []Account
+	result := inHousehold(db.Unscoped(), householdID).Where("id IN ?", ids).Preload("Owners").Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("as_of desc") }).Find(&accounts)
+	return accounts, result.Error
+}
+
+func GetAccount(db *gorm.DB, householdID, id uint) (Account, error) {
 	var account Account
-	result := db.Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("created_at desc") }).Where("name = ?", accountName).First(&account)
+	result := inHousehold(db, householdID).First(&account, id)
 	return account, result.Error
 }
 
-func GetAllAccountsByClassWithValues(db *gorm.DB, class AccountClass) ([]Account, error) {
+func GetAccountByName(db *gorm.DB, householdID uint, accountName string) (Account, error) {
+	var account Account
+	result := inHousehold(db, householdID).Where("name = ?", accountName).First(&account)
+	return account, result.Error
+}
+
+func GetAccountByNameWithValues(db *gorm.DB, householdID uint, accountName string) (Account, error) {
+	var account Account
+	result := inHousehold(db, householdID).Preload("Owners").Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("as_of desc") }).Where("name = ?", accountName).First(&account)
+	return account, result.Error
+}
+
+func GetAccountWithValues(db *gorm.DB, householdID, id uint) (Account, error) {
+	var account Account
+	result := inHousehold(db, householdID).Preload("Owners").Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("as_of desc") }).First(&account, id)
+	return account, result.Error
+}
+
+func GetAllAccountsByClassWithValues(db *gorm.DB, householdID uint, class AccountClass) ([]Account, error) {
 	var accounts []Account
-	result := db.Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("created_at desc") }).Where("class = ?", class).Find(&accounts)
+	result := inHousehold(db, householdID).Preload("Owners").Preload("Values", func(db *gorm.DB) *gorm.DB { return db.Order("as_of desc") }).Where("class = ?", class).Find(&accounts)
 	return accounts, result.Error
 }
 
-func UpdateAccount(db *gorm.DB, accountName string, updates Account) (Account, error) {
+func UpdateAccount(db *gorm.DB, householdID uint, accountName string, updates Account) (Account, error) {
 	if err := ValidateAccount(updates); err != nil {
 		return Account{}, err
 	}
 
-	var account Account
-	result := db.Where("name = ?", accountName).First(&account)
-	if result.Error != nil {
-		return account, result.Error
+	account, err := GetAccountByName(db, householdID, accountName)
+	if err != nil {
+		return account, err
 	}
 
-	result = db.Model(&account).Updates(&updates)
+	return updateAccount(db, householdID, account, updates)
+}
+
+// UpdateAccountByID updates the account with the given ID. If updates carries
+// a different name the account is renamed; its values reference the account
+// by ID and so stay attached. Accounts cannot be moved to another household.
+func UpdateAccountByID(db *gorm.DB, householdID, id uint, updates Account) (Account, error) {
+	if err := ValidateAccount(updates); err != nil {
+		return Account{}, err
+	}
+
+	account, err := GetAccount(db, householdID, id)
+	if err != nil {
+		return account, err
+	}
+
+	if updates.Name != account.Name {
+		taken, err := AccountExists(db, householdID, updates.Name)
+		if err != nil {
+			return account, err
+		}
+		if taken {
+			return account, fmt.Errorf("%w: %s", ErrAccountNameTaken, updates.Name)
+		}
+	}
+
+	return updateAccount(db, householdID, account, updates)
+}
+
+// updateAccount applies updates to the account. Its owners are replaced when
+// updates lists owners, even an empty list, and left alone otherwise.
+func updateAccount(db *gorm.DB, householdID uint, account Account, updates Account) (Account, error) {
+	owners := updates.Owners
+	updates.ID = 0
+	updates.HouseholdID = 0
+	updates.Owners = nil
+	updates.Values = nil
+	updates.DeletedAt = gorm.DeletedAt{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account).Updates(&updates).Error; err != nil {
+			return err
+		}
+		if owners == nil {
+			return nil
+		}
+		return setOwners(tx, householdID, &account, owners)
+	})
+	return account, err
+}
+
+func DeleteAccountByID(db *gorm.DB, householdID, id uint) (Account, error) {
+	account, err := GetAccount(db, householdID, id)
+	if err != nil {
+		return account, err
+	}
+
+	result := db.Delete(&account)
 	return account, result.Error
 }
 
-func DeleteAccount(db *gorm.DB, accountName string) (Account, error) {
-	var account Account
-	result := db.Where("name = ?", accountName).First(&account)
-	if result.Error != nil {
-		return account, result.Error
+func DeleteAccount(db *gorm.DB, householdID uint, accountName string) (Account, error) {
+	account, err := GetAccountByName(db, householdID, accountName)
+	if err != nil {
+		return account, err
 	}
 
-	result = db.Delete(&account)
+	result := db.Delete(&account)
 	return account, result.Error
 }
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrNotHouseholdMember is returned when an account is given an owner that is
+// not a member of the account's household.
+var ErrNotHouseholdMember = errors.New("owner is not a member of the household")
+
+var hundred = decimal.NewFromInt(100)
+
+// AccountOwner gives a member of the household a share of an account, as a
+// percentage of its value. An account without owners belongs to the household
+// as a whole: it counts toward the joint net worth but toward no member's.
+type AccountOwner struct {
+	AccountID uint            `json:"account_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint            `json:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	Share     decimal.Decimal `json:"share" gorm:"type:decimal(5,2)"`
+}
+
+// ValidateOwners checks that every owner is listed once with a share above
+// zero, and that the shares add up to the whole account.
+func ValidateOwners(owners []AccountOwner) error {
+	if len(owners) == 0 {
+		return nil
+	}
+
+	total := decimal.Zero
+	seen := map[uint]bool{}
+	for _, owner := range owners {
+		if owner.UserID == 0 {
+			return fmt.Errorf("no owner user_id provided")
+		}
+		if seen[owner.UserID] {
+			return fmt.Errorf("owner %d is listed more than once", owner.UserID)
+		}
+		seen[owner.UserID] = true
+		if !owner.Share.IsPositive() || owner.Share.GreaterThan(hundred) {
+			return fmt.Errorf("share of owner %d must be above 0 and at most 100, got %s", owner.UserID, owner.Share)
+		}
+		total = total.Add(owner.Share.Round(2))
+	}
+	if !total.Equal(hundred) {
+		return fmt.Errorf("owner shares must add up to 100, got %s", total)
+	}
+	return nil
+}
+
+// Share returns the percentage of the account owned by the user, and false
+// if they own none of it.
+func (account Account) Share(userID uint) (decimal.Decimal, bool) {
+	for _, owner := range account.Owners {
+		if owner.UserID == userID {
+			return owner.Share, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// checkMembers returns ErrNotHouseholdMember unless every owner is a member
+// of the household.
+func checkMembers(db *gorm.DB, householdID uint, owners []AccountOwner) error {
+	if len(owners) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(owners))
+	for i, owner := range owners {
+		ids[i] = owner.UserID
+	}
+	count := int64(0)
+	err := db.Model(&User{}).Where("household_id = ? AND id IN ?", householdID, ids).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count != int64(len(ids)) {
+		return ErrNotHouseholdMember
+	}
+	return nil
+}
+
+// setOwners replaces the owners of the account.
+func setOwners(tx *gorm.DB, householdID uint, account *Account, owners []AccountOwner) error {
+	if err := checkMembers(tx, householdID, owners); err != nil {
+		return err
+	}
+	if err := tx.Where("account_id = ?", account.ID).Delete(&AccountOwner{}).Error; err != nil {
+		return err
+	}
+	for i := range owners {
+		owners[i].AccountID = account.ID
+		owners[i].Share = owners[i].Share.Round(2)
+	}
+	if len(owners) > 0 {
+		if err := tx.Create(&owners).Error; err != nil {
+			return err
+		}
+	}
+	account.Owners = owners
+	return nil
+}
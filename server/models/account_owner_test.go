@@ -0,0 +1,80 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func TestValidateOwners(t *testing.T) {
+	share := func(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+	tests := []struct {
+		name    string
+		owners  []AccountOwner
+		wantErr bool
+	}{
+		{
+			name:    "account without owners is valid",
+			owners:  nil,
+			wantErr: false,
+		},
+		{
+			name:    "single owner of the whole account is valid",
+			owners:  []AccountOwner{{UserID: 1, Share: share("100")}},
+			wantErr: false,
+		},
+		{
+			name:    "shares adding up to 100 are valid",
+			owners:  []AccountOwner{{UserID: 1, Share: share("66.67")}, {UserID: 2, Share: share("33.33")}},
+			wantErr: false,
+		},
+		{
+			name:    "should error if shares add up to less than 100",
+			owners:  []AccountOwner{{UserID: 1, Share: share("50")}},
+			wantErr: true,
+		},
+		{
+			name:    "should error if shares add up to more than 100",
+			owners:  []AccountOwner{{UserID: 1, Share: share("60")}, {UserID: 2, Share: share("50")}},
+			wantErr: true,
+		},
+		{
+			name:    "should error if a share is not positive",
+			owners:  []AccountOwner{{UserID: 1, Share: share("100")}, {UserID: 2, Share: share("0")}},
+			wantErr: true,
+		},
+		{
+			name:    "should error if an owner is listed twice",
+			owners:  []AccountOwner{{UserID: 1, Share: share("50")}, {UserID: 1, Share: share("50")}},
+			wantErr: true,
+		},
+		{
+			name:    "should error if an owner has no user",
+			owners:  []AccountOwner{{Share: share("100")}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateOwners(test.owners)
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestAccountShare(t *testing.T) {
+	account := Account{Owners: []AccountOwner{
+		{UserID: 1, Share: decimal.NewFromInt(70)},
+		{UserID: 2, Share: decimal.NewFromInt(30)},
+	}}
+
+	share, ok := account.Share(2)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "30", share.String())
+
+	_, ok = account.Share(3)
+	assert.Equal(t, false, ok)
+}
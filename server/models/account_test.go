@@ -2,13 +2,16 @@ package models
 
 import (
 	"database/sql/driver"
+	"errors"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-playground/assert/v2"
 	"gorm.io/gorm"
 )
 
 func TestValidateAccount(t *testing.T) {
+	tooPrecise := MaxPrecision + 1
 	tests := []struct {
 		name    string
 		account Account
@@ -31,6 +34,36 @@ func TestValidateAccount(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "account in another currency is valid",
+			account: Account{
+				Name:     "test",
+				Category: Cash,
+				Class:    Asset,
+				Currency: "EUR",
+			},
+			wantErr: false,
+		},
+		{
+			name: "should error if currency is invalid",
+			account: Account{
+				Name:     "test",
+				Category: Cash,
+				Class:    Asset,
+				Currency: "euro",
+			},
+			wantErr: true,
+		},
+		{
+			name: "should error if precision is above the stored precision",
+			account: Account{
+				Name:      "test",
+				Category:  Cash,
+				Class:     Asset,
+				Precision: &tooPrecise,
+			},
+			wantErr: true,
+		},
 		{
 			name: "should error if class is blank",
 			account: Account{
@@ -129,7 +162,7 @@ func TestAccountExists(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			LoadStatements(mock, test.expectedStatements)
-			exists, err := AccountExists(db, "test")
+			exists, err := AccountExists(db, MockHouseholdID, "test")
 			if err != nil && !test.wantErr {
 				t.Errorf(err.Error())
 			}
@@ -189,7 +222,7 @@ func TestCreateAccount(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			LoadStatements(mock, test.expectedStatements)
-			err := CreateAccount(db, test.account)
+			_, err := CreateAccount(db, MockHouseholdID, test.account)
 			if err != nil && !test.wantErr {
 				t.Errorf(err.Error())
 			}
@@ -222,7 +255,7 @@ func TestGetAllAccountsWithValues(t *testing.T) {
 	}
 	numValues := 10
 	LoadStatements(mock, CreateStatementsGetAllAccountsWithValues(testAccounts, numValues))
-	resp, err := GetAllAccountsWithValues(db)
+	resp, err := GetAllAccountsWithValues(db, MockHouseholdID)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
@@ -238,6 +271,40 @@ func TestGetAllAccountsWithValues(t *testing.T) {
 	}
 }
 
+func TestGetAllAccountsWithValuesIncludingDeleted(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM \"accounts\" WHERE household_id = \\$1$").
+		WithArgs(MockHouseholdID).
+		WillReturnRows(AccountToSQLRow(Account{ID: 1, Name: "test", Class: Asset, Category: Cash}))
+	mock.ExpectQuery("SELECT \\* FROM \"account_owners\"").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(AccountOwnerColumns).AddRow(1, 2, "50").AddRow(1, 3, "50"))
+	mock.ExpectQuery("SELECT \\* FROM \"account_values\"").
+		WithArgs(1).
+		WillReturnRows(AddRandomAccountValues(sqlmock.NewRows(AccountValuesColumns), 1, 3))
+
+	resp, err := GetAllAccountsWithValuesIncludingDeleted(db, MockHouseholdID)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	assert.Equal(t, 1, len(resp))
+	assert.Equal(t, true, resp[0].DeletedAt.Valid)
+	assert.Equal(t, 3, len(resp[0].Values))
+	assert.Equal(t, 2, len(resp[0].Owners))
+	assert.Equal(t, uint(3), resp[0].Owners[1].UserID)
+}
+
 func TestGetAccountByNameWithValues(t *testing.T) {
 	db, mock, err := CreateMockDatabase()
 	if err != nil {
@@ -247,6 +314,7 @@ func TestGetAccountByNameWithValues(t *testing.T) {
 	defer d.Close()
 
 	testAccount := Account{
+		ID:       1,
 		Name:     "test",
 		Class:    Asset,
 		Category: Cash,
@@ -273,7 +341,7 @@ func TestGetAccountByNameWithValues(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			LoadStatements(mock, test.expectedStatements)
-			account, err := GetAccountByNameWithValues(db, "test")
+			account, err := GetAccountByNameWithValues(db, MockHouseholdID, "test")
 			if test.wantErr && (err == nil || err != gorm.ErrRecordNotFound) {
 				t.Errorf(err.Error())
 			}
@@ -334,7 +402,7 @@ func TestGetAccountByClassWithValues(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			LoadStatements(mock, test.expectedStatements)
-			accounts, err := GetAllAccountsByClassWithValues(db, test.class)
+			accounts, err := GetAllAccountsByClassWithValues(db, MockHouseholdID, test.class)
 			if test.wantErr && (err == nil || err != gorm.ErrRecordNotFound) {
 				t.Errorf(err.Error())
 			}
@@ -362,6 +430,7 @@ func TestUpdateAccount(t *testing.T) {
 	defer d.Close()
 
 	testAccount := Account{
+		ID:       1,
 		Name:     "test",
 		Category: Cash,
 		Class:    Asset,
@@ -386,7 +455,7 @@ func TestUpdateAccount(t *testing.T) {
 				Asset,
 				HSA,
 				AnyTime{},
-				"test",
+				testAccount.ID,
 			}),
 		},
 		{
@@ -414,7 +483,7 @@ func TestUpdateAccount(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			LoadStatements(mock, test.expectedStatements)
-			update, err := UpdateAccount(db, test.updatedAccount.Name, test.updatedAccount)
+			update, err := UpdateAccount(db, MockHouseholdID, test.updatedAccount.Name, test.updatedAccount)
 			if err != nil && !test.wantErr {
 				t.Errorf(err.Error())
 			}
@@ -437,6 +506,7 @@ func TestDeleteAccount(t *testing.T) {
 	defer d.Close()
 
 	testAccount := Account{
+		ID:       1,
 		Name:     "test",
 		Category: Cash,
 		Class:    Asset,
@@ -460,8 +530,9 @@ func TestDeleteAccount(t *testing.T) {
 			accountName: "test2",
 			expectedStatements: []ExpectedStatement{
 				{
-					statement: "SELECT .* \"accounts\" WHERE name",
+					statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND name",
 					args: []driver.Value{
+						MockHouseholdID,
 						"test2",
 					},
 					returnError: gorm.ErrRecordNotFound,
@@ -473,7 +544,7 @@ func TestDeleteAccount(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			LoadStatements(mock, test.expectedStatements)
-			account, err := DeleteAccount(db, test.accountName)
+			account, err := DeleteAccount(db, MockHouseholdID, test.accountName)
 			if test.wantErr && (err == nil || err != gorm.ErrRecordNotFound) {
 				t.Errorf(err.Error())
 			}
@@ -486,3 +557,285 @@ func TestDeleteAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestAccountExistsByID(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	tests := []struct {
+		name               string
+		wantExist          bool
+		expectedStatements []ExpectedStatement
+	}{
+		{
+			name:               "should find an account that exists",
+			wantExist:          true,
+			expectedStatements: CreateStatementsAccountExistsByID(1),
+		},
+		{
+			name:               "should not find account that does not exist",
+			wantExist:          false,
+			expectedStatements: CreateStatementsAccountDoesNotExistByID(1),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			exists, err := AccountExistsByID(db, MockHouseholdID, 1)
+			if err != nil {
+				t.Errorf(err.Error())
+			}
+			assert.Equal(t, test.wantExist, exists)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetAccountWithValues(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	testAccount := Account{
+		ID:       1,
+		Name:     "test",
+		Class:    Asset,
+		Category: Cash,
+	}
+	numValues := 10
+
+	tests := []struct {
+		name               string
+		id                 uint
+		wantErr            bool
+		expectedStatements []ExpectedStatement
+	}{
+		{
+			name:               "should retrieve record if id exists",
+			id:                 1,
+			wantErr:            false,
+			expectedStatements: CreateStatementsGetAccountWithValues(testAccount, numValues),
+		},
+		{
+			name:               "should error if id does not exist",
+			id:                 2,
+			wantErr:            true,
+			expectedStatements: CreateStatementsAccountIDCannotBeFound(2),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			account, err := GetAccountWithValues(db, MockHouseholdID, test.id)
+			if test.wantErr && (err == nil || err != gorm.ErrRecordNotFound) {
+				t.Errorf("wanted record not found, got: %v", err)
+			}
+			if !test.wantErr {
+				assert.Equal(t, testAccount.ID, account.ID)
+				assert.Equal(t, numValues, len(account.Values))
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestUpdateAccountByID(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	testAccount := Account{
+		ID:       1,
+		Name:     "test",
+		Category: Cash,
+		Class:    Asset,
+	}
+
+	tests := []struct {
+		name               string
+		id                 uint
+		updatedAccount     Account
+		wantName           string
+		wantErr            error
+		expectedStatements []ExpectedStatement
+	}{
+		{
+			name: "should rename an existing account",
+			id:   1,
+			updatedAccount: Account{
+				Name:     "renamed",
+				Category: Cash,
+				Class:    Asset,
+			},
+			wantName: "renamed",
+			expectedStatements: CreateStatementsUpdateAccountByID(testAccount, true, []driver.Value{
+				"renamed",
+				Asset,
+				Cash,
+				AnyTime{},
+				testAccount.ID,
+			}),
+		},
+		{
+			name: "should not rename an account to a name in use",
+			id:   1,
+			updatedAccount: Account{
+				Name:     "taken",
+				Category: Cash,
+				Class:    Asset,
+			},
+			wantErr: ErrAccountNameTaken,
+			expectedStatements: append(
+				CreateStatementsGetAccountWithValues(testAccount, 0)[:1],
+				CreateStatementsAccountExists("taken")...,
+			),
+		},
+		{
+			name: "should fail if account does not exist",
+			id:   2,
+			updatedAccount: Account{
+				Name:     "test",
+				Category: Cash,
+				Class:    Asset,
+			},
+			wantErr:            gorm.ErrRecordNotFound,
+			expectedStatements: CreateStatementsAccountIDCannotBeFound(2),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			account, err := UpdateAccountByID(db, MockHouseholdID, test.id, test.updatedAccount)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("wanted: %v, got: %v", test.wantErr, err)
+			}
+			if test.wantErr == nil {
+				assert.Equal(t, test.wantName, account.Name)
+				assert.Equal(t, testAccount.ID, account.ID)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestDeleteAccountByID(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	testAccount := Account{
+		ID:       1,
+		Name:     "test",
+		Category: Cash,
+		Class:    Asset,
+	}
+
+	tests := []struct {
+		name               string
+		id                 uint
+		wantErr            bool
+		expectedStatements []ExpectedStatement
+	}{
+		{
+			name:               "successfully deletes existing account",
+			id:                 1,
+			wantErr:            false,
+			expectedStatements: CreateStatementsDeleteAccountByID(testAccount),
+		},
+		{
+			name:               "should fail if account does not exist",
+			id:                 2,
+			wantErr:            true,
+			expectedStatements: CreateStatementsAccountIDCannotBeFound(2),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			_, err := DeleteAccountByID(db, MockHouseholdID, test.id)
+			if test.wantErr && err != gorm.ErrRecordNotFound {
+				t.Errorf("wanted record not found, got: %v", err)
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf(err.Error())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetAccountByName(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	testAccount := Account{
+		ID:       1,
+		Name:     "test",
+		Class:    Asset,
+		Category: Cash,
+	}
+
+	tests := []struct {
+		name               string
+		wantErr            bool
+		expectedStatements []ExpectedStatement
+	}{
+		{
+			name:               "should retrieve record if name exists",
+			wantErr:            false,
+			expectedStatements: CreateStatementsGetAccountByNameWithValues(testAccount, 0)[:1],
+		},
+		{
+			name:               "should error if name does not exist",
+			wantErr:            true,
+			expectedStatements: CreateStatementsAccountCannotBeFound("test"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			account, err := GetAccountByName(db, MockHouseholdID, "test")
+			if test.wantErr && err != gorm.ErrRecordNotFound {
+				t.Errorf("wanted record not found, got: %v", err)
+			}
+			if !test.wantErr {
+				assert.Equal(t, testAccount.ID, account.ID)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
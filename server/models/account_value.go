@@ -8,21 +8,89 @@ import (
 	"gorm.io/gorm"
 )
 
+// AccountValue is a balance observation for an account. AsOf is the date the
+// balance was effective (e.g. a statement date) and may be earlier than
+// CreatedAt when entries are backdated. Values are kept to MaxPrecision
+// decimal places and only rounded to the account's precision when shown.
 type AccountValue struct {
-	ID          uint
-	AccountName string          `json:"account_name" binding:"required"`
-	Value       decimal.Decimal `json:"value" gorm:"type:decimal(19,2)"`
-	CreatedAt   time.Time
+	ID        uint            `json:"id"`
+	AccountID uint            `json:"account_id" gorm:"index" binding:"required"`
+	Value     decimal.Decimal `json:"value" gorm:"type:decimal(28,8)"`
+	AsOf      time.Time       `json:"as_of" gorm:"index"`
+	CreatedAt time.Time
 }
 
-func CreateAccountValue(db *gorm.DB, av AccountValue) error {
-	if exists, err := AccountExists(db, av.AccountName); !exists {
-		return fmt.Errorf(`account %s does not exist`, av.AccountName)
+// CreateAccountValue records a value for one of the household's accounts.
+func CreateAccountValue(db *gorm.DB, householdID uint, av AccountValue) (AccountValue, error) {
+	if exists, err := AccountExistsByID(db, householdID, av.AccountID); !exists {
+		return av, fmt.Errorf(`account %d does not exist`, av.AccountID)
 	} else if err != nil {
-		return err
+		return av, err
 	}
 
-	av.Value = av.Value.Round(2)
+	if av.AsOf.IsZero() {
+		av.AsOf = time.Now()
+	}
+	av.Value = av.Value.Round(MaxPrecision)
 	result := db.Create(&av)
-	return result.Error
+	return av, result.Error
+}
+
+// AccountValueExists reports whether the account already has the same value
+// recorded as of the same time.
+func AccountValueExists(db *gorm.DB, householdID uint, av AccountValue) (bool, error) {
+	count := int64(0)
+	result := db.Model(&AccountValue{}).
+		Where("account_id IN (SELECT id FROM accounts WHERE household_id = ?)", householdID).
+		Where("account_id = ? AND as_of = ? AND value = ?", av.AccountID, av.AsOf, av.Value.Round(MaxPrecision)).
+		Count(&count)
+	return count > 0, result.Error
+}
+
+// GetAccountValue returns a value of one of the household's accounts. Values
+// of deleted accounts and of other households are not found.
+func GetAccountValue(db *gorm.DB, householdID, id uint) (AccountValue, error) {
+	var av AccountValue
+	result := db.First(&av, id)
+	if result.Error != nil {
+		return av, result.Error
+	}
+
+	if exists, err := AccountExistsByID(db, householdID, av.AccountID); err != nil {
+		return av, err
+	} else if !exists {
+		return av, fmt.Errorf(`account %d does not exist: %w`, av.AccountID, gorm.ErrRecordNotFound)
+	}
+	return av, nil
+}
+
+func UpdateAccountValue(db *gorm.DB, householdID, id uint, updates AccountValue) (AccountValue, error) {
+	av, err := GetAccountValue(db, householdID, id)
+	if err != nil {
+		return av, err
+	}
+
+	if updates.AccountID != 0 && updates.AccountID != av.AccountID {
+		if exists, err := AccountExistsByID(db, householdID, updates.AccountID); err != nil {
+			return av, err
+		} else if !exists {
+			return av, fmt.Errorf(`account %d does not exist`, updates.AccountID)
+		}
+	}
+
+	if !updates.Value.IsZero() {
+		updates.Value = updates.Value.Round(MaxPrecision)
+	}
+	result := db.Model(&av).Updates(&updates)
+	return av, result.Error
+}
+
+func DeleteAccountValue(db *gorm.DB, householdID, id uint) (AccountValue, error) {
+	av, err := GetAccountValue(db, householdID, id)
+	if err != nil {
+		return av, err
+	}
+
+	result := db.Delete(&av)
+	return av, result.Error
 }
@@ -1,9 +1,14 @@
 package models
 
 import (
+	"database/sql/driver"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 func TestCreateAccountValue(t *testing.T) {
@@ -15,8 +20,8 @@ func TestCreateAccountValue(t *testing.T) {
 	defer d.Close()
 
 	testAccountValue := AccountValue{
-		AccountName: "test",
-		Value:       decimal.NewFromFloat(1.01),
+		AccountID: 1,
+		Value:     decimal.NewFromFloat(1.01),
 	}
 
 	tests := []struct {
@@ -31,24 +36,254 @@ func TestCreateAccountValue(t *testing.T) {
 			accountValue:       testAccountValue,
 			expectedStatements: CreateStatementsCreateAccountValue(testAccountValue),
 		},
+		{
+			name:    "keeps a backdated as of date",
+			wantErr: false,
+			accountValue: AccountValue{
+				AccountID: 1,
+				Value:     decimal.NewFromFloat(1.01),
+				AsOf:      time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC),
+			},
+			expectedStatements: CreateStatementsCreateAccountValue(AccountValue{
+				AccountID: 1,
+				Value:     decimal.NewFromFloat(1.01),
+				AsOf:      time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC),
+			}),
+		},
 		{
 			name:    "fails to create value for account that doesn't exist",
 			wantErr: true,
 			accountValue: AccountValue{
-				AccountName: "test",
-				Value:       decimal.NewFromFloat(1.01),
+				AccountID: 1,
+				Value:     decimal.NewFromFloat(1.01),
+			},
+			expectedStatements: CreateStatementsAccountDoesNotExistByID(testAccountValue.AccountID),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			_, err := CreateAccountValue(db, MockHouseholdID, test.accountValue)
+			if err != nil && !test.wantErr {
+				t.Errorf(err.Error())
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetAccountValue(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	testAccountValue := AccountValue{
+		ID:        1,
+		AccountID: 1,
+		Value:     decimal.NewFromFloat(1.01),
+		AsOf:      time.Now(),
+	}
+
+	tests := []struct {
+		name               string
+		id                 uint
+		expectedStatements []ExpectedStatement
+		wantErr            bool
+	}{
+		{
+			name:               "should retrieve an existing account value",
+			id:                 1,
+			wantErr:            false,
+			expectedStatements: CreateStatementsGetAccountValue(testAccountValue),
+		},
+		{
+			name:               "should error if account value does not exist",
+			id:                 2,
+			wantErr:            true,
+			expectedStatements: CreateStatementsAccountValueCannotBeFound(2),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			av, err := GetAccountValue(db, MockHouseholdID, test.id)
+			if test.wantErr && (err == nil || err != gorm.ErrRecordNotFound) {
+				t.Errorf("wanted record not found, got: %v", err)
+			}
+			if !test.wantErr {
+				assert.Equal(t, testAccountValue.ID, av.ID)
+				assert.Equal(t, testAccountValue.AccountID, av.AccountID)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestUpdateAccountValue(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	testAccountValue := AccountValue{
+		ID:        1,
+		AccountID: 1,
+		Value:     decimal.NewFromFloat(1.01),
+		AsOf:      time.Now(),
+	}
+	asOf := time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		id                 uint
+		updates            AccountValue
+		expectedStatements []ExpectedStatement
+		wantErr            bool
+	}{
+		{
+			name: "should update the value and as of date",
+			id:   1,
+			updates: AccountValue{
+				Value: decimal.NewFromFloat(2.5),
+				AsOf:  asOf,
 			},
-			expectedStatements: CreateStatementsAccountCannotBeFound(testAccountValue.AccountName),
+			wantErr: false,
+			expectedStatements: CreateStatementsUpdateAccountValue(testAccountValue, []driver.Value{
+				decimal.NewFromFloat(2.5).Round(2),
+				asOf,
+				testAccountValue.ID,
+			}),
+		},
+		{
+			name: "should error if account value does not exist",
+			id:   2,
+			updates: AccountValue{
+				Value: decimal.NewFromFloat(2.5),
+			},
+			wantErr:            true,
+			expectedStatements: CreateStatementsAccountValueCannotBeFound(2),
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			LoadStatements(mock, test.expectedStatements)
-			err := CreateAccountValue(db, test.accountValue)
+			av, err := UpdateAccountValue(db, MockHouseholdID, test.id, test.updates)
 			if err != nil && !test.wantErr {
 				t.Errorf(err.Error())
 			}
+			if !test.wantErr {
+				assert.Equal(t, test.updates.Value.Round(2).String(), av.Value.String())
+				assert.Equal(t, asOf, av.AsOf)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestDeleteAccountValue(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	testAccountValue := AccountValue{
+		ID:        1,
+		AccountID: 1,
+		Value:     decimal.NewFromFloat(1.01),
+		AsOf:      time.Now(),
+	}
+
+	tests := []struct {
+		name               string
+		id                 uint
+		expectedStatements []ExpectedStatement
+		wantErr            bool
+	}{
+		{
+			name:               "should delete an existing account value",
+			id:                 1,
+			wantErr:            false,
+			expectedStatements: CreateStatementsDeleteAccountValue(testAccountValue),
+		},
+		{
+			name:               "should error if account value does not exist",
+			id:                 2,
+			wantErr:            true,
+			expectedStatements: CreateStatementsAccountValueCannotBeFound(2),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			_, err := DeleteAccountValue(db, MockHouseholdID, test.id)
+			if err != nil && !test.wantErr {
+				t.Errorf(err.Error())
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestAccountValueExists(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	av := AccountValue{
+		AccountID: 1,
+		Value:     decimal.NewFromFloat(1.01),
+		AsOf:      time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name      string
+		count     int
+		wantExist bool
+	}{
+		{name: "should find a recorded value", count: 1, wantExist: true},
+		{name: "should not find an unrecorded value", count: 0, wantExist: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, []ExpectedStatement{
+				{
+					statement:  "SELECT count(.+) FROM \"account_values\"",
+					args:       []driver.Value{MockHouseholdID, av.AccountID, av.AsOf, av.Value},
+					returnRows: sqlmock.NewRows([]string{"count"}).AddRow(test.count),
+				},
+			})
+			exists, err := AccountValueExists(db, MockHouseholdID, av)
+			if err != nil {
+				t.Errorf(err.Error())
+			}
+			assert.Equal(t, test.wantExist, exists)
 
 			if err := mock.ExpectationsWereMet(); err != nil {
 				t.Errorf("there were unfulfilled expectations: %s", err)
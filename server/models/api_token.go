@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TokenScope limits what an API token may do.
+type TokenScope string
+
+const (
+	// ScopeRead tokens may only read.
+	ScopeRead TokenScope = "read"
+	// ScopeWrite tokens may also create, change and delete.
+	ScopeWrite TokenScope = "write"
+)
+
+func (ts TokenScope) String() string {
+	return string(ts)
+}
+
+func ParseTokenScope(s string) (ts TokenScope, err error) {
+	scopes := map[TokenScope]struct{}{
+		ScopeRead:  {},
+		ScopeWrite: {},
+	}
+	scope := TokenScope(s)
+	_, ok := scopes[scope]
+	if !ok {
+		return ts, fmt.Errorf(`unknown or invalid token scope: %s`, s)
+	}
+	return scope, nil
+}
+
+// APIToken lets scripts use the API on behalf of a user without logging in.
+// As with sessions only a hash of the token is stored, and tokens are revoked
+// by deleting them.
+type APIToken struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`
+	UserID     uint       `json:"user_id" gorm:"index"`
+	User       User       `json:"-"`
+	Scope      TokenScope `json:"scope"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateAPIToken creates a token for the user and returns it. The token is not
+// stored and cannot be shown again.
+func CreateAPIToken(db *gorm.DB, userID uint, name string, scope TokenScope) (string, APIToken, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", APIToken{}, fmt.Errorf("no token name provided")
+	}
+	if _, err := ParseTokenScope(scope.String()); err != nil {
+		return "", APIToken{}, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", APIToken{}, err
+	}
+
+	apiToken := APIToken{
+		Name:      name,
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		Scope:     scope,
+	}
+	result := db.Create(&apiToken)
+	return token, apiToken, result.Error
+}
+
+// GetAPITokens returns the user's tokens, oldest first.
+func GetAPITokens(db *gorm.DB, userID uint) ([]APIToken, error) {
+	tokens := []APIToken{}
+	result := db.Where("user_id = ?", userID).Order("id").Find(&tokens)
+	return tokens, result.Error
+}
+
+// DeleteAPIToken revokes one of the user's tokens.
+func DeleteAPIToken(db *gorm.DB, userID, id uint) (APIToken, error) {
+	var apiToken APIToken
+	result := db.Where("user_id = ?", userID).First(&apiToken, id)
+	if result.Error != nil {
+		return apiToken, result.Error
+	}
+
+	result = db.Delete(&apiToken)
+	return apiToken, result.Error
+}
+
+// UseAPIToken returns the token and the user it belongs to, recording that it
+// was used.
+func UseAPIToken(db *gorm.DB, token string) (APIToken, error) {
+	var apiToken APIToken
+	result := db.Preload("User").Where("token_hash = ?", hashToken(token)).First(&apiToken)
+	if result.Error != nil {
+		return apiToken, result.Error
+	}
+
+	now := time.Now()
+	if result := db.Model(&APIToken{ID: apiToken.ID}).UpdateColumn("last_used_at", now); result.Error != nil {
+		return apiToken, result.Error
+	}
+	apiToken.LastUsedAt = &now
+	return apiToken, nil
+}
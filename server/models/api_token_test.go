@@ -0,0 +1,91 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+func TestParseTokenScope(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    TokenScope
+		wantErr bool
+	}{
+		{input: "read", want: ScopeRead},
+		{input: "write", want: ScopeWrite},
+		{input: "admin", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			scope, err := ParseTokenScope(test.input)
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.want, scope)
+		})
+	}
+}
+
+func TestCreateAPITokenInvalid(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	_, _, err = CreateAPIToken(db, 7, " ", ScopeRead)
+	assert.NotEqual(t, nil, err)
+	_, _, err = CreateAPIToken(db, 7, "cron", "admin")
+	assert.NotEqual(t, nil, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUseAPIToken(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	tokenColumns := []string{"id", "name", "token_hash", "user_id", "scope", "last_used_at", "created_at"}
+
+	mock.ExpectQuery("SELECT .* FROM \"api_tokens\" WHERE token_hash = ").
+		WithArgs(hashToken("token")).
+		WillReturnRows(sqlmock.NewRows(tokenColumns).AddRow(1, "cron", hashToken("token"), 7, "write", nil, time.Now()))
+	mock.ExpectQuery("SELECT .* FROM \"users\" WHERE \"users\".\"id\" = ").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(7, "admin", "", true, nil, nil))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE \"api_tokens\" SET \"last_used_at\"").
+		WithArgs(AnyTime{}, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	apiToken, err := UseAPIToken(db, "token")
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	assert.Equal(t, uint(7), apiToken.User.ID)
+	assert.Equal(t, ScopeWrite, apiToken.Scope)
+	assert.NotEqual(t, nil, apiToken.LastUsedAt)
+
+	mock.ExpectQuery("SELECT .* FROM \"api_tokens\" WHERE token_hash = ").
+		WithArgs(hashToken("revoked")).
+		WillReturnRows(sqlmock.NewRows(tokenColumns))
+
+	_, err = UseAPIToken(db, "revoked")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
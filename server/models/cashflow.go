@@ -0,0 +1,156 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// FlowType is the kind of money moving in or out of an account. Types are
+// named from the owner's side, so on a liability money paid in pays it down
+// and money taken out is borrowed.
+type FlowType string
+
+const (
+	// Contribution is money paid into the account, such as a paycheck
+	// deferral into a 401k or a payment toward a loan.
+	Contribution FlowType = "contribution"
+	// Withdrawal is money taken out of the account, or borrowed or charged on
+	// a liability.
+	Withdrawal FlowType = "withdrawal"
+	// Transfer is money moved between the account and another one, into the
+	// account when its amount is positive and out of it when negative.
+	Transfer FlowType = "transfer"
+	// Interest is interest or dividends paid to the account, or interest
+	// charged on a liability.
+	Interest FlowType = "interest"
+	// Fee is a fee charged to the account.
+	Fee FlowType = "fee"
+)
+
+func (ft FlowType) String() string {
+	return string(ft)
+}
+
+func ParseFlowType(s string) (ft FlowType, err error) {
+	switch flowType := FlowType(s); flowType {
+	case Contribution, Withdrawal, Transfer, Interest, Fee:
+		return flowType, nil
+	}
+	return ft, fmt.Errorf(`unknown or invalid cash flow type: %s, expected contribution, withdrawal, transfer, interest or fee`, s)
+}
+
+// IsExternal reports whether flows of the type bring money into or take it out
+// of the account from outside, rather than being earned or charged by it.
+func (ft FlowType) IsExternal() bool {
+	return ft == Contribution || ft == Withdrawal || ft == Transfer
+}
+
+// CashFlow is money moving in or out of an account on a date. Amount is
+// positive, except for transfers where its sign gives the direction.
+type CashFlow struct {
+	ID        uint            `json:"id"`
+	AccountID uint            `json:"account_id" gorm:"index"`
+	Type      FlowType        `json:"type" binding:"required"`
+	Amount    decimal.Decimal `json:"amount" gorm:"type:decimal(28,8)"`
+	Date      time.Time       `json:"date" gorm:"index"`
+	Memo      string          `json:"memo"`
+	CreatedAt time.Time
+}
+
+// Effect returns how much the flow changes the balance of an account of the
+// given class. The balance of a liability is what is owed, so contributions
+// lower it while withdrawals, interest and fees raise it.
+func (flow CashFlow) Effect(class AccountClass) decimal.Decimal {
+	if class == Liability {
+		if flow.Type == Contribution || flow.Type == Transfer {
+			return flow.Amount.Neg()
+		}
+		return flow.Amount
+	}
+	if flow.Type == Withdrawal || flow.Type == Fee {
+		return flow.Amount.Neg()
+	}
+	return flow.Amount
+}
+
+func ValidateCashFlow(flow CashFlow) error {
+	if flow.AccountID == 0 {
+		return fmt.Errorf("no account_id provided")
+	}
+	if _, err := ParseFlowType(flow.Type.String()); err != nil {
+		return err
+	}
+	if flow.Type == Transfer {
+		if flow.Amount.IsZero() {
+			return fmt.Errorf(`"amount" must not be 0`)
+		}
+	} else if !flow.Amount.IsPositive() {
+		return fmt.Errorf(`"amount" must be > 0, record money going out as a withdrawal or fee`)
+	}
+	return nil
+}
+
+// CreateCashFlow records a cash flow of one of the household's accounts, now
+// by default.
+func CreateCashFlow(db *gorm.DB, householdID uint, flow CashFlow) (CashFlow, error) {
+	if err := ValidateCashFlow(flow); err != nil {
+		return flow, err
+	}
+	if exists, err := AccountExistsByID(db, householdID, flow.AccountID); err != nil {
+		return flow, err
+	} else if !exists {
+		return flow, fmt.Errorf(`account %d does not exist`, flow.AccountID)
+	}
+
+	if flow.Date.IsZero() {
+		flow.Date = time.Now()
+	}
+	flow.Amount = flow.Amount.Round(MaxPrecision)
+	result := db.Create(&flow)
+	return flow, result.Error
+}
+
+// GetCashFlows returns the cash flows of one of the household's accounts,
+// newest first.
+func GetCashFlows(db *gorm.DB, householdID, accountID uint) ([]CashFlow, error) {
+	flows := []CashFlow{}
+	if exists, err := AccountExistsByID(db, householdID, accountID); err != nil {
+		return flows, err
+	} else if !exists {
+		return flows, fmt.Errorf(`account %d does not exist: %w`, accountID, gorm.ErrRecordNotFound)
+	}
+
+	result := db.Where("account_id = ?", accountID).Order("date DESC, id DESC").Find(&flows)
+	return flows, result.Error
+}
+
+// GetAllCashFlows returns the cash flows of every account in the household,
+// including deleted accounts, oldest first.
+func GetAllCashFlows(db *gorm.DB, householdID uint) ([]CashFlow, error) {
+	flows := []CashFlow{}
+	result := db.
+		Where("account_id IN (SELECT id FROM accounts WHERE household_id = ?)", householdID).
+		Order("date, id").
+		Find(&flows)
+	return flows, result.Error
+}
+
+// DeleteCashFlow deletes a cash flow of one of the household's accounts. Cash
+// flows of deleted accounts and of other households are not found.
+func DeleteCashFlow(db *gorm.DB, householdID, id uint) (CashFlow, error) {
+	var flow CashFlow
+	if err := db.First(&flow, id).Error; err != nil {
+		return flow, err
+	}
+	if exists, err := AccountExistsByID(db, householdID, flow.AccountID); err != nil {
+		return flow, err
+	} else if !exists {
+		return flow, fmt.Errorf(`account %d does not exist: %w`, flow.AccountID, gorm.ErrRecordNotFound)
+	}
+
+	result := db.Delete(&flow)
+	return flow, result.Error
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func TestValidateCashFlow(t *testing.T) {
+	tests := []struct {
+		name    string
+		flow    CashFlow
+		wantErr bool
+	}{
+		{name: "should accept a contribution", flow: CashFlow{AccountID: 1, Type: Contribution, Amount: decimal.NewFromInt(500)}},
+		{name: "should accept a transfer out", flow: CashFlow{AccountID: 1, Type: Transfer, Amount: decimal.NewFromInt(-500)}},
+		{name: "should error on a negative fee", flow: CashFlow{AccountID: 1, Type: Fee, Amount: decimal.NewFromInt(-5)}, wantErr: true},
+		{name: "should error on an empty transfer", flow: CashFlow{AccountID: 1, Type: Transfer}, wantErr: true},
+		{name: "should error on an unknown type", flow: CashFlow{AccountID: 1, Type: "dividend", Amount: decimal.NewFromInt(5)}, wantErr: true},
+		{name: "should error without an account", flow: CashFlow{Type: Interest, Amount: decimal.NewFromInt(5)}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.wantErr, ValidateCashFlow(test.flow) != nil)
+		})
+	}
+}
+
+func TestCashFlowEffect(t *testing.T) {
+	tests := []struct {
+		flowType FlowType
+		amount   int64
+		want     string
+		// wantOwed is the change in what is owed on a liability.
+		wantOwed string
+	}{
+		{flowType: Contribution, amount: 100, want: "100", wantOwed: "-100"},
+		{flowType: Withdrawal, amount: 100, want: "-100", wantOwed: "100"},
+		{flowType: Transfer, amount: -100, want: "-100", wantOwed: "100"},
+		{flowType: Interest, amount: 5, want: "5", wantOwed: "5"},
+		{flowType: Fee, amount: 5, want: "-5", wantOwed: "5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.flowType.String(), func(t *testing.T) {
+			flow := CashFlow{Type: test.flowType, Amount: decimal.NewFromInt(test.amount)}
+			assert.Equal(t, test.want, flow.Effect(Asset).String())
+			assert.Equal(t, test.wantOwed, flow.Effect(Liability).String())
+			assert.Equal(t, test.flowType != Interest && test.flowType != Fee, test.flowType.IsExternal())
+		})
+	}
+}
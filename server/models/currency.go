@@ -0,0 +1,123 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Currency is an ISO 4217 currency code such as USD or EUR.
+type Currency string
+
+// DefaultCurrency is the currency of accounts created without one, and the
+// currency net worth is reported in unless another is asked for.
+const DefaultCurrency Currency = "USD"
+
+// MaxPrecision is the number of decimal places values are stored with, enough
+// for assets such as bitcoin that are divided into 8 decimal places.
+const MaxPrecision int32 = 8
+
+// currencyPrecisions lists the currencies that are not shown to the cent.
+var currencyPrecisions = map[Currency]int32{
+	"BHD": 3,
+	"BTC": 8,
+	"CLP": 0,
+	"ETH": 8,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"VND": 0,
+}
+
+func (c Currency) String() string {
+	return string(c)
+}
+
+// Precision returns the number of decimal places amounts in the currency are
+// shown with, 2 unless the currency is known to use another.
+func (c Currency) Precision() int32 {
+	if precision, ok := currencyPrecisions[c]; ok {
+		return precision
+	}
+	return 2
+}
+
+// ParseCurrency accepts any three upper case letters. Codes are not checked
+// against the ISO 4217 list so that new or unusual currencies can be tracked.
+func ParseCurrency(s string) (c Currency, err error) {
+	if len(s) != 3 {
+		return c, fmt.Errorf(`unknown or invalid currency: %s`, s)
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return c, fmt.Errorf(`unknown or invalid currency: %s`, s)
+		}
+	}
+	return Currency(s), nil
+}
+
+// FXRate is the price of one unit of Base in Quote, in effect from Date until
+// the next rate for the same pair. Rates are shared by every household.
+type FXRate struct {
+	ID    uint            `json:"id"`
+	Date  time.Time       `json:"date" gorm:"uniqueIndex:idx_fx_rates_pair_date,priority:3"`
+	Base  Currency        `json:"base" gorm:"uniqueIndex:idx_fx_rates_pair_date,priority:1"`
+	Quote Currency        `json:"quote" gorm:"uniqueIndex:idx_fx_rates_pair_date,priority:2"`
+	Rate  decimal.Decimal `json:"rate" gorm:"type:decimal(19,8)"`
+}
+
+func ValidateFXRate(rate FXRate) error {
+	if _, err := ParseCurrency(rate.Base.String()); err != nil {
+		return err
+	}
+	if _, err := ParseCurrency(rate.Quote.String()); err != nil {
+		return err
+	}
+	if rate.Base == rate.Quote {
+		return fmt.Errorf("base and quote currency must differ, got %s", rate.Base)
+	}
+	if rate.Date.IsZero() {
+		return fmt.Errorf("no rate date provided")
+	}
+	if !rate.Rate.IsPositive() {
+		return fmt.Errorf(`"rate" must be > 0`)
+	}
+	return nil
+}
+
+// SaveFXRate stores the rate, replacing any rate already recorded for the same
+// pair on the same date.
+func SaveFXRate(db *gorm.DB, rate FXRate) (FXRate, error) {
+	if err := ValidateFXRate(rate); err != nil {
+		return rate, err
+	}
+
+	rate.Rate = rate.Rate.Round(8)
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate"}),
+	}).Create(&rate)
+	return rate, result.Error
+}
+
+// GetAllFXRates returns every rate, oldest first.
+func GetAllFXRates(db *gorm.DB) ([]FXRate, error) {
+	rates := []FXRate{}
+	result := db.Order("date, id").Find(&rates)
+	return rates, result.Error
+}
+
+// GetFXRates returns every rate quoted in either direction between the
+// currency and another, oldest first.
+func GetFXRates(db *gorm.DB, currency Currency) ([]FXRate, error) {
+	rates := []FXRate{}
+	result := db.Where("base = ? OR quote = ?", currency, currency).Order("date, id").Find(&rates)
+	return rates, result.Error
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Currency
+		wantErr bool
+	}{
+		{input: "USD", want: "USD"},
+		{input: "EUR", want: "EUR"},
+		{input: "usd", wantErr: true},
+		{input: "US", wantErr: true},
+		{input: "US1", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			currency, err := ParseCurrency(test.input)
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.want, currency)
+		})
+	}
+}
+
+func TestCurrencyPrecision(t *testing.T) {
+	assert.Equal(t, int32(2), Currency("USD").Precision())
+	assert.Equal(t, int32(2), Currency("XYZ").Precision())
+	assert.Equal(t, int32(0), Currency("JPY").Precision())
+	assert.Equal(t, int32(8), Currency("BTC").Precision())
+}
+
+func TestAccountValuePrecision(t *testing.T) {
+	zero := int32(0)
+	assert.Equal(t, int32(2), Account{}.ValuePrecision())
+	assert.Equal(t, int32(3), Account{Currency: "KWD"}.ValuePrecision())
+	assert.Equal(t, int32(0), Account{Currency: "BTC", Precision: &zero}.ValuePrecision())
+}
+
+func TestValidateFXRate(t *testing.T) {
+	date := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rate := decimal.RequireFromString("1.0712")
+
+	tests := []struct {
+		name    string
+		rate    FXRate
+		wantErr bool
+	}{
+		{
+			name:    "should accept a rate between two currencies",
+			rate:    FXRate{Date: date, Base: "EUR", Quote: "USD", Rate: rate},
+			wantErr: false,
+		},
+		{
+			name:    "should error if a currency is invalid",
+			rate:    FXRate{Date: date, Base: "euro", Quote: "USD", Rate: rate},
+			wantErr: true,
+		},
+		{
+			name:    "should error if both currencies are the same",
+			rate:    FXRate{Date: date, Base: "USD", Quote: "USD", Rate: rate},
+			wantErr: true,
+		},
+		{
+			name:    "should error without a date",
+			rate:    FXRate{Base: "EUR", Quote: "USD", Rate: rate},
+			wantErr: true,
+		},
+		{
+			name:    "should error if the rate is not positive",
+			rate:    FXRate{Date: date, Base: "EUR", Quote: "USD", Rate: decimal.Zero},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateFXRate(test.rate)
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSecurityExists is returned when creating a security with a symbol that is
+// already in use.
+var ErrSecurityExists = errors.New("security already exists")
+
+// Security is something an account can hold a quantity of, such as a stock or
+// a fund, identified by its ticker symbol. Securities and their prices are
+// shared by every household.
+type Security struct {
+	ID       uint     `json:"id"`
+	Symbol   string   `json:"symbol" gorm:"uniqueIndex" binding:"required"`
+	Name     string   `json:"name"`
+	Currency Currency `json:"currency"`
+}
+
+// ParseSymbol upper-cases a ticker symbol and checks it only holds letters,
+// digits, dots and dashes, as in BRK.B or BTC-USD.
+func ParseSymbol(s string) (string, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(s))
+	if symbol == "" || len(symbol) > 20 {
+		return "", fmt.Errorf(`invalid symbol: %q`, s)
+	}
+	for _, r := range symbol {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '.' && r != '-' {
+			return "", fmt.Errorf(`invalid symbol: %q`, s)
+		}
+	}
+	return symbol, nil
+}
+
+func ValidateSecurity(security Security) error {
+	if _, err := ParseSymbol(security.Symbol); err != nil {
+		return err
+	}
+	if security.Currency != "" {
+		if _, err := ParseCurrency(security.Currency.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateSecurity stores a new security. The symbol is upper-cased and the
+// currency defaults to DefaultCurrency.
+func CreateSecurity(db *gorm.DB, security Security) (Security, error) {
+	if err := ValidateSecurity(security); err != nil {
+		return security, err
+	}
+	security.Symbol, _ = ParseSymbol(security.Symbol)
+	if security.Currency == "" {
+		security.Currency = DefaultCurrency
+	}
+
+	count := int64(0)
+	if err := db.Model(&Security{}).Where("symbol = ?", security.Symbol).Count(&count).Error; err != nil {
+		return security, err
+	}
+	if count > 0 {
+		return security, fmt.Errorf("%w: %s", ErrSecurityExists, security.Symbol)
+	}
+
+	result := db.Create(&security)
+	return security, result.Error
+}
+
+// GetSecurities returns every security ordered by symbol.
+func GetSecurities(db *gorm.DB) ([]Security, error) {
+	securities := []Security{}
+	result := db.Order("symbol").Find(&securities)
+	return securities, result.Error
+}
+
+// GetSecuritiesByID returns the securities with the given IDs ordered by
+// symbol.
+func GetSecuritiesByID(db *gorm.DB, ids []uint) ([]Security, error) {
+	securities := []Security{}
+	if len(ids) == 0 {
+		return securities, nil
+	}
+	result := db.Where("id IN ?", ids).Order("symbol").Find(&securities)
+	return securities, result.Error
+}
+
+func GetSecurity(db *gorm.DB, id uint) (Security, error) {
+	var security Security
+	result := db.First(&security, id)
+	return security, result.Error
+}
+
+func GetSecurityBySymbol(db *gorm.DB, symbol string) (Security, error) {
+	var security Security
+	result := db.Where("symbol = ?", strings.ToUpper(symbol)).First(&security)
+	return security, result.Error
+}
+
+// Price is the price of one unit of a security on Date, in the security's
+// currency. It is in effect until the security's next price.
+type Price struct {
+	ID         uint            `json:"id"`
+	SecurityID uint            `json:"security_id" gorm:"uniqueIndex:idx_prices_security_date,priority:1"`
+	Date       time.Time       `json:"date" gorm:"uniqueIndex:idx_prices_security_date,priority:2"`
+	Price      decimal.Decimal `json:"price" gorm:"type:decimal(28,8)"`
+}
+
+func ValidatePrice(price Price) error {
+	if price.SecurityID == 0 {
+		return fmt.Errorf("no security_id provided")
+	}
+	if price.Date.IsZero() {
+		return fmt.Errorf("no price date provided")
+	}
+	if price.Price.IsNegative() {
+		return fmt.Errorf(`"price" must be >= 0`)
+	}
+	return nil
+}
+
+// SavePrice stores the price, replacing any price already recorded for the
+// security on the same date.
+func SavePrice(db *gorm.DB, price Price) (Price, error) {
+	if err := ValidatePrice(price); err != nil {
+		return price, err
+	}
+
+	price.Price = price.Price.Round(MaxPrecision)
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "security_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price"}),
+	}).Create(&price)
+	return price, result.Error
+}
+
+// GetPrices returns the prices of the securities, oldest first.
+func GetPrices(db *gorm.DB, securityIDs []uint) ([]Price, error) {
+	prices := []Price{}
+	if len(securityIDs) == 0 {
+		return prices, nil
+	}
+	result := db.Where("security_id IN ?", securityIDs).Order("date, id").Find(&prices)
+	return prices, result.Error
+}
+
+// Position is the quantity of a security held in an account as of a date, in
+// effect until the account's next position in the same security. CostBasis is
+// what was paid for the whole quantity. A position with a quantity of zero
+// records that the security was sold.
+type Position struct {
+	ID         uint            `json:"id"`
+	AccountID  uint            `json:"account_id" gorm:"index"`
+	SecurityID uint            `json:"security_id" gorm:"index" binding:"required"`
+	Security   *Security       `json:"security,omitempty"`
+	Quantity   decimal.Decimal `json:"quantity" gorm:"type:decimal(28,8)"`
+	CostBasis  decimal.Decimal `json:"cost_basis" gorm:"type:decimal(28,8)"`
+	AsOf       time.Time       `json:"as_of" gorm:"index"`
+	CreatedAt  time.Time
+}
+
+func ValidatePosition(position Position) error {
+	if position.AccountID == 0 {
+		return fmt.Errorf("no account_id provided")
+	}
+	if position.SecurityID == 0 {
+		return fmt.Errorf("no security_id provided")
+	}
+	if position.Quantity.IsNegative() {
+		return fmt.Errorf(`"quantity" must be >= 0`)
+	}
+	if position.CostBasis.IsNegative() {
+		return fmt.Errorf(`"cost_basis" must be >= 0`)
+	}
+	return nil
+}
+
+// CheckPositionCurrency checks that a security can be held in the account:
+// holdings are valued in the account's currency, so the security must be
+// priced in it.
+func CheckPositionCurrency(account Account, security Security) error {
+	currency := account.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if security.Currency != currency {
+		return fmt.Errorf("%s is priced in %s, not in the account's currency %s", security.Symbol, security.Currency, currency)
+	}
+	return nil
+}
+
+// CreatePosition records a position in one of the household's accounts.
+func CreatePosition(db *gorm.DB, householdID uint, position Position) (Position, error) {
+	if err := ValidatePosition(position); err != nil {
+		return position, err
+	}
+	account, err := GetAccount(db, householdID, position.AccountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return position, fmt.Errorf(`account %d does not exist`, position.AccountID)
+	} else if err != nil {
+		return position, err
+	}
+	security, err := GetSecurity(db, position.SecurityID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return position, fmt.Errorf(`security %d does not exist`, position.SecurityID)
+	} else if err != nil {
+		return position, err
+	}
+	if err := CheckPositionCurrency(account, security); err != nil {
+		return position, err
+	}
+
+	if position.AsOf.IsZero() {
+		position.AsOf = time.Now()
+	}
+	position.Quantity = position.Quantity.Round(MaxPrecision)
+	position.CostBasis = position.CostBasis.Round(MaxPrecision)
+	position.Security = nil
+	if err := db.Create(&position).Error; err != nil {
+		return position, err
+	}
+	position.Security = &security
+	return position, nil
+}
+
+// GetPositions returns the positions of one of the household's accounts with
+// their securities, newest first.
+func GetPositions(db *gorm.DB, householdID, accountID uint) ([]Position, error) {
+	positions := []Position{}
+	if exists, err := AccountExistsByID(db, householdID, accountID); err != nil {
+		return positions, err
+	} else if !exists {
+		return positions, fmt.Errorf(`account %d does not exist: %w`, accountID, gorm.ErrRecordNotFound)
+	}
+
+	result := db.Preload("Security").Where("account_id = ?", accountID).Order("as_of DESC, id DESC").Find(&positions)
+	return positions, result.Error
+}
+
+// GetAllPositions returns the positions of every account in the household,
+// including deleted accounts so that past net worth still counts them.
+func GetAllPositions(db *gorm.DB, householdID uint) ([]Position, error) {
+	positions := []Position{}
+	result := db.
+		Where("account_id IN (SELECT id FROM accounts WHERE household_id = ?)", householdID).
+		Order("as_of, id").
+		Find(&positions)
+	return positions, result.Error
+}
+
+// DeletePosition deletes a position of one of the household's accounts.
+// Positions of deleted accounts and of other households are not found.
+func DeletePosition(db *gorm.DB, householdID, id uint) (Position, error) {
+	var position Position
+	if err := db.First(&position, id).Error; err != nil {
+		return position, err
+	}
+	if exists, err := AccountExistsByID(db, householdID, position.AccountID); err != nil {
+		return position, err
+	} else if !exists {
+		return position, fmt.Errorf(`account %d does not exist: %w`, position.AccountID, gorm.ErrRecordNotFound)
+	}
+
+	result := db.Delete(&position)
+	return position, result.Error
+}
+
+// Holding is a security held in an account, valued on a date.
+type Holding struct {
+	Security  Security        `json:"security"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	CostBasis decimal.Decimal `json:"cost_basis"`
+	// Price is the latest price on or before the date, nil when the security
+	// had not been priced yet.
+	Price *Price          `json:"price"`
+	Value decimal.Decimal `json:"value"`
+}
+
+// ValueHoldings values an account's positions on date: the latest position in
+// each security as of date, at the security's latest price on or before date.
+// Securities sold by then are left out, and those without a price yet are
+// valued at zero. Holdings are ordered by symbol.
+func ValueHoldings(positions []Position, prices []Price, date time.Time) []Holding {
+	latest := map[uint]Position{}
+	for _, position := range positions {
+		if position.AsOf.After(date) {
+			continue
+		}
+		current, ok := latest[position.SecurityID]
+		if !ok || position.AsOf.After(current.AsOf) || (position.AsOf.Equal(current.AsOf) && position.ID > current.ID) {
+			latest[position.SecurityID] = position
+		}
+	}
+
+	holdings := []Holding{}
+	for securityID, position := range latest {
+		if position.Quantity.IsZero() {
+			continue
+		}
+		holding := Holding{
+			Security:  Security{ID: securityID},
+			Quantity:  position.Quantity,
+			CostBasis: position.CostBasis,
+			Value:     decimal.Zero,
+		}
+		if position.Security != nil {
+			holding.Security = *position.Security
+		}
+		for i := range prices {
+			price := &prices[i]
+			if price.SecurityID != securityID || price.Date.After(date) {
+				continue
+			}
+			if holding.Price == nil || !price.Date.Before(holding.Price.Date) {
+				holding.Price = price
+			}
+		}
+		if holding.Price != nil {
+			holding.Value = position.Quantity.Mul(holding.Price.Price).Round(MaxPrecision)
+		}
+		holdings = append(holdings, holding)
+	}
+	sort.Slice(holdings, func(i, j int) bool {
+		if holdings[i].Security.Symbol == holdings[j].Security.Symbol {
+			return holdings[i].Security.ID < holdings[j].Security.ID
+		}
+		return holdings[i].Security.Symbol < holdings[j].Security.Symbol
+	})
+	return holdings
+}
+
+// HoldingsValue returns the sum of the values of the holdings.
+func HoldingsValue(holdings []Holding) decimal.Decimal {
+	total := decimal.Zero
+	for _, holding := range holdings {
+		total = total.Add(holding.Value)
+	}
+	return total
+}
+
+// HoldingValues derives the values of an account from its positions, as if
+// they had been recorded: one on every date from the first position on where
+// a position or the price of one of its securities changed, holding the value
+// of the holdings on that date. Values are returned oldest first.
+func HoldingValues(positions []Position, prices []Price) []AccountValue {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	sortedPositions := make([]Position, len(positions))
+	copy(sortedPositions, positions)
+	sort.SliceStable(sortedPositions, func(i, j int) bool {
+		if sortedPositions[i].AsOf.Equal(sortedPositions[j].AsOf) {
+			return sortedPositions[i].ID < sortedPositions[j].ID
+		}
+		return sortedPositions[i].AsOf.Before(sortedPositions[j].AsOf)
+	})
+	held := map[uint]bool{}
+	for _, position := range sortedPositions {
+		held[position.SecurityID] = true
+	}
+	sortedPrices := []Price{}
+	for _, price := range prices {
+		if held[price.SecurityID] {
+			sortedPrices = append(sortedPrices, price)
+		}
+	}
+	sort.SliceStable(sortedPrices, func(i, j int) bool { return sortedPrices[i].Date.Before(sortedPrices[j].Date) })
+
+	// Walk positions and prices together, keeping the value of each security
+	// and their total up to date as of the current date.
+	first := sortedPositions[0].AsOf
+	quantities := map[uint]decimal.Decimal{}
+	latestPrices := map[uint]decimal.Decimal{}
+	holdingValues := map[uint]decimal.Decimal{}
+	total := decimal.Zero
+	revalue := func(securityID uint) {
+		price, ok := latestPrices[securityID]
+		value := decimal.Zero
+		if ok {
+			value = quantities[securityID].Mul(price).Round(MaxPrecision)
+		}
+		total = total.Sub(holdingValues[securityID]).Add(value)
+		holdingValues[securityID] = value
+	}
+
+	values := []AccountValue{}
+	nextPosition, nextPrice := 0, 0
+	for nextPosition < len(sortedPositions) || nextPrice < len(sortedPrices) {
+		var date time.Time
+		if nextPosition < len(sortedPositions) {
+			date = sortedPositions[nextPosition].AsOf
+		}
+		if nextPrice < len(sortedPrices) && (nextPosition == len(sortedPositions) || sortedPrices[nextPrice].Date.Before(date)) {
+			date = sortedPrices[nextPrice].Date
+		}
+
+		for ; nextPosition < len(sortedPositions) && !sortedPositions[nextPosition].AsOf.After(date); nextPosition++ {
+			position := sortedPositions[nextPosition]
+			quantities[position.SecurityID] = position.Quantity
+			revalue(position.SecurityID)
+		}
+		for ; nextPrice < len(sortedPrices) && !sortedPrices[nextPrice].Date.After(date); nextPrice++ {
+			price := sortedPrices[nextPrice]
+			latestPrices[price.SecurityID] = price.Price
+			revalue(price.SecurityID)
+		}
+		if date.Before(first) {
+			continue
+		}
+
+		values = append(values, AccountValue{
+			AccountID: positions[0].AccountID,
+			Value:     total,
+			AsOf:      date,
+		})
+	}
+	return values
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func TestParseSymbol(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "VTI", want: "VTI"},
+		{input: " brk.b ", want: "BRK.B"},
+		{input: "BTC-USD", want: "BTC-USD"},
+		{input: "", wantErr: true},
+		{input: "VTI/X", wantErr: true},
+		{input: "ABCDEFGHIJKLMNOPQRSTU", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			symbol, err := ParseSymbol(test.input)
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.want, symbol)
+		})
+	}
+}
+
+func TestValidatePosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		position Position
+		wantErr  bool
+	}{
+		{
+			name:     "should accept a position",
+			position: Position{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(10), CostBasis: decimal.NewFromInt(2000)},
+			wantErr:  false,
+		},
+		{
+			name:     "should accept a sold position",
+			position: Position{AccountID: 1, SecurityID: 1},
+			wantErr:  false,
+		},
+		{
+			name:     "should error without a security",
+			position: Position{AccountID: 1, Quantity: decimal.NewFromInt(10)},
+			wantErr:  true,
+		},
+		{
+			name:     "should error on a negative quantity",
+			position: Position{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(-10)},
+			wantErr:  true,
+		},
+		{
+			name:     "should error on a negative cost basis",
+			position: Position{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(10), CostBasis: decimal.NewFromInt(-1)},
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidatePosition(test.position)
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestCheckPositionCurrency(t *testing.T) {
+	vti := Security{Symbol: "VTI", Currency: "USD"}
+	assert.Equal(t, nil, CheckPositionCurrency(Account{}, vti))
+	assert.Equal(t, nil, CheckPositionCurrency(Account{Currency: "USD"}, vti))
+	assert.NotEqual(t, nil, CheckPositionCurrency(Account{Currency: "EUR"}, vti))
+}
+
+// holdingsFixture is an account that bought 10 VTI on January 10th and 20
+// VXUS on January 20th, then sold its VXUS on February 10th. VXUS was first
+// priced on January 25th.
+func holdingsFixture() ([]Position, []Price) {
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2023, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	vti := &Security{ID: 1, Symbol: "VTI"}
+	vxus := &Security{ID: 2, Symbol: "VXUS"}
+	positions := []Position{
+		{ID: 1, AccountID: 7, SecurityID: 1, Security: vti, Quantity: decimal.NewFromInt(10), CostBasis: decimal.NewFromInt(2000), AsOf: day(time.January, 10)},
+		{ID: 2, AccountID: 7, SecurityID: 2, Security: vxus, Quantity: decimal.NewFromInt(20), CostBasis: decimal.NewFromInt(1100), AsOf: day(time.January, 20)},
+		{ID: 3, AccountID: 7, SecurityID: 2, Security: vxus, Quantity: decimal.Zero, AsOf: day(time.February, 10)},
+	}
+	prices := []Price{
+		{ID: 1, SecurityID: 1, Date: day(time.January, 1), Price: decimal.RequireFromString("200")},
+		{ID: 2, SecurityID: 1, Date: day(time.January, 31), Price: decimal.RequireFromString("204.5")},
+		{ID: 3, SecurityID: 2, Date: day(time.January, 25), Price: decimal.RequireFromString("55.125")},
+		{ID: 4, SecurityID: 1, Date: day(time.February, 28), Price: decimal.RequireFromString("199")},
+	}
+	return positions, prices
+}
+
+func TestValueHoldings(t *testing.T) {
+	positions, prices := holdingsFixture()
+
+	tests := []struct {
+		name    string
+		date    time.Time
+		symbols []string
+		value   string
+	}{
+		{name: "should hold nothing before the first position", date: time.Date(2023, time.January, 9, 0, 0, 0, 0, time.UTC), symbols: []string{}, value: "0"},
+		{name: "should value a security without a price at zero", date: time.Date(2023, time.January, 21, 0, 0, 0, 0, time.UTC), symbols: []string{"VTI", "VXUS"}, value: "2000"},
+		{name: "should use the latest price on the date", date: time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC), symbols: []string{"VTI", "VXUS"}, value: "3147.5"},
+		{name: "should leave out sold securities", date: time.Date(2023, time.February, 10, 0, 0, 0, 0, time.UTC), symbols: []string{"VTI"}, value: "2045"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			holdings := ValueHoldings(positions, prices, test.date)
+			symbols := []string{}
+			for _, holding := range holdings {
+				symbols = append(symbols, holding.Security.Symbol)
+			}
+			assert.Equal(t, test.symbols, symbols)
+			assert.Equal(t, test.value, HoldingsValue(holdings).String())
+		})
+	}
+}
+
+func TestHoldingValues(t *testing.T) {
+	positions, prices := holdingsFixture()
+
+	values := HoldingValues(positions, prices)
+	got := []string{}
+	for _, value := range values {
+		assert.Equal(t, uint(7), value.AccountID)
+		got = append(got, value.AsOf.Format("2006-01-02")+" "+value.Value.String())
+	}
+	assert.Equal(t, []string{
+		"2023-01-10 2000",
+		"2023-01-20 2000",
+		"2023-01-25 3102.5",
+		"2023-01-31 3147.5",
+		"2023-02-10 2045",
+		"2023-02-28 1990",
+	}, got)
+
+	// Prices and positions need not be in date order.
+	for i, j := 0, len(prices)-1; i < j; i, j = i+1, j-1 {
+		prices[i], prices[j] = prices[j], prices[i]
+	}
+	for i, j := 0, len(positions)-1; i < j; i, j = i+1, j-1 {
+		positions[i], positions[j] = positions[j], positions[i]
+	}
+	assert.Equal(t, values, HoldingValues(positions, prices))
+
+	assert.Equal(t, 0, len(HoldingValues(nil, prices)))
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InvitationDuration is how long an invitation to a household can be
+// accepted for.
+const InvitationDuration = 7 * 24 * time.Hour
+
+// ErrInvalidInvitation is returned when accepting an invitation that does not
+// exist, has expired or has already been accepted.
+var ErrInvalidInvitation = errors.New("invitation is invalid or has expired")
+
+// Household owns accounts and is shared by its members. Every account and
+// value is read and written on behalf of a single household, so members of
+// one household never see another's data.
+type Household struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Invitation lets someone create a user in a household. Like sessions, only a
+// hash of the invitation token is stored.
+type Invitation struct {
+	ID          uint       `json:"id"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex"`
+	HouseholdID uint       `json:"household_id" gorm:"index"`
+	InvitedByID uint       `json:"invited_by_id"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+
+	CreatedAt time.Time
+}
+
+func CreateHousehold(db *gorm.DB, name string) (Household, error) {
+	if strings.TrimSpace(name) == "" {
+		return Household{}, fmt.Errorf("no household name provided")
+	}
+	household := Household{Name: name}
+	result := db.Create(&household)
+	return household, result.Error
+}
+
+func GetHousehold(db *gorm.DB, id uint) (Household, error) {
+	var household Household
+	result := db.First(&household, id)
+	return household, result.Error
+}
+
+// FirstOrCreateHousehold returns the oldest household, creating one with the
+// given name if there are none. It is used by commands that run without a
+// logged in user.
+func FirstOrCreateHousehold(db *gorm.DB, name string) (Household, error) {
+	var household Household
+	result := db.Order("id").First(&household)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return CreateHousehold(db, name)
+	}
+	return household, result.Error
+}
+
+func GetHouseholdMembers(db *gorm.DB, householdID uint) ([]User, error) {
+	var users []User
+	result := db.Where("household_id = ?", householdID).Order("id").Find(&users)
+	return users, result.Error
+}
+
+// IsHouseholdMember reports whether the user belongs to the household.
+func IsHouseholdMember(db *gorm.DB, householdID, userID uint) (bool, error) {
+	count := int64(0)
+	result := db.Model(&User{}).Where("household_id = ? AND id = ?", householdID, userID).Count(&count)
+	return count > 0, result.Error
+}
+
+// CreateInvitation invites someone to the household and returns the token
+// they accept it with.
+func CreateInvitation(db *gorm.DB, householdID, invitedByID uint) (string, Invitation, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", Invitation{}, err
+	}
+
+	invitation := Invitation{
+		TokenHash:   hashToken(token),
+		HouseholdID: householdID,
+		InvitedByID: invitedByID,
+		ExpiresAt:   time.Now().Add(InvitationDuration),
+	}
+	result := db.Create(&invitation)
+	return token, invitation, result.Error
+}
+
+// AcceptInvitation creates a user in the household the invitation is for. An
+// invitation can only be accepted once.
+func AcceptInvitation(db *gorm.DB, token, username, password string) (User, error) {
+	var user User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var invitation Invitation
+		result := tx.Where("token_hash = ?", hashToken(token)).First(&invitation)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrInvalidInvitation
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if invitation.AcceptedAt != nil || !invitation.ExpiresAt.After(time.Now()) {
+			return ErrInvalidInvitation
+		}
+
+		var err error
+		user, err = CreateUser(tx, invitation.HouseholdID, username, password, false)
+		if err != nil {
+			return err
+		}
+
+		// Only mark the invitation accepted if nobody else did in the meantime.
+		result = tx.Model(&Invitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Update("accepted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidInvitation
+		}
+		return nil
+	})
+	return user, err
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+)
+
+func TestAcceptInvitationInvalid(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	invitationColumns := []string{"id", "token_hash", "household_id", "invited_by_id", "expires_at", "accepted_at", "created_at"}
+	accepted := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+	}{
+		{
+			name: "should not accept an unknown invitation",
+			rows: sqlmock.NewRows(invitationColumns),
+		},
+		{
+			name: "should not accept an expired invitation",
+			rows: sqlmock.NewRows(invitationColumns).
+				AddRow(1, hashToken("token"), MockHouseholdID, 1, time.Now().Add(-time.Minute), nil, time.Now()),
+		},
+		{
+			name: "should not accept an invitation twice",
+			rows: sqlmock.NewRows(invitationColumns).
+				AddRow(1, hashToken("token"), MockHouseholdID, 1, time.Now().Add(time.Hour), accepted, time.Now()),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT .* FROM \"invitations\" WHERE token_hash = ").
+				WithArgs(hashToken("token")).
+				WillReturnRows(test.rows)
+			mock.ExpectRollback()
+
+			_, err := AcceptInvitation(db, "token", "partner", "correct horse")
+			assert.Equal(t, ErrInvalidInvitation, err)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
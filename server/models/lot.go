@@ -0,0 +1,405 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// LotMethod decides which lots a sale is matched against.
+type LotMethod string
+
+const (
+	// FIFO sells the oldest lots first.
+	FIFO LotMethod = "fifo"
+	// SpecificID sells the lots listed in the sale's matches.
+	SpecificID LotMethod = "specific-id"
+)
+
+func (m LotMethod) String() string {
+	return string(m)
+}
+
+func ParseLotMethod(s string) (m LotMethod, err error) {
+	switch method := LotMethod(s); method {
+	case FIFO, SpecificID:
+		return method, nil
+	}
+	return m, fmt.Errorf(`unknown or invalid lot method: %s, expected fifo or specific-id`, s)
+}
+
+// ErrTradeOutOfOrder is returned when a buy or sale is dated before the latest
+// position the account holds in the security. Trades update that position, so
+// they must be recorded in the order they happened.
+var ErrTradeOutOfOrder = errors.New("trade is dated before the latest position in the security")
+
+// ErrInsufficientLots is returned when the lots open on the date of a sale do
+// not hold the quantity sold.
+var ErrInsufficientLots = errors.New("not enough open lots")
+
+// Lot is a purchase of a security in an account. Cost is what was paid for
+// the whole quantity, fees included. Sales take quantity out of lots through
+// their matches.
+type Lot struct {
+	ID         uint            `json:"id"`
+	AccountID  uint            `json:"account_id" gorm:"index"`
+	SecurityID uint            `json:"security_id" gorm:"index" binding:"required"`
+	Security   *Security       `json:"security,omitempty"`
+	Quantity   decimal.Decimal `json:"quantity" gorm:"type:decimal(28,8)"`
+	Cost       decimal.Decimal `json:"cost" gorm:"type:decimal(28,8)"`
+	AcquiredAt time.Time       `json:"acquired_at" gorm:"index"`
+	CreatedAt  time.Time
+}
+
+// Sale is a sale of a security from an account. Proceeds are what was received
+// for the whole quantity, net of fees. Matches records the lots the quantity
+// was taken from; for a SpecificID sale the lots and quantities are given by
+// the caller and their cost and proceeds filled in.
+type Sale struct {
+	ID         uint            `json:"id"`
+	AccountID  uint            `json:"account_id" gorm:"index"`
+	SecurityID uint            `json:"security_id" gorm:"index" binding:"required"`
+	Quantity   decimal.Decimal `json:"quantity" gorm:"type:decimal(28,8)"`
+	Proceeds   decimal.Decimal `json:"proceeds" gorm:"type:decimal(28,8)"`
+	SoldAt     time.Time       `json:"sold_at" gorm:"index"`
+	Method     LotMethod       `json:"method"`
+	Matches    []LotMatch      `json:"matches"`
+	CreatedAt  time.Time
+}
+
+// LotMatch is the part of a lot sold by a sale, with its share of the lot's
+// cost and of the sale's proceeds.
+type LotMatch struct {
+	ID       uint            `json:"id"`
+	SaleID   uint            `json:"sale_id" gorm:"index"`
+	LotID    uint            `json:"lot_id" gorm:"index"`
+	Quantity decimal.Decimal `json:"quantity" gorm:"type:decimal(28,8)"`
+	Cost     decimal.Decimal `json:"cost" gorm:"type:decimal(28,8)"`
+	Proceeds decimal.Decimal `json:"proceeds" gorm:"type:decimal(28,8)"`
+}
+
+func ValidateLot(lot Lot) error {
+	if lot.AccountID == 0 {
+		return fmt.Errorf("no account_id provided")
+	}
+	if lot.SecurityID == 0 {
+		return fmt.Errorf("no security_id provided")
+	}
+	if !lot.Quantity.IsPositive() {
+		return fmt.Errorf(`"quantity" must be > 0`)
+	}
+	if lot.Cost.IsNegative() {
+		return fmt.Errorf(`"cost" must be >= 0`)
+	}
+	return nil
+}
+
+// ValidateSale checks a sale before it is matched. A sale without a method is
+// matched FIFO.
+func ValidateSale(sale Sale) error {
+	if sale.AccountID == 0 {
+		return fmt.Errorf("no account_id provided")
+	}
+	if sale.SecurityID == 0 {
+		return fmt.Errorf("no security_id provided")
+	}
+	if !sale.Quantity.IsPositive() {
+		return fmt.Errorf(`"quantity" must be > 0`)
+	}
+	if sale.Proceeds.IsNegative() {
+		return fmt.Errorf(`"proceeds" must be >= 0`)
+	}
+
+	switch sale.Method {
+	case "", FIFO:
+		if len(sale.Matches) > 0 {
+			return fmt.Errorf("lots can only be chosen for specific-id sales")
+		}
+	case SpecificID:
+		total := decimal.Zero
+		for _, match := range sale.Matches {
+			if match.LotID == 0 {
+				return fmt.Errorf("no lot_id provided")
+			}
+			if !match.Quantity.IsPositive() {
+				return fmt.Errorf(`"quantity" of lot %d must be > 0`, match.LotID)
+			}
+			total = total.Add(match.Quantity)
+		}
+		if !total.Equal(sale.Quantity) {
+			return fmt.Errorf("quantities of the chosen lots must add up to %s, got %s", sale.Quantity, total)
+		}
+	default:
+		_, err := ParseLotMethod(sale.Method.String())
+		return err
+	}
+	return nil
+}
+
+// OpenQuantity returns the quantity of the lot not yet sold as of date by the
+// sales.
+func OpenQuantity(lot Lot, sales []Sale, date time.Time) decimal.Decimal {
+	open := lot.Quantity
+	for _, sale := range sales {
+		if sale.SoldAt.After(date) {
+			continue
+		}
+		for _, match := range sale.Matches {
+			if match.LotID == lot.ID {
+				open = open.Sub(match.Quantity)
+			}
+		}
+	}
+	return open
+}
+
+// MatchLots matches the sale against the lots of its account and security,
+// given the sales already recorded, and fills in its matches. Each match
+// carries its share of the lot's cost and of the sale's proceeds; the last
+// match takes whatever is left of the proceeds so they add up exactly.
+func MatchLots(sale Sale, lots []Lot, sales []Sale) (Sale, error) {
+	if sale.Method == "" {
+		sale.Method = FIFO
+	}
+
+	open := map[uint]decimal.Decimal{}
+	byID := map[uint]Lot{}
+	candidates := []Lot{}
+	for _, lot := range lots {
+		if lot.AccountID != sale.AccountID || lot.SecurityID != sale.SecurityID || lot.AcquiredAt.After(sale.SoldAt) {
+			continue
+		}
+		open[lot.ID] = OpenQuantity(lot, sales, sale.SoldAt)
+		byID[lot.ID] = lot
+		candidates = append(candidates, lot)
+	}
+
+	var wanted []LotMatch
+	switch sale.Method {
+	case FIFO:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].AcquiredAt.Equal(candidates[j].AcquiredAt) {
+				return candidates[i].ID < candidates[j].ID
+			}
+			return candidates[i].AcquiredAt.Before(candidates[j].AcquiredAt)
+		})
+		remaining := sale.Quantity
+		for _, lot := range candidates {
+			if !remaining.IsPositive() {
+				break
+			}
+			quantity := decimal.Min(remaining, open[lot.ID])
+			if !quantity.IsPositive() {
+				continue
+			}
+			wanted = append(wanted, LotMatch{LotID: lot.ID, Quantity: quantity})
+			remaining = remaining.Sub(quantity)
+		}
+		if remaining.IsPositive() {
+			return sale, fmt.Errorf("%w: %s short of the %s sold", ErrInsufficientLots, remaining, sale.Quantity)
+		}
+	case SpecificID:
+		for _, match := range sale.Matches {
+			if _, ok := byID[match.LotID]; !ok {
+				return sale, fmt.Errorf("%w: lot %d is not a lot of this security held before the sale", ErrInsufficientLots, match.LotID)
+			}
+			if match.Quantity.GreaterThan(open[match.LotID]) {
+				return sale, fmt.Errorf("%w: lot %d only has %s left", ErrInsufficientLots, match.LotID, open[match.LotID])
+			}
+			open[match.LotID] = open[match.LotID].Sub(match.Quantity)
+			wanted = append(wanted, LotMatch{LotID: match.LotID, Quantity: match.Quantity})
+		}
+	default:
+		return sale, fmt.Errorf(`unknown or invalid lot method: %s`, sale.Method)
+	}
+
+	proceeds := sale.Proceeds
+	for i := range wanted {
+		match := &wanted[i]
+		lot := byID[match.LotID]
+		match.Cost = lot.Cost.Mul(match.Quantity).Div(lot.Quantity).Round(MaxPrecision)
+		if i == len(wanted)-1 {
+			match.Proceeds = proceeds
+		} else {
+			match.Proceeds = sale.Proceeds.Mul(match.Quantity).Div(sale.Quantity).Round(MaxPrecision)
+			proceeds = proceeds.Sub(match.Proceeds)
+		}
+	}
+	sale.Matches = wanted
+	return sale, nil
+}
+
+// SaleCost returns the cost of the lots the sale was matched against.
+func SaleCost(sale Sale) decimal.Decimal {
+	cost := decimal.Zero
+	for _, match := range sale.Matches {
+		cost = cost.Add(match.Cost)
+	}
+	return cost
+}
+
+// IsLongTerm reports whether something acquired at one time and sold, or
+// valued, at another was held for more than a year.
+func IsLongTerm(acquiredAt, soldAt time.Time) bool {
+	return soldAt.After(acquiredAt.AddDate(1, 0, 0))
+}
+
+// TradePosition returns the position a trade on date leaves the account
+// with, given its latest position in the security before the trade, which
+// may be nil. The trade changes the quantity and cost basis by the amounts
+// given, negative for a sale.
+func TradePosition(latest *Position, accountID, securityID uint, quantity, cost decimal.Decimal, date time.Time) (Position, error) {
+	position := Position{AccountID: accountID, SecurityID: securityID, Quantity: quantity, CostBasis: cost, AsOf: date}
+	if latest == nil {
+		if quantity.IsNegative() {
+			return position, fmt.Errorf("%w: the account holds no position in the security", ErrInsufficientLots)
+		}
+		return position, nil
+	}
+	if date.Before(latest.AsOf) {
+		return position, fmt.Errorf("%w, %s", ErrTradeOutOfOrder, latest.AsOf.Format(time.RFC3339))
+	}
+	position.Quantity = latest.Quantity.Add(quantity)
+	position.CostBasis = latest.CostBasis.Add(cost)
+	if position.Quantity.IsNegative() {
+		return position, fmt.Errorf("%w: the account's position only holds %s", ErrInsufficientLots, latest.Quantity)
+	}
+	if position.CostBasis.IsNegative() || position.Quantity.IsZero() {
+		position.CostBasis = decimal.Zero
+	}
+	return position, nil
+}
+
+// checkHolding checks that the security exists and can be held in one of the
+// household's accounts.
+func checkHolding(db *gorm.DB, householdID, accountID, securityID uint) error {
+	account, err := GetAccount(db, householdID, accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf(`account %d does not exist`, accountID)
+	} else if err != nil {
+		return err
+	}
+	security, err := GetSecurity(db, securityID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf(`security %d does not exist`, securityID)
+	} else if err != nil {
+		return err
+	}
+	return CheckPositionCurrency(account, security)
+}
+
+// latestPosition returns the account's latest position in the security, or
+// nil if it has none.
+func latestPosition(db *gorm.DB, accountID, securityID uint) (*Position, error) {
+	var position Position
+	result := db.Where("account_id = ? AND security_id = ?", accountID, securityID).Order("as_of DESC, id DESC").Limit(1).Find(&position)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return &position, nil
+}
+
+// CreateLot records a purchase in one of the household's accounts, along with
+// the position it leaves the account with.
+func CreateLot(db *gorm.DB, householdID uint, lot Lot) (Lot, error) {
+	if err := ValidateLot(lot); err != nil {
+		return lot, err
+	}
+	if lot.AcquiredAt.IsZero() {
+		lot.AcquiredAt = time.Now()
+	}
+	lot.Quantity = lot.Quantity.Round(MaxPrecision)
+	lot.Cost = lot.Cost.Round(MaxPrecision)
+	lot.Security = nil
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := checkHolding(tx, householdID, lot.AccountID, lot.SecurityID); err != nil {
+			return err
+		}
+		latest, err := latestPosition(tx, lot.AccountID, lot.SecurityID)
+		if err != nil {
+			return err
+		}
+		position, err := TradePosition(latest, lot.AccountID, lot.SecurityID, lot.Quantity, lot.Cost, lot.AcquiredAt)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&lot).Error; err != nil {
+			return err
+		}
+		return tx.Create(&position).Error
+	})
+	return lot, err
+}
+
+// CreateSale records a sale from one of the household's accounts, matched
+// against its open lots, along with the position it leaves the account with.
+func CreateSale(db *gorm.DB, householdID uint, sale Sale) (Sale, error) {
+	if err := ValidateSale(sale); err != nil {
+		return sale, err
+	}
+	if sale.SoldAt.IsZero() {
+		sale.SoldAt = time.Now()
+	}
+	sale.Quantity = sale.Quantity.Round(MaxPrecision)
+	sale.Proceeds = sale.Proceeds.Round(MaxPrecision)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := checkHolding(tx, householdID, sale.AccountID, sale.SecurityID); err != nil {
+			return err
+		}
+		latest, err := latestPosition(tx, sale.AccountID, sale.SecurityID)
+		if err != nil {
+			return err
+		}
+
+		lots := []Lot{}
+		if err := tx.Where("account_id = ? AND security_id = ?", sale.AccountID, sale.SecurityID).Find(&lots).Error; err != nil {
+			return err
+		}
+		sales := []Sale{}
+		if err := tx.Preload("Matches").Where("account_id = ? AND security_id = ?", sale.AccountID, sale.SecurityID).Find(&sales).Error; err != nil {
+			return err
+		}
+		sale, err = MatchLots(sale, lots, sales)
+		if err != nil {
+			return err
+		}
+		position, err := TradePosition(latest, sale.AccountID, sale.SecurityID, sale.Quantity.Neg(), SaleCost(sale).Neg(), sale.SoldAt)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(&sale).Error; err != nil {
+			return err
+		}
+		return tx.Create(&position).Error
+	})
+	return sale, err
+}
+
+// GetAllLots returns the lots of every account in the household with their
+// securities, including those of deleted accounts, oldest first.
+func GetAllLots(db *gorm.DB, householdID uint) ([]Lot, error) {
+	lots := []Lot{}
+	result := db.Preload("Security").
+		Where("account_id IN (SELECT id FROM accounts WHERE household_id = ?)", householdID).
+		Order("acquired_at, id").
+		Find(&lots)
+	return lots, result.Error
+}
+
+// GetAllSales returns the sales of every account in the household with their
+// matches, including those of deleted accounts, oldest first.
+func GetAllSales(db *gorm.DB, householdID uint) ([]Sale, error) {
+	sales := []Sale{}
+	result := db.Preload("Matches").
+		Where("account_id IN (SELECT id FROM accounts WHERE household_id = ?)", householdID).
+		Order("sold_at, id").
+		Find(&sales)
+	return sales, result.Error
+}
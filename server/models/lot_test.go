@@ -0,0 +1,168 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func TestValidateSale(t *testing.T) {
+	tests := []struct {
+		name    string
+		sale    Sale
+		wantErr bool
+	}{
+		{
+			name:    "should accept a fifo sale",
+			sale:    Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(5), Proceeds: decimal.NewFromInt(1000)},
+			wantErr: false,
+		},
+		{
+			name: "should accept a specific-id sale",
+			sale: Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(5), Method: SpecificID, Matches: []LotMatch{
+				{LotID: 1, Quantity: decimal.NewFromInt(2)},
+				{LotID: 2, Quantity: decimal.NewFromInt(3)},
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "should error on a zero quantity",
+			sale:    Sale{AccountID: 1, SecurityID: 1},
+			wantErr: true,
+		},
+		{
+			name:    "should error on chosen lots for a fifo sale",
+			sale:    Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(5), Matches: []LotMatch{{LotID: 1, Quantity: decimal.NewFromInt(5)}}},
+			wantErr: true,
+		},
+		{
+			name:    "should error when chosen lots do not add up",
+			sale:    Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(5), Method: SpecificID, Matches: []LotMatch{{LotID: 1, Quantity: decimal.NewFromInt(4)}}},
+			wantErr: true,
+		},
+		{
+			name:    "should error on an unknown method",
+			sale:    Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(5), Method: "lifo"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.wantErr, ValidateSale(test.sale) != nil)
+		})
+	}
+}
+
+// lotsFixture returns two lots of 10 bought a month apart for 1000 and 1500,
+// and a sale of 4 from the first.
+func lotsFixture() ([]Lot, []Sale) {
+	jan := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	lots := []Lot{
+		{ID: 2, AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(10), Cost: decimal.NewFromInt(1500), AcquiredAt: jan.AddDate(0, 1, 0)},
+		{ID: 1, AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(10), Cost: decimal.NewFromInt(1000), AcquiredAt: jan},
+	}
+	sales := []Sale{
+		{ID: 1, AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(4), SoldAt: jan.AddDate(0, 2, 0), Matches: []LotMatch{
+			{LotID: 1, Quantity: decimal.NewFromInt(4), Cost: decimal.NewFromInt(400)},
+		}},
+	}
+	return lots, sales
+}
+
+func TestOpenQuantity(t *testing.T) {
+	lots, sales := lotsFixture()
+	assert.Equal(t, "10", OpenQuantity(lots[1], sales, sales[0].SoldAt.Add(-time.Second)).String())
+	assert.Equal(t, "6", OpenQuantity(lots[1], sales, sales[0].SoldAt).String())
+	assert.Equal(t, "10", OpenQuantity(lots[0], sales, sales[0].SoldAt).String())
+}
+
+func TestMatchLots(t *testing.T) {
+	lots, sales := lotsFixture()
+	soldAt := time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		sale    Sale
+		want    []LotMatch
+		wantErr error
+	}{
+		{
+			name: "should sell the oldest open lots first",
+			sale: Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(8), Proceeds: decimal.NewFromInt(1600), SoldAt: soldAt},
+			want: []LotMatch{
+				{LotID: 1, Quantity: decimal.NewFromInt(6), Cost: decimal.NewFromInt(600), Proceeds: decimal.NewFromInt(1200)},
+				{LotID: 2, Quantity: decimal.NewFromInt(2), Cost: decimal.NewFromInt(300), Proceeds: decimal.NewFromInt(400)},
+			},
+		},
+		{
+			name: "should sell the chosen lots",
+			sale: Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(3), Proceeds: decimal.NewFromInt(500), SoldAt: soldAt, Method: SpecificID, Matches: []LotMatch{
+				{LotID: 2, Quantity: decimal.NewFromInt(3)},
+			}},
+			want: []LotMatch{
+				{LotID: 2, Quantity: decimal.NewFromInt(3), Cost: decimal.NewFromInt(450), Proceeds: decimal.NewFromInt(500)},
+			},
+		},
+		{
+			name:    "should error selling more than the open lots hold",
+			sale:    Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(17), SoldAt: soldAt},
+			wantErr: ErrInsufficientLots,
+		},
+		{
+			name: "should error selling more than a chosen lot holds",
+			sale: Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(7), SoldAt: soldAt, Method: SpecificID, Matches: []LotMatch{
+				{LotID: 1, Quantity: decimal.NewFromInt(7)},
+			}},
+			wantErr: ErrInsufficientLots,
+		},
+		{
+			name:    "should not sell lots bought after the sale",
+			sale:    Sale{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(11), SoldAt: time.Date(2022, time.January, 15, 0, 0, 0, 0, time.UTC)},
+			wantErr: ErrInsufficientLots,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sale, err := MatchLots(test.sale, lots, sales)
+			assert.Equal(t, true, errors.Is(err, test.wantErr))
+			if test.wantErr != nil {
+				return
+			}
+			assert.Equal(t, len(test.want), len(sale.Matches))
+			for i, match := range sale.Matches {
+				assert.Equal(t, test.want[i].LotID, match.LotID)
+				assert.Equal(t, test.want[i].Quantity.String(), match.Quantity.String())
+				assert.Equal(t, test.want[i].Cost.String(), match.Cost.String())
+				assert.Equal(t, test.want[i].Proceeds.String(), match.Proceeds.String())
+			}
+		})
+	}
+}
+
+func TestIsLongTerm(t *testing.T) {
+	bought := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, false, IsLongTerm(bought, bought.AddDate(1, 0, 0)))
+	assert.Equal(t, true, IsLongTerm(bought, bought.AddDate(1, 0, 1)))
+}
+
+func TestTradePosition(t *testing.T) {
+	jan := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	latest := &Position{AccountID: 1, SecurityID: 1, Quantity: decimal.NewFromInt(10), CostBasis: decimal.NewFromInt(1000), AsOf: jan}
+
+	position, err := TradePosition(latest, 1, 1, decimal.NewFromInt(-4), decimal.NewFromInt(-400), jan.AddDate(0, 1, 0))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "6", position.Quantity.String())
+	assert.Equal(t, "600", position.CostBasis.String())
+
+	_, err = TradePosition(latest, 1, 1, decimal.NewFromInt(1), decimal.NewFromInt(100), jan.AddDate(0, 0, -1))
+	assert.Equal(t, true, errors.Is(err, ErrTradeOutOfOrder))
+	_, err = TradePosition(latest, 1, 1, decimal.NewFromInt(-11), decimal.Zero, jan)
+	assert.Equal(t, true, errors.Is(err, ErrInsufficientLots))
+	_, err = TradePosition(nil, 1, 1, decimal.NewFromInt(-1), decimal.Zero, jan)
+	assert.Equal(t, true, errors.Is(err, ErrInsufficientLots))
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OFXAccountMapping maps the account ID a financial institution uses in its
+// OFX/QFX exports to one of the household's accounts, so repeated imports of
+// statements for that account land on the same Account.
+type OFXAccountMapping struct {
+	ID           uint   `json:"id"`
+	HouseholdID  uint   `json:"household_id" gorm:"uniqueIndex:idx_ofx_account_mappings_household_ofx_account,priority:1"`
+	OFXAccountID string `json:"ofx_account_id" gorm:"uniqueIndex:idx_ofx_account_mappings_household_ofx_account,priority:2" binding:"required"`
+	AccountID    uint   `json:"account_id" binding:"required"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func GetOFXAccountMappings(db *gorm.DB, householdID uint) ([]OFXAccountMapping, error) {
+	var mappings []OFXAccountMapping
+	result := inHousehold(db, householdID).Order("ofx_account_id").Find(&mappings)
+	return mappings, result.Error
+}
+
+func GetOFXAccountMapping(db *gorm.DB, householdID uint, ofxAccountID string) (OFXAccountMapping, error) {
+	var mapping OFXAccountMapping
+	result := inHousehold(db, householdID).Where("ofx_account_id = ?", ofxAccountID).First(&mapping)
+	return mapping, result.Error
+}
+
+// SaveOFXAccountMapping maps an OFX account ID to one of the household's
+// accounts, replacing any existing mapping for the OFX account ID.
+func SaveOFXAccountMapping(db *gorm.DB, householdID uint, mapping OFXAccountMapping) (OFXAccountMapping, error) {
+	if mapping.OFXAccountID == "" {
+		return mapping, fmt.Errorf("no OFX account id provided")
+	}
+	if exists, err := AccountExistsByID(db, householdID, mapping.AccountID); err != nil {
+		return mapping, err
+	} else if !exists {
+		return mapping, fmt.Errorf(`account %d does not exist`, mapping.AccountID)
+	}
+
+	existing, err := GetOFXAccountMapping(db, householdID, mapping.OFXAccountID)
+	if err == gorm.ErrRecordNotFound {
+		mapping.ID = 0
+		mapping.HouseholdID = householdID
+		result := db.Create(&mapping)
+		return mapping, result.Error
+	}
+	if err != nil {
+		return mapping, err
+	}
+
+	result := db.Model(&existing).Update("account_id", mapping.AccountID)
+	return existing, result.Error
+}
+
+func DeleteOFXAccountMapping(db *gorm.DB, householdID, id uint) (OFXAccountMapping, error) {
+	var mapping OFXAccountMapping
+	result := inHousehold(db, householdID).First(&mapping, id)
+	if result.Error != nil {
+		return mapping, result.Error
+	}
+
+	result = db.Delete(&mapping)
+	return mapping, result.Error
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+var ofxAccountMappingColumns = []string{"id", "ofx_account_id", "account_id", "created_at", "updated_at"}
+
+func TestGetOFXAccountMapping(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	tests := []struct {
+		name               string
+		wantErr            bool
+		expectedStatements []ExpectedStatement
+	}{
+		{
+			name:    "should find a mapped OFX account",
+			wantErr: false,
+			expectedStatements: []ExpectedStatement{
+				{
+					statement:  "SELECT .* FROM \"ofx_account_mappings\" WHERE household_id = .+ AND ofx_account_id",
+					args:       []driver.Value{MockHouseholdID, "0001234"},
+					returnRows: sqlmock.NewRows(ofxAccountMappingColumns).AddRow(1, "0001234", 3, nil, nil),
+				},
+			},
+		},
+		{
+			name:    "should error for an unmapped OFX account",
+			wantErr: true,
+			expectedStatements: []ExpectedStatement{
+				{
+					statement:   "SELECT .* FROM \"ofx_account_mappings\" WHERE household_id = .+ AND ofx_account_id",
+					args:        []driver.Value{MockHouseholdID, "0001234"},
+					returnError: gorm.ErrRecordNotFound,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			mapping, err := GetOFXAccountMapping(db, MockHouseholdID, "0001234")
+			if test.wantErr && err != gorm.ErrRecordNotFound {
+				t.Errorf("wanted record not found, got: %v", err)
+			}
+			if !test.wantErr {
+				assert.Equal(t, uint(3), mapping.AccountID)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestSaveOFXAccountMapping(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	tests := []struct {
+		name               string
+		mapping            OFXAccountMapping
+		wantErr            bool
+		expectedStatements []ExpectedStatement
+	}{
+		{
+			name:    "should create a new mapping",
+			mapping: OFXAccountMapping{OFXAccountID: "0001234", AccountID: 3},
+			wantErr: false,
+			expectedStatements: append(
+				CreateStatementsAccountExistsByID(3),
+				ExpectedStatement{
+					statement:   "SELECT .* FROM \"ofx_account_mappings\" WHERE household_id = .+ AND ofx_account_id",
+					args:        []driver.Value{MockHouseholdID, "0001234"},
+					returnError: gorm.ErrRecordNotFound,
+				},
+				ExpectedStatement{
+					statement:    "INSERT INTO \"ofx_account_mappings\"",
+					args:         []driver.Value{MockHouseholdID, "0001234", 3, AnyTime{}, AnyTime{}},
+					returnResult: sqlmock.NewResult(1, 1),
+				},
+			),
+		},
+		{
+			name:    "should replace an existing mapping",
+			mapping: OFXAccountMapping{OFXAccountID: "0001234", AccountID: 4},
+			wantErr: false,
+			expectedStatements: append(
+				CreateStatementsAccountExistsByID(4),
+				ExpectedStatement{
+					statement:  "SELECT .* FROM \"ofx_account_mappings\" WHERE household_id = .+ AND ofx_account_id",
+					args:       []driver.Value{MockHouseholdID, "0001234"},
+					returnRows: sqlmock.NewRows(ofxAccountMappingColumns).AddRow(1, "0001234", 3, nil, nil),
+				},
+				ExpectedStatement{
+					statement:    "UPDATE \"ofx_account_mappings\"",
+					args:         []driver.Value{4, AnyTime{}, 1},
+					returnResult: sqlmock.NewResult(1, 1),
+				},
+			),
+		},
+		{
+			name:               "should not map to an account that does not exist",
+			mapping:            OFXAccountMapping{OFXAccountID: "0001234", AccountID: 5},
+			wantErr:            true,
+			expectedStatements: CreateStatementsAccountDoesNotExistByID(5),
+		},
+		{
+			name:               "should not map an empty OFX account id",
+			mapping:            OFXAccountMapping{AccountID: 3},
+			wantErr:            true,
+			expectedStatements: []ExpectedStatement{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, test.expectedStatements)
+			mapping, err := SaveOFXAccountMapping(db, MockHouseholdID, test.mapping)
+			assert.Equal(t, test.wantErr, err != nil)
+			if !test.wantErr {
+				assert.Equal(t, test.mapping.AccountID, mapping.AccountID)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestDeleteOFXAccountMapping(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	LoadStatements(mock, []ExpectedStatement{
+		{
+			statement:  "SELECT .* FROM \"ofx_account_mappings\" WHERE household_id = .+ AND \"ofx_account_mappings\".\"id\"",
+			args:       []driver.Value{MockHouseholdID, 1},
+			returnRows: sqlmock.NewRows(ofxAccountMappingColumns).AddRow(1, "0001234", 3, nil, nil),
+		},
+		{
+			statement:    "DELETE FROM \"ofx_account_mappings\"",
+			args:         []driver.Value{1},
+			returnResult: sqlmock.NewResult(1, 1),
+		},
+	})
+
+	mapping, err := DeleteOFXAccountMapping(db, MockHouseholdID, 1)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	assert.Equal(t, "0001234", mapping.OFXAccountID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrNoReturn is returned when a return cannot be worked out, for example
+// because nothing was invested over the period.
+var ErrNoReturn = errors.New("no return for the period")
+
+// Valuation is what a set of accounts was worth at a point in time.
+type Valuation struct {
+	Date  time.Time
+	Value decimal.Decimal
+}
+
+// ExternalFlow is money moved into a set of accounts from outside it, or out
+// of it when negative.
+type ExternalFlow struct {
+	Date   time.Time
+	Amount decimal.Decimal
+}
+
+// TimeWeightedReturn links the returns between consecutive valuations, which
+// must be in date order, into the return over the whole period. The return
+// between two valuations is the Modified Dietz return, weighting each flow
+// dated after the first and on or before the second by how much of the period
+// it was invested for. Periods that start with nothing invested are skipped.
+func TimeWeightedReturn(valuations []Valuation, flows []ExternalFlow) (decimal.Decimal, error) {
+	one := decimal.NewFromInt(1)
+	growth := one
+	linked := false
+	for i := 1; i < len(valuations); i++ {
+		start, end := valuations[i-1], valuations[i]
+		length := end.Date.Sub(start.Date).Seconds()
+		if length <= 0 {
+			continue
+		}
+
+		net, weighted := decimal.Zero, decimal.Zero
+		for _, flow := range flows {
+			if !flow.Date.After(start.Date) || flow.Date.After(end.Date) {
+				continue
+			}
+			weight := decimal.NewFromFloat(end.Date.Sub(flow.Date).Seconds() / length)
+			net = net.Add(flow.Amount)
+			weighted = weighted.Add(flow.Amount.Mul(weight))
+		}
+
+		invested := start.Value.Add(weighted)
+		if !invested.IsPositive() {
+			continue
+		}
+		r := end.Value.Sub(start.Value).Sub(net).Div(invested)
+		growth = growth.Mul(one.Add(r))
+		linked = true
+	}
+	if !linked {
+		return decimal.Zero, ErrNoReturn
+	}
+	return growth.Sub(one), nil
+}
+
+// XIRR returns the annual rate of return that discounts the flows, seen from
+// the investor's side, to zero: money invested is negative and money received
+// or still held at the end positive. Years are 365 days from the earliest
+// flow.
+func XIRR(flows []ExternalFlow) (float64, error) {
+	if len(flows) == 0 {
+		return 0, ErrNoReturn
+	}
+	sorted := make([]ExternalFlow, len(flows))
+	copy(sorted, flows)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	amounts := make([]float64, len(sorted))
+	years := make([]float64, len(sorted))
+	positive, negative := false, false
+	for i, flow := range sorted {
+		amounts[i] = flow.Amount.InexactFloat64()
+		years[i] = sorted[i].Date.Sub(sorted[0].Date).Hours() / 24 / 365
+		positive = positive || amounts[i] > 0
+		negative = negative || amounts[i] < 0
+	}
+	if !positive || !negative {
+		return 0, ErrNoReturn
+	}
+
+	npv := func(rate float64) float64 {
+		total := 0.0
+		for i, amount := range amounts {
+			total += amount / math.Pow(1+rate, years[i])
+		}
+		return total
+	}
+
+	// Bisect between a total loss and a rate high enough for the value to
+	// change sign.
+	lo, hi := -0.999999, 1.0
+	for npv(lo)*npv(hi) > 0 {
+		if hi > 1e6 {
+			return 0, ErrNoReturn
+		}
+		hi *= 10
+	}
+	for i := 0; i < 200 && hi-lo > 1e-12; i++ {
+		mid := (lo + hi) / 2
+		if npv(lo)*npv(mid) <= 0 {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return (lo + hi) / 2, nil
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+func TestTimeWeightedReturn(t *testing.T) {
+	jan := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	valuation := func(days int, value int64) Valuation {
+		return Valuation{Date: jan.AddDate(0, 0, days), Value: decimal.NewFromInt(value)}
+	}
+	flow := func(days int, amount int64) ExternalFlow {
+		return ExternalFlow{Date: jan.AddDate(0, 0, days), Amount: decimal.NewFromInt(amount)}
+	}
+
+	tests := []struct {
+		name       string
+		valuations []Valuation
+		flows      []ExternalFlow
+		want       string
+		wantErr    error
+	}{
+		{
+			name:       "should return the growth without flows",
+			valuations: []Valuation{valuation(0, 100), valuation(10, 110)},
+			want:       "0.1",
+		},
+		{
+			name:       "should not count a flow at the end as growth",
+			valuations: []Valuation{valuation(0, 100), valuation(10, 210)},
+			flows:      []ExternalFlow{flow(10, 100)},
+			want:       "0.1",
+		},
+		{
+			name:       "should weight a flow by how long it was invested",
+			valuations: []Valuation{valuation(0, 100), valuation(10, 215)},
+			flows:      []ExternalFlow{flow(5, 100)},
+			want:       "0.1",
+		},
+		{
+			name:       "should link the returns of each period",
+			valuations: []Valuation{valuation(0, 100), valuation(10, 110), valuation(20, 181)},
+			flows:      []ExternalFlow{flow(20, 60)},
+			want:       "0.21",
+		},
+		{
+			name:       "should skip periods with nothing invested",
+			valuations: []Valuation{valuation(0, 0), valuation(10, 100), valuation(20, 120)},
+			flows:      []ExternalFlow{flow(10, 100)},
+			want:       "0.2",
+		},
+		{
+			name:       "should error with nothing invested",
+			valuations: []Valuation{valuation(0, 0), valuation(10, 100)},
+			flows:      []ExternalFlow{flow(10, 100)},
+			wantErr:    ErrNoReturn,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			twr, err := TimeWeightedReturn(test.valuations, test.flows)
+			assert.Equal(t, true, errors.Is(err, test.wantErr))
+			if test.wantErr == nil {
+				assert.Equal(t, test.want, twr.Round(10).String())
+			}
+		})
+	}
+}
+
+func TestXIRR(t *testing.T) {
+	jan := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		flows   []ExternalFlow
+		want    float64
+		wantErr error
+	}{
+		{
+			name: "should return the yearly rate",
+			flows: []ExternalFlow{
+				{Date: jan, Amount: decimal.NewFromInt(-1000)},
+				{Date: jan.AddDate(0, 0, 365), Amount: decimal.NewFromInt(1100)},
+			},
+			want: 0.1,
+		},
+		{
+			name: "should annualize a shorter period",
+			flows: []ExternalFlow{
+				{Date: jan.AddDate(0, 0, 365), Amount: decimal.NewFromInt(1210)},
+				{Date: jan.AddDate(0, 0, -365), Amount: decimal.NewFromInt(-1000)},
+			},
+			want: 0.1,
+		},
+		{
+			name: "should return a loss",
+			flows: []ExternalFlow{
+				{Date: jan, Amount: decimal.NewFromInt(-1000)},
+				{Date: jan.AddDate(0, 0, 182), Amount: decimal.NewFromInt(-1000)},
+				{Date: jan.AddDate(0, 0, 365), Amount: decimal.NewFromInt(1500)},
+			},
+			want: -0.3226,
+		},
+		{
+			name:    "should error without money coming back",
+			flows:   []ExternalFlow{{Date: jan, Amount: decimal.NewFromInt(-1000)}},
+			wantErr: ErrNoReturn,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rate, err := XIRR(test.flows)
+			assert.Equal(t, true, errors.Is(err, test.wantErr))
+			if test.wantErr == nil {
+				assert.Equal(t, true, math.Abs(rate-test.want) < 1e-4)
+			}
+		})
+	}
+}
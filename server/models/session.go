@@ -0,0 +1,85 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionDuration is how long a session lasts after logging in.
+const SessionDuration = 30 * 24 * time.Hour
+
+// ErrSessionExpired is returned when looking up a session that has expired.
+var ErrSessionExpired = errors.New("session expired")
+
+// Session is a logged in user. Only a hash of the session token is stored so
+// the tokens handed out in cookies cannot be recovered from the database.
+type Session struct {
+	ID        uint      `json:"id"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	User      User      `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	CreatedAt time.Time
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateSession starts a session for the user and returns the token that
+// identifies it.
+func CreateSession(db *gorm.DB, userID uint) (string, Session, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", Session{}, err
+	}
+
+	session := Session{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(SessionDuration),
+	}
+	result := db.Create(&session)
+	return token, session, result.Error
+}
+
+// GetSessionUser returns the user a session token belongs to. Expired sessions
+// are deleted and return ErrSessionExpired.
+func GetSessionUser(db *gorm.DB, token string) (User, error) {
+	var session Session
+	result := db.Preload("User").Where("token_hash = ?", hashToken(token)).First(&session)
+	if result.Error != nil {
+		return User{}, result.Error
+	}
+
+	if !session.ExpiresAt.After(time.Now()) {
+		if result := db.Delete(&session); result.Error != nil {
+			return User{}, result.Error
+		}
+		return User{}, ErrSessionExpired
+	}
+	return session.User, nil
+}
+
+// DeleteSession ends the session identified by token. Ending a session that
+// does not exist is not an error.
+func DeleteSession(db *gorm.DB, token string) error {
+	result := db.Where("token_hash = ?", hashToken(token)).Delete(&Session{})
+	return result.Error
+}
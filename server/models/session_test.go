@@ -0,0 +1,82 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+)
+
+func TestNewToken(t *testing.T) {
+	first, err := newToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := newToken()
+
+	assert.NotEqual(t, first, second)
+	assert.NotEqual(t, first, hashToken(first))
+	assert.Equal(t, hashToken(first), hashToken(first))
+}
+
+func TestGetSessionUser(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	sessionColumns := []string{"id", "token_hash", "user_id", "expires_at", "created_at"}
+
+	tests := []struct {
+		name         string
+		expiresAt    time.Time
+		expectations func()
+		wantErr      error
+	}{
+		{
+			name:      "should return the user of a current session",
+			expiresAt: time.Now().Add(time.Hour),
+			expectations: func() {
+				mock.ExpectQuery("SELECT .* FROM \"users\" WHERE \"users\".\"id\" = ").
+					WithArgs(7).
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(7, "admin", "", true, nil, nil))
+			},
+		},
+		{
+			name:      "should delete an expired session",
+			expiresAt: time.Now().Add(-time.Hour),
+			expectations: func() {
+				mock.ExpectQuery("SELECT .* FROM \"users\" WHERE \"users\".\"id\" = ").
+					WithArgs(7).
+					WillReturnRows(sqlmock.NewRows(userColumns).AddRow(7, "admin", "", true, nil, nil))
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM \"sessions\" WHERE \"sessions\".\"id\" = ").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: ErrSessionExpired,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectQuery("SELECT .* FROM \"sessions\" WHERE token_hash = ").
+				WithArgs(hashToken("token")).
+				WillReturnRows(sqlmock.NewRows(sessionColumns).AddRow(1, hashToken("token"), 7, test.expiresAt, time.Now()))
+			test.expectations()
+
+			user, err := GetSessionUser(db, "token")
+			assert.Equal(t, test.wantErr, err)
+			if test.wantErr == nil {
+				assert.Equal(t, uint(7), user.ID)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -42,6 +42,9 @@ func CreateMockDatabase() (*gorm.DB, sqlmock.Sqlmock, error) {
 	return db, mock, err
 }
 
+// MockHouseholdID is the household the mocked queries are made on behalf of.
+const MockHouseholdID uint = 1
+
 type AnyTime struct{}
 
 // Match satisfies sqlmock.Argument interface
@@ -51,6 +54,7 @@ func (a AnyTime) Match(v driver.Value) bool {
 }
 
 var AccountColumns = []string{
+	"ID",
 	"Name",
 	"Class",
 	"Category",
@@ -62,13 +66,31 @@ var AccountColumns = []string{
 
 var AccountValuesColumns = []string{
 	"ID",
-	"AccountName",
+	"AccountID",
 	"Value",
+	"AsOf",
 	"CreatedAt",
 }
 
+var AccountOwnerColumns = []string{
+	"AccountID",
+	"UserID",
+	"Share",
+}
+
+// ownersStatement expects the owners of the accounts to be loaded. The mocked
+// accounts have none.
+func ownersStatement(accountIDs ...driver.Value) ExpectedStatement {
+	return ExpectedStatement{
+		statement:  "SELECT (.+) FROM \"account_owners\"",
+		args:       accountIDs,
+		returnRows: sqlmock.NewRows(AccountOwnerColumns),
+	}
+}
+
 func AccountToSQLRow(account Account) *sqlmock.Rows {
 	return sqlmock.NewRows(AccountColumns).AddRow(
+		account.ID,
 		account.Name,
 		string(account.Class),
 		string(account.Category),
@@ -81,6 +103,7 @@ func AccountToSQLRow(account Account) *sqlmock.Rows {
 
 func AddAccountToRows(rows *sqlmock.Rows, account Account) *sqlmock.Rows {
 	return rows.AddRow(
+		account.ID,
 		account.Name,
 		string(account.Class),
 		string(account.Category),
@@ -91,9 +114,9 @@ func AddAccountToRows(rows *sqlmock.Rows, account Account) *sqlmock.Rows {
 	)
 }
 
-func AddRandomAccountValues(rows *sqlmock.Rows, accountName string, numRows int) *sqlmock.Rows {
+func AddRandomAccountValues(rows *sqlmock.Rows, accountID uint, numRows int) *sqlmock.Rows {
 	for i := 0; i < numRows; i++ {
-		rows.AddRow(i, accountName, randomDollarAmount(), time.Now())
+		rows.AddRow(i, accountID, randomDollarAmount(), time.Now(), time.Now())
 	}
 	return rows
 }
@@ -111,6 +134,7 @@ func CreateStatementsAccountExists(name string) []ExpectedStatement {
 		{
 			statement: "SELECT .+ FROM \"accounts\"",
 			args: []driver.Value{
+				MockHouseholdID,
 				name,
 			},
 			returnRows: sqlmock.NewRows([]string{"count"}).AddRow(1),
@@ -118,11 +142,38 @@ func CreateStatementsAccountExists(name string) []ExpectedStatement {
 	}
 }
 
+func CreateStatementsAccountExistsByID(id uint) []ExpectedStatement {
+	return []ExpectedStatement{
+		{
+			statement: "SELECT .+ FROM \"accounts\"",
+			args: []driver.Value{
+				MockHouseholdID,
+				id,
+			},
+			returnRows: sqlmock.NewRows([]string{"count"}).AddRow(1),
+		},
+	}
+}
+
+func CreateStatementsAccountDoesNotExistByID(id uint) []ExpectedStatement {
+	return []ExpectedStatement{
+		{
+			statement: "SELECT .+ FROM \"accounts\"",
+			args: []driver.Value{
+				MockHouseholdID,
+				id,
+			},
+			returnRows: sqlmock.NewRows([]string{"count"}).AddRow(0),
+		},
+	}
+}
+
 func CreateStatementsAccountDoesNotExist(name string) []ExpectedStatement {
 	return []ExpectedStatement{
 		{
 			statement: "SELECT .+ FROM \"accounts\"",
 			args: []driver.Value{
+				MockHouseholdID,
 				name,
 			},
 			returnRows: sqlmock.NewRows([]string{"count"}).AddRow(0),
@@ -131,14 +182,21 @@ func CreateStatementsAccountDoesNotExist(name string) []ExpectedStatement {
 }
 
 func CreateStatementsCreateAccount(account Account) []ExpectedStatement {
+	currency := account.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
 	return []ExpectedStatement{
 		{
 			statement: "INSERT INTO \"accounts\" .*",
 			args: []driver.Value{
+				MockHouseholdID,
 				account.Name,
 				account.Class,
 				account.Category,
 				account.TaxBucket,
+				currency,
+				account.Precision,
 				AnyTime{},
 				AnyTime{},
 				nil,
@@ -148,24 +206,31 @@ func CreateStatementsCreateAccount(account Account) []ExpectedStatement {
 	}
 }
 
+// CreateStatementsGetAllAccountsWithValues expects all accounts to be loaded
+// along with their values. Accounts without an ID are numbered from 1.
 func CreateStatementsGetAllAccountsWithValues(accounts []Account, valuesPerAccount int) []ExpectedStatement {
 	accountRows := sqlmock.NewRows(AccountColumns)
 	accountValuesRows := sqlmock.NewRows(AccountValuesColumns)
-	accountNames := []driver.Value{}
-	for _, account := range accounts {
+	accountIDs := []driver.Value{}
+	for i, account := range accounts {
+		if account.ID == 0 {
+			account.ID = uint(i + 1)
+		}
 		AddAccountToRows(accountRows, account)
-		accountNames = append(accountNames, account.Name)
-		AddRandomAccountValues(accountValuesRows, account.Name, valuesPerAccount)
+		accountIDs = append(accountIDs, account.ID)
+		AddRandomAccountValues(accountValuesRows, account.ID, valuesPerAccount)
 	}
 
 	return []ExpectedStatement{
 		{
 			statement:  "SELECT (.+) FROM \"accounts\"",
+			args:       []driver.Value{MockHouseholdID},
 			returnRows: accountRows,
 		},
+		ownersStatement(accountIDs...),
 		{
 			statement:  "SELECT (.+) FROM \"account_values\"",
-			args:       accountNames,
+			args:       accountIDs,
 			returnRows: accountValuesRows,
 		},
 	}
@@ -174,18 +239,54 @@ func CreateStatementsGetAllAccountsWithValues(accounts []Account, valuesPerAccou
 func CreateStatementsGetAccountByNameWithValues(account Account, numValues int) []ExpectedStatement {
 	return []ExpectedStatement{
 		{
-			statement: "SELECT .* \"accounts\" WHERE name",
+			statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND name",
 			args: []driver.Value{
+				MockHouseholdID,
 				account.Name,
 			},
 			returnRows: AddAccountToRows(sqlmock.NewRows(AccountColumns), account),
 		},
+		ownersStatement(account.ID),
 		{
-			statement: "SELECT .* \"account_values\" WHERE \"account_values\".\"account_name\"",
+			statement: "SELECT .* \"account_values\" WHERE \"account_values\".\"account_id\"",
 			args: []driver.Value{
-				account.Name,
+				account.ID,
 			},
-			returnRows: AddRandomAccountValues(sqlmock.NewRows(AccountValuesColumns), account.Name, numValues),
+			returnRows: AddRandomAccountValues(sqlmock.NewRows(AccountValuesColumns), account.ID, numValues),
+		},
+	}
+}
+
+func CreateStatementsGetAccountWithValues(account Account, numValues int) []ExpectedStatement {
+	return []ExpectedStatement{
+		{
+			statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND \"accounts\".\"id\"",
+			args: []driver.Value{
+				MockHouseholdID,
+				account.ID,
+			},
+			returnRows: AddAccountToRows(sqlmock.NewRows(AccountColumns), account),
+		},
+		ownersStatement(account.ID),
+		{
+			statement: "SELECT .* \"account_values\" WHERE \"account_values\".\"account_id\"",
+			args: []driver.Value{
+				account.ID,
+			},
+			returnRows: AddRandomAccountValues(sqlmock.NewRows(AccountValuesColumns), account.ID, numValues),
+		},
+	}
+}
+
+func CreateStatementsAccountIDCannotBeFound(id uint) []ExpectedStatement {
+	return []ExpectedStatement{
+		{
+			statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND \"accounts\".\"id\"",
+			args: []driver.Value{
+				MockHouseholdID,
+				id,
+			},
+			returnError: gorm.ErrRecordNotFound,
 		},
 	}
 }
@@ -193,24 +294,29 @@ func CreateStatementsGetAccountByNameWithValues(account Account, numValues int)
 func CreateStatementsGetAccountsByClassWithValues(class string, accounts []Account, valuesPerAccount int) []ExpectedStatement {
 	accountRows := sqlmock.NewRows(AccountColumns)
 	accountValuesRows := sqlmock.NewRows(AccountValuesColumns)
-	accountNames := []driver.Value{}
-	for _, account := range accounts {
-		accountNames = append(accountNames, account.Name)
+	accountIDs := []driver.Value{}
+	for i, account := range accounts {
+		if account.ID == 0 {
+			account.ID = uint(i + 1)
+		}
+		accountIDs = append(accountIDs, account.ID)
 		AddAccountToRows(accountRows, account)
-		AddRandomAccountValues(accountValuesRows, account.Name, valuesPerAccount)
+		AddRandomAccountValues(accountValuesRows, account.ID, valuesPerAccount)
 	}
 
 	return []ExpectedStatement{
 		{
-			statement: "SELECT .* \"accounts\" WHERE class",
+			statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND class",
 			args: []driver.Value{
+				MockHouseholdID,
 				class,
 			},
 			returnRows: accountRows,
 		},
+		ownersStatement(accountIDs...),
 		{
 			statement:  "SELECT (.+) FROM \"account_values\"",
-			args:       accountNames,
+			args:       accountIDs,
 			returnRows: accountValuesRows,
 		},
 	}
@@ -219,8 +325,9 @@ func CreateStatementsGetAccountsByClassWithValues(class string, accounts []Accou
 func CreateStatementsAccountCannotBeFound(name string) []ExpectedStatement {
 	return []ExpectedStatement{
 		{
-			statement: "SELECT .* \"accounts\" WHERE name",
+			statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND name",
 			args: []driver.Value{
+				MockHouseholdID,
 				name,
 			},
 			returnRows:  nil,
@@ -232,8 +339,9 @@ func CreateStatementsAccountCannotBeFound(name string) []ExpectedStatement {
 func CreateStatementsUpdateAccount(existingAccount Account, updateArgs []driver.Value) []ExpectedStatement {
 	return []ExpectedStatement{
 		{
-			statement: "SELECT .* \"accounts\" WHERE name",
+			statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND name",
 			args: []driver.Value{
+				MockHouseholdID,
 				existingAccount.Name,
 			},
 			returnRows: AddAccountToRows(sqlmock.NewRows(AccountColumns), existingAccount),
@@ -246,12 +354,38 @@ func CreateStatementsUpdateAccount(existingAccount Account, updateArgs []driver.
 	}
 }
 
+func CreateStatementsUpdateAccountByID(existingAccount Account, renamed bool, updateArgs []driver.Value) []ExpectedStatement {
+	statements := []ExpectedStatement{
+		{
+			statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND \"accounts\".\"id\"",
+			args: []driver.Value{
+				MockHouseholdID,
+				existingAccount.ID,
+			},
+			returnRows: AddAccountToRows(sqlmock.NewRows(AccountColumns), existingAccount),
+		},
+	}
+	if renamed {
+		statements = append(statements, ExpectedStatement{
+			statement:  "SELECT .+ FROM \"accounts\"",
+			args:       []driver.Value{MockHouseholdID, updateArgs[0]},
+			returnRows: sqlmock.NewRows([]string{"count"}).AddRow(0),
+		})
+	}
+	return append(statements, ExpectedStatement{
+		statement:    "UPDATE \"accounts\"",
+		args:         updateArgs,
+		returnResult: sqlmock.NewResult(1, 1),
+	})
+}
+
 func CreateStatementsDeleteAccount(account Account) []ExpectedStatement {
 	return []ExpectedStatement{
 		{
-			statement: "SELECT .* \"accounts\" WHERE name",
+			statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND name",
 			args: []driver.Value{
-				"test",
+				MockHouseholdID,
+				account.Name,
 			},
 			returnRows: AddAccountToRows(sqlmock.NewRows(AccountColumns), account),
 		},
@@ -259,7 +393,28 @@ func CreateStatementsDeleteAccount(account Account) []ExpectedStatement {
 			statement: "UPDATE \"accounts\" SET \"deleted_at\"",
 			args: []driver.Value{
 				AnyTime{},
-				account.Name,
+				account.ID,
+			},
+			returnResult: sqlmock.NewResult(1, 1),
+		},
+	}
+}
+
+func CreateStatementsDeleteAccountByID(account Account) []ExpectedStatement {
+	return []ExpectedStatement{
+		{
+			statement: "SELECT .* \"accounts\" WHERE household_id = .+ AND \"accounts\".\"id\"",
+			args: []driver.Value{
+				MockHouseholdID,
+				account.ID,
+			},
+			returnRows: AddAccountToRows(sqlmock.NewRows(AccountColumns), account),
+		},
+		{
+			statement: "UPDATE \"accounts\" SET \"deleted_at\"",
+			args: []driver.Value{
+				AnyTime{},
+				account.ID,
 			},
 			returnResult: sqlmock.NewResult(1, 1),
 		},
@@ -267,19 +422,25 @@ func CreateStatementsDeleteAccount(account Account) []ExpectedStatement {
 }
 
 func CreateStatementsCreateAccountValue(av AccountValue) []ExpectedStatement {
+	var asOf driver.Value = AnyTime{}
+	if !av.AsOf.IsZero() {
+		asOf = av.AsOf
+	}
 	return []ExpectedStatement{
 		{
 			statement: "SELECT .* FROM \"accounts\"",
 			args: []driver.Value{
-				av.AccountName,
+				MockHouseholdID,
+				av.AccountID,
 			},
 			returnRows: sqlmock.NewRows([]string{"count"}).AddRow(1),
 		},
 		{
 			statement: "INSERT INTO \"account_values\" .*",
 			args: []driver.Value{
-				av.AccountName,
+				av.AccountID,
 				av.Value,
+				asOf,
 				AnyTime{},
 			},
 			returnResult: sqlmock.NewResult(1, 1),
@@ -287,6 +448,66 @@ func CreateStatementsCreateAccountValue(av AccountValue) []ExpectedStatement {
 	}
 }
 
+func AddAccountValueToRows(rows *sqlmock.Rows, av AccountValue) *sqlmock.Rows {
+	return rows.AddRow(av.ID, av.AccountID, av.Value, av.AsOf, time.Now())
+}
+
+func CreateStatementsGetAccountValue(av AccountValue) []ExpectedStatement {
+	return []ExpectedStatement{
+		{
+			statement: "SELECT .* FROM \"account_values\" WHERE \"account_values\".\"id\"",
+			args: []driver.Value{
+				av.ID,
+			},
+			returnRows: AddAccountValueToRows(sqlmock.NewRows(AccountValuesColumns), av),
+		},
+		{
+			statement: "SELECT .* FROM \"accounts\"",
+			args: []driver.Value{
+				MockHouseholdID,
+				av.AccountID,
+			},
+			returnRows: sqlmock.NewRows([]string{"count"}).AddRow(1),
+		},
+	}
+}
+
+func CreateStatementsAccountValueCannotBeFound(id uint) []ExpectedStatement {
+	return []ExpectedStatement{
+		{
+			statement: "SELECT .* FROM \"account_values\" WHERE \"account_values\".\"id\"",
+			args: []driver.Value{
+				id,
+			},
+			returnError: gorm.ErrRecordNotFound,
+		},
+	}
+}
+
+func CreateStatementsUpdateAccountValue(existing AccountValue, updateArgs []driver.Value) []ExpectedStatement {
+	return append(
+		CreateStatementsGetAccountValue(existing),
+		ExpectedStatement{
+			statement:    "UPDATE \"account_values\"",
+			args:         updateArgs,
+			returnResult: sqlmock.NewResult(1, 1),
+		},
+	)
+}
+
+func CreateStatementsDeleteAccountValue(av AccountValue) []ExpectedStatement {
+	return append(
+		CreateStatementsGetAccountValue(av),
+		ExpectedStatement{
+			statement: "DELETE FROM \"account_values\"",
+			args: []driver.Value{
+				av.ID,
+			},
+			returnResult: sqlmock.NewResult(1, 1),
+		},
+	)
+}
+
 func LoadStatements(mock sqlmock.Sqlmock, statements []ExpectedStatement) {
 	for _, statement := range statements {
 		if strings.Contains(statement.statement, "SELECT") {
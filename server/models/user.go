@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MinPasswordLength is the shortest password a user may set.
+const MinPasswordLength = 8
+
+// ErrInvalidCredentials is returned when a username and password do not match
+// a user. It deliberately does not say which of the two was wrong.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrUsernameTaken is returned when creating a user with a username that
+// another user already has.
+var ErrUsernameTaken = errors.New("username already in use")
+
+// User is someone who can log in. Every user is a member of one household and
+// sees that household's accounts.
+type User struct {
+	ID           uint   `json:"id"`
+	HouseholdID  uint   `json:"household_id" gorm:"index"`
+	Username     string `json:"username" gorm:"uniqueIndex"`
+	PasswordHash string `json:"-"`
+	Admin        bool   `json:"admin"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func ValidateUser(username, password string) error {
+	if strings.TrimSpace(username) == "" {
+		return fmt.Errorf("no username provided")
+	}
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	return nil
+}
+
+func CountUsers(db *gorm.DB) (int64, error) {
+	count := int64(0)
+	result := db.Model(&User{}).Count(&count)
+	return count, result.Error
+}
+
+// CreateUser stores a new member of the household with a bcrypt hash of
+// password.
+func CreateUser(db *gorm.DB, householdID uint, username, password string, admin bool) (User, error) {
+	if err := ValidateUser(username, password); err != nil {
+		return User{}, err
+	}
+
+	count := int64(0)
+	result := db.Model(&User{}).Where("username = ?", username).Count(&count)
+	if result.Error != nil {
+		return User{}, result.Error
+	}
+	if count > 0 {
+		return User{}, fmt.Errorf("%w: %s", ErrUsernameTaken, username)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, err
+	}
+
+	user := User{HouseholdID: householdID, Username: username, PasswordHash: string(hash), Admin: admin}
+	result = db.Create(&user)
+	return user, result.Error
+}
+
+func GetUser(db *gorm.DB, id uint) (User, error) {
+	var user User
+	result := db.First(&user, id)
+	return user, result.Error
+}
+
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// compareDummyHash spends as long as checking a real password, so failed logins
+// take the same time whether or not the username exists.
+func compareDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// AuthenticateUser returns the user with the given username if password
+// matches, and ErrInvalidCredentials otherwise.
+func AuthenticateUser(db *gorm.DB, username, password string) (User, error) {
+	var user User
+	result := db.Where("username = ?", username).First(&user)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		compareDummyHash(password)
+		return User{}, ErrInvalidCredentials
+	}
+	if result.Error != nil {
+		return User{}, result.Error
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return User{}, ErrInvalidCredentials
+	}
+	return user, nil
+}
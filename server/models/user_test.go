@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var userColumns = []string{"id", "username", "password_hash", "admin", "created_at", "updated_at"}
+
+func TestValidateUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  bool
+	}{
+		{name: "should accept a username and long enough password", username: "admin", password: "correct horse"},
+		{name: "should reject an empty username", username: " ", password: "correct horse", wantErr: true},
+		{name: "should reject a short password", username: "admin", password: strings.Repeat("a", MinPasswordLength-1), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateUser(test.username, test.password)
+			assert.Equal(t, test.wantErr, err != nil)
+		})
+	}
+}
+
+func TestCreateUserUsernameTaken(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	LoadStatements(mock, []ExpectedStatement{
+		{
+			statement:  "SELECT count(.+) FROM \"users\" WHERE username",
+			args:       []driver.Value{"admin"},
+			returnRows: sqlmock.NewRows([]string{"count"}).AddRow(1),
+		},
+	})
+
+	_, err = CreateUser(db, MockHouseholdID, "admin", "correct horse", true)
+	assert.Equal(t, true, errors.Is(err, ErrUsernameTaken))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	db, mock, err := CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		rows     *sqlmock.Rows
+		wantErr  error
+	}{
+		{
+			name:     "should authenticate with the right password",
+			password: "correct horse",
+			rows:     sqlmock.NewRows(userColumns).AddRow(1, "admin", string(hash), true, nil, nil),
+		},
+		{
+			name:     "should not authenticate with the wrong password",
+			password: "battery staple",
+			rows:     sqlmock.NewRows(userColumns).AddRow(1, "admin", string(hash), true, nil, nil),
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:     "should not authenticate an unknown user",
+			password: "correct horse",
+			rows:     sqlmock.NewRows(userColumns),
+			wantErr:  ErrInvalidCredentials,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			LoadStatements(mock, []ExpectedStatement{
+				{
+					statement:  "SELECT .* FROM \"users\" WHERE username",
+					args:       []driver.Value{"admin"},
+					returnRows: test.rows,
+				},
+			})
+			user, err := AuthenticateUser(db, "admin", test.password)
+			assert.Equal(t, test.wantErr, err)
+			if test.wantErr == nil {
+				assert.Equal(t, "admin", user.Username)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
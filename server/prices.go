@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/prices"
+	"github.com/Jrc356/financial_dashboard/store"
+	"gorm.io/gorm"
+)
+
+// priceProvider returns the provider selected by PRICE_PROVIDER, or nil when
+// prices are not refreshed automatically. The file provider reads the
+// directory at PRICE_DIR. The http provider calls the URL template in
+// PRICE_URL, sending PRICE_HEADER, a "Name: value" pair, with each request.
+func priceProvider() (prices.PriceProvider, error) {
+	switch kind := getenv("PRICE_PROVIDER", ""); kind {
+	case "":
+		return nil, nil
+	case "file":
+		return prices.NewFileProvider(getenv("PRICE_DIR", "prices")), nil
+	case "http":
+		endpoint := getenv("PRICE_URL", "")
+		if endpoint == "" {
+			return nil, fmt.Errorf("PRICE_URL is required by the http price provider")
+		}
+		provider := prices.NewHTTPProvider(endpoint)
+		if header := getenv("PRICE_HEADER", ""); header != "" {
+			name, value, ok := strings.Cut(header, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid PRICE_HEADER, expected \"Name: value\"")
+			}
+			provider.Header.Set(textproto.TrimString(name), textproto.TrimString(value))
+		}
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("invalid PRICE_PROVIDER %q, expected file or http", kind)
+	}
+}
+
+// startPriceScheduler refreshes prices in the background once a day when a
+// price provider is configured.
+func startPriceScheduler(db *gorm.DB) {
+	provider, err := priceProvider()
+	if err != nil {
+		log.Panic(err)
+	}
+	if provider == nil {
+		return
+	}
+	go prices.NewScheduler(store.NewGormStore(db), provider).Run(context.Background())
+}
+
+func pricesCommand(db *gorm.DB, args []string) {
+	flags := flag.NewFlagSet("prices", flag.ExitOnError)
+	date := flags.String("date", "", "date to quote prices as of, YYYY-MM-DD, today when empty")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: prices [-date YYYY-MM-DD] refresh")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.Arg(0) != "refresh" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	asOf := time.Now()
+	if *date != "" {
+		var err error
+		asOf, err = time.Parse("2006-01-02", *date)
+		if err != nil {
+			log.Fatalf("invalid date %q, expected YYYY-MM-DD", *date)
+		}
+		asOf = asOf.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	provider, err := priceProvider()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if provider == nil {
+		log.Fatal("no price provider configured, set PRICE_PROVIDER to file or http")
+	}
+
+	result, err := prices.NewScheduler(store.NewGormStore(db), provider).Refresh(context.Background(), asOf)
+	fmt.Printf("saved: %d, missing: %d, failed: %d\n", result.Saved, result.Missing, result.Failed)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,128 @@
+package prices
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/shopspring/decimal"
+)
+
+// FileProvider quotes securities from files in Dir named after their symbol,
+// such as VTI.csv or VTI.json. CSV files have a header naming the date and
+// price columns. JSON files hold a list of objects with the same fields, as in
+// [{"date": "2023-01-31", "price": "204.42"}]. Files are read on every quote,
+// so they can be replaced while the server is running.
+type FileProvider struct {
+	Dir string
+}
+
+func NewFileProvider(dir string) *FileProvider {
+	return &FileProvider{Dir: dir}
+}
+
+func (p *FileProvider) Quote(ctx context.Context, symbol string, date time.Time) (Quote, error) {
+	symbol, err := models.ParseSymbol(symbol)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	quotes, err := p.readCSV(symbol)
+	if errors.Is(err, fs.ErrNotExist) {
+		quotes, err = p.readJSON(symbol)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return Quote{}, fmt.Errorf("%w: no price file for %s in %s", ErrNoQuote, symbol, p.Dir)
+	}
+	if err != nil {
+		return Quote{}, err
+	}
+	return latest(symbol, quotes, date)
+}
+
+func (p *FileProvider) readCSV(symbol string) ([]Quote, error) {
+	path := filepath.Join(p.Dir, symbol+".csv")
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading csv header: %w", path, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	dateColumn, ok := columns["date"]
+	if !ok {
+		return nil, fmt.Errorf("%s: missing required column \"date\"", path)
+	}
+	priceColumn, ok := columns["price"]
+	if !ok {
+		return nil, fmt.Errorf("%s: missing required column \"price\"", path)
+	}
+
+	quotes := []Quote{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		date, err := parseDate(strings.TrimSpace(record[dateColumn]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		price, err := decimal.NewFromString(strings.TrimSpace(record[priceColumn]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid price %q", path, line, record[priceColumn])
+		}
+		quotes = append(quotes, Quote{Symbol: symbol, Date: date, Price: price})
+	}
+	return quotes, nil
+}
+
+// jsonQuote is a quote as written in JSON price files and HTTP responses.
+type jsonQuote struct {
+	Date  string          `json:"date"`
+	Price decimal.Decimal `json:"price"`
+}
+
+func (p *FileProvider) readJSON(symbol string) ([]Quote, error) {
+	path := filepath.Join(p.Dir, symbol+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []jsonQuote
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	quotes := make([]Quote, len(entries))
+	for i, entry := range entries {
+		date, err := parseDate(entry.Date)
+		if err != nil {
+			return nil, fmt.Errorf("%s: entry %d: %w", path, i, err)
+		}
+		quotes[i] = Quote{Symbol: symbol, Date: date, Price: entry.Price}
+	}
+	return quotes, nil
+}
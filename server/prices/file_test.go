@@ -0,0 +1,64 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "VTI.csv", "date,price\n2023-02-28,199.07\n2023-01-31,204.42\n")
+	writeFile(t, dir, "VXUS.json", `[{"date": "2023-01-31", "price": "55.13"}, {"date": "2023-02-28", "price": 53.9}]`)
+	writeFile(t, dir, "BAD.csv", "day,close\n2023-01-31,1\n")
+	provider := NewFileProvider(dir)
+
+	feb := time.Date(2023, time.February, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		symbol  string
+		date    time.Time
+		want    string
+		wantDay string
+		wantErr error
+	}{
+		{name: "should quote the latest price on or before the date from csv", symbol: "vti", date: feb, want: "204.42", wantDay: "2023-01-31"},
+		{name: "should quote a price on the date", symbol: "VTI", date: feb.AddDate(0, 0, 13), want: "199.07", wantDay: "2023-02-28"},
+		{name: "should quote from json", symbol: "VXUS", date: feb.AddDate(0, 1, 0), want: "53.9", wantDay: "2023-02-28"},
+		{name: "should have no quote before the first price", symbol: "VTI", date: feb.AddDate(0, -1, 0), wantErr: ErrNoQuote},
+		{name: "should have no quote without a file", symbol: "BND", date: feb, wantErr: ErrNoQuote},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			quote, err := provider.Quote(context.Background(), test.symbol, test.date)
+			if test.wantErr != nil {
+				assert.Equal(t, true, errors.Is(err, test.wantErr))
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.want, quote.Price.String())
+			assert.Equal(t, test.wantDay, quote.Date.Format("2006-01-02"))
+		})
+	}
+
+	_, err := provider.Quote(context.Background(), "BAD", feb)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, false, errors.Is(err, ErrNoQuote))
+
+	_, err = provider.Quote(context.Background(), "../VTI", feb)
+	assert.NotEqual(t, nil, err)
+}
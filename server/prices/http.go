@@ -0,0 +1,84 @@
+package prices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPProvider quotes securities from any HTTP endpoint. URL is a template in
+// which {symbol} and {date}, as YYYY-MM-DD, are replaced, such as
+// https://prices.example.com/quote?symbol={symbol}&date={date}. The endpoint
+// answers with the latest price on or before the date as a JSON object, as in
+// {"date": "2023-01-31", "price": "204.42"}, or with 404 Not Found when it has
+// none. The date may be left out when it is the date asked for.
+type HTTPProvider struct {
+	URL string
+	// Header is sent with every request, for instance to pass an API key.
+	Header http.Header
+	Client *http.Client
+}
+
+// defaultHTTPTimeout bounds each request made by an HTTPProvider without a
+// client of its own.
+const defaultHTTPTimeout = 30 * time.Second
+
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{
+		URL:    url,
+		Header: http.Header{},
+		Client: &http.Client{Timeout: defaultHTTPTimeout},
+	}
+}
+
+func (p *HTTPProvider) Quote(ctx context.Context, symbol string, date time.Time) (Quote, error) {
+	endpoint := strings.NewReplacer(
+		"{symbol}", url.QueryEscape(symbol),
+		"{date}", date.Format("2006-01-02"),
+	).Replace(p.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	for name, values := range p.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Quote{}, fmt.Errorf("%w: %s on or before %s", ErrNoQuote, symbol, date.Format("2006-01-02"))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("quoting %s: unexpected status %s", symbol, resp.Status)
+	}
+
+	var body jsonQuote
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Quote{}, fmt.Errorf("quoting %s: %w", symbol, err)
+	}
+	quote := Quote{Symbol: symbol, Date: date, Price: body.Price}
+	if body.Date != "" {
+		quote.Date, err = parseDate(body.Date)
+		if err != nil {
+			return Quote{}, fmt.Errorf("quoting %s: %w", symbol, err)
+		}
+	}
+	if quote.Date.After(date) {
+		return Quote{}, fmt.Errorf("quoting %s: got a price for %s, after %s", symbol, quote.Date.Format("2006-01-02"), date.Format("2006-01-02"))
+	}
+	return quote, nil
+}
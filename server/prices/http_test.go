@@ -0,0 +1,65 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+// stubServer answers quotes for VTI, dated the Friday before weekends, and
+// 404 for every other symbol. Requests without the API key are refused.
+func stubServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Query().Get("symbol") {
+		case "VTI":
+			if r.URL.Query().Get("date") == "2023-01-29" {
+				w.Write([]byte(`{"date": "2023-01-27", "price": "204.42"}`))
+				return
+			}
+			w.Write([]byte(`{"price": 205.1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPProvider(t *testing.T) {
+	server := stubServer(t)
+	provider := NewHTTPProvider(server.URL + "/quote?symbol={symbol}&date={date}")
+	provider.Header.Set("X-API-Key", "secret")
+
+	sunday := time.Date(2023, time.January, 29, 12, 0, 0, 0, time.UTC)
+	quote, err := provider.Quote(context.Background(), "VTI", sunday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "204.42", quote.Price.String())
+	assert.Equal(t, "2023-01-27", quote.Date.Format("2006-01-02"))
+
+	monday := sunday.AddDate(0, 0, 1)
+	quote, err = provider.Quote(context.Background(), "VTI", monday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "205.1", quote.Price.String())
+	assert.Equal(t, true, quote.Date.Equal(monday))
+
+	_, err = provider.Quote(context.Background(), "BND", monday)
+	assert.Equal(t, true, errors.Is(err, ErrNoQuote))
+
+	provider.Header.Del("X-API-Key")
+	_, err = provider.Quote(context.Background(), "VTI", monday)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, false, errors.Is(err, ErrNoQuote))
+}
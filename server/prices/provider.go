@@ -0,0 +1,58 @@
+// Package prices fetches the prices of securities from outside sources and
+// keeps the stored price history up to date.
+package prices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrNoQuote is returned by a PriceProvider that has no price for a security
+// on or before the date asked for.
+var ErrNoQuote = errors.New("no quote")
+
+// Quote is the price of one unit of a security on Date.
+type Quote struct {
+	Symbol string          `json:"symbol"`
+	Date   time.Time       `json:"date"`
+	Price  decimal.Decimal `json:"price"`
+}
+
+// PriceProvider quotes securities by symbol. Quote returns the latest price
+// on or before date, which may be from an earlier day when markets were
+// closed, and an error wrapping ErrNoQuote when there is none.
+type PriceProvider interface {
+	Quote(ctx context.Context, symbol string, date time.Time) (Quote, error)
+}
+
+// day returns midnight UTC of the date of t, the form prices are stored in.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// latest returns the latest of the quotes on or before date.
+func latest(symbol string, quotes []Quote, date time.Time) (Quote, error) {
+	sort.SliceStable(quotes, func(i, j int) bool { return quotes[i].Date.Before(quotes[j].Date) })
+	i := sort.Search(len(quotes), func(i int) bool { return quotes[i].Date.After(date) })
+	if i == 0 {
+		return Quote{}, fmt.Errorf("%w: %s on or before %s", ErrNoQuote, symbol, date.Format("2006-01-02"))
+	}
+	return quotes[i-1], nil
+}
+
+// parseDate accepts a date (YYYY-MM-DD) or an RFC 3339 timestamp.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	return t, nil
+}
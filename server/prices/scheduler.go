@@ -0,0 +1,97 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+)
+
+// Scheduler keeps the price of every security up to date from a provider.
+type Scheduler struct {
+	Store    store.Store
+	Provider PriceProvider
+	// Interval is the time between refreshes, a day unless set.
+	Interval time.Duration
+	// Logger reports the outcome of each refresh, the standard logger unless
+	// set.
+	Logger *log.Logger
+}
+
+func NewScheduler(s store.Store, provider PriceProvider) *Scheduler {
+	return &Scheduler{Store: s, Provider: provider, Interval: 24 * time.Hour}
+}
+
+// RefreshResult counts what happened to the securities in a refresh.
+type RefreshResult struct {
+	Saved   int
+	Missing int
+	Failed  int
+}
+
+// Refresh quotes every security as of date and saves the prices, replacing
+// any already recorded for the day of the quote. Securities the provider has
+// no quote for are counted as missing and left alone. A failure to quote one
+// security does not stop the others from being refreshed; the first such
+// error is returned once they all have been tried.
+func (s *Scheduler) Refresh(ctx context.Context, date time.Time) (RefreshResult, error) {
+	result := RefreshResult{}
+	securities, err := s.Store.GetSecurities()
+	if err != nil {
+		return result, err
+	}
+
+	var firstErr error
+	for _, security := range securities {
+		quote, err := s.Provider.Quote(ctx, security.Symbol, date)
+		if errors.Is(err, ErrNoQuote) {
+			result.Missing++
+			continue
+		}
+		if err == nil {
+			_, err = s.Store.SavePrice(models.Price{SecurityID: security.ID, Date: day(quote.Date), Price: quote.Price})
+		}
+		if err != nil {
+			result.Failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("refreshing %s: %w", security.Symbol, err)
+			}
+			continue
+		}
+		result.Saved++
+	}
+	return result, firstErr
+}
+
+// Run refreshes prices straight away and then once every Interval until ctx
+// is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	logger := s.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := s.Refresh(ctx, time.Now())
+		if err != nil {
+			logger.Printf("price refresh: %s", err)
+		}
+		logger.Printf("price refresh: saved %d, missing %d, failed %d", result.Saved, result.Missing, result.Failed)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
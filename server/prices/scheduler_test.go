@@ -0,0 +1,77 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/Jrc356/financial_dashboard/store"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+)
+
+// stubProvider quotes from a map of symbols to prices, dated the day before
+// the date asked for. Symbols mapped to an empty string fail to quote.
+type stubProvider map[string]string
+
+func (p stubProvider) Quote(ctx context.Context, symbol string, date time.Time) (Quote, error) {
+	price, ok := p[symbol]
+	if !ok {
+		return Quote{}, ErrNoQuote
+	}
+	if price == "" {
+		return Quote{}, errors.New("provider unavailable")
+	}
+	return Quote{Symbol: symbol, Date: date.AddDate(0, 0, -1), Price: decimal.RequireFromString(price)}, nil
+}
+
+func TestSchedulerRefresh(t *testing.T) {
+	s := store.NewMemoryStore()
+	for _, symbol := range []string{"VTI", "VXUS", "BND", "BTC-USD"} {
+		if _, err := s.CreateSecurity(models.Security{Symbol: symbol}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scheduler := NewScheduler(s, stubProvider{"VTI": "204.42", "VXUS": "55.123456789", "BTC-USD": ""})
+
+	date := time.Date(2023, time.January, 31, 18, 30, 0, 0, time.UTC)
+	result, err := scheduler.Refresh(context.Background(), date)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, RefreshResult{Saved: 2, Missing: 1, Failed: 1}, result)
+
+	// Refreshing again replaces the prices of the same day.
+	if _, err := scheduler.Refresh(context.Background(), date); err == nil {
+		t.Fatal("expected the failing provider to be reported")
+	}
+	prices, err := s.GetPrices([]uint{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(prices))
+	assert.Equal(t, "2023-01-30T00:00:00Z", prices[0].Date.Format(time.RFC3339))
+	assert.Equal(t, "55.12345679", prices[1].Price.String())
+}
+
+func TestSchedulerRunStopsWithContext(t *testing.T) {
+	s := store.NewMemoryStore()
+	if _, err := s.CreateSecurity(models.Security{Symbol: "VTI"}); err != nil {
+		t.Fatal(err)
+	}
+	scheduler := NewScheduler(s, stubProvider{"VTI": "204.42"})
+	scheduler.Interval = time.Millisecond
+	scheduler.Logger = log.New(io.Discard, "", 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	scheduler.Run(ctx)
+
+	prices, err := s.GetPrices([]uint{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(prices))
+}
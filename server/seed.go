@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/migrations"
+	"github.com/Jrc356/financial_dashboard/seed"
+	"gorm.io/gorm"
+)
+
+func seedCommand(db *gorm.DB, args []string) {
+	now := time.Now()
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	months := flags.Int("months", 24, "number of months of history to generate")
+	randomSeed := flags.Int64("seed", 1, "random seed, the same seed always generates the same history")
+	householdID := flags.Uint("household", 0, "household to seed, the first household when 0")
+	end := flags.String("end", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02"), "date of the most recent balance (YYYY-MM-DD)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: seed [-months n] [-seed n] [-end YYYY-MM-DD] [-household id]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	endDate, err := time.Parse("2006-01-02", *end)
+	if err != nil {
+		log.Fatalf("invalid -end: %s", err)
+	}
+
+	accounts, err := seed.Generate(seed.Config{Months: *months, Seed: *randomSeed, End: endDate})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := migrations.Up(db); err != nil {
+		log.Fatal(err)
+	}
+	household := lookupHousehold(db, *householdID)
+	if err := seed.Insert(db, household.ID, accounts); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("seeded %d accounts with %d months of history", len(accounts), *months)
+}
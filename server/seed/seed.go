@@ -0,0 +1,188 @@
+// Package seed generates plausible demo history for the dashboard.
+package seed
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Config controls the generated history. The same Config always produces the
+// same accounts and values.
+type Config struct {
+	// Months is the number of monthly balances generated for each account.
+	Months int
+	// Seed initialises the random number generator.
+	Seed int64
+	// End is the as of date of the most recent balance. Earlier balances fall
+	// on the same day of each preceding month.
+	End time.Time
+}
+
+// balanceFunc returns the next monthly balance given the previous one.
+type balanceFunc func(r *rand.Rand, previous float64) float64
+
+type profile struct {
+	account models.Account
+	opening float64
+	next    balanceFunc
+}
+
+// growth models an investment account receiving a fixed monthly contribution
+// and a normally distributed monthly return.
+func growth(contribution, meanReturn, volatility float64) balanceFunc {
+	return func(r *rand.Rand, previous float64) float64 {
+		monthlyReturn := meanReturn + r.NormFloat64()*volatility
+		return previous*(1+monthlyReturn) + contribution
+	}
+}
+
+// amortizing models a fixed rate loan paid down by a fixed monthly payment.
+func amortizing(annualRate, payment float64) balanceFunc {
+	return func(r *rand.Rand, previous float64) float64 {
+		balance := previous*(1+annualRate/12) - payment
+		return math.Max(balance, 0)
+	}
+}
+
+// fluctuating models a balance that moves randomly around a mean, such as a
+// checking account or a credit card that is paid off every month.
+func fluctuating(mean, spread float64) balanceFunc {
+	return func(r *rand.Rand, previous float64) float64 {
+		return math.Max(mean+(r.Float64()*2-1)*spread, 0)
+	}
+}
+
+// saving models an account with irregular deposits and the odd withdrawal.
+func saving(deposit float64) balanceFunc {
+	return func(r *rand.Rand, previous float64) float64 {
+		if r.Intn(6) == 0 {
+			return math.Max(previous-deposit*(2+r.Float64()*4), 0)
+		}
+		return previous + deposit*(0.5+r.Float64())
+	}
+}
+
+func profiles() []profile {
+	return []profile{
+		{
+			account: models.Account{Name: "Our Savings Account", Class: models.Asset, Category: models.Cash},
+			opening: 15000,
+			next:    saving(500),
+		},
+		{
+			account: models.Account{Name: "Our Checking Account", Class: models.Asset, Category: models.Cash},
+			opening: 4000,
+			next:    fluctuating(4000, 1500),
+		},
+		{
+			account: models.Account{Name: "My 401k", Class: models.Asset, Category: models.Retirement, TaxBucket: models.TaxDeferred},
+			opening: 42000,
+			next:    growth(850, 0.006, 0.04),
+		},
+		{
+			account: models.Account{Name: "SO 401k", Class: models.Asset, Category: models.Retirement, TaxBucket: models.TaxDeferred},
+			opening: 36000,
+			next:    growth(700, 0.006, 0.04),
+		},
+		{
+			account: models.Account{Name: "My IRA", Class: models.Asset, Category: models.Retirement, TaxBucket: models.Roth},
+			opening: 18000,
+			next:    growth(540, 0.006, 0.045),
+		},
+		{
+			account: models.Account{Name: "SO IRA", Class: models.Asset, Category: models.Retirement, TaxBucket: models.Roth},
+			opening: 12000,
+			next:    growth(540, 0.006, 0.045),
+		},
+		{
+			account: models.Account{Name: "House", Class: models.Asset, Category: models.RealEstate},
+			opening: 350000,
+			next:    growth(0, 0.003, 0.006),
+		},
+		{
+			account: models.Account{Name: "Student Loan", Class: models.Liability, Category: models.Loan},
+			opening: 28000,
+			next:    amortizing(0.05, 320),
+		},
+		{
+			account: models.Account{Name: "Mortgage", Class: models.Liability, Category: models.Loan},
+			opening: 280000,
+			next:    amortizing(0.065, 1770),
+		},
+		{
+			account: models.Account{Name: "Auto Loan", Class: models.Liability, Category: models.Loan},
+			opening: 24000,
+			next:    amortizing(0.07, 475),
+		},
+		{
+			account: models.Account{Name: "Credit Card", Class: models.Liability, Category: models.CreditCard},
+			opening: 1800,
+			next:    fluctuating(1800, 1300),
+		},
+	}
+}
+
+// Generate returns the demo accounts with cfg.Months monthly values each,
+// ordered newest first like the models package returns them.
+func Generate(cfg Config) ([]models.Account, error) {
+	if cfg.Months < 1 {
+		return nil, fmt.Errorf("months must be at least 1, got %d", cfg.Months)
+	}
+
+	r := rand.New(rand.NewSource(cfg.Seed))
+	accounts := []models.Account{}
+	for _, p := range profiles() {
+		account := p.account
+		account.Values = make([]models.AccountValue, cfg.Months)
+
+		balance := p.opening
+		for i := cfg.Months - 1; i >= 0; i-- {
+			account.Values[i] = models.AccountValue{
+				Value: decimal.NewFromFloat(balance).Round(2),
+				AsOf:  cfg.End.AddDate(0, -i, 0),
+			}
+			balance = p.next(r, balance)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// Insert writes the generated accounts and their values into the household in
+// a single transaction. It refuses to run against a household that already
+// has accounts so demo data is never mixed with real data.
+func Insert(db *gorm.DB, householdID uint, accounts []models.Account) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		existing := int64(0)
+		if err := tx.Model(&models.Account{}).Where("household_id = ?", householdID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("household already has %d accounts, seed only runs against an empty household", existing)
+		}
+
+		for _, account := range accounts {
+			values := account.Values
+			account.Values = nil
+
+			account, err := models.CreateAccount(tx, householdID, account)
+			if err != nil {
+				return err
+			}
+
+			for _, value := range values {
+				value.AccountID = account.ID
+				if _, err := models.CreateAccountValue(tx, householdID, value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,103 @@
+package seed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+)
+
+var testEnd = time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	first, err := Generate(Config{Months: 12, Seed: 42, End: testEnd})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	second, err := Generate(Config{Months: 12, Seed: 42, End: testEnd})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	other, err := Generate(Config{Months: 12, Seed: 43, End: testEnd})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	differs := false
+	for i := range first {
+		assert.Equal(t, first[i].Name, second[i].Name)
+		for j := range first[i].Values {
+			if !first[i].Values[j].Value.Equal(second[i].Values[j].Value) {
+				t.Errorf("%s value %d: wanted %v, got %v", first[i].Name, j, first[i].Values[j].Value, second[i].Values[j].Value)
+			}
+			if !first[i].Values[j].Value.Equal(other[i].Values[j].Value) {
+				differs = true
+			}
+		}
+	}
+	if !differs {
+		t.Errorf("wanted a different seed to generate different history")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	months := 36
+	accounts, err := Generate(Config{Months: months, Seed: 1, End: testEnd})
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+
+	for _, account := range accounts {
+		t.Run(account.Name, func(t *testing.T) {
+			if err := models.ValidateAccount(account); err != nil {
+				t.Errorf(err.Error())
+			}
+			assert.Equal(t, months, len(account.Values))
+			assert.Equal(t, testEnd, account.Values[0].AsOf)
+			assert.Equal(t, testEnd.AddDate(0, -(months-1), 0), account.Values[months-1].AsOf)
+
+			for i, value := range account.Values {
+				if value.Value.IsNegative() {
+					t.Errorf("value %d is negative: %v", i, value.Value)
+				}
+				if i > 0 && !value.AsOf.Before(account.Values[i-1].AsOf) {
+					t.Errorf("values are not ordered newest first")
+				}
+				if account.Category == models.Loan && i > 0 && value.Value.LessThan(account.Values[i-1].Value) {
+					t.Errorf("loan balance grew from %v to %v", value.Value, account.Values[i-1].Value)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsNoMonths(t *testing.T) {
+	_, err := Generate(Config{Months: 0, Seed: 1, End: testEnd})
+	if err == nil {
+		t.Errorf("wanted error, got nil")
+	}
+}
+
+func TestInsertRefusesNonEmptyDatabase(t *testing.T) {
+	db, mock, err := models.CreateMockDatabase()
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	d, _ := db.DB()
+	defer d.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT count(.+) FROM \"accounts\" WHERE household_id").WithArgs(models.MockHouseholdID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectRollback()
+
+	accounts, _ := Generate(Config{Months: 1, Seed: 1, End: testEnd})
+	if err := Insert(db, models.MockHouseholdID, accounts); err == nil {
+		t.Errorf("wanted error, got nil")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
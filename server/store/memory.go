@@ -0,0 +1,929 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/models"
+	"gorm.io/gorm"
+)
+
+// MemoryStore keeps accounts and values in memory. It behaves like GormStore,
+// so tests can exercise the API without a database: households only see their
+// own accounts, deleted accounts are kept but hidden, names only need to be
+// unique among a household's accounts that have not been deleted, and values
+// are returned newest first. Only users added with AddMember can own accounts.
+type MemoryStore struct {
+	mu           sync.Mutex
+	accounts     map[uint]models.Account
+	values       map[uint]models.AccountValue
+	members      map[uint]uint
+	rates        []models.FXRate
+	securities   map[uint]models.Security
+	prices       []models.Price
+	positions    map[uint]models.Position
+	lots         map[uint]models.Lot
+	sales        map[uint]models.Sale
+	flows        map[uint]models.CashFlow
+	lastAccount  uint
+	lastValue    uint
+	lastRate     uint
+	lastPrice    uint
+	lastSecurity uint
+	lastPosition uint
+	lastLot      uint
+	lastSale     uint
+	lastMatch    uint
+	lastFlow     uint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts:   map[uint]models.Account{},
+		values:     map[uint]models.AccountValue{},
+		members:    map[uint]uint{},
+		securities: map[uint]models.Security{},
+		positions:  map[uint]models.Position{},
+		lots:       map[uint]models.Lot{},
+		sales:      map[uint]models.Sale{},
+		flows:      map[uint]models.CashFlow{},
+	}
+}
+
+// AddMember makes the user a member of the household, as creating the user in
+// the database would.
+func (s *MemoryStore) AddMember(householdID, userID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.members[userID] = householdID
+}
+
+// owners returns a copy of the owners ready to be stored on the account, or
+// models.ErrNotHouseholdMember if any of them is not in the household.
+func (s *MemoryStore) owners(householdID, accountID uint, owners []models.AccountOwner) ([]models.AccountOwner, error) {
+	if owners == nil {
+		return nil, nil
+	}
+	stored := make([]models.AccountOwner, len(owners))
+	for i, owner := range owners {
+		if household, ok := s.members[owner.UserID]; !ok || household != householdID {
+			return nil, models.ErrNotHouseholdMember
+		}
+		owner.AccountID = accountID
+		owner.Share = owner.Share.Round(2)
+		stored[i] = owner
+	}
+	return stored, nil
+}
+
+// live returns the household's account with the given ID unless it does not
+// exist or has been deleted.
+func (s *MemoryStore) live(householdID, id uint) (models.Account, bool) {
+	account, ok := s.accounts[id]
+	if !ok || account.HouseholdID != householdID || account.DeletedAt.Valid {
+		return models.Account{}, false
+	}
+	return account, true
+}
+
+func (s *MemoryStore) liveByName(householdID uint, name string) (models.Account, bool) {
+	for _, account := range s.accounts {
+		if account.HouseholdID == householdID && account.Name == name && !account.DeletedAt.Valid {
+			return account, true
+		}
+	}
+	return models.Account{}, false
+}
+
+// sortedAccounts returns the household's accounts ordered by ID, including
+// deleted ones when unscoped is set.
+func (s *MemoryStore) sortedAccounts(householdID uint, unscoped bool) []models.Account {
+	accounts := []models.Account{}
+	for _, account := range s.accounts {
+		if account.HouseholdID == householdID && (unscoped || !account.DeletedAt.Valid) {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+	return accounts
+}
+
+// withValues returns a copy of account holding its values, newest first.
+func (s *MemoryStore) withValues(account models.Account) models.Account {
+	account.Values = []models.AccountValue{}
+	for _, av := range s.values {
+		if av.AccountID == account.ID {
+			account.Values = append(account.Values, av)
+		}
+	}
+	sort.Slice(account.Values, func(i, j int) bool {
+		if account.Values[i].AsOf.Equal(account.Values[j].AsOf) {
+			return account.Values[i].ID < account.Values[j].ID
+		}
+		return account.Values[i].AsOf.After(account.Values[j].AsOf)
+	})
+	return account
+}
+
+func (s *MemoryStore) allWithValues(householdID uint, unscoped bool, keep func(models.Account) bool) []models.Account {
+	accounts := []models.Account{}
+	for _, account := range s.sortedAccounts(householdID, unscoped) {
+		if keep(account) {
+			accounts = append(accounts, s.withValues(account))
+		}
+	}
+	return accounts
+}
+
+// update applies the non-zero fields of updates to the account, as gorm does
+// when updating with a struct.
+func (s *MemoryStore) update(account models.Account, updates models.Account) (models.Account, error) {
+	if updates.Name != "" && updates.Name != account.Name {
+		if _, taken := s.liveByName(account.HouseholdID, updates.Name); taken {
+			return account, fmt.Errorf("%w: %s", models.ErrAccountNameTaken, updates.Name)
+		}
+		account.Name = updates.Name
+	}
+	if updates.Class != "" {
+		account.Class = updates.Class
+	}
+	if updates.Category != "" {
+		account.Category = updates.Category
+	}
+	if updates.TaxBucket != "" {
+		account.TaxBucket = updates.TaxBucket
+	}
+	if updates.Currency != "" {
+		account.Currency = updates.Currency
+	}
+	if updates.Precision != nil {
+		account.Precision = updates.Precision
+	}
+	if updates.Owners != nil {
+		owners, err := s.owners(account.HouseholdID, account.ID, updates.Owners)
+		if err != nil {
+			return account, err
+		}
+		account.Owners = owners
+	}
+	account.UpdatedAt = time.Now()
+	s.accounts[account.ID] = account
+	return account, nil
+}
+
+func (s *MemoryStore) delete(account models.Account) models.Account {
+	account.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	s.accounts[account.ID] = account
+	return account
+}
+
+func (s *MemoryStore) AccountExists(householdID uint, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.liveByName(householdID, name)
+	return ok, nil
+}
+
+func (s *MemoryStore) AccountExistsByID(householdID, id uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.live(householdID, id)
+	return ok, nil
+}
+
+// CreateAccount stores the account in the household along with any values it
+// holds. An ID, timestamps or deletion time set on the account are kept, which
+// lets tests load fixtures as they would be read back from a database.
+func (s *MemoryStore) CreateAccount(householdID uint, account models.Account) (models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account.HouseholdID = householdID
+	if !account.DeletedAt.Valid {
+		if _, taken := s.liveByName(householdID, account.Name); taken {
+			return account, fmt.Errorf("%w: %s", models.ErrAccountNameTaken, account.Name)
+		}
+	}
+	if account.Currency == "" {
+		account.Currency = models.DefaultCurrency
+	}
+	if account.ID == 0 {
+		account.ID = s.lastAccount + 1
+	} else if _, ok := s.accounts[account.ID]; ok {
+		return account, fmt.Errorf("account %d already exists", account.ID)
+	}
+	owners, err := s.owners(householdID, account.ID, account.Owners)
+	if err != nil {
+		return account, err
+	}
+	account.Owners = owners
+	if account.ID > s.lastAccount {
+		s.lastAccount = account.ID
+	}
+
+	now := time.Now()
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = now
+	}
+	if account.UpdatedAt.IsZero() {
+		account.UpdatedAt = now
+	}
+
+	for i := range account.Values {
+		av := &account.Values[i]
+		if av.ID == 0 {
+			av.ID = s.lastValue + 1
+		}
+		if av.ID > s.lastValue {
+			s.lastValue = av.ID
+		}
+		if av.CreatedAt.IsZero() {
+			av.CreatedAt = now
+		}
+		av.AccountID = account.ID
+		s.values[av.ID] = *av
+	}
+
+	stored := account
+	stored.Values = nil
+	s.accounts[account.ID] = stored
+	return account, nil
+}
+
+func (s *MemoryStore) GetAllAccountsWithValues(householdID uint) ([]models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.allWithValues(householdID, false, func(models.Account) bool { return true }), nil
+}
+
+func (s *MemoryStore) GetAllAccountsWithValuesIncludingDeleted(householdID uint) ([]models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.allWithValues(householdID, true, func(models.Account) bool { return true }), nil
+}
+
+func (s *MemoryStore) GetAllAccountsByClassWithValues(householdID uint, class models.AccountClass) ([]models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.allWithValues(householdID, false, func(account models.Account) bool { return account.Class == class }), nil
+}
+
+func (s *MemoryStore) GetAccount(householdID, id uint) (models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.live(householdID, id)
+	if !ok {
+		return account, gorm.ErrRecordNotFound
+	}
+	return account, nil
+}
+
+func (s *MemoryStore) GetAccountByName(householdID uint, name string) (models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.liveByName(householdID, name)
+	if !ok {
+		return account, gorm.ErrRecordNotFound
+	}
+	return account, nil
+}
+
+func (s *MemoryStore) GetAccountByNameWithValues(householdID uint, name string) (models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.liveByName(householdID, name)
+	if !ok {
+		return account, gorm.ErrRecordNotFound
+	}
+	return s.withValues(account), nil
+}
+
+func (s *MemoryStore) GetAccountWithValues(householdID, id uint) (models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.live(householdID, id)
+	if !ok {
+		return account, gorm.ErrRecordNotFound
+	}
+	return s.withValues(account), nil
+}
+
+func (s *MemoryStore) UpdateAccount(householdID uint, name string, updates models.Account) (models.Account, error) {
+	if err := models.ValidateAccount(updates); err != nil {
+		return models.Account{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.liveByName(householdID, name)
+	if !ok {
+		return account, gorm.ErrRecordNotFound
+	}
+	return s.update(account, updates)
+}
+
+func (s *MemoryStore) UpdateAccountByID(householdID, id uint, updates models.Account) (models.Account, error) {
+	if err := models.ValidateAccount(updates); err != nil {
+		return models.Account{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.live(householdID, id)
+	if !ok {
+		return account, gorm.ErrRecordNotFound
+	}
+	return s.update(account, updates)
+}
+
+func (s *MemoryStore) DeleteAccount(householdID uint, name string) (models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.liveByName(householdID, name)
+	if !ok {
+		return account, gorm.ErrRecordNotFound
+	}
+	return s.delete(account), nil
+}
+
+func (s *MemoryStore) DeleteAccountByID(householdID, id uint) (models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.live(householdID, id)
+	if !ok {
+		return account, gorm.ErrRecordNotFound
+	}
+	return s.delete(account), nil
+}
+
+func (s *MemoryStore) CreateAccountValue(householdID uint, av models.AccountValue) (models.AccountValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.live(householdID, av.AccountID); !ok {
+		return av, fmt.Errorf(`account %d does not exist`, av.AccountID)
+	}
+
+	now := time.Now()
+	if av.AsOf.IsZero() {
+		av.AsOf = now
+	}
+	av.Value = av.Value.Round(models.MaxPrecision)
+	av.ID = s.lastValue + 1
+	s.lastValue = av.ID
+	av.CreatedAt = now
+	s.values[av.ID] = av
+	return av, nil
+}
+
+func (s *MemoryStore) AccountValueExists(householdID uint, av models.AccountValue) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value := av.Value.Round(models.MaxPrecision)
+	for _, existing := range s.values {
+		if s.accounts[existing.AccountID].HouseholdID != householdID {
+			continue
+		}
+		if existing.AccountID == av.AccountID && existing.AsOf.Equal(av.AsOf) && existing.Value.Equal(value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// value returns the account value with the given ID, treating values of
+// deleted accounts and of other households as not found.
+func (s *MemoryStore) value(householdID, id uint) (models.AccountValue, error) {
+	av, ok := s.values[id]
+	if !ok {
+		return av, gorm.ErrRecordNotFound
+	}
+	if _, ok := s.live(householdID, av.AccountID); !ok {
+		return av, fmt.Errorf(`account %d does not exist: %w`, av.AccountID, gorm.ErrRecordNotFound)
+	}
+	return av, nil
+}
+
+func (s *MemoryStore) GetAccountValue(householdID, id uint) (models.AccountValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.value(householdID, id)
+}
+
+func (s *MemoryStore) UpdateAccountValue(householdID, id uint, updates models.AccountValue) (models.AccountValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	av, err := s.value(householdID, id)
+	if err != nil {
+		return av, err
+	}
+
+	if updates.AccountID != 0 && updates.AccountID != av.AccountID {
+		if _, ok := s.live(householdID, updates.AccountID); !ok {
+			return av, fmt.Errorf(`account %d does not exist`, updates.AccountID)
+		}
+		av.AccountID = updates.AccountID
+	}
+	if !updates.Value.IsZero() {
+		av.Value = updates.Value.Round(models.MaxPrecision)
+	}
+	if !updates.AsOf.IsZero() {
+		av.AsOf = updates.AsOf
+	}
+	s.values[id] = av
+	return av, nil
+}
+
+func (s *MemoryStore) DeleteAccountValue(householdID, id uint) (models.AccountValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	av, err := s.value(householdID, id)
+	if err != nil {
+		return av, err
+	}
+	delete(s.values, id)
+	return av, nil
+}
+
+func (s *MemoryStore) IsHouseholdMember(householdID, userID uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	household, ok := s.members[userID]
+	return ok && household == householdID, nil
+}
+
+// SaveFXRate stores the rate, replacing any rate already recorded for the same
+// pair on the same date.
+func (s *MemoryStore) SaveFXRate(rate models.FXRate) (models.FXRate, error) {
+	if err := models.ValidateFXRate(rate); err != nil {
+		return rate, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rate.Rate = rate.Rate.Round(8)
+	for i, existing := range s.rates {
+		if existing.Base == rate.Base && existing.Quote == rate.Quote && existing.Date.Equal(rate.Date) {
+			rate.ID = existing.ID
+			s.rates[i] = rate
+			return rate, nil
+		}
+	}
+	s.lastRate++
+	rate.ID = s.lastRate
+	s.rates = append(s.rates, rate)
+	return rate, nil
+}
+
+func (s *MemoryStore) GetFXRates(currency models.Currency) ([]models.FXRate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rates := []models.FXRate{}
+	for _, rate := range s.rates {
+		if rate.Base == currency || rate.Quote == currency {
+			rates = append(rates, rate)
+		}
+	}
+	sort.SliceStable(rates, func(i, j int) bool {
+		if rates[i].Date.Equal(rates[j].Date) {
+			return rates[i].ID < rates[j].ID
+		}
+		return rates[i].Date.Before(rates[j].Date)
+	})
+	return rates, nil
+}
+
+func (s *MemoryStore) CreateSecurity(security models.Security) (models.Security, error) {
+	if err := models.ValidateSecurity(security); err != nil {
+		return security, err
+	}
+	security.Symbol, _ = models.ParseSymbol(security.Symbol)
+	if security.Currency == "" {
+		security.Currency = models.DefaultCurrency
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.securities {
+		if existing.Symbol == security.Symbol {
+			return security, fmt.Errorf("%w: %s", models.ErrSecurityExists, security.Symbol)
+		}
+	}
+	s.lastSecurity++
+	security.ID = s.lastSecurity
+	s.securities[security.ID] = security
+	return security, nil
+}
+
+func (s *MemoryStore) GetSecurities() ([]models.Security, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	securities := []models.Security{}
+	for _, security := range s.securities {
+		securities = append(securities, security)
+	}
+	sort.Slice(securities, func(i, j int) bool { return securities[i].Symbol < securities[j].Symbol })
+	return securities, nil
+}
+
+func (s *MemoryStore) GetSecurity(id uint) (models.Security, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	security, ok := s.securities[id]
+	if !ok {
+		return security, gorm.ErrRecordNotFound
+	}
+	return security, nil
+}
+
+// SavePrice stores the price, replacing any price already recorded for the
+// security on the same date.
+func (s *MemoryStore) SavePrice(price models.Price) (models.Price, error) {
+	if err := models.ValidatePrice(price); err != nil {
+		return price, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	price.Price = price.Price.Round(models.MaxPrecision)
+	for i, existing := range s.prices {
+		if existing.SecurityID == price.SecurityID && existing.Date.Equal(price.Date) {
+			price.ID = existing.ID
+			s.prices[i] = price
+			return price, nil
+		}
+	}
+	s.lastPrice++
+	price.ID = s.lastPrice
+	s.prices = append(s.prices, price)
+	return price, nil
+}
+
+func (s *MemoryStore) GetPrices(securityIDs []uint) ([]models.Price, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := map[uint]bool{}
+	for _, id := range securityIDs {
+		wanted[id] = true
+	}
+	prices := []models.Price{}
+	for _, price := range s.prices {
+		if wanted[price.SecurityID] {
+			prices = append(prices, price)
+		}
+	}
+	sort.SliceStable(prices, func(i, j int) bool {
+		if prices[i].Date.Equal(prices[j].Date) {
+			return prices[i].ID < prices[j].ID
+		}
+		return prices[i].Date.Before(prices[j].Date)
+	})
+	return prices, nil
+}
+
+func (s *MemoryStore) CreatePosition(householdID uint, position models.Position) (models.Position, error) {
+	if err := models.ValidatePosition(position); err != nil {
+		return position, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkHolding(householdID, position.AccountID, position.SecurityID); err != nil {
+		return position, err
+	}
+
+	if position.AsOf.IsZero() {
+		position.AsOf = time.Now()
+	}
+	position.Quantity = position.Quantity.Round(models.MaxPrecision)
+	position.CostBasis = position.CostBasis.Round(models.MaxPrecision)
+	position = s.addPosition(position)
+	security := s.securities[position.SecurityID]
+	position.Security = &security
+	return position, nil
+}
+
+// checkHolding checks that the security exists and can be held in one of the
+// household's accounts.
+func (s *MemoryStore) checkHolding(householdID, accountID, securityID uint) error {
+	account, ok := s.live(householdID, accountID)
+	if !ok {
+		return fmt.Errorf(`account %d does not exist`, accountID)
+	}
+	security, ok := s.securities[securityID]
+	if !ok {
+		return fmt.Errorf(`security %d does not exist`, securityID)
+	}
+	return models.CheckPositionCurrency(account, security)
+}
+
+func (s *MemoryStore) addPosition(position models.Position) models.Position {
+	s.lastPosition++
+	position.ID = s.lastPosition
+	position.CreatedAt = time.Now()
+	position.Security = nil
+	s.positions[position.ID] = position
+	return position
+}
+
+// latestPosition returns the account's latest position in the security, or
+// nil if it has none.
+func (s *MemoryStore) latestPosition(accountID, securityID uint) *models.Position {
+	positions := s.sortedPositions(func(position models.Position) bool {
+		return position.AccountID == accountID && position.SecurityID == securityID
+	})
+	if len(positions) == 0 {
+		return nil
+	}
+	return &positions[len(positions)-1]
+}
+
+// sortedPositions returns the positions kept by keep, oldest first.
+func (s *MemoryStore) sortedPositions(keep func(models.Position) bool) []models.Position {
+	positions := []models.Position{}
+	for _, position := range s.positions {
+		if keep(position) {
+			positions = append(positions, position)
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].AsOf.Equal(positions[j].AsOf) {
+			return positions[i].ID < positions[j].ID
+		}
+		return positions[i].AsOf.Before(positions[j].AsOf)
+	})
+	return positions
+}
+
+func (s *MemoryStore) GetPositions(householdID, accountID uint) ([]models.Position, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.live(householdID, accountID); !ok {
+		return []models.Position{}, fmt.Errorf(`account %d does not exist: %w`, accountID, gorm.ErrRecordNotFound)
+	}
+	sorted := s.sortedPositions(func(position models.Position) bool { return position.AccountID == accountID })
+	positions := make([]models.Position, len(sorted))
+	for i, position := range sorted {
+		security := s.securities[position.SecurityID]
+		position.Security = &security
+		positions[len(sorted)-1-i] = position
+	}
+	return positions, nil
+}
+
+func (s *MemoryStore) GetAllPositions(householdID uint) ([]models.Position, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedPositions(func(position models.Position) bool {
+		account, ok := s.accounts[position.AccountID]
+		return ok && account.HouseholdID == householdID
+	}), nil
+}
+
+func (s *MemoryStore) DeletePosition(householdID, id uint) (models.Position, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	position, ok := s.positions[id]
+	if !ok {
+		return position, gorm.ErrRecordNotFound
+	}
+	if _, ok := s.live(householdID, position.AccountID); !ok {
+		return position, fmt.Errorf(`account %d does not exist: %w`, position.AccountID, gorm.ErrRecordNotFound)
+	}
+	delete(s.positions, id)
+	return position, nil
+}
+
+// CreateLot records a purchase in one of the household's accounts, along with
+// the position it leaves the account with.
+func (s *MemoryStore) CreateLot(householdID uint, lot models.Lot) (models.Lot, error) {
+	if err := models.ValidateLot(lot); err != nil {
+		return lot, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkHolding(householdID, lot.AccountID, lot.SecurityID); err != nil {
+		return lot, err
+	}
+	now := time.Now()
+	if lot.AcquiredAt.IsZero() {
+		lot.AcquiredAt = now
+	}
+	lot.Quantity = lot.Quantity.Round(models.MaxPrecision)
+	lot.Cost = lot.Cost.Round(models.MaxPrecision)
+	position, err := models.TradePosition(s.latestPosition(lot.AccountID, lot.SecurityID), lot.AccountID, lot.SecurityID, lot.Quantity, lot.Cost, lot.AcquiredAt)
+	if err != nil {
+		return lot, err
+	}
+
+	s.lastLot++
+	lot.ID = s.lastLot
+	lot.CreatedAt = now
+	lot.Security = nil
+	s.lots[lot.ID] = lot
+	s.addPosition(position)
+	return lot, nil
+}
+
+// CreateSale records a sale from one of the household's accounts, matched
+// against its open lots, along with the position it leaves the account with.
+func (s *MemoryStore) CreateSale(householdID uint, sale models.Sale) (models.Sale, error) {
+	if err := models.ValidateSale(sale); err != nil {
+		return sale, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkHolding(householdID, sale.AccountID, sale.SecurityID); err != nil {
+		return sale, err
+	}
+	now := time.Now()
+	if sale.SoldAt.IsZero() {
+		sale.SoldAt = now
+	}
+	sale.Quantity = sale.Quantity.Round(models.MaxPrecision)
+	sale.Proceeds = sale.Proceeds.Round(models.MaxPrecision)
+
+	lots := []models.Lot{}
+	for _, lot := range s.lots {
+		lots = append(lots, lot)
+	}
+	sales := []models.Sale{}
+	for _, existing := range s.sales {
+		sales = append(sales, existing)
+	}
+	sale, err := models.MatchLots(sale, lots, sales)
+	if err != nil {
+		return sale, err
+	}
+	position, err := models.TradePosition(s.latestPosition(sale.AccountID, sale.SecurityID), sale.AccountID, sale.SecurityID, sale.Quantity.Neg(), models.SaleCost(sale).Neg(), sale.SoldAt)
+	if err != nil {
+		return sale, err
+	}
+
+	s.lastSale++
+	sale.ID = s.lastSale
+	sale.CreatedAt = now
+	for i := range sale.Matches {
+		s.lastMatch++
+		sale.Matches[i].ID = s.lastMatch
+		sale.Matches[i].SaleID = sale.ID
+	}
+	s.sales[sale.ID] = sale
+	s.addPosition(position)
+	return sale, nil
+}
+
+func (s *MemoryStore) GetAllLots(householdID uint) ([]models.Lot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lots := []models.Lot{}
+	for _, lot := range s.lots {
+		if account, ok := s.accounts[lot.AccountID]; ok && account.HouseholdID == householdID {
+			security := s.securities[lot.SecurityID]
+			lot.Security = &security
+			lots = append(lots, lot)
+		}
+	}
+	sort.Slice(lots, func(i, j int) bool {
+		if lots[i].AcquiredAt.Equal(lots[j].AcquiredAt) {
+			return lots[i].ID < lots[j].ID
+		}
+		return lots[i].AcquiredAt.Before(lots[j].AcquiredAt)
+	})
+	return lots, nil
+}
+
+func (s *MemoryStore) GetAllSales(householdID uint) ([]models.Sale, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sales := []models.Sale{}
+	for _, sale := range s.sales {
+		if account, ok := s.accounts[sale.AccountID]; ok && account.HouseholdID == householdID {
+			sales = append(sales, sale)
+		}
+	}
+	sort.Slice(sales, func(i, j int) bool {
+		if sales[i].SoldAt.Equal(sales[j].SoldAt) {
+			return sales[i].ID < sales[j].ID
+		}
+		return sales[i].SoldAt.Before(sales[j].SoldAt)
+	})
+	return sales, nil
+}
+
+func (s *MemoryStore) CreateCashFlow(householdID uint, flow models.CashFlow) (models.CashFlow, error) {
+	if err := models.ValidateCashFlow(flow); err != nil {
+		return flow, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.live(householdID, flow.AccountID); !ok {
+		return flow, fmt.Errorf(`account %d does not exist`, flow.AccountID)
+	}
+	if flow.Date.IsZero() {
+		flow.Date = time.Now()
+	}
+	flow.Amount = flow.Amount.Round(models.MaxPrecision)
+	s.lastFlow++
+	flow.ID = s.lastFlow
+	flow.CreatedAt = time.Now()
+	s.flows[flow.ID] = flow
+	return flow, nil
+}
+
+// sortedCashFlows returns the cash flows kept by keep, oldest first.
+func (s *MemoryStore) sortedCashFlows(keep func(models.CashFlow) bool) []models.CashFlow {
+	flows := []models.CashFlow{}
+	for _, flow := range s.flows {
+		if keep(flow) {
+			flows = append(flows, flow)
+		}
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		if flows[i].Date.Equal(flows[j].Date) {
+			return flows[i].ID < flows[j].ID
+		}
+		return flows[i].Date.Before(flows[j].Date)
+	})
+	return flows
+}
+
+func (s *MemoryStore) GetCashFlows(householdID, accountID uint) ([]models.CashFlow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.live(householdID, accountID); !ok {
+		return []models.CashFlow{}, fmt.Errorf(`account %d does not exist: %w`, accountID, gorm.ErrRecordNotFound)
+	}
+	sorted := s.sortedCashFlows(func(flow models.CashFlow) bool { return flow.AccountID == accountID })
+	flows := make([]models.CashFlow, len(sorted))
+	for i, flow := range sorted {
+		flows[len(sorted)-1-i] = flow
+	}
+	return flows, nil
+}
+
+func (s *MemoryStore) GetAllCashFlows(householdID uint) ([]models.CashFlow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedCashFlows(func(flow models.CashFlow) bool {
+		account, ok := s.accounts[flow.AccountID]
+		return ok && account.HouseholdID == householdID
+	}), nil
+}
+
+func (s *MemoryStore) DeleteCashFlow(householdID, id uint) (models.CashFlow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flow, ok := s.flows[id]
+	if !ok {
+		return flow, gorm.ErrRecordNotFound
+	}
+	if _, ok := s.live(householdID, flow.AccountID); !ok {
+		return flow, fmt.Errorf(`account %d does not exist: %w`, flow.AccountID, gorm.ErrRecordNotFound)
+	}
+	delete(s.flows, id)
+	return flow, nil
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type Driver string
+
+const (
+	Postgres Driver = "postgres"
+	SQLite   Driver = "sqlite"
+)
+
+func ParseDriver(s string) (Driver, error) {
+	switch driver := Driver(s); driver {
+	case Postgres, SQLite:
+		return driver, nil
+	}
+	return "", fmt.Errorf("invalid database driver %q, expected postgres or sqlite", s)
+}
+
+// Config selects the database to connect to. DSN is a Postgres connection
+// string, or for SQLite the path of the database file.
+type Config struct {
+	Driver Driver
+	DSN    string
+}
+
+// sqlitePragmas are set on every SQLite connection. Foreign keys are off by
+// default in SQLite, and the busy timeout lets concurrent requests wait for
+// the write lock instead of failing.
+var sqlitePragmas = []string{
+	"foreign_keys(1)",
+	"busy_timeout(5000)",
+	"journal_mode(WAL)",
+}
+
+func sqliteDSN(path string) string {
+	params := make([]string, len(sqlitePragmas))
+	for i, pragma := range sqlitePragmas {
+		params[i] = "_pragma=" + pragma
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + strings.Join(params, "&")
+}
+
+// Open connects to the database described by config.
+func Open(config Config, gormConfig *gorm.Config) (*gorm.DB, error) {
+	switch config.Driver {
+	case Postgres:
+		return gorm.Open(postgres.Open(config.DSN), gormConfig)
+	case SQLite:
+		db, err := gorm.Open(sqlite.Open(sqliteDSN(config.DSN)), gormConfig)
+		if err != nil {
+			return nil, err
+		}
+		// SQLite allows a single writer, so share one connection rather than
+		// have requests fail with "database is locked".
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		return db, nil
+	}
+	return nil, fmt.Errorf("invalid database driver %q, expected postgres or sqlite", config.Driver)
+}
@@ -0,0 +1,229 @@
+// Package store defines how the API reads and writes accounts and their
+// values, independent of the database they are kept in.
+package store
+
+import (
+	"github.com/Jrc356/financial_dashboard/models"
+	"gorm.io/gorm"
+)
+
+// Store reads and writes accounts and account values on behalf of a household.
+// Records of other households are never returned or changed. Lookups of
+// records that do not exist return an error wrapping gorm.ErrRecordNotFound,
+// renaming an account to a name in use returns one wrapping
+// models.ErrAccountNameTaken, and giving an account an owner outside the
+// household returns models.ErrNotHouseholdMember. Exchange rates, securities
+// and their prices are shared by every household.
+type Store interface {
+	AccountExists(householdID uint, name string) (bool, error)
+	AccountExistsByID(householdID, id uint) (bool, error)
+	CreateAccount(householdID uint, account models.Account) (models.Account, error)
+	GetAllAccountsWithValues(householdID uint) ([]models.Account, error)
+	GetAllAccountsWithValuesIncludingDeleted(householdID uint) ([]models.Account, error)
+	GetAllAccountsByClassWithValues(householdID uint, class models.AccountClass) ([]models.Account, error)
+	GetAccount(householdID, id uint) (models.Account, error)
+	GetAccountByName(householdID uint, name string) (models.Account, error)
+	GetAccountByNameWithValues(householdID uint, name string) (models.Account, error)
+	GetAccountWithValues(householdID, id uint) (models.Account, error)
+	UpdateAccount(householdID uint, name string, updates models.Account) (models.Account, error)
+	UpdateAccountByID(householdID, id uint, updates models.Account) (models.Account, error)
+	DeleteAccount(householdID uint, name string) (models.Account, error)
+	DeleteAccountByID(householdID, id uint) (models.Account, error)
+
+	CreateAccountValue(householdID uint, av models.AccountValue) (models.AccountValue, error)
+	AccountValueExists(householdID uint, av models.AccountValue) (bool, error)
+	GetAccountValue(householdID, id uint) (models.AccountValue, error)
+	UpdateAccountValue(householdID, id uint, updates models.AccountValue) (models.AccountValue, error)
+	DeleteAccountValue(householdID, id uint) (models.AccountValue, error)
+
+	IsHouseholdMember(householdID, userID uint) (bool, error)
+
+	SaveFXRate(rate models.FXRate) (models.FXRate, error)
+	GetFXRates(currency models.Currency) ([]models.FXRate, error)
+
+	CreateSecurity(security models.Security) (models.Security, error)
+	GetSecurities() ([]models.Security, error)
+	GetSecurity(id uint) (models.Security, error)
+	SavePrice(price models.Price) (models.Price, error)
+	GetPrices(securityIDs []uint) ([]models.Price, error)
+
+	CreatePosition(householdID uint, position models.Position) (models.Position, error)
+	GetPositions(householdID, accountID uint) ([]models.Position, error)
+	GetAllPositions(householdID uint) ([]models.Position, error)
+	DeletePosition(householdID, id uint) (models.Position, error)
+
+	CreateLot(householdID uint, lot models.Lot) (models.Lot, error)
+	CreateSale(householdID uint, sale models.Sale) (models.Sale, error)
+	GetAllLots(householdID uint) ([]models.Lot, error)
+	GetAllSales(householdID uint) ([]models.Sale, error)
+	CreateCashFlow(householdID uint, flow models.CashFlow) (models.CashFlow, error)
+	GetCashFlows(householdID, accountID uint) ([]models.CashFlow, error)
+	GetAllCashFlows(householdID uint) ([]models.CashFlow, error)
+	DeleteCashFlow(householdID, id uint) (models.CashFlow, error)
+}
+
+// GormStore keeps accounts in a SQL database through gorm. It is used for
+// both Postgres and SQLite, see Open.
+type GormStore struct {
+	DB *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{DB: db}
+}
+
+func (s *GormStore) AccountExists(householdID uint, name string) (bool, error) {
+	return models.AccountExists(s.DB, householdID, name)
+}
+
+func (s *GormStore) AccountExistsByID(householdID, id uint) (bool, error) {
+	return models.AccountExistsByID(s.DB, householdID, id)
+}
+
+func (s *GormStore) CreateAccount(householdID uint, account models.Account) (models.Account, error) {
+	return models.CreateAccount(s.DB, householdID, account)
+}
+
+func (s *GormStore) GetAllAccountsWithValues(householdID uint) ([]models.Account, error) {
+	return models.GetAllAccountsWithValues(s.DB, householdID)
+}
+
+func (s *GormStore) GetAllAccountsWithValuesIncludingDeleted(householdID uint) ([]models.Account, error) {
+	return models.GetAllAccountsWithValuesIncludingDeleted(s.DB, householdID)
+}
+
+func (s *GormStore) GetAllAccountsByClassWithValues(householdID uint, class models.AccountClass) ([]models.Account, error) {
+	return models.GetAllAccountsByClassWithValues(s.DB, householdID, class)
+}
+
+func (s *GormStore) GetAccount(householdID, id uint) (models.Account, error) {
+	return models.GetAccount(s.DB, householdID, id)
+}
+
+func (s *GormStore) GetAccountByName(householdID uint, name string) (models.Account, error) {
+	return models.GetAccountByName(s.DB, householdID, name)
+}
+
+func (s *GormStore) GetAccountByNameWithValues(householdID uint, name string) (models.Account, error) {
+	return models.GetAccountByNameWithValues(s.DB, householdID, name)
+}
+
+func (s *GormStore) GetAccountWithValues(householdID, id uint) (models.Account, error) {
+	return models.GetAccountWithValues(s.DB, householdID, id)
+}
+
+func (s *GormStore) UpdateAccount(householdID uint, name string, updates models.Account) (models.Account, error) {
+	return models.UpdateAccount(s.DB, householdID, name, updates)
+}
+
+func (s *GormStore) UpdateAccountByID(householdID, id uint, updates models.Account) (models.Account, error) {
+	return models.UpdateAccountByID(s.DB, householdID, id, updates)
+}
+
+func (s *GormStore) DeleteAccount(householdID uint, name string) (models.Account, error) {
+	return models.DeleteAccount(s.DB, householdID, name)
+}
+
+func (s *GormStore) DeleteAccountByID(householdID, id uint) (models.Account, error) {
+	return models.DeleteAccountByID(s.DB, householdID, id)
+}
+
+func (s *GormStore) CreateAccountValue(householdID uint, av models.AccountValue) (models.AccountValue, error) {
+	return models.CreateAccountValue(s.DB, householdID, av)
+}
+
+func (s *GormStore) AccountValueExists(householdID uint, av models.AccountValue) (bool, error) {
+	return models.AccountValueExists(s.DB, householdID, av)
+}
+
+func (s *GormStore) GetAccountValue(householdID, id uint) (models.AccountValue, error) {
+	return models.GetAccountValue(s.DB, householdID, id)
+}
+
+func (s *GormStore) UpdateAccountValue(householdID, id uint, updates models.AccountValue) (models.AccountValue, error) {
+	return models.UpdateAccountValue(s.DB, householdID, id, updates)
+}
+
+func (s *GormStore) DeleteAccountValue(householdID, id uint) (models.AccountValue, error) {
+	return models.DeleteAccountValue(s.DB, householdID, id)
+}
+
+func (s *GormStore) IsHouseholdMember(householdID, userID uint) (bool, error) {
+	return models.IsHouseholdMember(s.DB, householdID, userID)
+}
+
+func (s *GormStore) SaveFXRate(rate models.FXRate) (models.FXRate, error) {
+	return models.SaveFXRate(s.DB, rate)
+}
+
+func (s *GormStore) GetFXRates(currency models.Currency) ([]models.FXRate, error) {
+	return models.GetFXRates(s.DB, currency)
+}
+
+func (s *GormStore) CreateSecurity(security models.Security) (models.Security, error) {
+	return models.CreateSecurity(s.DB, security)
+}
+
+func (s *GormStore) GetSecurities() ([]models.Security, error) {
+	return models.GetSecurities(s.DB)
+}
+
+func (s *GormStore) GetSecurity(id uint) (models.Security, error) {
+	return models.GetSecurity(s.DB, id)
+}
+
+func (s *GormStore) SavePrice(price models.Price) (models.Price, error) {
+	return models.SavePrice(s.DB, price)
+}
+
+func (s *GormStore) GetPrices(securityIDs []uint) ([]models.Price, error) {
+	return models.GetPrices(s.DB, securityIDs)
+}
+
+func (s *GormStore) CreatePosition(householdID uint, position models.Position) (models.Position, error) {
+	return models.CreatePosition(s.DB, householdID, position)
+}
+
+func (s *GormStore) GetPositions(householdID, accountID uint) ([]models.Position, error) {
+	return models.GetPositions(s.DB, householdID, accountID)
+}
+
+func (s *GormStore) GetAllPositions(householdID uint) ([]models.Position, error) {
+	return models.GetAllPositions(s.DB, householdID)
+}
+
+func (s *GormStore) DeletePosition(householdID, id uint) (models.Position, error) {
+	return models.DeletePosition(s.DB, householdID, id)
+}
+
+func (s *GormStore) CreateLot(householdID uint, lot models.Lot) (models.Lot, error) {
+	return models.CreateLot(s.DB, householdID, lot)
+}
+
+func (s *GormStore) CreateSale(householdID uint, sale models.Sale) (models.Sale, error) {
+	return models.CreateSale(s.DB, householdID, sale)
+}
+
+func (s *GormStore) GetAllLots(householdID uint) ([]models.Lot, error) {
+	return models.GetAllLots(s.DB, householdID)
+}
+
+func (s *GormStore) GetAllSales(householdID uint) ([]models.Sale, error) {
+	return models.GetAllSales(s.DB, householdID)
+}
+
+func (s *GormStore) CreateCashFlow(householdID uint, flow models.CashFlow) (models.CashFlow, error) {
+	return models.CreateCashFlow(s.DB, householdID, flow)
+}
+
+func (s *GormStore) GetCashFlows(householdID, accountID uint) ([]models.CashFlow, error) {
+	return models.GetCashFlows(s.DB, householdID, accountID)
+}
+
+func (s *GormStore) GetAllCashFlows(householdID uint) ([]models.CashFlow, error) {
+	return models.GetAllCashFlows(s.DB, householdID)
+}
+
+func (s *GormStore) DeleteCashFlow(householdID, id uint) (models.CashFlow, error) {
+	return models.DeleteCashFlow(s.DB, householdID, id)
+}
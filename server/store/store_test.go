@@ -0,0 +1,661 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jrc356/financial_dashboard/migrations"
+	"github.com/Jrc356/financial_dashboard/models"
+	"github.com/go-playground/assert/v2"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestParseDriver(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Driver
+		wantErr bool
+	}{
+		{input: "postgres", want: Postgres},
+		{input: "sqlite", want: SQLite},
+		{input: "mysql", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			driver, err := ParseDriver(test.input)
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.want, driver)
+		})
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	assert.Equal(t,
+		"data.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+		sqliteDSN("data.db"),
+	)
+	assert.Equal(t,
+		"file:data.db?mode=rwc&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+		sqliteDSN("file:data.db?mode=rwc"),
+	)
+}
+
+// openSQLite returns a migrated SQLite database in a temporary directory.
+func openSQLite(t *testing.T) *gorm.DB {
+	db, err := Open(
+		Config{Driver: SQLite, DSN: filepath.Join(t.TempDir(), "test.db")},
+		&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		d, _ := db.DB()
+		d.Close()
+	})
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	db := openSQLite(t)
+
+	statuses, err := migrations.GetStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		assert.Equal(t, true, status.Applied)
+	}
+
+	reverted, err := migrations.Down(db, len(migrations.Migrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(migrations.Migrations), len(reverted))
+	assert.Equal(t, false, db.Migrator().HasTable("accounts"))
+}
+
+func TestGormStoreSQLite(t *testing.T) {
+	db := openSQLite(t)
+	testStore(t, NewGormStore(db))
+
+	users := make([]models.User, 3)
+	for i, username := range []string{"alex", "sam", "neighbour"} {
+		householdID := household
+		if username == "neighbour" {
+			householdID = 2
+		}
+		user, err := models.CreateUser(db, householdID, username, "correct horse", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[i] = user
+	}
+	testAccountOwners(t, NewGormStore(db), users[0].ID, users[1].ID, users[2].ID)
+}
+
+// TestGormStoreAccountValuesStayInHousehold checks that values sent along
+// with an account, as the accounts API accepts them, cannot move another
+// household's values into it.
+func TestGormStoreAccountValuesStayInHousehold(t *testing.T) {
+	const other uint = 2
+	s := NewGormStore(openSQLite(t))
+
+	checking, err := s.CreateAccount(household, models.Account{Name: "Checking", Class: models.Asset, Category: models.Cash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := s.CreateAccountValue(household, models.AccountValue{AccountID: checking.ID, Value: decimal.NewFromInt(100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stolen := []models.AccountValue{{ID: value.ID, AccountID: checking.ID, Value: decimal.NewFromInt(1)}}
+	loot, err := s.CreateAccount(other, models.Account{ID: checking.ID, Name: "loot", Class: models.Asset, Category: models.Cash, Values: stolen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, checking.ID, loot.ID)
+	if _, err := s.UpdateAccount(other, "loot", models.Account{Name: "loot", Class: models.Asset, Category: models.Cash, Values: stolen}); err != nil {
+		t.Fatal(err)
+	}
+
+	loot, err = s.GetAccountWithValues(other, loot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(loot.Values))
+	got, err := s.GetAccountValue(household, value.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, checking.ID, got.AccountID)
+	assert.Equal(t, "100", got.Value.String())
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	testStore(t, s)
+
+	s.AddMember(household, 1)
+	s.AddMember(household, 2)
+	s.AddMember(2, 3)
+	testAccountOwners(t, s, 1, 2, 3)
+}
+
+// household owns the accounts created by the store tests.
+const household uint = 1
+
+// testStore checks the behaviour every Store implementation must share.
+func testStore(t *testing.T, s Store) {
+	checking, err := s.CreateAccount(household, models.Account{Name: "Checking", Class: models.Asset, Category: models.Cash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.CreateAccount(household, models.Account{Name: "Savings", Class: models.Asset, Category: models.Cash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jan := time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC)
+	for i, value := range []string{"1520.25", "1610.10"} {
+		_, err := s.CreateAccountValue(household, models.AccountValue{
+			AccountID: checking.ID,
+			Value:     decimal.RequireFromString(value),
+			AsOf:      jan.AddDate(0, i, 0),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	account, err := s.GetAccountWithValues(household, checking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(account.Values))
+	assert.Equal(t, "1610.1", account.Values[0].Value.String())
+	assert.Equal(t, true, jan.Equal(account.Values[1].AsOf))
+
+	exists, err := s.AccountValueExists(household, models.AccountValue{AccountID: checking.ID, Value: decimal.RequireFromString("1520.25"), AsOf: jan})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, true, exists)
+
+	_, err = s.UpdateAccountByID(household, checking.ID, models.Account{Name: "Savings", Class: models.Asset, Category: models.Cash})
+	assert.Equal(t, true, errors.Is(err, models.ErrAccountNameTaken))
+
+	renamed, err := s.UpdateAccountByID(household, checking.ID, models.Account{Name: "Joint Checking", Class: models.Asset, Category: models.Cash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Joint Checking", renamed.Name)
+
+	if _, err := s.DeleteAccount(household, "Savings"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetAccountByName(household, "Savings")
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+
+	// The unique index on names ignores deleted accounts, so the name can be
+	// reused.
+	if _, err := s.CreateAccount(household, models.Account{Name: "Savings", Class: models.Asset, Category: models.Cash}); err != nil {
+		t.Fatal(err)
+	}
+
+	accounts, err := s.GetAllAccountsWithValues(household)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(accounts))
+
+	accounts, err = s.GetAllAccountsWithValuesIncludingDeleted(household)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, len(accounts))
+
+	_, err = s.GetAccountValue(household, 1000)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+
+	latest := account.Values[0]
+	updated, err := s.UpdateAccountValue(household, latest.ID, models.AccountValue{Value: decimal.RequireFromString("1700.123456789")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "1700.12345679", updated.Value.String())
+	assert.Equal(t, true, latest.AsOf.Equal(updated.AsOf))
+
+	if _, err := s.DeleteAccountValue(household, latest.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetAccountValue(household, latest.ID)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+
+	hsa, err := s.UpdateAccount(household, "Savings", models.Account{Name: "Savings", Class: models.Asset, Category: models.HSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, models.HSA, hsa.Category)
+
+	_, err = s.CreateAccount(household, models.Account{Name: "Mortgage", Class: models.Liability, Category: models.Loan})
+	if err != nil {
+		t.Fatal(err)
+	}
+	liabilities, err := s.GetAllAccountsByClassWithValues(household, models.Liability)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(liabilities))
+	assert.Equal(t, "Mortgage", liabilities[0].Name)
+
+	assert.Equal(t, models.DefaultCurrency, checking.Currency)
+	euro, err := s.UpdateAccountByID(household, checking.ID, models.Account{Name: "Joint Checking", Class: models.Asset, Category: models.Cash, Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, models.Currency("EUR"), euro.Currency)
+
+	testHouseholdIsolation(t, s, checking.ID, account.Values[1].ID)
+	testFXRates(t, s)
+	testHoldings(t, s)
+	testLots(t, s)
+	testCashFlows(t, s)
+}
+
+// testFXRates checks rates are found quoted either way round and that saving a
+// rate for the same pair and date replaces it.
+func testFXRates(t *testing.T, s Store) {
+	jan := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rates := []models.FXRate{
+		{Date: jan.AddDate(0, 1, 0), Base: "EUR", Quote: "USD", Rate: decimal.RequireFromString("1.08")},
+		{Date: jan, Base: "EUR", Quote: "USD", Rate: decimal.RequireFromString("1.07")},
+		{Date: jan, Base: "USD", Quote: "JPY", Rate: decimal.RequireFromString("130.5")},
+		{Date: jan, Base: "EUR", Quote: "USD", Rate: decimal.RequireFromString("1.06")},
+	}
+	for _, rate := range rates {
+		if _, err := s.SaveFXRate(rate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := s.SaveFXRate(models.FXRate{Date: jan, Base: "EUR", Quote: "EUR", Rate: decimal.NewFromInt(1)})
+	assert.NotEqual(t, nil, err)
+
+	euro, err := s.GetFXRates("EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(euro))
+	assert.Equal(t, "1.06", euro[0].Rate.String())
+	assert.Equal(t, "1.08", euro[1].Rate.String())
+
+	yen, err := s.GetFXRates("JPY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(yen))
+	assert.Equal(t, models.Currency("USD"), yen[0].Base)
+}
+
+// testHoldings checks that positions are kept per account and household, and
+// that saving a price for the same security and date replaces it.
+func testHoldings(t *testing.T, s Store) {
+	brokerage, err := s.CreateAccount(household, models.Account{Name: "Taxable Brokerage", Class: models.Asset, Category: models.Cash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vti, err := s.CreateSecurity(models.Security{Symbol: "vti", Name: "Vanguard Total Stock Market"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "VTI", vti.Symbol)
+	assert.Equal(t, models.DefaultCurrency, vti.Currency)
+	_, err = s.CreateSecurity(models.Security{Symbol: "VTI"})
+	assert.Equal(t, true, errors.Is(err, models.ErrSecurityExists))
+	vwrl, err := s.CreateSecurity(models.Security{Symbol: "VWRL", Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	securities, err := s.GetSecurities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(securities))
+	_, err = s.GetSecurity(1000)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+
+	jan := time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC)
+	for _, price := range []models.Price{
+		{SecurityID: vti.ID, Date: jan.AddDate(0, 1, 0), Price: decimal.RequireFromString("199.07")},
+		{SecurityID: vti.ID, Date: jan, Price: decimal.RequireFromString("204")},
+		{SecurityID: vti.ID, Date: jan, Price: decimal.RequireFromString("204.123456789")},
+		{SecurityID: vwrl.ID, Date: jan, Price: decimal.RequireFromString("98.1")},
+	} {
+		if _, err := s.SavePrice(price); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prices, err := s.GetPrices([]uint{vti.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(prices))
+	assert.Equal(t, "204.12345679", prices[0].Price.String())
+
+	for i, quantity := range []string{"10", "12.5"} {
+		_, err := s.CreatePosition(household, models.Position{
+			AccountID:  brokerage.ID,
+			SecurityID: vti.ID,
+			Quantity:   decimal.RequireFromString(quantity),
+			CostBasis:  decimal.NewFromInt(2000),
+			AsOf:       jan.AddDate(0, i, 0),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = s.CreatePosition(household, models.Position{AccountID: brokerage.ID, SecurityID: vwrl.ID, Quantity: decimal.NewFromInt(1)})
+	assert.NotEqual(t, nil, err)
+
+	positions, err := s.GetPositions(household, brokerage.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(positions))
+	assert.Equal(t, "12.5", positions[0].Quantity.String())
+	assert.Equal(t, "VTI", positions[0].Security.Symbol)
+
+	const other uint = 2
+	_, err = s.GetPositions(other, brokerage.ID)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+	_, err = s.DeletePosition(other, positions[0].ID)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+	all, err := s.GetAllPositions(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(all))
+
+	if _, err := s.DeletePosition(household, positions[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	all, err = s.GetAllPositions(household)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(all))
+	assert.Equal(t, "10", all[0].Quantity.String())
+}
+
+func testLots(t *testing.T, s Store) {
+	account, err := s.CreateAccount(household, models.Account{Name: "Taxable Lots", Class: models.Asset, Category: models.Cash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vxus, err := s.CreateSecurity(models.Security{Symbol: "VXUS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jan := time.Date(2022, time.January, 3, 0, 0, 0, 0, time.UTC)
+	for i, cost := range []int64{500, 600} {
+		_, err := s.CreateLot(household, models.Lot{
+			AccountID:  account.ID,
+			SecurityID: vxus.ID,
+			Quantity:   decimal.NewFromInt(10),
+			Cost:       decimal.NewFromInt(cost),
+			AcquiredAt: jan.AddDate(0, i, 0),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = s.CreateLot(household, models.Lot{AccountID: account.ID, SecurityID: vxus.ID, Quantity: decimal.NewFromInt(1), AcquiredAt: jan})
+	assert.Equal(t, true, errors.Is(err, models.ErrTradeOutOfOrder))
+
+	sale, err := s.CreateSale(household, models.Sale{
+		AccountID:  account.ID,
+		SecurityID: vxus.ID,
+		Quantity:   decimal.NewFromInt(12),
+		Proceeds:   decimal.NewFromInt(720),
+		SoldAt:     jan.AddDate(0, 3, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, models.FIFO, sale.Method)
+	assert.Equal(t, 2, len(sale.Matches))
+	assert.Equal(t, "620", models.SaleCost(sale).String())
+
+	_, err = s.CreateSale(household, models.Sale{AccountID: account.ID, SecurityID: vxus.ID, Quantity: decimal.NewFromInt(9), SoldAt: jan.AddDate(0, 4, 0)})
+	assert.Equal(t, true, errors.Is(err, models.ErrInsufficientLots))
+	_, err = s.CreateLot(2, models.Lot{AccountID: account.ID, SecurityID: vxus.ID, Quantity: decimal.NewFromInt(1)})
+	assert.NotEqual(t, nil, err)
+
+	positions, err := s.GetPositions(household, account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, len(positions))
+	assert.Equal(t, "8", positions[0].Quantity.String())
+	assert.Equal(t, "480", positions[0].CostBasis.String())
+
+	lots, err := s.GetAllLots(household)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(lots))
+	assert.Equal(t, "VXUS", lots[0].Security.Symbol)
+	sales, err := s.GetAllSales(household)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(sales))
+	assert.Equal(t, 2, len(sales[0].Matches))
+	assert.Equal(t, "0", models.OpenQuantity(lots[0], sales, sale.SoldAt).String())
+	assert.Equal(t, "8", models.OpenQuantity(lots[1], sales, sale.SoldAt).String())
+
+	other, err := s.GetAllLots(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(other))
+}
+
+func testCashFlows(t *testing.T, s Store) {
+	account, err := s.CreateAccount(household, models.Account{Name: "401k", Class: models.Asset, Category: models.Cash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jan := time.Date(2023, time.January, 15, 0, 0, 0, 0, time.UTC)
+	for i, flow := range []models.CashFlow{
+		{AccountID: account.ID, Type: models.Contribution, Amount: decimal.RequireFromString("500.123456789")},
+		{AccountID: account.ID, Type: models.Transfer, Amount: decimal.NewFromInt(-100), Memo: "to savings"},
+	} {
+		flow.Date = jan.AddDate(0, i, 0)
+		if _, err := s.CreateCashFlow(household, flow); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = s.CreateCashFlow(household, models.CashFlow{AccountID: account.ID, Type: models.Fee, Amount: decimal.NewFromInt(-1)})
+	assert.NotEqual(t, nil, err)
+	_, err = s.CreateCashFlow(2, models.CashFlow{AccountID: account.ID, Type: models.Fee, Amount: decimal.NewFromInt(1)})
+	assert.NotEqual(t, nil, err)
+
+	flows, err := s.GetCashFlows(household, account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(flows))
+	assert.Equal(t, models.Transfer, flows[0].Type)
+	assert.Equal(t, "500.12345679", flows[1].Amount.String())
+
+	const other uint = 2
+	_, err = s.GetCashFlows(other, account.ID)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+	_, err = s.DeleteCashFlow(other, flows[0].ID)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+
+	if _, err := s.DeleteCashFlow(household, flows[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	all, err := s.GetAllCashFlows(household)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(all))
+	assert.Equal(t, models.Contribution, all[0].Type)
+}
+
+// testHouseholdIsolation checks another household can neither see nor change
+// the first household's account and value.
+func testHouseholdIsolation(t *testing.T, s Store, accountID, valueID uint) {
+	const other uint = 2
+
+	accounts, err := s.GetAllAccountsWithValuesIncludingDeleted(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(accounts))
+
+	exists, _ := s.AccountExistsByID(other, accountID)
+	assert.Equal(t, false, exists)
+	_, err = s.GetAccountWithValues(other, accountID)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+	_, err = s.DeleteAccountByID(other, accountID)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+	_, err = s.GetAccountValue(other, valueID)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+	_, err = s.CreateAccountValue(other, models.AccountValue{AccountID: accountID, Value: decimal.NewFromInt(1)})
+	assert.NotEqual(t, nil, err)
+
+	// Names only need to be unique within a household.
+	if _, err := s.CreateAccount(other, models.Account{Name: "Savings", Class: models.Asset, Category: models.Cash}); err != nil {
+		t.Fatal(err)
+	}
+	exists, _ = s.AccountExistsByID(household, accountID)
+	assert.Equal(t, true, exists)
+}
+
+// testAccountOwners checks that owners are kept with an account and replaced
+// when it is updated with owners, and that only members of the household can
+// own it. first and second are members of the household, outsider is not.
+func testAccountOwners(t *testing.T, s Store, first, second, outsider uint) {
+	isMember, err := s.IsHouseholdMember(household, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, true, isMember)
+	isMember, _ = s.IsHouseholdMember(household, outsider)
+	assert.Equal(t, false, isMember)
+
+	brokerage := models.Account{
+		Name:     "Brokerage",
+		Class:    models.Asset,
+		Category: models.Cash,
+		Owners: []models.AccountOwner{
+			{UserID: first, Share: decimal.NewFromInt(60)},
+			{UserID: second, Share: decimal.RequireFromString("40.001")},
+		},
+	}
+	created, err := s.CreateAccount(household, brokerage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := s.GetAccountWithValues(household, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(account.Owners))
+	share, ok := account.Share(second)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "40", share.String())
+
+	_, err = s.CreateAccount(household, models.Account{
+		Name:     "Neighbour's Savings",
+		Class:    models.Asset,
+		Category: models.Cash,
+		Owners:   []models.AccountOwner{{UserID: outsider, Share: decimal.NewFromInt(100)}},
+	})
+	assert.Equal(t, true, errors.Is(err, models.ErrNotHouseholdMember))
+
+	brokerage.Owners = []models.AccountOwner{{UserID: outsider, Share: decimal.NewFromInt(100)}}
+	_, err = s.UpdateAccountByID(household, created.ID, brokerage)
+	assert.Equal(t, true, errors.Is(err, models.ErrNotHouseholdMember))
+
+	brokerage.Owners = []models.AccountOwner{{UserID: first, Share: decimal.NewFromInt(100)}}
+	if _, err := s.UpdateAccountByID(household, created.ID, brokerage); err != nil {
+		t.Fatal(err)
+	}
+	account, _ = s.GetAccountWithValues(household, created.ID)
+	assert.Equal(t, 1, len(account.Owners))
+	_, ok = account.Share(second)
+	assert.Equal(t, false, ok)
+
+	// Updates without owners leave them alone, an empty list removes them.
+	brokerage.Owners = nil
+	brokerage.Category = models.Retirement
+	brokerage.TaxBucket = models.Taxable
+	if _, err := s.UpdateAccountByID(household, created.ID, brokerage); err != nil {
+		t.Fatal(err)
+	}
+	account, _ = s.GetAccountWithValues(household, created.ID)
+	assert.Equal(t, 1, len(account.Owners))
+
+	brokerage.Owners = []models.AccountOwner{}
+	if _, err := s.UpdateAccount(household, "Brokerage", brokerage); err != nil {
+		t.Fatal(err)
+	}
+	account, _ = s.GetAccountWithValues(household, created.ID)
+	assert.Equal(t, 0, len(account.Owners))
+}
+
+func TestMemoryStoreFixtures(t *testing.T) {
+	s := NewMemoryStore()
+
+	deletedAt := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	_, err := s.CreateAccount(household, models.Account{
+		ID:        7,
+		Name:      "Old Checking",
+		Class:     models.Asset,
+		Category:  models.Cash,
+		Values:    []models.AccountValue{{Value: decimal.NewFromInt(10), AsOf: deletedAt.AddDate(0, -1, 0)}},
+		DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.CreateAccount(household, models.Account{ID: 7, Name: "Checking", Class: models.Asset, Category: models.Cash})
+	assert.NotEqual(t, nil, err)
+
+	account, err := s.CreateAccount(household, models.Account{Name: "Old Checking", Class: models.Asset, Category: models.Cash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint(8), account.ID)
+
+	exists, _ := s.AccountExistsByID(household, 7)
+	assert.Equal(t, false, exists)
+
+	accounts, _ := s.GetAllAccountsWithValuesIncludingDeleted(household)
+	assert.Equal(t, 2, len(accounts))
+	assert.Equal(t, true, deletedAt.Equal(accounts[0].DeletedAt.Time))
+	assert.Equal(t, uint(7), accounts[0].Values[0].AccountID)
+
+	_, err = s.GetAccountValue(household, accounts[0].Values[0].ID)
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+}